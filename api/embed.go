@@ -2,5 +2,7 @@ package api
 
 import _ "embed"
 
+//go:generate protoc --go_out=bankpb --go_opt=paths=source_relative --go-grpc_out=bankpb --go-grpc_opt=paths=source_relative bank.proto
+
 //go:embed openapi.json
 var OpenAPISpec []byte
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,11 +14,17 @@ import (
 
 	"github.com/vitaliy-ukiru/bank-service/internal/application"
 	"github.com/vitaliy-ukiru/bank-service/internal/config"
-	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/jwtauth"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/publish"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/storage"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/webhook"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/grpcapi"
 	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi"
 	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/controllers"
-	"github.com/vitaliy-ukiru/bank-service/pkg/client/pg"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/middlewares"
 	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -29,46 +36,175 @@ func main() {
 		panic(fmt.Errorf("fail load config:%w", err))
 	}
 
-	cfg := config.Get()
+	if err := run(config.Get()); err != nil {
+		os.Exit(1)
+	}
+}
 
+// run starts application and blocks until shutdown signal. Errors are logged
+// before return, so deferred cleanup runs before main exits.
+func run(cfg config.Config) error {
 	log := logging.New(os.Stdout, cfg.Env == config.EnvDev)
+	logging.ConfigureLogLogger(log, slog.LevelInfo)
 
 	log.Info("InitConfig", "config initialized", logging.String("env", string(cfg.Env)))
 
-	db, err := pg.New(context.Background(), pg.ConnString(
-		cfg.Database.User,
-		cfg.Database.Password,
-		cfg.Database.Database,
-		cfg.Database.Host,
-		cfg.Database.Port,
-	))
+	st, err := storage.New(context.Background(), cfg, log)
+	if err != nil {
+		log.Error("InitStorage", "fail init storage", err, logging.String("backend", string(cfg.StorageBackend)))
+		return err
+	}
+	defer st.Close()
 
+	conversion, err := conversionPolicy(cfg.Exchange)
 	if err != nil {
-		log.Error("InitPostgres", "fail init postgres", err)
-		os.Exit(1)
+		log.Error("InitExchangeRates", "invalid conversion policy", err)
+		return err
 	}
 
-	defer db.Close()
+	accountService := application.NewAccountService(
+		st.Locker,
+		st.Repo,
+		st.Rates,
+		conversion,
+		cfg.Holds.TTL,
+		account.StatusPolicy{FrozenAcceptsDeposits: cfg.Accounts.FrozenAcceptDeposits},
+		cfg.Idempotency.KeyRetention,
+	)
 
-	if err := db.Ping(context.Background()); err != nil {
-		log.Error("PingDatabase", "fail ping database", err)
-		os.Exit(1)
+	accountController := controllers.NewAccountController(accountService)
+	webhookController := controllers.NewWebhookController(application.NewWebhookService(st.Webhooks))
+	balanceStream := application.NewBalanceStream(st.Repo, cfg.Stream.PollInterval, cfg.Stream.StallTimeout)
+	streamController := controllers.NewStreamController(balanceStream, cfg.Stream.AllowedOrigins)
+
+	apiKeyService, err := application.NewAPIKeyService(st.APIKeys, cfg.Auth.BootstrapKey)
+	if err != nil {
+		log.Error("InitAuth", "fail init api keys", err)
+		return err
+	}
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+	// nil authenticator disables authentication of APIs
+	var (
+		authenticator middlewares.Authenticator
+		jwtVerifier   *jwtauth.Verifier
+	)
+	if cfg.Auth.Enabled {
+		if cfg.Auth.BootstrapKey == "" {
+			if cfg.StorageBackend == config.StorageMemory {
+				// bankctl can't issue keys into memory storage of this process, no request would be accepted
+				err := errors.New("set AUTH_BOOTSTRAP_KEY or disable authentication by AUTH_ENABLED=false")
+				log.Error("InitAuth", "memory storage requires bootstrap key", err)
+				return err
+			}
+			log.Info("InitAuth", "authentication is enabled without bootstrap key, keys must be issued by bankctl")
+		}
+		// nil verifier rejects bearer tokens
+		var tokens application.TokenVerifier
+		if cfg.Auth.JWT.Enabled() {
+			if jwtVerifier, err = jwtauth.New(cfg.Auth.JWT); err != nil {
+				log.Error("InitAuth", "fail init jwt verifier", err)
+				return err
+			}
+			tokens = jwtVerifier
+		}
+		authenticator = application.NewAuthenticator(apiKeyService, tokens)
 	}
 
-	accountsRepository := accounts.NewRepository(db)
-	accountService := application.NewAccountService(accountsRepository, accountsRepository)
+	workersCtx, stopWorkers := context.WithCancel(logging.Context(context.Background(), log))
+	defer stopWorkers()
 
-	accountController := controllers.NewAccountController(accountService)
+	if jwtVerifier != nil {
+		go jwtVerifier.Run(workersCtx)
+	}
+
+	idempotencyCleaner := application.NewIdempotencyCleaner(
+		st.Repo,
+		cfg.Idempotency.KeyRetention,
+		cfg.Idempotency.CleanupInterval,
+	)
+	go idempotencyCleaner.Run(workersCtx)
+
+	holdSweeper := application.NewHoldSweeper(
+		st.Repo,
+		st.Locker,
+		cfg.Holds.SweepInterval,
+	)
+	go holdSweeper.Run(workersCtx)
+
+	publisher, closePublisher, err := newPublisher(cfg.Outbox)
+	if err != nil {
+		log.Error("InitOutbox", "fail init outbox publisher", err)
+		return err
+	}
+	defer closePublisher()
+	var dispatcher application.Publisher = application.NewWebhookDispatcher(st.Webhooks)
+	if publisher != nil {
+		dispatcher = publish.NewFanout(dispatcher, publisher)
+	}
+	relay := application.NewOutboxRelay(
+		st.Repo,
+		dispatcher,
+		application.OutboxRetryPolicy{
+			MaxAttempts: cfg.Outbox.MaxAttempts,
+			Backoff:     cfg.Outbox.Backoff,
+			MaxBackoff:  cfg.Outbox.MaxBackoff,
+		},
+		cfg.Outbox.RelayInterval,
+		cfg.Outbox.BatchSize,
+	)
+	go relay.Run(workersCtx)
 
-	apiServer := webapi.New(cfg, accountController, log)
+	webhookSender := application.NewWebhookSender(
+		st.Webhooks,
+		webhook.NewClient(cfg.Webhooks.Timeout),
+		application.WebhookRetryPolicy{
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			Backoff:     cfg.Webhooks.Backoff,
+			MaxBackoff:  cfg.Webhooks.MaxBackoff,
+		},
+		cfg.Webhooks.SendInterval,
+		cfg.Webhooks.BatchSize,
+	)
+	go webhookSender.Run(workersCtx)
 
+	// stopped before server shutdown, so open streams don't delay it
+	go balanceStream.Run(workersCtx)
+
+	for _, worker := range st.Workers {
+		go worker(workersCtx)
+	}
+
+	apiServer := webapi.New(
+		cfg,
+		accountController,
+		webhookController,
+		streamController,
+		apiKeyController,
+		authenticator,
+		log,
+	)
+
+	// failure of any server stops application as shutdown signal
+	serveErr := make(chan error, 2)
 	go func() {
 		if err := apiServer.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error("StartServer", "fail run server", err)
-			os.Exit(1)
+			serveErr <- err
 		}
 	}()
 
+	var grpcServer *grpcapi.Server
+	if cfg.GRPC.Enabled {
+		grpcServer = grpcapi.New(cfg, grpcapi.NewAccountServer(accountService, balanceStream), authenticator, log)
+		go func() {
+			if err := grpcServer.Start(); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				log.Error("StartGRPCServer", "fail run grpc server", err)
+				serveErr <- err
+			}
+		}()
+	}
+
+	var runErr error
 	{
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit,
@@ -77,14 +213,59 @@ func main() {
 			syscall.SIGKILL,
 			syscall.SIGTERM,
 		)
-		sig := <-quit
-		log.Info("shutdown", "shutdown app", logging.String("signal", sig.String()))
+		select {
+		case sig := <-quit:
+			log.Info("shutdown", "shutdown app", logging.String("signal", sig.String()))
+		case runErr = <-serveErr:
+		}
 	}
 
+	stopWorkers()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	if grpcServer != nil {
+		if err := grpcServer.Shutdown(ctx); err != nil {
+			log.Error("ShutdownGRPCServer", "fail shutdown grpc server", err)
+		}
+	}
 	if err := apiServer.Shutdown(ctx); err != nil {
 		log.Error("ShutdownServer", "fail shutdown server", err)
-		os.Exit(1)
+		return err
+	}
+	return runErr
+}
+
+func conversionPolicy(cfg config.ExchangeConfig) (account.ConversionPolicy, error) {
+	spread, err := account.ParseRate(cfg.Spread)
+	if err != nil {
+		return account.ConversionPolicy{}, err
+	}
+	policy := account.ConversionPolicy{
+		Spread:   spread,
+		Rounding: account.RoundingMode(cfg.Rounding),
+	}
+	return policy, policy.Validate()
+}
+
+// newPublisher returns nil publisher if messages are delivered only to webhooks.
+func newPublisher(cfg config.OutboxConfig) (application.Publisher, func(), error) {
+	switch cfg.Publisher {
+	case config.OutboxPublisherNone:
+		return nil, func() {}, nil
+	case config.OutboxPublisherStdout:
+		return publish.NewWriterPublisher(os.Stdout), func() {}, nil
+	case config.OutboxPublisherFile:
+		p, err := publish.OpenFilePublisher(cfg.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		return p, func() { p.Close() }, nil
+	case config.OutboxPublisherHTTP:
+		if cfg.URL == "" {
+			return nil, nil, fmt.Errorf("outbox publisher %q requires url", cfg.Publisher)
+		}
+		return publish.NewHTTPPublisher(cfg.URL, cfg.HTTPTimeout), func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown outbox publisher %q", cfg.Publisher)
 	}
 }
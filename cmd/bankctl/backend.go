@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/config"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/storage"
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/bank"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
+)
+
+// backend is part of API client used by commands, it is implemented by
+// bank.Client and by storageBackend of admin mode.
+type backend interface {
+	CreateAccount(ctx context.Context, currency string) (int64, error)
+	Deposit(ctx context.Context, accountId int64, amount, currency string) error
+	Withdraw(ctx context.Context, accountId int64, amount, currency string) error
+	GetBalance(ctx context.Context, accountId int64) (bank.Balance, error)
+	ListAccounts(ctx context.Context, filter bank.AccountsFilter) (bank.AccountsPage, error)
+	ListTransactions(ctx context.Context, accountId int64, filter bank.TransactionsFilter) (bank.TransactionsPage, error)
+	IssueAPIKey(ctx context.Context, req bank.IssueAPIKeyRequest) (bank.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]bank.APIKey, error)
+	RotateAPIKey(ctx context.Context, keyId string, gracePeriod time.Duration) (bank.APIKey, error)
+	RevokeAPIKey(ctx context.Context, keyId string) (bank.APIKey, error)
+}
+
+func openBackend(ctx context.Context, opts options, log logging.Logger) (backend, func(), error) {
+	if !opts.admin {
+		return bank.New(opts.url, bank.WithAPIKey(opts.apiKey)), func() {}, nil
+	}
+
+	cfg := opts.cfg
+	if cfg.StorageBackend == config.StorageMemory {
+		// data of memory storage is owned by process of service
+		return nil, nil, fmt.Errorf("admin mode requires postgres or sqlite storage backend")
+	}
+	if cfg.Concurrency.Acquirer == config.AcquirerMemory {
+		// service with memory acquirer saves accounts without locks of storage,
+		// so locks of bankctl don't exclude it and its changes would be lost
+		return nil, nil, fmt.Errorf("admin mode requires repository or advisory acquirer of service (ACQUIRER), memory one locks only within process")
+	}
+
+	st, err := storage.New(ctx, cfg, log)
+	if err != nil {
+		return nil, nil, fmt.Errorf("init storage: %w", err)
+	}
+	service := application.NewAccountService(
+		st.Locker,
+		st.Repo,
+		st.Rates,
+		// transfers aren't made by bankctl, conversion policy is never used
+		account.ConversionPolicy{Rounding: account.RoundHalfEven},
+		cfg.Holds.TTL,
+		account.StatusPolicy{FrozenAcceptsDeposits: cfg.Accounts.FrozenAcceptDeposits},
+		cfg.Idempotency.KeyRetention,
+	)
+	// keys are managed without bootstrap key, it is used only by authentication
+	apiKeys, err := application.NewAPIKeyService(st.APIKeys, "")
+	if err != nil {
+		st.Close()
+		return nil, nil, err
+	}
+	return &storageBackend{service: service, apiKeys: apiKeys}, st.Close, nil
+}
+
+// storageBackend runs commands with AccountService over storage of service.
+// Changes are saved with outbox messages, so relay of running service publishes them.
+// API keys can be issued by it before any key exists.
+type storageBackend struct {
+	service *application.AccountService
+	apiKeys *application.APIKeyService
+}
+
+// withRequestId marks journal entries of operation like requests of API do.
+func withRequestId(ctx context.Context) context.Context {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return requestid.Context(ctx, "bankctl-"+hex.EncodeToString(b[:]))
+}
+
+func (s *storageBackend) CreateAccount(ctx context.Context, currency string) (int64, error) {
+	return s.service.CreateAccount(withRequestId(ctx), application.CreateAccountCommand{
+		Currency: account.Currency(currency),
+	})
+}
+
+func (s *storageBackend) Deposit(ctx context.Context, accountId int64, amount, currency string) error {
+	money, cur, err := parseAmount(amount, currency)
+	if err != nil {
+		return err
+	}
+	return s.service.DepositBalance(withRequestId(ctx), application.DepositBalanceCommand{
+		AccountId: accountId,
+		Amount:    money,
+		Currency:  cur,
+	})
+}
+
+func (s *storageBackend) Withdraw(ctx context.Context, accountId int64, amount, currency string) error {
+	money, cur, err := parseAmount(amount, currency)
+	if err != nil {
+		return err
+	}
+	return s.service.WithdrawBalance(withRequestId(ctx), application.WithdrawBalanceCommand{
+		AccountId: accountId,
+		Amount:    money,
+		Currency:  cur,
+	})
+}
+
+// parseAmount returns empty currency for empty code.
+func parseAmount(amount, currency string) (account.Money, account.Currency, error) {
+	money, err := account.ParseMoney(amount)
+	if err != nil {
+		return account.Money{}, "", err
+	}
+	if currency == "" {
+		return money, "", nil
+	}
+	cur, err := account.ParseCurrency(currency)
+	return money, cur, err
+}
+
+func (s *storageBackend) GetBalance(ctx context.Context, accountId int64) (bank.Balance, error) {
+	balance, err := s.service.GetBalance(ctx, application.GetBalanceCommand{AccountId: accountId})
+	if err != nil {
+		return bank.Balance{}, err
+	}
+	return balanceOf(balance), nil
+}
+
+func (s *storageBackend) ListAccounts(ctx context.Context, filter bank.AccountsFilter) (bank.AccountsPage, error) {
+	page, err := s.service.ListAccounts(ctx, application.ListAccountsCommand{
+		Cursor: filter.Cursor,
+		Limit:  filter.Limit,
+		Status: account.Status(filter.Status),
+	})
+	if err != nil {
+		return bank.AccountsPage{}, err
+	}
+
+	result := bank.AccountsPage{
+		Items:      make([]bank.AccountSummary, 0, len(page.Items)),
+		NextCursor: page.NextCursor,
+	}
+	for _, summary := range page.Items {
+		result.Items = append(result.Items, bank.AccountSummary{
+			Id:      summary.Id,
+			Balance: balanceOf(summary.AccountBalance),
+		})
+	}
+	return result, nil
+}
+
+func (s *storageBackend) ListTransactions(ctx context.Context, accountId int64, filter bank.TransactionsFilter) (bank.TransactionsPage, error) {
+	types := make([]account.OperationType, 0, len(filter.Types))
+	for _, raw := range filter.Types {
+		t, err := account.ParseOperationType(raw)
+		if err != nil {
+			return bank.TransactionsPage{}, err
+		}
+		types = append(types, t)
+	}
+
+	page, err := s.service.ListTransactions(ctx, application.ListTransactionsCommand{
+		AccountId: accountId,
+		Cursor:    filter.Cursor,
+		Limit:     filter.Limit,
+		From:      filter.From,
+		To:        filter.To,
+		Types:     types,
+	})
+	if err != nil {
+		return bank.TransactionsPage{}, err
+	}
+
+	result := bank.TransactionsPage{
+		Items:      make([]bank.Transaction, 0, len(page.Items)),
+		NextCursor: page.NextCursor,
+	}
+	for _, entry := range page.Items {
+		result.Items = append(result.Items, transactionOf(entry))
+	}
+	return result, nil
+}
+
+func balanceOf(balance application.AccountBalance) bank.Balance {
+	return bank.Balance{
+		Balance:          balance.Balance.String(),
+		AvailableBalance: balance.Available.String(),
+		CreditLimit:      balance.CreditLimit.String(),
+		CreditHeadroom:   balance.CreditHeadroom.String(),
+		Currency:         string(balance.Currency),
+		Status:           string(balance.Status),
+	}
+}
+
+func transactionOf(entry application.JournalEntry) bank.Transaction {
+	tx := bank.Transaction{
+		Id:           entry.Id,
+		AccountId:    entry.AccountId,
+		Type:         string(entry.Type),
+		Currency:     string(entry.Currency),
+		Amount:       entry.Amount.String(),
+		BalanceAfter: entry.BalanceAfter.String(),
+		RequestId:    entry.RequestId,
+		CreatedAt:    entry.CreatedAt,
+		HoldId:       entry.HoldId,
+	}
+	if t := entry.Transfer; t != nil {
+		tx.Transfer = &bank.Transfer{
+			FromAccountId:  t.FromAccountId,
+			ToAccountId:    t.ToAccountId,
+			Debit:          t.Debit.String(),
+			DebitCurrency:  string(t.DebitCurrency),
+			Credit:         t.Credit.String(),
+			CreditCurrency: string(t.CreditCurrency),
+			Rate:           t.Rate.String(),
+			MarketRate:     t.MarketRate.String(),
+			Spread:         t.Spread.String(),
+		}
+	}
+	return tx
+}
+
+func (s *storageBackend) IssueAPIKey(ctx context.Context, req bank.IssueAPIKeyRequest) (bank.APIKey, error) {
+	scopes := make([]application.Scope, 0, len(req.Scopes))
+	for _, raw := range req.Scopes {
+		scope, err := application.ParseScope(raw)
+		if err != nil {
+			return bank.APIKey{}, err
+		}
+		scopes = append(scopes, scope)
+	}
+	issued, err := s.apiKeys.IssueAPIKey(ctx, application.IssueAPIKeyCommand{Name: req.Name, Scopes: scopes})
+	if err != nil {
+		return bank.APIKey{}, err
+	}
+	key := apiKeyOf(issued.APIKey)
+	key.Key = issued.Token
+	return key, nil
+}
+
+func (s *storageBackend) ListAPIKeys(ctx context.Context) ([]bank.APIKey, error) {
+	keys, err := s.apiKeys.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]bank.APIKey, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, apiKeyOf(key))
+	}
+	return result, nil
+}
+
+func (s *storageBackend) RotateAPIKey(ctx context.Context, keyId string, gracePeriod time.Duration) (bank.APIKey, error) {
+	issued, err := s.apiKeys.RotateAPIKey(ctx, application.RotateAPIKeyCommand{Id: keyId, GracePeriod: gracePeriod})
+	if err != nil {
+		return bank.APIKey{}, err
+	}
+	key := apiKeyOf(issued.APIKey)
+	key.Key = issued.Token
+	return key, nil
+}
+
+func (s *storageBackend) RevokeAPIKey(ctx context.Context, keyId string) (bank.APIKey, error) {
+	key, err := s.apiKeys.RevokeAPIKey(ctx, keyId)
+	if err != nil {
+		return bank.APIKey{}, err
+	}
+	return apiKeyOf(key), nil
+}
+
+func apiKeyOf(key application.APIKey) bank.APIKey {
+	scopes := make([]string, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+	result := bank.APIKey{
+		Id:        key.Id,
+		Name:      key.Name,
+		Scopes:    scopes,
+		CreatedAt: key.CreatedAt,
+		RotatedAt: key.RotatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+	if key.PreviousSecretHash != "" {
+		result.PreviousExpiresAt = key.PreviousExpiresAt
+	}
+	return result
+}
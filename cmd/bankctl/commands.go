@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/bank"
+)
+
+type command func(ctx context.Context, b backend, out *printer, args []string) error
+
+var commands = map[string]command{
+	"create":   createCommand,
+	"deposit":  depositCommand,
+	"withdraw": withdrawCommand,
+	"balance":  balanceCommand,
+	"list":     listCommand,
+	"export":   exportCommand,
+	"keys":     keysCommand,
+}
+
+// parseFlags parses flags of command, flag errors are returned as errUsage.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return nil
+}
+
+func parseAccountId(raw string) (int64, error) {
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: invalid account id %q", errUsage, raw)
+	}
+	return id, nil
+}
+
+func createCommand(ctx context.Context, b backend, out *printer, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	currency := flags.String("currency", "", "Currency of account, default currency of service if empty")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", errUsage, flags.Args())
+	}
+
+	id, err := b.CreateAccount(ctx, *currency)
+	if err != nil {
+		return err
+	}
+	return out.account(id)
+}
+
+func depositCommand(ctx context.Context, b backend, out *printer, args []string) error {
+	return moveCommand("deposit", b.Deposit)(ctx, b, out, args)
+}
+
+func withdrawCommand(ctx context.Context, b backend, out *printer, args []string) error {
+	return moveCommand("withdraw", b.Withdraw)(ctx, b, out, args)
+}
+
+// moveCommand changes balance with op and prints balance after it.
+func moveCommand(name string, op func(ctx context.Context, accountId int64, amount, currency string) error) command {
+	return func(ctx context.Context, b backend, out *printer, args []string) error {
+		flags := flag.NewFlagSet(name, flag.ContinueOnError)
+		currency := flags.String("currency", "", "Currency of operation, it must match currency of account")
+		if err := parseFlags(flags, args); err != nil {
+			return err
+		}
+		if flags.NArg() != 2 {
+			return fmt.Errorf("%w: account id and amount are required", errUsage)
+		}
+		id, err := parseAccountId(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		if err := op(ctx, id, flags.Arg(1), *currency); err != nil {
+			return err
+		}
+		balance, err := b.GetBalance(ctx, id)
+		if err != nil {
+			return err
+		}
+		return out.accounts([]bank.AccountSummary{{Id: id, Balance: balance}})
+	}
+}
+
+func balanceCommand(ctx context.Context, b backend, out *printer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: account id is required", errUsage)
+	}
+	summaries := make([]bank.AccountSummary, 0, len(args))
+	for _, raw := range args {
+		id, err := parseAccountId(raw)
+		if err != nil {
+			return err
+		}
+		balance, err := b.GetBalance(ctx, id)
+		if err != nil {
+			return fmt.Errorf("account %d: %w", id, err)
+		}
+		summaries = append(summaries, bank.AccountSummary{Id: id, Balance: balance})
+	}
+	return out.accounts(summaries)
+}
+
+func listCommand(ctx context.Context, b backend, out *printer, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	var filter bank.AccountsFilter
+	flags.StringVar(&filter.Status, "status", "", "Show only accounts with status: active, frozen or closed")
+	flags.IntVar(&filter.Limit, "limit", 0, "Number of accounts, default page size of service")
+	flags.StringVar(&filter.Cursor, "cursor", "", "Show accounts after cursor printed by previous list")
+	all := flags.Bool("all", false, "Show all accounts, limit is size of page")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *all {
+		var summaries []bank.AccountSummary
+		err := eachAccount(ctx, b, filter, func(summary bank.AccountSummary) error {
+			summaries = append(summaries, summary)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return out.accounts(summaries)
+	}
+
+	page, err := b.ListAccounts(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return out.accountsPage(page)
+}
+
+// eachAccount calls fn for accounts of all pages starting from filter.Cursor.
+func eachAccount(ctx context.Context, b backend, filter bank.AccountsFilter, fn func(bank.AccountSummary) error) error {
+	for {
+		page, err := b.ListAccounts(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, summary := range page.Items {
+			if err := fn(summary); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+// keysCommand manages API keys, issued and rotated keys are printed once.
+func keysCommand(ctx context.Context, b backend, out *printer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: subcommand list, issue, rotate or revoke is required", errUsage)
+	}
+	switch sub, args := args[0], args[1:]; sub {
+	case "list":
+		if len(args) != 0 {
+			return fmt.Errorf("%w: unexpected arguments %v", errUsage, args)
+		}
+		keys, err := b.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		return out.apiKeys(keys)
+	case "issue":
+		flags := flag.NewFlagSet("keys issue", flag.ContinueOnError)
+		var req bank.IssueAPIKeyRequest
+		flags.StringVar(&req.Name, "name", "", "Name of key, e.g. name of its client")
+		scopes := flags.String("scopes", "", "Comma separated scopes: read, deposit, withdraw, admin")
+		if err := parseFlags(flags, args); err != nil {
+			return err
+		}
+		if flags.NArg() != 0 {
+			return fmt.Errorf("%w: unexpected arguments %v", errUsage, flags.Args())
+		}
+		if *scopes != "" {
+			req.Scopes = strings.Split(*scopes, ",")
+		}
+		key, err := b.IssueAPIKey(ctx, req)
+		if err != nil {
+			return err
+		}
+		return out.apiKeys([]bank.APIKey{key})
+	case "rotate":
+		flags := flag.NewFlagSet("keys rotate", flag.ContinueOnError)
+		grace := flags.Duration("grace", 0, "Period when previous key is accepted, e.g. 10m")
+		if err := parseFlags(flags, args); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return fmt.Errorf("%w: key id is required", errUsage)
+		}
+		key, err := b.RotateAPIKey(ctx, flags.Arg(0), *grace)
+		if err != nil {
+			return err
+		}
+		return out.apiKeys([]bank.APIKey{key})
+	case "revoke":
+		if len(args) != 1 {
+			return fmt.Errorf("%w: key id is required", errUsage)
+		}
+		key, err := b.RevokeAPIKey(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return out.apiKeys([]bank.APIKey{key})
+	default:
+		return fmt.Errorf("%w: unknown subcommand %q", errUsage, sub)
+	}
+}
+
+const (
+	exportAccounts     = "accounts"
+	exportTransactions = "transactions"
+
+	exportJSONLines = "jsonl"
+	exportCSV       = "csv"
+)
+
+// exportCommand streams records, so export of large storage isn't kept in memory.
+// Transactions are exported by account in order of accounts, newest first within account.
+func exportCommand(ctx context.Context, b backend, out *printer, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	data := flags.String("data", exportAccounts, "Exported data: accounts or transactions")
+	format := flags.String("format", exportJSONLines, "Format of records: jsonl or csv")
+	file := flags.String("file", "", "Output file, stdout if empty")
+	var filter bank.AccountsFilter
+	flags.StringVar(&filter.Status, "status", "", "Export only accounts with status and their transactions")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", errUsage, flags.Args())
+	}
+
+	var header []string
+	switch *data {
+	case exportAccounts:
+		header = accountColumns
+	case exportTransactions:
+		header = transactionColumns
+	default:
+		return fmt.Errorf("%w: unknown data %q", errUsage, *data)
+	}
+
+	w := out.w
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var enc recordEncoder
+	switch *format {
+	case exportJSONLines:
+		enc = &jsonLinesEncoder{enc: json.NewEncoder(w)}
+	case exportCSV:
+		c := &csvEncoder{w: csv.NewWriter(w)}
+		if err := c.w.Write(header); err != nil {
+			return err
+		}
+		enc = c
+	default:
+		return fmt.Errorf("%w: unknown format %q", errUsage, *format)
+	}
+
+	err := eachAccount(ctx, b, filter, func(summary bank.AccountSummary) error {
+		if *data == exportAccounts {
+			return enc.encode(summary, accountRow(summary))
+		}
+		return eachTransaction(ctx, b, summary.Id, func(tx bank.Transaction) error {
+			return enc.encode(tx, transactionRow(tx))
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return enc.flush()
+}
+
+// eachTransaction calls fn for all journal entries of account, newest first.
+func eachTransaction(ctx context.Context, b backend, accountId int64, fn func(bank.Transaction) error) error {
+	var filter bank.TransactionsFilter
+	for {
+		page, err := b.ListTransactions(ctx, accountId, filter)
+		if err != nil {
+			return fmt.Errorf("account %d: %w", accountId, err)
+		}
+		for _, tx := range page.Items {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+type recordEncoder interface {
+	encode(v any, row []string) error
+	flush() error
+}
+
+type jsonLinesEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *jsonLinesEncoder) encode(v any, _ []string) error {
+	return e.enc.Encode(v)
+}
+
+func (e *jsonLinesEncoder) flush() error {
+	return nil
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) encode(_ any, row []string) error {
+	return e.w.Write(row)
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+var transactionColumns = []string{
+	"id", "account_id", "type", "currency", "amount", "balance_after",
+	"request_id", "created_at", "from_account_id", "to_account_id", "rate", "hold_id",
+}
+
+func transactionRow(tx bank.Transaction) []string {
+	var from, to, rate string
+	if tx.Transfer != nil {
+		from = strconv.FormatInt(tx.Transfer.FromAccountId, 10)
+		to = strconv.FormatInt(tx.Transfer.ToAccountId, 10)
+		rate = tx.Transfer.Rate
+	}
+	return []string{
+		strconv.FormatInt(tx.Id, 10),
+		strconv.FormatInt(tx.AccountId, 10),
+		tx.Type,
+		tx.Currency,
+		tx.Amount,
+		tx.BalanceAfter,
+		tx.RequestId,
+		tx.CreatedAt.Format(time.RFC3339Nano),
+		from,
+		to,
+		rate,
+		tx.HoldId,
+	}
+}
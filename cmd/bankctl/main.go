@@ -0,0 +1,188 @@
+// Command bankctl manages accounts through HTTP API of service
+// or, in admin mode, directly in storage of service.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/config"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+const usage = `Usage: bankctl [flags] <command> [command flags] [args]
+
+Commands:
+  create    [-currency CODE]               open account
+  deposit   [-currency CODE] <id> <amount> deposit to account
+  withdraw  [-currency CODE] <id> <amount> withdraw from account
+  balance   <id>...                        show balances of accounts
+  list      [-status S] [-limit N] [-all]  list accounts oldest first
+  export    [-data D] [-format F] [-file PATH] [-status S]
+                                           export accounts or transactions of all accounts
+  keys      list | issue -name N -scopes S,... | rotate [-grace D] <id> | revoke <id>
+                                           manage API keys
+
+Configuration is read from the same environment variables (and .env file) as service,
+flags override them. API key is taken from BANK_API_KEY or AUTH_BOOTSTRAP_KEY if -api-key is empty.
+
+Flags:
+`
+
+// errUsage is returned for invalid arguments of command, usage is printed for it.
+var errUsage = errors.New("invalid usage")
+
+type options struct {
+	url     string
+	apiKey  string
+	admin   bool
+	output  string
+	timeout time.Duration
+	verbose bool
+	cfg     config.Config
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("bankctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	envPath := flags.String("env-path", "", "Path to .env file")
+	var (
+		opts      options
+		overrides config.Config
+	)
+	flags.StringVar(&opts.url, "url", "", "Base url of API, http://APP_HOST:APP_PORT by default")
+	flags.StringVar(&opts.apiKey, "api-key", "", "API key sent to service (BANK_API_KEY)")
+	flags.BoolVar(&opts.admin, "admin", false, "Work with storage of service directly instead of API")
+	flags.StringVar(&opts.output, "o", formatTable, "Output format: table or json")
+	flags.DurationVar(&opts.timeout, "timeout", 30*time.Second, "Timeout of command, zero disables it")
+	flags.BoolVar(&opts.verbose, "v", false, "Log operations of admin mode to stderr")
+	flags.StringVar((*string)(&overrides.StorageBackend), "storage", "", "Storage backend of admin mode: postgres or sqlite (STORAGE_BACKEND)")
+	flags.StringVar(&overrides.Database.Host, "db-host", "", "Database host (DB_HOST)")
+	flags.IntVar(&overrides.Database.Port, "db-port", 0, "Database port (DB_PORT)")
+	flags.StringVar(&overrides.Database.User, "db-user", "", "Database user (DB_USER)")
+	flags.StringVar(&overrides.Database.Database, "db-name", "", "Database name (DB_DATABASE)")
+	flags.StringVar(&overrides.SQLite.Path, "sqlite-path", "", "Path to sqlite database (SQLITE_PATH)")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if opts.output != formatTable && opts.output != formatJSON {
+		fmt.Fprintf(stderr, "bankctl: unknown output format %q\n", opts.output)
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "bankctl: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	if err := config.LoadConfig(*envPath); err != nil {
+		fmt.Fprintf(stderr, "bankctl: load config: %v\n", err)
+		return 1
+	}
+	opts.cfg = applyOverrides(config.Get(), overrides)
+	if opts.url == "" {
+		opts.url = apiURL(opts.cfg.Server)
+	}
+	if opts.apiKey == "" {
+		opts.apiKey = os.Getenv("BANK_API_KEY")
+	}
+	if opts.apiKey == "" {
+		opts.apiKey = opts.cfg.Auth.BootstrapKey
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	log := logging.Discard()
+	if opts.verbose {
+		log = logging.New(stderr, false)
+	}
+	ctx = logging.Context(ctx, log)
+
+	b, closeBackend, err := openBackend(ctx, opts, log)
+	if err != nil {
+		fmt.Fprintf(stderr, "bankctl: %v\n", err)
+		return 1
+	}
+	defer closeBackend()
+
+	out := &printer{w: stdout, format: opts.output}
+	if err := cmd(ctx, b, out, flags.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(stderr, "bankctl %s: %v\n", flags.Arg(0), err)
+			return 2
+		}
+		fmt.Fprintf(stderr, "bankctl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// applyOverrides replaces values of cfg by non-zero flags.
+func applyOverrides(cfg config.Config, o config.Config) config.Config {
+	if o.StorageBackend != "" {
+		cfg.StorageBackend = o.StorageBackend
+	}
+	if o.Database.Host != "" {
+		cfg.Database.Host = o.Database.Host
+	}
+	if o.Database.Port != 0 {
+		cfg.Database.Port = o.Database.Port
+	}
+	if o.Database.User != "" {
+		cfg.Database.User = o.Database.User
+	}
+	if o.Database.Database != "" {
+		cfg.Database.Database = o.Database.Database
+	}
+	if o.SQLite.Path != "" {
+		cfg.SQLite.Path = o.SQLite.Path
+	}
+	return cfg
+}
+
+// defaultPort is port of API in .env.example and docker-compose.
+const defaultPort = 8000
+
+func apiURL(cfg config.WebServerConfig) string {
+	host, port := cfg.Host, cfg.Port
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	if port == 0 {
+		port = defaultPort
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/bank"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// printer writes results of commands in chosen format.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+var accountColumns = []string{
+	"id", "currency", "balance", "available_balance", "credit_limit", "credit_headroom", "status",
+}
+
+func accountRow(s bank.AccountSummary) []string {
+	return []string{
+		strconv.FormatInt(s.Id, 10),
+		s.Currency,
+		s.Balance.Balance,
+		s.AvailableBalance,
+		s.CreditLimit,
+		s.CreditHeadroom,
+		s.Status,
+	}
+}
+
+func (p *printer) account(id int64) error {
+	if p.format == formatJSON {
+		return p.json(struct {
+			Id int64 `json:"id"`
+		}{id})
+	}
+	return p.table([]string{"id"}, [][]string{{strconv.FormatInt(id, 10)}})
+}
+
+func (p *printer) accounts(summaries []bank.AccountSummary) error {
+	if p.format == formatJSON {
+		if summaries == nil {
+			summaries = []bank.AccountSummary{}
+		}
+		return p.json(summaries)
+	}
+	rows := make([][]string, 0, len(summaries))
+	for _, s := range summaries {
+		rows = append(rows, accountRow(s))
+	}
+	return p.table(accountColumns, rows)
+}
+
+// accountsPage prints cursor of the next page after table.
+func (p *printer) accountsPage(page bank.AccountsPage) error {
+	if p.format == formatJSON {
+		if page.Items == nil {
+			page.Items = []bank.AccountSummary{}
+		}
+		return p.json(page)
+	}
+	if err := p.accounts(page.Items); err != nil {
+		return err
+	}
+	if page.NextCursor != "" {
+		_, err := fmt.Fprintf(p.w, "\nnext page: -cursor %s\n", page.NextCursor)
+		return err
+	}
+	return nil
+}
+
+// apiKeys prints column of key only when some key has it.
+func (p *printer) apiKeys(keys []bank.APIKey) error {
+	if p.format == formatJSON {
+		if keys == nil {
+			keys = []bank.APIKey{}
+		}
+		return p.json(keys)
+	}
+
+	header := []string{"id", "name", "scopes", "created_at", "rotated_at", "revoked_at"}
+	withKey := false
+	for _, key := range keys {
+		withKey = withKey || key.Key != ""
+	}
+	if withKey {
+		header = append(header, "key")
+	}
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		row := []string{
+			key.Id,
+			key.Name,
+			strings.Join(key.Scopes, ","),
+			formatTime(key.CreatedAt),
+			formatTime(key.RotatedAt),
+			formatTime(key.RevokedAt),
+		}
+		if withKey {
+			row = append(row, key.Key)
+		}
+		rows = append(rows, row)
+	}
+	return p.table(header, rows)
+}
+
+// formatTime prints zero time as empty column.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func (p *printer) json(v any) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (p *printer) table(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,189 @@
+// Command conformance runs repository conformance checks against storage backends.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/config"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/acquire"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/conformance"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/wal"
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/pg"
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/sqlite"
+)
+
+func main() {
+	envPath := flag.String("env-path", "", "Path to .env file")
+	backends := flag.String("backends", "memory,memory-durable,sqlite",
+		"Comma separated backends: memory, memory-durable, sqlite, postgres")
+	migrations := flag.String("migrations", "migrations", "Directory of migrations")
+	flag.Parse()
+
+	if err := config.LoadConfig(*envPath); err != nil {
+		panic(fmt.Errorf("load config: %w", err))
+	}
+	cfg := config.Get()
+
+	tmp, err := os.MkdirTemp("", "conformance")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	ctx := context.Background()
+	failed := 0
+	for _, name := range strings.Split(*backends, ",") {
+		suites, closeBackend, err := openBackend(ctx, strings.TrimSpace(name), cfg, tmp, *migrations)
+		if err != nil {
+			fmt.Printf("%s: %v\n", name, err)
+			failed++
+			continue
+		}
+		for _, s := range suites {
+			r := &reporter{}
+			fmt.Printf("%s\n", s.name)
+			conformance.Run(r, s.backend)
+			failed += r.failed
+		}
+		closeBackend()
+	}
+
+	if failed != 0 {
+		fmt.Printf("FAIL: %d checks failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("PASS")
+}
+
+type suite struct {
+	name    string
+	backend conformance.Backend
+}
+
+// openBackend returns suites for every acquirer supported by backend.
+func openBackend(
+	ctx context.Context,
+	name string,
+	cfg config.Config,
+	tmp string,
+	migrations string,
+) ([]suite, func(), error) {
+	switch name {
+	case "memory":
+		repo := accounts.NewInMemory()
+		return []suite{
+			{"memory", conformance.Backend{Storage: repo, Acquirer: acquire.NewInMemoryAcquirer(repo)}},
+		}, func() {}, nil
+
+	case "memory-durable":
+		repo, err := accounts.OpenDurableInMemory(filepath.Join(tmp, "memory"), wal.Options{Sync: wal.SyncBatch})
+		if err != nil {
+			return nil, nil, err
+		}
+		return []suite{
+			{"memory-durable", conformance.Backend{Storage: repo, Acquirer: acquire.NewInMemoryAcquirer(repo)}},
+		}, func() { repo.Close() }, nil
+
+	case "sqlite":
+		path := filepath.Join(tmp, "bank.db")
+		m, err := migrate.New("file://"+filepath.Join(migrations, "sqlite"), sqlite.MigrateConnString(path))
+		if err != nil {
+			return nil, nil, fmt.Errorf("migrate: %w", err)
+		}
+		if err := m.Up(); err != nil {
+			return nil, nil, fmt.Errorf("migrate: %w", err)
+		}
+		m.Close()
+
+		db, err := sqlite.New(ctx, sqlite.ConnString(path, cfg.SQLite.BusyTimeout))
+		if err != nil {
+			return nil, nil, err
+		}
+		repo := accounts.NewSQLiteRepository(db, cfg.SQLite.BusyTimeout)
+		return []suite{
+			{"sqlite/repository", conformance.Backend{Storage: repo, Acquirer: repo}},
+			{"sqlite/memory", conformance.Backend{Storage: repo, Acquirer: acquire.NewInMemoryAcquirer(repo)}},
+		}, func() { db.Close() }, nil
+
+	case "postgres":
+		// database must be migrated by migrator
+		db, err := pg.New(ctx, pg.ConnString(
+			cfg.Database.User,
+			cfg.Database.Password,
+			cfg.Database.Database,
+			cfg.Database.Host,
+			cfg.Database.Port,
+		))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := db.Ping(ctx); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+
+		var suites []suite
+		for _, strategy := range []accounts.Strategy{
+			accounts.StrategyPessimistic,
+			accounts.StrategySerializable,
+			accounts.StrategyOptimistic,
+		} {
+			concurrency := accounts.DefaultConcurrency
+			concurrency.Strategy = strategy
+			repo := accounts.NewRepository(db, concurrency)
+			suites = append(suites, suite{
+				"postgres/repository/" + string(strategy),
+				conformance.Backend{Storage: repo, Acquirer: repo},
+			})
+		}
+
+		repo := accounts.NewRepository(db, accounts.DefaultConcurrency)
+		advisory := acquire.NewAdvisoryAcquirer(db, func(tx pgx.Tx) acquire.AccountStorage {
+			return repo.WithTx(tx)
+		}, acquire.NewLockWaitMetrics(), cfg.Concurrency.AdvisoryMaxWait)
+		suites = append(suites,
+			suite{"postgres/advisory", conformance.Backend{Storage: repo, Acquirer: advisory}},
+			suite{"postgres/memory", conformance.Backend{Storage: repo, Acquirer: acquire.NewInMemoryAcquirer(repo)}},
+		)
+
+		es := accounts.NewEventStore(db, 3, accounts.DefaultConcurrency.MaxRetries)
+		eventsAdvisory := acquire.NewAdvisoryAcquirer(db, func(tx pgx.Tx) acquire.AccountStorage {
+			return es.WithTx(tx)
+		}, acquire.NewLockWaitMetrics(), cfg.Concurrency.AdvisoryMaxWait)
+		suites = append(suites,
+			suite{"postgres/events/repository", conformance.Backend{Storage: es, Acquirer: es}},
+			suite{"postgres/events/advisory", conformance.Backend{Storage: es, Acquirer: eventsAdvisory}},
+			suite{"postgres/events/memory", conformance.Backend{Storage: es, Acquirer: acquire.NewInMemoryAcquirer(es)}},
+		)
+		return suites, db.Close, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown backend %q", name)
+	}
+}
+
+// reporter prints results of checks like go test -v.
+type reporter struct {
+	failed int
+}
+
+func (r *reporter) Helper() {}
+
+func (r *reporter) Logf(format string, args ...any) {
+	fmt.Printf("    "+format+"\n", args...)
+}
+
+func (r *reporter) Errorf(format string, args ...any) {
+	r.failed++
+	fmt.Printf("    FAIL "+format+"\n", args...)
+}
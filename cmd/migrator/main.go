@@ -7,9 +7,11 @@ import (
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/pgx"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/vitaliy-ukiru/bank-service/internal/config"
 	"github.com/vitaliy-ukiru/bank-service/pkg/client/pg"
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/sqlite"
 )
 
 func main() {
@@ -24,16 +26,19 @@ func main() {
 	}
 
 	cfg := config.Get()
-	m, err := migrate.New(
-		"file://migrations",
-		pg.PgxConnString(
-			cfg.Database.User,
-			cfg.Database.Password,
-			cfg.Database.Database,
-			cfg.Database.Host,
-			cfg.Database.Port,
-		),
+	source, database := "file://migrations", pg.PgxConnString(
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.Database,
+		cfg.Database.Host,
+		cfg.Database.Port,
 	)
+	// sqlite has its own migration set
+	if cfg.StorageBackend == config.StorageSQLite {
+		source, database = "file://migrations/sqlite", sqlite.MigrateConnString(cfg.SQLite.Path)
+	}
+
+	m, err := migrate.New(source, database)
 	if err != nil {
 		log.Fatalf("migrate.New: %v", err)
 	}
@@ -0,0 +1,86 @@
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+// AccountFilter selects accounts in order of id.
+type AccountFilter struct {
+	// AfterId selects accounts with id greater than it.
+	AfterId int64
+	// Status is optional, empty status doesn't filter.
+	Status account.Status
+	Limit  int
+}
+
+type AccountSummary struct {
+	Id int64
+	AccountBalance
+}
+
+type AccountsPage struct {
+	Items []AccountSummary
+	// NextCursor is empty when there are no more accounts.
+	NextCursor string
+}
+
+const (
+	DefaultAccountsLimit = 100
+	MaxAccountsLimit     = 1000
+)
+
+// ListAccounts returns accounts oldest first, balances are read without acquiring accounts.
+func (a *AccountService) ListAccounts(ctx context.Context, cmd ListAccountsCommand) (page AccountsPage, err error) {
+	const op = "ListAccounts"
+	log := logging.FromContext(ctx)
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail list accounts", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		}
+	}()
+
+	filter := AccountFilter{Limit: cmd.Limit}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAccountsLimit
+	}
+	filter.Limit = min(filter.Limit, MaxAccountsLimit)
+
+	if cmd.Status != "" {
+		filter.Status, err = account.ParseStatus(string(cmd.Status))
+		if err != nil {
+			return
+		}
+	}
+	if cmd.Cursor != "" {
+		filter.AfterId, err = decodeCursor(cmd.Cursor)
+		if err != nil {
+			return
+		}
+	}
+
+	// request one extra account to know whether next page exists
+	limit := filter.Limit
+	filter.Limit++
+	accs, err := a.repo.ListAccounts(ctx, filter)
+	if err != nil {
+		return
+	}
+
+	if len(accs) > limit {
+		accs = accs[:limit]
+		page.NextCursor = encodeCursor(accs[limit-1].Id())
+	}
+	page.Items = make([]AccountSummary, 0, len(accs))
+	for i := range accs {
+		page.Items = append(page.Items, AccountSummary{
+			Id:             accs[i].Id(),
+			AccountBalance: balanceOf(&accs[i]),
+		})
+	}
+	return page, nil
+}
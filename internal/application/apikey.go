@@ -0,0 +1,344 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+// Scope is permission of API key, ScopeAdmin includes all others.
+type Scope string
+
+const (
+	// ScopeRead allows reading balances, journals and streams of accounts.
+	ScopeRead Scope = "read"
+	// ScopeDeposit allows opening accounts and deposits.
+	ScopeDeposit Scope = "deposit"
+	// ScopeWithdraw allows withdrawals, transfers and holds.
+	ScopeWithdraw Scope = "withdraw"
+	// ScopeAdmin allows everything, including admin endpoints, webhooks and API keys.
+	ScopeAdmin Scope = "admin"
+)
+
+var (
+	ErrUnknownScope         = errors.New("unknown scope")
+	ErrAPIKeyNotFound       = errors.New("api key not found")
+	ErrAPIKeyNameRequired   = errors.New("api key name is required")
+	ErrAPIKeyScopesRequired = errors.New("api key must have at least one scope")
+	ErrAPIKeyRevoked        = errors.New("api key is revoked")
+	ErrNegativeGracePeriod  = errors.New("grace period must not be negative")
+	// ErrUnauthenticated is returned for missing, unknown, revoked or mismatched key and invalid token.
+	ErrUnauthenticated = errors.New("valid api key or bearer token is required")
+	// ErrPermissionDenied is returned when principal has no scope required for operation.
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+func ParseScope(s string) (Scope, error) {
+	switch sc := Scope(s); sc {
+	case ScopeRead, ScopeDeposit, ScopeWithdraw, ScopeAdmin:
+		return sc, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownScope, s)
+	}
+}
+
+// APIKey keeps only hash of secret, the secret is shown once when key is issued or rotated.
+type APIKey struct {
+	Id     string
+	Name   string
+	Scopes []Scope
+	// SecretHash is hex encoded SHA-256 of secret.
+	SecretHash string
+	// PreviousSecretHash is hash of secret replaced by rotation,
+	// it is accepted until PreviousExpiresAt.
+	PreviousSecretHash string
+	PreviousExpiresAt  time.Time
+	CreatedAt          time.Time
+	// RotatedAt and RevokedAt are zero until key is rotated or revoked.
+	RotatedAt time.Time
+	RevokedAt time.Time
+}
+
+func (k APIKey) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// matches compares hash of secret with current secret and with previous one before it expires.
+func (k APIKey) matches(secret string, now time.Time) bool {
+	hash := hashSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(k.SecretHash)) == 1 {
+		return true
+	}
+	return k.PreviousSecretHash != "" &&
+		now.Before(k.PreviousExpiresAt) &&
+		subtle.ConstantTimeCompare([]byte(hash), []byte(k.PreviousSecretHash)) == 1
+}
+
+type APIKeyStore interface {
+	// SaveAPIKey adds or replaces key by id.
+	SaveAPIKey(ctx context.Context, key APIKey) error
+	// GetAPIKey returns ErrAPIKeyNotFound for unknown id.
+	GetAPIKey(ctx context.Context, id string) (APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+}
+
+// Principal is caller authenticated by API key or bearer token.
+type Principal struct {
+	// KeyId is empty for bearer token.
+	KeyId  string
+	Name   string
+	Scopes []Scope
+	// Subject is "sub" claim of bearer token, empty for API key.
+	Subject string
+	// AccountScoped principal operates only on AccountIds, principal of API key operates on all accounts.
+	AccountScoped bool
+	AccountIds    []int64
+}
+
+func (p Principal) Allows(scope Scope) bool {
+	return slices.Contains(p.Scopes, ScopeAdmin) || slices.Contains(p.Scopes, scope)
+}
+
+func (p Principal) CanAccess(accountId int64) bool {
+	return !p.AccountScoped || slices.Contains(p.AccountIds, accountId)
+}
+
+// Id identifies principal: id of API key or subject of bearer token.
+func (p Principal) Id() string {
+	if p.Subject != "" {
+		return "sub:" + p.Subject
+	}
+	return "key:" + p.KeyId
+}
+
+// LogAttr identifies principal in logs.
+func (p Principal) LogAttr() logging.Attr {
+	if p.Subject != "" {
+		return logging.String("subject", p.Subject)
+	}
+	return logging.String("principal_id", p.KeyId)
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns false if request isn't authenticated, e.g. authentication is disabled.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// apiKeyPrefix marks keys in configs and logs, token is apiKeyPrefix + id + "_" + secret.
+const apiKeyPrefix = "bk_"
+
+// BootstrapKeyId is id of principal authenticated by bootstrap key of config.
+const BootstrapKeyId = "bootstrap"
+
+// MinBootstrapKeyLength is minimal length of bootstrap key.
+const MinBootstrapKeyLength = 32
+
+var ErrWeakBootstrapKey = fmt.Errorf("bootstrap key must have at least %d characters", MinBootstrapKeyLength)
+
+// IssuedAPIKey is key with its token, the token isn't stored.
+type IssuedAPIKey struct {
+	APIKey
+	Token string
+}
+
+type APIKeyService struct {
+	store APIKeyStore
+	// bootstrapKey is admin key of config, it isn't stored, empty disables it.
+	bootstrapKey string
+}
+
+func NewAPIKeyService(store APIKeyStore, bootstrapKey string) (*APIKeyService, error) {
+	if bootstrapKey != "" && len(bootstrapKey) < MinBootstrapKeyLength {
+		return nil, ErrWeakBootstrapKey
+	}
+	return &APIKeyService{store: store, bootstrapKey: bootstrapKey}, nil
+}
+
+func (s *APIKeyService) IssueAPIKey(ctx context.Context, cmd IssueAPIKeyCommand) (issued IssuedAPIKey, err error) {
+	const op = "IssueAPIKey"
+	log := logging.FromContext(ctx)
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail issue api key", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "api key issued", logging.String("api_key_id", issued.Id))
+		}
+	}()
+
+	name := strings.TrimSpace(cmd.Name)
+	if name == "" {
+		return IssuedAPIKey{}, ErrAPIKeyNameRequired
+	}
+	if len(cmd.Scopes) == 0 {
+		return IssuedAPIKey{}, ErrAPIKeyScopesRequired
+	}
+
+	id, err := newRandomId()
+	if err != nil {
+		return
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return
+	}
+	scopes := slices.Clone(cmd.Scopes)
+	slices.Sort(scopes)
+	key := APIKey{
+		Id:         id,
+		Name:       name,
+		Scopes:     slices.Compact(scopes),
+		SecretHash: hashSecret(secret),
+		CreatedAt:  time.Now(),
+	}
+	if err = s.store.SaveAPIKey(ctx, key); err != nil {
+		return
+	}
+	return IssuedAPIKey{APIKey: key, Token: apiKeyPrefix + id + "_" + secret}, nil
+}
+
+// RotateAPIKey replaces secret of key, previous secret is accepted during grace period.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, cmd RotateAPIKeyCommand) (issued IssuedAPIKey, err error) {
+	const op = "RotateAPIKey"
+	log := logging.FromContext(ctx).With(logging.String("api_key_id", cmd.Id))
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail rotate api key", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "api key rotated")
+		}
+	}()
+
+	if cmd.GracePeriod < 0 {
+		return IssuedAPIKey{}, ErrNegativeGracePeriod
+	}
+	key, err := s.store.GetAPIKey(ctx, cmd.Id)
+	if err != nil {
+		return
+	}
+	if key.Revoked() {
+		return IssuedAPIKey{}, ErrAPIKeyRevoked
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	key.PreviousSecretHash, key.PreviousExpiresAt = "", time.Time{}
+	if cmd.GracePeriod > 0 {
+		key.PreviousSecretHash, key.PreviousExpiresAt = key.SecretHash, now.Add(cmd.GracePeriod)
+	}
+	key.SecretHash = hashSecret(secret)
+	key.RotatedAt = now
+	if err = s.store.SaveAPIKey(ctx, key); err != nil {
+		return
+	}
+	return IssuedAPIKey{APIKey: key, Token: apiKeyPrefix + key.Id + "_" + secret}, nil
+}
+
+// RevokeAPIKey disables key at once, revoked key is kept for audit.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) (key APIKey, err error) {
+	const op = "RevokeAPIKey"
+	log := logging.FromContext(ctx).With(logging.String("api_key_id", id))
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail revoke api key", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "api key revoked")
+		}
+	}()
+
+	key, err = s.store.GetAPIKey(ctx, id)
+	if err != nil {
+		return
+	}
+	if key.Revoked() {
+		return key, nil
+	}
+	key.RevokedAt = time.Now()
+	key.PreviousSecretHash, key.PreviousExpiresAt = "", time.Time{}
+	if err = s.store.SaveAPIKey(ctx, key); err != nil {
+		return
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) GetAPIKey(ctx context.Context, id string) (APIKey, error) {
+	const op = "GetAPIKey"
+	key, err := s.store.GetAPIKey(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx).Error(op, "fail get api key", err, logging.String("api_key_id", id))
+		return APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	const op = "ListAPIKeys"
+	keys, err := s.store.ListAPIKeys(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error(op, "fail list api keys", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return keys, nil
+}
+
+// Authenticate resolves token into principal, it returns ErrUnauthenticated
+// without telling whether key is unknown, revoked or secret is wrong.
+func (s *APIKeyService) Authenticate(ctx context.Context, token string) (Principal, error) {
+	const op = "Authenticate"
+
+	if s.bootstrapKey != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.bootstrapKey)) == 1 {
+		return Principal{KeyId: BootstrapKeyId, Name: BootstrapKeyId, Scopes: []Scope{ScopeAdmin}}, nil
+	}
+
+	id, secret, ok := strings.Cut(strings.TrimPrefix(token, apiKeyPrefix), "_")
+	if !ok || !strings.HasPrefix(token, apiKeyPrefix) || id == "" || secret == "" {
+		return Principal{}, fmt.Errorf("%s: %w", op, ErrUnauthenticated)
+	}
+	key, err := s.store.GetAPIKey(ctx, id)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return Principal{}, fmt.Errorf("%s: %w", op, ErrUnauthenticated)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error(op, "fail get api key", err, logging.String("api_key_id", id))
+		return Principal{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if key.Revoked() || !key.matches(secret, time.Now()) {
+		return Principal{}, fmt.Errorf("%s: %w", op, ErrUnauthenticated)
+	}
+	return Principal{KeyId: key.Id, Name: key.Name, Scopes: key.Scopes}, nil
+}
+
+func newSecret() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// hashSecret doesn't need salt and slow hash, secrets are random 256 bit values.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -6,37 +6,108 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
 	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
 )
 
 type BankAccount interface {
-	Deposit(amount float64) error
-	Withdraw(amount float64) error
-	GetBalance() float64
+	Deposit(amount account.Money) error
+	Withdraw(amount account.Money) error
+	GetBalance() account.Money
+	AvailableBalance() account.Money
+	CreditLimit() account.Money
+	CreditHeadroom() account.Money
+	SetCreditLimit(limit account.Money) error
+	Id() int64
+	Currency() account.Currency
+	CheckCurrency(currency account.Currency) error
+	Status() account.Status
+	CheckDeposit(policy account.StatusPolicy) error
+	ChangeStatus(to account.Status, reason string) (account.StatusChange, error)
+	SendTransfer(t account.Transfer) error
+	ReceiveTransfer(t account.Transfer) error
+
+	Holds() []account.Hold
+	PlaceHold(id string, amount account.Money, now, expiresAt time.Time) (account.Hold, error)
+	CaptureHold(id string, amount account.Money, now time.Time) (account.Hold, error)
+	ReleaseHold(id string) (account.Hold, error)
+	ExpireHolds(now time.Time) []account.Hold
 }
 
 type AccountProcessFunc func(account BankAccount) error
 
+// PairProcessFunc receives accounts in the same order as ids passed to AcquirePair.
+type PairProcessFunc func(first, second BankAccount) error
+
+// Acquirer gives exclusive access to accounts and saves their changes.
+// If context has Idempotency, its record is persisted atomically with changes.
 type Acquirer interface {
 	Acquire(ctx context.Context, id int64, fn AccountProcessFunc) error
+	// AcquirePair locks both accounts in deterministic order (e.g. by id),
+	// so concurrent pairs never deadlock. Changes of both accounts
+	// are saved atomically, error from fn discards all of them.
+	AcquirePair(ctx context.Context, firstId, secondId int64, fn PairProcessFunc) error
 }
 
 type Repository interface {
-	NewAccount(ctx context.Context) (int64, error)
+	IdempotencyStore
+	HoldStore
+	OutboxStore
+	NewAccount(ctx context.Context, currency account.Currency) (int64, error)
+	// ListJournalEntries returns ErrAccountNotFound for unknown account.
+	ListJournalEntries(ctx context.Context, filter JournalFilter) ([]JournalEntry, error)
+	ListAccounts(ctx context.Context, filter AccountFilter) ([]account.Account, error)
+}
+
+// AccountReader is implemented by Repository keeping read model of accounts,
+// GetBalance reads it instead of acquiring account.
+type AccountReader interface {
+	ReadAccount(ctx context.Context, id int64) (account.Account, error)
 }
 
 type AccountService struct {
 	locker Acquirer
 	repo   Repository
+
+	rates      ExchangeRateProvider
+	conversion account.ConversionPolicy
+
+	holdTTL time.Duration
+	status  account.StatusPolicy
+	// idempotencyRetention is age of idempotency records which are no longer replayed
+	idempotencyRetention time.Duration
 }
 
-func NewAccountService(locker Acquirer, repo Repository) *AccountService {
-	return &AccountService{locker: locker, repo: repo}
+// NewAccountService creates service, zero idempotencyRetention keeps idempotency records forever.
+func NewAccountService(
+	locker Acquirer,
+	repo Repository,
+	rates ExchangeRateProvider,
+	conversion account.ConversionPolicy,
+	holdTTL time.Duration,
+	status account.StatusPolicy,
+	idempotencyRetention time.Duration,
+) *AccountService {
+	return &AccountService{
+		locker:               locker,
+		repo:                 repo,
+		rates:                rates,
+		conversion:           conversion,
+		holdTTL:              holdTTL,
+		status:               status,
+		idempotencyRetention: idempotencyRetention,
+	}
 }
 
-var ErrAccountNotFound = errors.New("account not found")
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	ErrSameAccount     = errors.New("source and destination accounts are the same")
+	// ErrAccountBusy returned by Acquirer when account can't be locked
+	// or concurrent changes conflict after all retries.
+	ErrAccountBusy = errors.New("account is busy, retry later")
+)
 
-func (a *AccountService) CreateAccount(ctx context.Context) (accountId int64, err error) {
+func (a *AccountService) CreateAccount(ctx context.Context, cmd CreateAccountCommand) (accountId int64, err error) {
 	const op = "CreateAccount"
 	log := logging.FromContext(ctx)
 
@@ -49,7 +120,15 @@ func (a *AccountService) CreateAccount(ctx context.Context) (accountId int64, er
 		}
 	}()
 
-	accountId, err = a.repo.NewAccount(ctx)
+	currency := account.DefaultCurrency
+	if cmd.Currency != "" {
+		currency, err = account.ParseCurrency(string(cmd.Currency))
+		if err != nil {
+			return
+		}
+	}
+
+	accountId, err = a.repo.NewAccount(ctx, currency)
 	if err != nil {
 		return
 	}
@@ -71,6 +150,12 @@ func (a *AccountService) DepositBalance(ctx context.Context, cmd DepositBalanceC
 
 	err = a.locker.Acquire(ctx, cmd.AccountId, func(account BankAccount) error {
 		time.Sleep(time.Second)
+		if err := account.CheckCurrency(cmd.Currency); err != nil {
+			return err
+		}
+		if err := account.CheckDeposit(a.status); err != nil {
+			return err
+		}
 		return account.Deposit(cmd.Amount)
 	})
 	return
@@ -91,12 +176,39 @@ func (a *AccountService) WithdrawBalance(ctx context.Context, cmd WithdrawBalanc
 	}()
 
 	err = a.locker.Acquire(ctx, cmd.AccountId, func(account BankAccount) error {
+		if err := account.CheckCurrency(cmd.Currency); err != nil {
+			return err
+		}
 		return account.Withdraw(cmd.Amount)
 	})
 	return
 }
 
-func (a *AccountService) GetBalance(ctx context.Context, cmd GetBalanceCommand) (balance float64, err error) {
+type AccountBalance struct {
+	// Balance is ledger balance.
+	Balance account.Money
+	// Available is amount which can be spent, it includes unused credit
+	// and excludes active holds.
+	Available account.Money
+	// CreditHeadroom is unused part of CreditLimit.
+	CreditLimit    account.Money
+	CreditHeadroom account.Money
+	Currency       account.Currency
+	Status         account.Status
+}
+
+func balanceOf(acc BankAccount) AccountBalance {
+	return AccountBalance{
+		Balance:        acc.GetBalance(),
+		Available:      acc.AvailableBalance(),
+		CreditLimit:    acc.CreditLimit(),
+		CreditHeadroom: acc.CreditHeadroom(),
+		Currency:       acc.Currency(),
+		Status:         acc.Status(),
+	}
+}
+
+func (a *AccountService) GetBalance(ctx context.Context, cmd GetBalanceCommand) (balance AccountBalance, err error) {
 	const op = "GetBalance"
 	log := logging.FromContext(ctx).With(logging.AccountId(cmd.AccountId))
 	defer func() {
@@ -109,10 +221,121 @@ func (a *AccountService) GetBalance(ctx context.Context, cmd GetBalanceCommand)
 
 	}()
 
+	if reader, ok := a.repo.(AccountReader); ok {
+		var acc account.Account
+		if acc, err = reader.ReadAccount(ctx, cmd.AccountId); err == nil {
+			balance = balanceOf(&acc)
+		}
+		return
+	}
+
+	err = a.locker.Acquire(ctx, cmd.AccountId, func(account BankAccount) error {
+		balance = balanceOf(account)
+		return nil
+	})
+	return
+
+}
+
+func (a *AccountService) Transfer(ctx context.Context, cmd TransferCommand) (transfer account.Transfer, err error) {
+	const op = "Transfer"
+	log := logging.FromContext(ctx).With(
+		logging.Int64("from_account_id", cmd.FromAccountId),
+		logging.Int64("to_account_id", cmd.ToAccountId),
+	)
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail transfer", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "success transfer")
+		}
+	}()
+
+	if cmd.FromAccountId == cmd.ToAccountId {
+		err = ErrSameAccount
+		return
+	}
+
+	err = a.locker.AcquirePair(ctx, cmd.FromAccountId, cmd.ToAccountId, func(from, to BankAccount) error {
+		if err := from.CheckCurrency(cmd.Currency); err != nil {
+			return err
+		}
+		if err := to.CheckDeposit(a.status); err != nil {
+			return err
+		}
+
+		rate, err := a.marketRate(ctx, from.Currency(), to.Currency())
+		if err != nil {
+			return err
+		}
+		t, err := account.NewTransfer(from, to, cmd.Amount, rate, a.conversion)
+		if err != nil {
+			return err
+		}
+
+		if err := from.SendTransfer(t); err != nil {
+			return err
+		}
+		if err := to.ReceiveTransfer(t); err != nil {
+			return err
+		}
+		transfer = t
+		return nil
+	})
+	return
+}
+
+func (a *AccountService) ChangeStatus(ctx context.Context, cmd ChangeStatusCommand) (change account.StatusChange, err error) {
+	const op = "ChangeStatus"
+	log := logging.FromContext(ctx).With(
+		logging.AccountId(cmd.AccountId),
+		logging.String("status", string(cmd.Status)),
+	)
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail change account status", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "account status changed",
+				logging.String("from", string(change.From)),
+				logging.String("reason", change.Reason),
+			)
+		}
+	}()
+
 	err = a.locker.Acquire(ctx, cmd.AccountId, func(account BankAccount) error {
-		balance = account.GetBalance()
+		changed, err := account.ChangeStatus(cmd.Status, cmd.Reason)
+		if err != nil {
+			return err
+		}
+		change = changed
 		return nil
 	})
 	return
+}
+
+func (a *AccountService) SetCreditLimit(ctx context.Context, cmd SetCreditLimitCommand) (balance AccountBalance, err error) {
+	const op = "SetCreditLimit"
+	log := logging.FromContext(ctx).With(logging.AccountId(cmd.AccountId))
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail set credit limit", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "credit limit changed", logging.String("credit_limit", cmd.CreditLimit.String()))
+		}
+	}()
 
+	err = a.locker.Acquire(ctx, cmd.AccountId, func(account BankAccount) error {
+		if err := account.CheckCurrency(cmd.Currency); err != nil {
+			return err
+		}
+		if err := account.SetCreditLimit(cmd.CreditLimit); err != nil {
+			return err
+		}
+		balance = balanceOf(account)
+		return nil
+	})
+	return
 }
@@ -0,0 +1,59 @@
+package application
+
+import (
+	"context"
+	"fmt"
+)
+
+// Credentials are sent by caller, at most one of them is used.
+type Credentials struct {
+	APIKey string
+	// BearerToken is preferred over APIKey.
+	BearerToken string
+}
+
+// TokenVerifier resolves bearer token into account scoped principal,
+// it returns ErrUnauthenticated for invalid token.
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (Principal, error)
+}
+
+// Authenticator resolves credentials by API keys or, for bearer token, by token verifier.
+type Authenticator struct {
+	apiKeys *APIKeyService
+	// tokens is nil when bearer tokens aren't configured.
+	tokens TokenVerifier
+}
+
+func NewAuthenticator(apiKeys *APIKeyService, tokens TokenVerifier) *Authenticator {
+	return &Authenticator{apiKeys: apiKeys, tokens: tokens}
+}
+
+func (a *Authenticator) Authenticate(ctx context.Context, creds Credentials) (Principal, error) {
+	const op = "Authenticate"
+	if creds.BearerToken == "" {
+		return a.apiKeys.Authenticate(ctx, creds.APIKey)
+	}
+	if a.tokens == nil {
+		return Principal{}, fmt.Errorf("%s: %w", op, ErrUnauthenticated)
+	}
+	return a.tokens.VerifyToken(ctx, creds.BearerToken)
+}
+
+// AuthorizeAccount checks that principal of ctx may operate on account.
+// Unauthenticated context is allowed, it is made when authentication is disabled.
+func AuthorizeAccount(ctx context.Context, accountId int64) error {
+	if p, ok := PrincipalFromContext(ctx); ok && !p.CanAccess(accountId) {
+		return fmt.Errorf("%w: account %d isn't available to caller", ErrPermissionDenied, accountId)
+	}
+	return nil
+}
+
+// AuthorizeAllAccounts checks that principal of ctx isn't account scoped,
+// e.g. accounts are opened only by API keys, because new account isn't in claims of token.
+func AuthorizeAllAccounts(ctx context.Context) error {
+	if p, ok := PrincipalFromContext(ctx); ok && p.AccountScoped {
+		return fmt.Errorf("%w: operation isn't available to account scoped caller", ErrPermissionDenied)
+	}
+	return nil
+}
@@ -1,15 +1,117 @@
 package application
 
+import (
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+type CreateAccountCommand struct {
+	// Currency of account, DefaultCurrency if empty.
+	Currency account.Currency
+}
+
 type GetBalanceCommand struct {
 	AccountId int64
 }
 
 type DepositBalanceCommand struct {
 	AccountId int64
-	Amount    float64
+	Amount    account.Money
+	// Currency of operation, if set it must match account currency.
+	Currency account.Currency
 }
 
 type WithdrawBalanceCommand struct {
 	AccountId int64
-	Amount    float64
+	Amount    account.Money
+	// Currency of operation, if set it must match account currency.
+	Currency account.Currency
+}
+
+type TransferCommand struct {
+	FromAccountId int64
+	ToAccountId   int64
+	Amount        account.Money
+	// Currency of operation, if set it must match source account currency.
+	Currency account.Currency
+}
+
+type ListTransactionsCommand struct {
+	AccountId int64
+	Cursor    string
+	Limit     int
+	From      time.Time
+	To        time.Time
+	Types     []account.OperationType
+}
+
+type ListAccountsCommand struct {
+	Cursor string
+	Limit  int
+	// Status is optional, empty status doesn't filter.
+	Status account.Status
+}
+
+type PlaceHoldCommand struct {
+	AccountId int64
+	Amount    account.Money
+	Currency  account.Currency
+}
+
+type CaptureHoldCommand struct {
+	AccountId int64
+	HoldId    string
+	// Amount is nil for capture of full hold.
+	Amount *account.Money
+}
+
+type ReleaseHoldCommand struct {
+	AccountId int64
+	HoldId    string
+}
+
+type ChangeStatusCommand struct {
+	AccountId int64
+	Status    account.Status
+	Reason    string
+}
+
+type SetCreditLimitCommand struct {
+	AccountId   int64
+	CreditLimit account.Money
+	Currency    account.Currency
+}
+
+type CreateWebhookCommand struct {
+	URL    string
+	Events []account.EventType
+	// AccountId is zero for webhook of all accounts.
+	AccountId int64
+	// LowBalanceThreshold is required for EventBalanceLow.
+	LowBalanceThreshold *account.Money
+	// Secret is generated if empty.
+	Secret string
+}
+
+type ListWebhookDeliveriesCommand struct {
+	WebhookId string
+	Status    DeliveryStatus
+	Limit     int
+}
+
+type RetryWebhookDeliveryCommand struct {
+	WebhookId  string
+	DeliveryId string
+}
+
+type IssueAPIKeyCommand struct {
+	Name   string
+	Scopes []Scope
+}
+
+type RotateAPIKeyCommand struct {
+	Id string
+	// GracePeriod keeps previous secret valid, zero revokes it at once.
+	GracePeriod time.Duration
 }
@@ -0,0 +1,24 @@
+package application
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+var ErrExchangeRateNotFound = errors.New("exchange rate not found")
+
+// ExchangeRateProvider gives market rate for converting amount in from currency to currency.
+type ExchangeRateProvider interface {
+	// GetRate returns ErrExchangeRateNotFound if pair is not supported.
+	GetRate(ctx context.Context, from, to account.Currency) (account.Rate, error)
+}
+
+// marketRate skips provider for transfers within one currency.
+func (a *AccountService) marketRate(ctx context.Context, from, to account.Currency) (account.Rate, error) {
+	if from == to {
+		return account.OneRate, nil
+	}
+	return a.rates.GetRate(ctx, from, to)
+}
@@ -0,0 +1,175 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+// HoldStore finds accounts which holds must be expired by HoldSweeper.
+type HoldStore interface {
+	// AccountsWithExpiredHolds returns ids of accounts having active holds expired at now.
+	AccountsWithExpiredHolds(ctx context.Context, now time.Time) ([]int64, error)
+}
+
+func (a *AccountService) PlaceHold(ctx context.Context, cmd PlaceHoldCommand) (hold account.Hold, err error) {
+	const op = "PlaceHold"
+	log := logging.FromContext(ctx).With(logging.AccountId(cmd.AccountId))
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail place hold", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "hold placed", logging.String("hold_id", hold.Id()))
+		}
+	}()
+
+	id, err := newRandomId()
+	if err != nil {
+		return
+	}
+
+	err = a.locker.Acquire(ctx, cmd.AccountId, func(account BankAccount) error {
+		if err := account.CheckCurrency(cmd.Currency); err != nil {
+			return err
+		}
+		now := time.Now()
+		placed, err := account.PlaceHold(id, cmd.Amount, now, now.Add(a.holdTTL))
+		if err != nil {
+			return err
+		}
+		hold = placed
+		return nil
+	})
+	return
+}
+
+func (a *AccountService) CaptureHold(ctx context.Context, cmd CaptureHoldCommand) (hold account.Hold, err error) {
+	const op = "CaptureHold"
+	log := logging.FromContext(ctx).With(
+		logging.AccountId(cmd.AccountId),
+		logging.String("hold_id", cmd.HoldId),
+	)
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail capture hold", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "hold captured")
+		}
+	}()
+
+	err = a.locker.Acquire(ctx, cmd.AccountId, func(acc BankAccount) error {
+		amount, err := captureAmount(acc, cmd)
+		if err != nil {
+			return err
+		}
+		captured, err := acc.CaptureHold(cmd.HoldId, amount, time.Now())
+		if err != nil {
+			return err
+		}
+		hold = captured
+		return nil
+	})
+	return
+}
+
+// captureAmount defaults to full amount of hold.
+func captureAmount(acc BankAccount, cmd CaptureHoldCommand) (account.Money, error) {
+	if cmd.Amount != nil {
+		return *cmd.Amount, nil
+	}
+	for _, h := range acc.Holds() {
+		if h.Id() == cmd.HoldId {
+			return h.Amount(), nil
+		}
+	}
+	return account.Money{}, account.ErrHoldNotFound
+}
+
+func (a *AccountService) ReleaseHold(ctx context.Context, cmd ReleaseHoldCommand) (hold account.Hold, err error) {
+	const op = "ReleaseHold"
+	log := logging.FromContext(ctx).With(
+		logging.AccountId(cmd.AccountId),
+		logging.String("hold_id", cmd.HoldId),
+	)
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail release hold", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "hold released")
+		}
+	}()
+
+	err = a.locker.Acquire(ctx, cmd.AccountId, func(account BankAccount) error {
+		released, err := account.ReleaseHold(cmd.HoldId)
+		if err != nil {
+			return err
+		}
+		hold = released
+		return nil
+	})
+	return
+}
+
+func newRandomId() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// HoldSweeper expires holds which TTL passed.
+type HoldSweeper struct {
+	store    HoldStore
+	locker   Acquirer
+	interval time.Duration
+}
+
+func NewHoldSweeper(store HoldStore, locker Acquirer, interval time.Duration) *HoldSweeper {
+	return &HoldSweeper{store: store, locker: locker, interval: interval}
+}
+
+// Run blocks until ctx is done.
+func (s *HoldSweeper) Run(ctx context.Context) {
+	const op = "HoldSweeper"
+	log := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		ids, err := s.store.AccountsWithExpiredHolds(ctx, now)
+		if err != nil {
+			log.Error(op, "fail find expired holds", err)
+			continue
+		}
+		for _, id := range ids {
+			var expired int
+			err := s.locker.Acquire(ctx, id, func(account BankAccount) error {
+				expired = len(account.ExpireHolds(now))
+				return nil
+			})
+			if err != nil {
+				log.Error(op, "fail expire holds", err, logging.AccountId(id))
+				continue
+			}
+			if expired != 0 {
+				log.Info(op, "holds expired", logging.AccountId(id), logging.Int64("count", int64(expired)))
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+var (
+	ErrIdempotencyRecordNotFound = errors.New("idempotency record not found")
+	// ErrIdempotencyKeyConflict returned by storages when record with the same key
+	// was persisted concurrently. All changes of operation are discarded.
+	ErrIdempotencyKeyConflict = errors.New("idempotency key is already used")
+	ErrIdempotencyKeyReused   = errors.New("idempotency key reused with different request")
+)
+
+// IdempotentResponse is the response for the first request with idempotency key,
+// it replayed for repeated requests.
+type IdempotentResponse struct {
+	Status int
+	Body   []byte
+}
+
+type IdempotencyRecord struct {
+	Key string
+	// Fingerprint identifies request payload, so key can't be reused for other request.
+	Fingerprint string
+	Response    IdempotentResponse
+	CreatedAt   time.Time
+}
+
+// OperationResult passed to Idempotency.Render for building response.
+type OperationResult struct {
+	AccountId int64
+	// Balance is state of account after operation.
+	Balance AccountBalance
+	// Transfer is set for transfer operations.
+	Transfer *account.Transfer
+	// Hold is set for hold operations.
+	Hold *account.Hold
+	// StatusChange is set for change of account status.
+	StatusChange *account.StatusChange
+}
+
+// OperationResultOf builds result of operation from saved accounts,
+// first account is the one operation was addressed to.
+func OperationResultOf(accs ...account.Account) OperationResult {
+	if len(accs) == 0 {
+		return OperationResult{}
+	}
+
+	result := OperationResult{
+		AccountId: accs[0].Id(),
+		Balance:   balanceOf(&accs[0]),
+	}
+	for _, operation := range accs[0].Operations() {
+		if operation.Transfer != nil {
+			result.Transfer = operation.Transfer
+		}
+	}
+	if changes := accs[0].HoldChanges(); len(changes) != 0 {
+		hold := changes[len(changes)-1]
+		result.Hold = &hold
+	}
+	if changes := accs[0].StatusChanges(); len(changes) != 0 {
+		change := changes[len(changes)-1]
+		result.StatusChange = &change
+	}
+	return result
+}
+
+// Idempotency makes storages persist IdempotencyRecord
+// atomically with changes of operation.
+type Idempotency struct {
+	Key         string
+	Fingerprint string
+	// Render builds response of successful operation. It called by storage
+	// inside transaction, so must not block.
+	Render func(result OperationResult) (IdempotentResponse, error)
+}
+
+type idempotencyKey struct{}
+
+func WithIdempotency(ctx context.Context, idempotency Idempotency) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, idempotency)
+}
+
+// IdempotencyRecordFromContext returns record which must be persisted with operation.
+// Returns nil if context has no Idempotency.
+func IdempotencyRecordFromContext(ctx context.Context, result OperationResult) (*IdempotencyRecord, error) {
+	idempotency, ok := ctx.Value(idempotencyKey{}).(Idempotency)
+	if !ok {
+		return nil, nil
+	}
+
+	resp, err := idempotency.Render(result)
+	if err != nil {
+		return nil, fmt.Errorf("render idempotent response: %w", err)
+	}
+	return &IdempotencyRecord{
+		Key:         scopedIdempotencyKey(ctx, idempotency.Key),
+		Fingerprint: idempotency.Fingerprint,
+		Response:    resp,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// scopedIdempotencyKey is key of stored record, so keys of different principals don't collide
+// and response of one principal isn't replayed to another. Principal id is prefixed with its length,
+// so any key is unambiguous. Keys of unauthenticated requests (authentication is disabled) are stored as is.
+func scopedIdempotencyKey(ctx context.Context, key string) string {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return key
+	}
+	id := p.Id()
+	return fmt.Sprintf("%d:%s:%s", len(id), id, key)
+}
+
+type IdempotencyStore interface {
+	// GetIdempotencyRecord returns ErrIdempotencyRecordNotFound for unknown key.
+	GetIdempotencyRecord(ctx context.Context, key string) (IdempotencyRecord, error)
+	DeleteIdempotencyRecords(ctx context.Context, createdBefore time.Time) (int64, error)
+}
+
+// LookupIdempotentResponse returns stored response for key of principal of ctx,
+// records older than retention period aren't found.
+// If key was used with other fingerprint returns ErrIdempotencyKeyReused.
+func (a *AccountService) LookupIdempotentResponse(
+	ctx context.Context,
+	key string,
+	fingerprint string,
+) (resp IdempotentResponse, found bool, err error) {
+	const op = "LookupIdempotentResponse"
+
+	record, err := a.repo.GetIdempotencyRecord(ctx, scopedIdempotencyKey(ctx, key))
+	if err != nil {
+		if errors.Is(err, ErrIdempotencyRecordNotFound) {
+			return resp, false, nil
+		}
+		return resp, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if a.idempotencyRetention > 0 {
+		// expired record isn't replayed even if cleaner hasn't removed it yet.
+		// Expired records are removed now, so the key can be stored again by operation.
+		if expiresBefore := time.Now().Add(-a.idempotencyRetention); record.CreatedAt.Before(expiresBefore) {
+			if _, err := a.repo.DeleteIdempotencyRecords(ctx, expiresBefore); err != nil {
+				return resp, false, fmt.Errorf("%s: %w", op, err)
+			}
+			return resp, false, nil
+		}
+	}
+
+	if record.Fingerprint != fingerprint {
+		return resp, false, fmt.Errorf("%s: %w", op, ErrIdempotencyKeyReused)
+	}
+	return record.Response, true, nil
+}
+
+// IdempotencyCleaner removes records older than retention period.
+type IdempotencyCleaner struct {
+	store     IdempotencyStore
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewIdempotencyCleaner(store IdempotencyStore, retention, interval time.Duration) *IdempotencyCleaner {
+	return &IdempotencyCleaner{store: store, retention: retention, interval: interval}
+}
+
+// Run blocks until ctx is done.
+func (c *IdempotencyCleaner) Run(ctx context.Context) {
+	const op = "IdempotencyCleaner"
+	log := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := c.store.DeleteIdempotencyRecords(ctx, time.Now().Add(-c.retention))
+		if err != nil {
+			log.Error(op, "fail delete expired idempotency records", err)
+			continue
+		}
+		if deleted != 0 {
+			log.Info(op, "expired idempotency records deleted", logging.Int64("count", deleted))
+		}
+	}
+}
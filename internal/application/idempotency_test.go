@@ -0,0 +1,116 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/acquire"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/exchange"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+)
+
+const fingerprint = "fingerprint"
+
+func newService(t *testing.T, retention time.Duration) (*application.AccountService, int64) {
+	t.Helper()
+	repo := accounts.NewInMemory()
+	rates, err := exchange.NewStaticProvider(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := application.NewAccountService(
+		acquire.NewInMemoryAcquirer(repo),
+		repo,
+		rates,
+		account.ConversionPolicy{Rounding: account.RoundHalfEven},
+		time.Hour,
+		account.StatusPolicy{},
+		retention,
+	)
+	id, err := service.CreateAccount(context.Background(), application.CreateAccountCommand{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service, id
+}
+
+// setCreditLimit runs operation storing response of key.
+func setCreditLimit(t *testing.T, ctx context.Context, service *application.AccountService, id int64, key string) {
+	t.Helper()
+	ctx = application.WithIdempotency(ctx, application.Idempotency{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Render: func(application.OperationResult) (application.IdempotentResponse, error) {
+			return application.IdempotentResponse{Status: 200, Body: []byte(`{"ok":true}`)}, nil
+		},
+	})
+	_, err := service.SetCreditLimit(ctx, application.SetCreditLimitCommand{
+		AccountId:   id,
+		CreditLimit: account.MustParseMoney("10"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func lookup(t *testing.T, ctx context.Context, service *application.AccountService, key string) bool {
+	t.Helper()
+	_, found, err := service.LookupIdempotentResponse(ctx, key, fingerprint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+func TestIdempotencyKeyOfOtherPrincipal(t *testing.T) {
+	service, id := newService(t, time.Hour)
+	alice := application.ContextWithPrincipal(context.Background(), application.Principal{KeyId: "alice"})
+	bob := application.ContextWithPrincipal(context.Background(), application.Principal{KeyId: "bob"})
+	// subject of token equal to id of key is other principal
+	token := application.ContextWithPrincipal(context.Background(), application.Principal{Subject: "alice"})
+
+	setCreditLimit(t, alice, service, id, "key")
+
+	if !lookup(t, alice, service, "key") {
+		t.Fatal("response isn't replayed to the same principal")
+	}
+	if lookup(t, bob, service, "key") {
+		t.Fatal("response of alice is replayed to bob")
+	}
+	if lookup(t, token, service, "key") {
+		t.Fatal("response of api key is replayed to token with the same subject")
+	}
+	if lookup(t, context.Background(), service, "key") {
+		t.Fatal("response of alice is replayed to unauthenticated request")
+	}
+
+	// bob stores own response with the same key
+	setCreditLimit(t, bob, service, id, "key")
+	if !lookup(t, bob, service, "key") {
+		t.Fatal("response of bob isn't stored")
+	}
+}
+
+func TestIdempotencyRecordExpires(t *testing.T) {
+	const retention = 50 * time.Millisecond
+	service, id := newService(t, retention)
+	ctx := context.Background()
+
+	setCreditLimit(t, ctx, service, id, "key")
+	if !lookup(t, ctx, service, "key") {
+		t.Fatal("response isn't replayed within retention")
+	}
+
+	time.Sleep(2 * retention)
+	if lookup(t, ctx, service, "key") {
+		t.Fatal("expired response is replayed")
+	}
+	// expired key is used again
+	setCreditLimit(t, ctx, service, id, "key")
+	if !lookup(t, ctx, service, "key") {
+		t.Fatal("response of reused key isn't stored")
+	}
+}
@@ -0,0 +1,119 @@
+package application
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+// JournalEntry is immutable record about balance change.
+type JournalEntry struct {
+	Id           int64
+	AccountId    int64
+	Type         account.OperationType
+	Currency     account.Currency
+	Amount       account.Money
+	BalanceAfter account.Money
+	RequestId    string
+	CreatedAt    time.Time
+	// Transfer is set for transfer operations.
+	Transfer *account.Transfer
+	// HoldId is set for capture of hold.
+	HoldId string
+}
+
+// JournalFilter selects entries of account, newest first.
+type JournalFilter struct {
+	AccountId int64
+	// BeforeId selects entries with id less than it, zero means from newest.
+	BeforeId int64
+	// AfterId selects entries with id greater than it. Entries of account are
+	// committed in order of id, so journal is followed by AfterId with OldestFirst.
+	AfterId     int64
+	OldestFirst bool
+	Limit       int
+	// From and To bound creation time (inclusive from, exclusive to), zero value is unbounded.
+	From  time.Time
+	To    time.Time
+	Types []account.OperationType
+}
+
+type TransactionsPage struct {
+	Items []JournalEntry
+	// NextCursor is empty when there are no more entries.
+	NextCursor string
+}
+
+const (
+	DefaultTransactionsLimit = 50
+	MaxTransactionsLimit     = 500
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func (a *AccountService) ListTransactions(ctx context.Context, cmd ListTransactionsCommand) (page TransactionsPage, err error) {
+	const op = "ListTransactions"
+	log := logging.FromContext(ctx).With(logging.AccountId(cmd.AccountId))
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail list transactions", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		}
+	}()
+
+	filter := JournalFilter{
+		AccountId: cmd.AccountId,
+		Limit:     cmd.Limit,
+		From:      cmd.From,
+		To:        cmd.To,
+		Types:     cmd.Types,
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultTransactionsLimit
+	}
+	filter.Limit = min(filter.Limit, MaxTransactionsLimit)
+
+	if cmd.Cursor != "" {
+		filter.BeforeId, err = decodeCursor(cmd.Cursor)
+		if err != nil {
+			return
+		}
+	}
+
+	// request one extra entry to know whether next page exists
+	limit := filter.Limit
+	filter.Limit++
+	entries, err := a.repo.ListJournalEntries(ctx, filter)
+	if err != nil {
+		return
+	}
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+		page.NextCursor = encodeCursor(entries[limit-1].Id)
+	}
+	page.Items = entries
+	return page, nil
+}
+
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
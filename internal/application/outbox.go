@@ -0,0 +1,314 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+// OutboxMessage is event of account saved in the same transaction as account.
+// Messages of account are published in order of Id.
+type OutboxMessage struct {
+	Id        int64
+	AccountId int64
+	Type      account.EventType
+	// Payload is JSON object with fields of event.
+	Payload   []byte
+	RequestId string
+	CreatedAt time.Time
+	// Attempts counts failed publishing, it isn't part of published form.
+	Attempts int
+}
+
+// outboxMessageJSON is published form of message, consumers deduplicate by id.
+type outboxMessageJSON struct {
+	Id        int64             `json:"id"`
+	AccountId int64             `json:"account_id"`
+	Type      account.EventType `json:"type"`
+	Payload   json.RawMessage   `json:"payload"`
+	RequestId string            `json:"request_id,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// MarshalJSON returns published form of message.
+func (m OutboxMessage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(outboxMessageJSON{
+		Id:        m.Id,
+		AccountId: m.AccountId,
+		Type:      m.Type,
+		Payload:   m.Payload,
+		RequestId: m.RequestId,
+		CreatedAt: m.CreatedAt,
+	})
+}
+
+func (m *OutboxMessage) UnmarshalJSON(data []byte) error {
+	var v outboxMessageJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*m = OutboxMessage{
+		Id:        v.Id,
+		AccountId: v.AccountId,
+		Type:      v.Type,
+		Payload:   v.Payload,
+		RequestId: v.RequestId,
+		CreatedAt: v.CreatedAt,
+	}
+	return nil
+}
+
+type eventPayload struct {
+	Currency account.Currency `json:"currency"`
+	Amount   *account.Money   `json:"amount,omitempty"`
+	// Balance is ledger balance after event changing it.
+	Balance       *account.Money `json:"balance,omitempty"`
+	FromAccountId int64          `json:"from_account_id,omitempty"`
+	ToAccountId   int64          `json:"to_account_id,omitempty"`
+	HoldId        string         `json:"hold_id,omitempty"`
+	ExpiresAt     *time.Time     `json:"expires_at,omitempty"`
+	FromStatus    account.Status `json:"from_status,omitempty"`
+	ToStatus      account.Status `json:"to_status,omitempty"`
+	Reason        string         `json:"reason,omitempty"`
+}
+
+// OutboxMessagesOf builds messages from pending events of account,
+// storages set Id, RequestId and CreatedAt.
+func OutboxMessagesOf(acc account.Account) ([]OutboxMessage, error) {
+	events := acc.Events()
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	// balance before events
+	balance := acc.GetBalance()
+	for _, e := range events {
+		if delta, ok := balanceDelta(e); ok {
+			balance = balance.Sub(delta)
+		}
+	}
+
+	messages := make([]OutboxMessage, 0, len(events))
+	for _, e := range events {
+		payload := eventPayload{
+			Currency:   acc.Currency(),
+			HoldId:     e.HoldId,
+			FromStatus: e.From,
+			ToStatus:   e.To,
+			Reason:     e.Reason,
+		}
+		if e.Type != account.EventAccountOpened && e.Type != account.EventStatusChanged &&
+			e.Type != account.EventHoldReleased && e.Type != account.EventHoldExpired {
+			amount := e.Amount
+			payload.Amount = &amount
+		}
+		if delta, ok := balanceDelta(e); ok {
+			balance = balance.Add(delta)
+			after := balance
+			payload.Balance = &after
+		}
+		if e.Transfer != nil {
+			payload.FromAccountId = e.Transfer.FromAccountId
+			payload.ToAccountId = e.Transfer.ToAccountId
+		}
+		if !e.ExpiresAt.IsZero() {
+			expiresAt := e.ExpiresAt
+			payload.ExpiresAt = &expiresAt
+		}
+
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, OutboxMessage{
+			AccountId: acc.Id(),
+			Type:      e.Type,
+			Payload:   data,
+		})
+	}
+	return messages, nil
+}
+
+// balanceDelta returns change of ledger balance by event, false for events not changing it.
+func balanceDelta(e account.Event) (account.Money, bool) {
+	switch e.Type {
+	case account.EventDeposited, account.EventTransferReceived:
+		return e.Amount, true
+	case account.EventWithdrawn, account.EventTransferSent, account.EventHoldCaptured:
+		return e.Amount.Neg(), true
+	default:
+		return account.Money{}, false
+	}
+}
+
+// OutboxStore keeps messages until they are published.
+type OutboxStore interface {
+	// ClaimOutbox leases up to limit oldest due messages ordered by id until now+lease,
+	// meanwhile relays of other instances don't get them. Message isn't due while
+	// earlier message of its account is leased or waits for retry, so messages
+	// of account are published in order. Dead messages are never claimed.
+	ClaimOutbox(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]OutboxMessage, error)
+	// CompleteOutbox deletes published messages and saves outcome of other claimed ones.
+	CompleteOutbox(ctx context.Context, result OutboxResult) error
+}
+
+// OutboxResult is outcome of publishing claimed messages.
+type OutboxResult struct {
+	Published []int64
+	Failed    []OutboxFailure
+	// Released messages weren't attempted, they are due at once.
+	Released []int64
+}
+
+// OutboxFailure is failed attempt of publishing message, it is claimed again at RetryAt.
+type OutboxFailure struct {
+	Id       int64
+	Attempts int
+	Error    string
+	RetryAt  time.Time
+	// Dead message is kept in outbox, but it is never claimed and doesn't block later messages.
+	Dead bool
+}
+
+// Publisher delivers message to other services.
+type Publisher interface {
+	// Publish returns nil only if message is delivered. Message may be
+	// published again if relay fails after it, consumers deduplicate by id.
+	Publish(ctx context.Context, message OutboxMessage) error
+}
+
+type OutboxRetryPolicy struct {
+	// MaxAttempts failed ones make message dead.
+	MaxAttempts int
+	// Backoff is delay after first failed attempt, it doubles after every next one up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func (p OutboxRetryPolicy) delay(attempts int) time.Duration {
+	return backoffDelay(p.Backoff, p.MaxBackoff, attempts)
+}
+
+// outboxLease must be longer than publishing of batch, otherwise
+// messages are claimed again by other relay while they are published.
+const outboxLease = time.Minute
+
+// OutboxRelay publishes messages at least once. Failed message of account
+// stops publishing of its later messages until it is published or dead,
+// so order of account messages is kept.
+type OutboxRelay struct {
+	store     OutboxStore
+	publisher Publisher
+	retry     OutboxRetryPolicy
+	interval  time.Duration
+	batchSize int
+}
+
+func NewOutboxRelay(
+	store OutboxStore,
+	publisher Publisher,
+	retry OutboxRetryPolicy,
+	interval time.Duration,
+	batchSize int,
+) *OutboxRelay {
+	return &OutboxRelay{
+		store:     store,
+		publisher: publisher,
+		retry:     retry,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run blocks until ctx is done.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	const op = "OutboxRelay"
+	log := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		published, err := r.Relay(ctx)
+		if err != nil {
+			log.Error(op, "fail relay outbox", err)
+		}
+		if published != 0 {
+			log.Info(op, "outbox messages published", logging.Int64("count", int64(published)))
+		}
+	}
+}
+
+// Relay publishes due messages while batches are full and make progress.
+// Messages are published without transaction of store.
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	var published int
+	for ctx.Err() == nil {
+		messages, err := r.store.ClaimOutbox(ctx, time.Now(), outboxLease, r.batchSize)
+		if err != nil {
+			return published, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		result := r.publish(ctx, messages)
+		// claimed messages are published again after lease if result isn't saved
+		if err := r.store.CompleteOutbox(ctx, result); err != nil {
+			return published, err
+		}
+		published += len(result.Published)
+		if len(messages) < r.batchSize || len(result.Published) == 0 {
+			break
+		}
+	}
+	return published, nil
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, messages []OutboxMessage) OutboxResult {
+	const op = "OutboxRelay"
+	log := logging.FromContext(ctx)
+
+	var (
+		result  OutboxResult
+		blocked = make(map[int64]bool)
+	)
+	for _, message := range messages {
+		if blocked[message.AccountId] {
+			result.Released = append(result.Released, message.Id)
+			continue
+		}
+		err := r.publisher.Publish(ctx, message)
+		if err == nil {
+			result.Published = append(result.Published, message.Id)
+			continue
+		}
+
+		blocked[message.AccountId] = true
+		failure := OutboxFailure{
+			Id:       message.Id,
+			Attempts: message.Attempts + 1,
+			Error:    err.Error(),
+		}
+		failure.RetryAt = time.Now().Add(r.retry.delay(failure.Attempts))
+		failure.Dead = failure.Attempts >= r.retry.MaxAttempts
+		log.Error(op, "fail publish outbox message", err,
+			logging.AccountId(message.AccountId),
+			logging.Int64("message_id", message.Id),
+			logging.Int64("attempts", int64(failure.Attempts)),
+		)
+		if failure.Dead {
+			log.Error(op, "outbox message is dead", err, logging.Int64("message_id", message.Id))
+		}
+		result.Failed = append(result.Failed, failure)
+	}
+	return result
+}
@@ -0,0 +1,224 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+)
+
+// fakePublisher fails messages of account while it has failures left.
+type fakePublisher struct {
+	mu        sync.Mutex
+	failures  map[int64]int
+	published []application.OutboxMessage
+}
+
+func (p *fakePublisher) Publish(_ context.Context, m application.OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures[m.AccountId] != 0 {
+		p.failures[m.AccountId]--
+		return errors.New("broker is unavailable")
+	}
+	p.published = append(p.published, m)
+	return nil
+}
+
+// publishedOf returns ids of published messages of account.
+func (p *fakePublisher) publishedOf(id int64) []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var ids []int64
+	for _, m := range p.published {
+		if m.AccountId == id {
+			ids = append(ids, m.Id)
+		}
+	}
+	return ids
+}
+
+// failingCompleteStore loses results of claims.
+type failingCompleteStore struct {
+	application.OutboxStore
+}
+
+func (failingCompleteStore) CompleteOutbox(context.Context, application.OutboxResult) error {
+	return errors.New("connection is lost")
+}
+
+// accountWithDeposits creates account with deposits, each of them is outbox message.
+func accountWithDeposits(t *testing.T, repo *accounts.AccountStorage, deposits int) int64 {
+	t.Helper()
+	ctx := context.Background()
+	id, err := repo.NewAccount(ctx, account.DefaultCurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < deposits; i++ {
+		acc, err := repo.GetAccountById(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := acc.Deposit(account.MustParseMoney("1")); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.SaveAccounts(ctx, acc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return id
+}
+
+func relay(t *testing.T, r *application.OutboxRelay) int {
+	t.Helper()
+	published, err := r.Relay(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return published
+}
+
+// claimAll claims messages of account due at now.
+func claimAll(t *testing.T, repo *accounts.AccountStorage, now time.Time, id int64) []application.OutboxMessage {
+	t.Helper()
+	messages, err := repo.ClaimOutbox(context.Background(), now, time.Minute, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return slices.DeleteFunc(messages, func(m application.OutboxMessage) bool { return m.AccountId != id })
+}
+
+func TestOutboxRelayOrder(t *testing.T) {
+	repo := accounts.NewInMemory()
+	first := accountWithDeposits(t, repo, 3)
+	second := accountWithDeposits(t, repo, 2)
+	publisher := &fakePublisher{}
+	// small batches make relay continue with next claims
+	r := application.NewOutboxRelay(repo, publisher, application.OutboxRetryPolicy{MaxAttempts: 3}, time.Second, 2)
+
+	if got := relay(t, r); got != len(publisher.published) || got == 0 {
+		t.Fatalf("relay returned %d, published %d", got, len(publisher.published))
+	}
+	for _, id := range []int64{first, second} {
+		ids := publisher.publishedOf(id)
+		if len(ids) == 0 || !slices.IsSorted(ids) {
+			t.Fatalf("messages of account %d are published in order %v", id, ids)
+		}
+	}
+	if got := relay(t, r); got != 0 {
+		t.Fatalf("published messages are published again: %d", got)
+	}
+}
+
+func TestOutboxRelayRetry(t *testing.T) {
+	const backoff = 50 * time.Millisecond
+	repo := accounts.NewInMemory()
+	failing := accountWithDeposits(t, repo, 2)
+	healthy := accountWithDeposits(t, repo, 2)
+	publisher := &fakePublisher{failures: map[int64]int{failing: 1}}
+	r := application.NewOutboxRelay(
+		repo,
+		publisher,
+		application.OutboxRetryPolicy{MaxAttempts: 3, Backoff: backoff, MaxBackoff: time.Second},
+		time.Second,
+		100,
+	)
+
+	relay(t, r)
+	if got := publisher.publishedOf(failing); len(got) != 0 {
+		t.Fatalf("messages %v are published after earlier failed message", got)
+	}
+	if got := publisher.publishedOf(healthy); len(got) == 0 {
+		t.Fatal("failed message of other account blocks account")
+	}
+
+	// message isn't retried before backoff
+	relay(t, r)
+	if got := publisher.publishedOf(failing); len(got) != 0 {
+		t.Fatalf("messages %v are published before backoff", got)
+	}
+
+	time.Sleep(backoff)
+	relay(t, r)
+	if ids := publisher.publishedOf(failing); len(ids) == 0 || !slices.IsSorted(ids) {
+		t.Fatalf("messages of account are published in order %v after retry", ids)
+	}
+	retried := publisher.published[slices.IndexFunc(publisher.published, func(m application.OutboxMessage) bool {
+		return m.AccountId == failing
+	})]
+	if retried.Attempts != 1 {
+		t.Fatalf("retried message has %d attempts, want 1", retried.Attempts)
+	}
+}
+
+func TestOutboxRelayDead(t *testing.T) {
+	const maxAttempts = 3
+	repo := accounts.NewInMemory()
+	id := accountWithDeposits(t, repo, 2)
+	all := claimAll(t, repo, time.Now(), id)
+	if err := repo.CompleteOutbox(context.Background(), application.OutboxResult{Released: idsOf(all)}); err != nil {
+		t.Fatal(err)
+	}
+	publisher := &fakePublisher{failures: map[int64]int{id: maxAttempts}}
+	r := application.NewOutboxRelay(repo, publisher, application.OutboxRetryPolicy{MaxAttempts: maxAttempts}, time.Second, 100)
+
+	for i := 0; i < maxAttempts; i++ {
+		relay(t, r)
+		if got := publisher.publishedOf(id); len(got) != 0 {
+			t.Fatalf("attempt %d: messages %v are published after failed one", i+1, got)
+		}
+	}
+
+	// dead message doesn't block later ones
+	relay(t, r)
+	if got, want := publisher.publishedOf(id), idsOf(all[1:]); !slices.Equal(got, want) {
+		t.Fatalf("published %v after dead message, want %v", got, want)
+	}
+	if rest := claimAll(t, repo, time.Now().Add(time.Hour), id); len(rest) != 0 {
+		t.Fatalf("dead message is claimed: %v", idsOf(rest))
+	}
+}
+
+func TestOutboxRelayRedelivery(t *testing.T) {
+	repo := accounts.NewInMemory()
+	id := accountWithDeposits(t, repo, 1)
+	publisher := &fakePublisher{}
+	r := application.NewOutboxRelay(
+		failingCompleteStore{repo},
+		publisher,
+		application.OutboxRetryPolicy{MaxAttempts: 3},
+		time.Second,
+		100,
+	)
+
+	if _, err := r.Relay(context.Background()); err == nil {
+		t.Fatal("relay doesn't return error of lost result")
+	}
+	published := publisher.publishedOf(id)
+	if len(published) == 0 {
+		t.Fatal("nothing is published")
+	}
+
+	// messages are claimed by other relay after lease
+	if got := claimAll(t, repo, time.Now(), id); len(got) != 0 {
+		t.Fatalf("leased messages %v are claimed", idsOf(got))
+	}
+	if got := idsOf(claimAll(t, repo, time.Now().Add(2*time.Minute), id)); !slices.Equal(got, published) {
+		t.Fatalf("claimed %v after lease, want %v", got, published)
+	}
+}
+
+func idsOf(messages []application.OutboxMessage) []int64 {
+	ids := make([]int64, len(messages))
+	for i, m := range messages {
+		ids[i] = m.Id
+	}
+	return ids
+}
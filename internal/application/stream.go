@@ -0,0 +1,250 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+var ErrStreamClosed = errors.New("balance stream is closed")
+
+// JournalReader is part of Repository read by BalanceStream.
+type JournalReader interface {
+	ListJournalEntries(ctx context.Context, filter JournalFilter) ([]JournalEntry, error)
+}
+
+const (
+	// streamBuffer is number of entries kept for subscriber, the rest are
+	// passed on next polls after subscriber reads them.
+	streamBuffer = 64
+	streamBatch  = 100
+)
+
+// BalanceStream pushes committed journal entries of accounts to subscribers
+// without acquiring accounts. Journal of followed account is polled once per
+// interval for all of its subscribers, so they add no load on storage, and
+// changes made by other instances are pushed too.
+type BalanceStream struct {
+	journal  JournalReader
+	interval time.Duration
+	// stallTimeout drops subscriber not reading entries,
+	// it reconnects and resumes with last event id.
+	stallTimeout time.Duration
+
+	mu sync.Mutex
+	// ctx is set by Run, done ctx closes stream
+	ctx   context.Context
+	feeds map[int64]*balanceFeed
+}
+
+// balanceFeed is followed journal of account.
+type balanceFeed struct {
+	subscribers map[*BalanceSubscription]struct{}
+	stop        context.CancelFunc
+}
+
+func (f *balanceFeed) has(sub *BalanceSubscription) bool {
+	_, ok := f.subscribers[sub]
+	return ok
+}
+
+func NewBalanceStream(journal JournalReader, interval, stallTimeout time.Duration) *BalanceStream {
+	return &BalanceStream{
+		journal:      journal,
+		interval:     interval,
+		stallTimeout: stallTimeout,
+		feeds:        make(map[int64]*balanceFeed),
+	}
+}
+
+// Run accepts subscribers until ctx is done, then closes all subscriptions.
+func (s *BalanceStream) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	<-ctx.Done()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for accountId, feed := range s.feeds {
+		feed.stop()
+		for sub := range feed.subscribers {
+			close(sub.events)
+		}
+		delete(s.feeds, accountId)
+	}
+}
+
+// BalanceSubscription receives entries of account in order of id.
+type BalanceSubscription struct {
+	stream    *BalanceStream
+	accountId int64
+	// after is id of last entry passed to events
+	after int64
+	// stalledAt is set when events are full
+	stalledAt time.Time
+	events    chan JournalEntry
+}
+
+// Events is closed when subscription is closed or subscriber doesn't keep up with entries.
+func (sub *BalanceSubscription) Events() <-chan JournalEntry {
+	return sub.events
+}
+
+// Close must be called when subscriber stops reading events.
+func (sub *BalanceSubscription) Close() {
+	sub.stream.mu.Lock()
+	defer sub.stream.mu.Unlock()
+	sub.stream.remove(sub)
+}
+
+// Subscribe follows journal of account. Subscriber resuming after lastEventId
+// receives entries with greater ids, new subscriber (zero lastEventId) receives
+// the latest entry first, so it knows current balance.
+func (s *BalanceStream) Subscribe(ctx context.Context, accountId int64, lastEventId int64) (*BalanceSubscription, error) {
+	const op = "SubscribeBalance"
+
+	// it checks that account exists too
+	latest, err := s.journal.ListJournalEntries(ctx, JournalFilter{AccountId: accountId, Limit: 1})
+	if err != nil {
+		logging.FromContext(ctx).Error(op, "fail read journal", err, logging.AccountId(accountId))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sub := &BalanceSubscription{
+		stream:    s,
+		accountId: accountId,
+		after:     lastEventId,
+		events:    make(chan JournalEntry, streamBuffer),
+	}
+	if lastEventId <= 0 && len(latest) != 0 {
+		sub.after = latest[0].Id
+		sub.events <- latest[0]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil || s.ctx.Err() != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrStreamClosed)
+	}
+	feed, ok := s.feeds[accountId]
+	if !ok {
+		ctx, stop := context.WithCancel(s.ctx)
+		feed = &balanceFeed{
+			subscribers: make(map[*BalanceSubscription]struct{}),
+			stop:        stop,
+		}
+		s.feeds[accountId] = feed
+		go s.follow(ctx, accountId, feed)
+	}
+	feed.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// remove must be called under lock, the last subscriber stops feed.
+func (s *BalanceStream) remove(sub *BalanceSubscription) {
+	feed, ok := s.feeds[sub.accountId]
+	if !ok || !feed.has(sub) {
+		return
+	}
+	delete(feed.subscribers, sub)
+	close(sub.events)
+	if len(feed.subscribers) == 0 {
+		feed.stop()
+		delete(s.feeds, sub.accountId)
+	}
+}
+
+func (s *BalanceStream) follow(ctx context.Context, accountId int64, feed *balanceFeed) {
+	const op = "BalanceStream"
+	log := logging.FromContext(ctx).With(logging.AccountId(accountId))
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// subscribers resumed from older entries are caught up by batches
+		for ctx.Err() == nil {
+			subscribers, after := s.subscribersOf(feed)
+			if len(subscribers) == 0 {
+				break
+			}
+			entries, err := s.journal.ListJournalEntries(ctx, JournalFilter{
+				AccountId:   accountId,
+				AfterId:     after,
+				OldestFirst: true,
+				Limit:       streamBatch,
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Error(op, "fail read journal", err)
+				}
+				break
+			}
+			if progressed := s.send(subscribers, entries); !progressed || len(entries) < streamBatch {
+				break
+			}
+		}
+	}
+}
+
+// subscribersOf returns subscribers of feed and the least id of entries passed to them.
+func (s *BalanceStream) subscribersOf(feed *balanceFeed) ([]*BalanceSubscription, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var (
+		subscribers = make([]*BalanceSubscription, 0, len(feed.subscribers))
+		after       int64
+	)
+	for sub := range feed.subscribers {
+		if len(subscribers) == 0 || sub.after < after {
+			after = sub.after
+		}
+		subscribers = append(subscribers, sub)
+	}
+	return subscribers, after
+}
+
+// send passes entries to subscribers while they have room, returns true if any entry is passed.
+func (s *BalanceStream) send(subscribers []*BalanceSubscription, entries []JournalEntry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	progressed := false
+	for _, sub := range subscribers {
+		// subscriber may be closed after subscribersOf
+		if feed, ok := s.feeds[sub.accountId]; !ok || !feed.has(sub) {
+			continue
+		}
+	entries:
+		for _, entry := range entries {
+			if entry.Id <= sub.after {
+				continue
+			}
+			select {
+			case sub.events <- entry:
+				sub.after = entry.Id
+				sub.stalledAt = time.Time{}
+				progressed = true
+			default:
+				if sub.stalledAt.IsZero() {
+					sub.stalledAt = now
+				} else if now.Sub(sub.stalledAt) > s.stallTimeout {
+					s.remove(sub)
+				}
+				break entries
+			}
+		}
+	}
+	return progressed
+}
@@ -0,0 +1,74 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+func TestBalanceStreamStallTimeout(t *testing.T) {
+	const (
+		interval     = 5 * time.Millisecond
+		stallTimeout = 50 * time.Millisecond
+		// more than buffer of subscription
+		deposits = 100
+	)
+	repo := accounts.NewInMemory()
+	id := accountWithDeposits(t, repo, deposits)
+	ctx, stop := context.WithCancel(logging.Context(context.Background(), logging.Discard()))
+	defer stop()
+	stream := application.NewBalanceStream(repo, interval, stallTimeout)
+	go stream.Run(ctx)
+
+	first, err := repo.ListJournalEntries(ctx, application.JournalFilter{AccountId: id, OldestFirst: true, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribe := func() *application.BalanceSubscription {
+		t.Helper()
+		// Run may not accept subscribers yet
+		for {
+			sub, err := stream.Subscribe(ctx, id, first[0].Id)
+			if err == nil {
+				t.Cleanup(sub.Close)
+				return sub
+			}
+			time.Sleep(interval)
+		}
+	}
+	stalled, reading := subscribe(), subscribe()
+
+	// reading subscriber receives all entries while other one stalls
+	timeout := time.After(5 * time.Second)
+	for received := 1; received < deposits; received++ {
+		select {
+		case _, ok := <-reading.Events():
+			if !ok {
+				t.Fatalf("reading subscriber is dropped after %d entries", received)
+			}
+		case <-timeout:
+			t.Fatalf("reading subscriber received %d entries", received)
+		}
+	}
+
+	time.Sleep(2 * stallTimeout)
+	var buffered int
+	for {
+		select {
+		case _, ok := <-stalled.Events():
+			if !ok {
+				if buffered == 0 || buffered >= deposits-1 {
+					t.Fatalf("stalled subscriber got %d entries before drop", buffered)
+				}
+				return
+			}
+			buffered++
+		case <-timeout:
+			t.Fatalf("stalled subscriber isn't dropped, it got %d entries", buffered)
+		}
+	}
+}
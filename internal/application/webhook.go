@@ -0,0 +1,548 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+// EventBalanceLow is delivered only to webhooks, when balance of account
+// drops below threshold of webhook.
+const EventBalanceLow account.EventType = "balance_low"
+
+var (
+	ErrWebhookNotFound             = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL           = errors.New("webhook url must be absolute http or https url")
+	ErrWebhookEventsRequired       = errors.New("webhook must subscribe to at least one event")
+	ErrWeakWebhookSecret           = errors.New("webhook secret must have at least 16 characters")
+	ErrLowBalanceThresholdRequired = errors.New("low balance threshold is required for balance_low event")
+)
+
+// MinWebhookSecretLength is minimal length of secret set by client.
+const MinWebhookSecretLength = 16
+
+type Webhook struct {
+	Id  string
+	URL string
+	// Events filters delivered messages by type.
+	Events []account.EventType
+	// AccountId limits webhook to one account, zero means all accounts.
+	AccountId int64
+	// LowBalanceThreshold is set for webhooks of EventBalanceLow.
+	LowBalanceThreshold *account.Money
+	// Secret signs deliveries.
+	Secret    string
+	CreatedAt time.Time
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead is set after all attempts failed, delivery is retried only on request.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+var ErrUnknownDeliveryStatus = errors.New("unknown delivery status")
+
+func ParseDeliveryStatus(s string) (DeliveryStatus, error) {
+	switch st := DeliveryStatus(s); st {
+	case DeliveryPending, DeliveryDelivered, DeliveryDead:
+		return st, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownDeliveryStatus, s)
+	}
+}
+
+// WebhookDelivery is message sent to webhook with log of its attempts.
+type WebhookDelivery struct {
+	Id        string
+	WebhookId string
+	// MessageId is id of outbox message, with Event it identifies delivery for consumer.
+	MessageId int64
+	Event     account.EventType
+	// Body is sent as is on every attempt.
+	Body          []byte
+	Status        DeliveryStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	// LastAttemptAt is zero until first attempt.
+	LastAttemptAt time.Time
+	// LastStatusCode is zero if last attempt got no response.
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+}
+
+// DeliveryFilter selects deliveries of webhook, newest first.
+type DeliveryFilter struct {
+	WebhookId string
+	// Status is empty for all statuses.
+	Status DeliveryStatus
+	Limit  int
+}
+
+type WebhookStore interface {
+	SaveWebhook(ctx context.Context, webhook Webhook) error
+	// GetWebhook returns ErrWebhookNotFound for unknown id.
+	GetWebhook(ctx context.Context, id string) (Webhook, error)
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	// DeleteWebhook removes webhook with its deliveries, returns ErrWebhookNotFound for unknown id.
+	DeleteWebhook(ctx context.Context, id string) error
+
+	// AddWebhookDeliveries skips deliveries of message and event already added for webhook,
+	// so message published again isn't delivered twice.
+	AddWebhookDeliveries(ctx context.Context, deliveries ...WebhookDelivery) error
+	// ClaimWebhookDeliveries returns pending deliveries due at now and postpones
+	// them by lease, so other senders don't get them while they are sent.
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error)
+	SaveWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error
+	// GetWebhookDelivery returns ErrWebhookDeliveryNotFound for unknown id.
+	GetWebhookDelivery(ctx context.Context, webhookId, id string) (WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, filter DeliveryFilter) ([]WebhookDelivery, error)
+}
+
+type WebhookService struct {
+	store WebhookStore
+}
+
+func NewWebhookService(store WebhookStore) *WebhookService {
+	return &WebhookService{store: store}
+}
+
+const (
+	DefaultDeliveriesLimit = 50
+	MaxDeliveriesLimit     = 500
+)
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, cmd CreateWebhookCommand) (webhook Webhook, err error) {
+	const op = "CreateWebhook"
+	log := logging.FromContext(ctx)
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail create webhook", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "webhook created", logging.String("webhook_id", webhook.Id))
+		}
+	}()
+
+	if err = validateWebhookURL(cmd.URL); err != nil {
+		return
+	}
+	if len(cmd.Events) == 0 {
+		return Webhook{}, ErrWebhookEventsRequired
+	}
+	if slices.Contains(cmd.Events, EventBalanceLow) != (cmd.LowBalanceThreshold != nil) {
+		return Webhook{}, ErrLowBalanceThresholdRequired
+	}
+
+	secret := cmd.Secret
+	if secret == "" {
+		if secret, err = newRandomId(); err != nil {
+			return
+		}
+	} else if len(secret) < MinWebhookSecretLength {
+		return Webhook{}, ErrWeakWebhookSecret
+	}
+
+	id, err := newRandomId()
+	if err != nil {
+		return
+	}
+	events := slices.Clone(cmd.Events)
+	slices.Sort(events)
+	webhook = Webhook{
+		Id:                  id,
+		URL:                 cmd.URL,
+		Events:              slices.Compact(events),
+		AccountId:           cmd.AccountId,
+		LowBalanceThreshold: cmd.LowBalanceThreshold,
+		Secret:              secret,
+		CreatedAt:           time.Now(),
+	}
+	if err = s.store.SaveWebhook(ctx, webhook); err != nil {
+		return
+	}
+	return webhook, nil
+}
+
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+// ParseWebhookEvent accepts account event types and EventBalanceLow.
+func ParseWebhookEvent(s string) (account.EventType, error) {
+	if account.EventType(s) == EventBalanceLow {
+		return EventBalanceLow, nil
+	}
+	return account.ParseEventType(s)
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, id string) (webhook Webhook, err error) {
+	const op = "GetWebhook"
+	webhook, err = s.store.GetWebhook(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx).Error(op, "fail get webhook", err, logging.String("webhook_id", id))
+		return Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return webhook, nil
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	const op = "ListWebhooks"
+	webhooks, err := s.store.ListWebhooks(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error(op, "fail list webhooks", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) (err error) {
+	const op = "DeleteWebhook"
+	log := logging.FromContext(ctx).With(logging.String("webhook_id", id))
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail delete webhook", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "webhook deleted")
+		}
+	}()
+
+	return s.store.DeleteWebhook(ctx, id)
+}
+
+func (s *WebhookService) ListWebhookDeliveries(ctx context.Context, cmd ListWebhookDeliveriesCommand) (deliveries []WebhookDelivery, err error) {
+	const op = "ListWebhookDeliveries"
+	defer func() {
+		if err != nil {
+			logging.FromContext(ctx).Error(op, "fail list webhook deliveries", err, logging.String("webhook_id", cmd.WebhookId))
+			err = fmt.Errorf("%s: %w", op, err)
+		}
+	}()
+
+	limit := cmd.Limit
+	if limit <= 0 {
+		limit = DefaultDeliveriesLimit
+	}
+	limit = min(limit, MaxDeliveriesLimit)
+
+	if _, err = s.store.GetWebhook(ctx, cmd.WebhookId); err != nil {
+		return
+	}
+	return s.store.ListWebhookDeliveries(ctx, DeliveryFilter{
+		WebhookId: cmd.WebhookId,
+		Status:    cmd.Status,
+		Limit:     limit,
+	})
+}
+
+// RetryWebhookDelivery schedules delivery for immediate sending with new attempts,
+// it is used for dead deliveries after receiver is fixed.
+func (s *WebhookService) RetryWebhookDelivery(ctx context.Context, cmd RetryWebhookDeliveryCommand) (delivery WebhookDelivery, err error) {
+	const op = "RetryWebhookDelivery"
+	log := logging.FromContext(ctx).With(
+		logging.String("webhook_id", cmd.WebhookId),
+		logging.String("delivery_id", cmd.DeliveryId),
+	)
+	defer func() {
+		if err != nil {
+			log.Error(op, "fail retry webhook delivery", err)
+			err = fmt.Errorf("%s: %w", op, err)
+		} else {
+			log.Info(op, "webhook delivery scheduled")
+		}
+	}()
+
+	delivery, err = s.store.GetWebhookDelivery(ctx, cmd.WebhookId, cmd.DeliveryId)
+	if err != nil {
+		return
+	}
+	if delivery.Status == DeliveryPending {
+		return delivery, nil
+	}
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err = s.store.SaveWebhookDelivery(ctx, delivery); err != nil {
+		return
+	}
+	return delivery, nil
+}
+
+// WebhookDispatcher is Publisher adding deliveries of message for matching webhooks,
+// WebhookSender sends them.
+type WebhookDispatcher struct {
+	store WebhookStore
+}
+
+func NewWebhookDispatcher(store WebhookStore) *WebhookDispatcher {
+	return &WebhookDispatcher{store: store}
+}
+
+func (d *WebhookDispatcher) Publish(ctx context.Context, message OutboxMessage) error {
+	webhooks, err := d.store.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var deliveries []WebhookDelivery
+	add := func(w Webhook, message OutboxMessage) error {
+		delivery, err := newWebhookDelivery(w, message, now)
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, delivery)
+		return nil
+	}
+	for _, w := range webhooks {
+		if w.AccountId != 0 && w.AccountId != message.AccountId {
+			continue
+		}
+		if slices.Contains(w.Events, message.Type) {
+			if err := add(w, message); err != nil {
+				return err
+			}
+		}
+		if slices.Contains(w.Events, EventBalanceLow) && w.LowBalanceThreshold != nil {
+			low, ok, err := lowBalanceMessage(message, *w.LowBalanceThreshold)
+			if err != nil {
+				return err
+			}
+			if ok {
+				if err := add(w, low); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return d.store.AddWebhookDeliveries(ctx, deliveries...)
+}
+
+func newWebhookDelivery(w Webhook, message OutboxMessage, now time.Time) (WebhookDelivery, error) {
+	id, err := newRandomId()
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	return WebhookDelivery{
+		Id:            id,
+		WebhookId:     w.Id,
+		MessageId:     message.Id,
+		Event:         message.Type,
+		Body:          body,
+		Status:        DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
+
+// lowBalanceMessage returns EventBalanceLow message if balance crossed threshold downwards.
+func lowBalanceMessage(message OutboxMessage, threshold account.Money) (OutboxMessage, bool, error) {
+	switch message.Type {
+	case account.EventWithdrawn, account.EventTransferSent, account.EventHoldCaptured:
+	default:
+		return OutboxMessage{}, false, nil
+	}
+
+	var payload eventPayload
+	if err := json.Unmarshal(message.Payload, &payload); err != nil {
+		return OutboxMessage{}, false, err
+	}
+	if payload.Balance == nil || payload.Amount == nil {
+		return OutboxMessage{}, false, nil
+	}
+	before := payload.Balance.Add(*payload.Amount)
+	if !payload.Balance.LessThan(threshold) || before.LessThan(threshold) {
+		return OutboxMessage{}, false, nil
+	}
+
+	data, err := json.Marshal(struct {
+		Currency  account.Currency `json:"currency"`
+		Balance   account.Money    `json:"balance"`
+		Threshold account.Money    `json:"threshold"`
+	}{payload.Currency, *payload.Balance, threshold})
+	if err != nil {
+		return OutboxMessage{}, false, err
+	}
+	low := message
+	low.Type = EventBalanceLow
+	low.Payload = data
+	return low, true, nil
+}
+
+// WebhookClient sends delivery signed with secret of webhook.
+type WebhookClient interface {
+	// Send returns status code of response, error if request failed or response isn't 2xx.
+	Send(ctx context.Context, webhook Webhook, delivery WebhookDelivery) (int, error)
+}
+
+type WebhookRetryPolicy struct {
+	// MaxAttempts failed ones make delivery dead.
+	MaxAttempts int
+	// Backoff is delay after first failed attempt, it doubles after every next one up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func (p WebhookRetryPolicy) delay(attempts int) time.Duration {
+	return backoffDelay(p.Backoff, p.MaxBackoff, attempts)
+}
+
+// backoffDelay doubles backoff after every failed attempt up to maxBackoff.
+func backoffDelay(backoff, maxBackoff time.Duration, attempts int) time.Duration {
+	delay := backoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+const (
+	// webhookLease must be longer than timeout of WebhookClient.
+	webhookLease = time.Minute
+	// webhookSenders bounds deliveries sent concurrently.
+	webhookSenders = 8
+)
+
+// WebhookSender sends due deliveries, failed ones are retried with exponential backoff.
+type WebhookSender struct {
+	store     WebhookStore
+	client    WebhookClient
+	retry     WebhookRetryPolicy
+	interval  time.Duration
+	batchSize int
+}
+
+func NewWebhookSender(
+	store WebhookStore,
+	client WebhookClient,
+	retry WebhookRetryPolicy,
+	interval time.Duration,
+	batchSize int,
+) *WebhookSender {
+	return &WebhookSender{
+		store:     store,
+		client:    client,
+		retry:     retry,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run blocks until ctx is done.
+func (s *WebhookSender) Run(ctx context.Context) {
+	const op = "WebhookSender"
+	log := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := s.SendDue(ctx); err != nil {
+			log.Error(op, "fail claim webhook deliveries", err)
+		}
+	}
+}
+
+// SendDue sends due deliveries while batches are full and returns count of attempts.
+func (s *WebhookSender) SendDue(ctx context.Context) (int, error) {
+	var sent int
+	for ctx.Err() == nil {
+		deliveries, err := s.store.ClaimWebhookDeliveries(ctx, time.Now(), webhookLease, s.batchSize)
+		if err != nil {
+			return sent, err
+		}
+		s.sendAll(ctx, deliveries)
+		sent += len(deliveries)
+		if len(deliveries) < s.batchSize {
+			break
+		}
+	}
+	return sent, nil
+}
+
+func (s *WebhookSender) sendAll(ctx context.Context, deliveries []WebhookDelivery) {
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, webhookSenders)
+	)
+	for _, delivery := range deliveries {
+		delivery := delivery
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.send(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *WebhookSender) send(ctx context.Context, delivery WebhookDelivery) {
+	const op = "WebhookSender"
+	log := logging.FromContext(ctx).With(
+		logging.String("webhook_id", delivery.WebhookId),
+		logging.String("delivery_id", delivery.Id),
+	)
+
+	webhook, err := s.store.GetWebhook(ctx, delivery.WebhookId)
+	if err != nil {
+		// deliveries of deleted webhook are deleted with it
+		if !errors.Is(err, ErrWebhookNotFound) {
+			log.Error(op, "fail get webhook", err)
+		}
+		return
+	}
+
+	code, err := s.client.Send(ctx, webhook, delivery)
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = now
+	delivery.LastStatusCode = code
+	switch {
+	case err == nil:
+		delivery.Status = DeliveryDelivered
+		delivery.LastError = ""
+	case delivery.Attempts >= s.retry.MaxAttempts:
+		delivery.Status = DeliveryDead
+		delivery.LastError = err.Error()
+		log.Error(op, "webhook delivery is dead", err, logging.Int64("attempts", int64(delivery.Attempts)))
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(s.retry.delay(delivery.Attempts))
+	}
+
+	if err := s.store.SaveWebhookDelivery(ctx, delivery); err != nil {
+		log.Error(op, "fail save webhook delivery", err)
+	}
+}
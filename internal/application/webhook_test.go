@@ -0,0 +1,273 @@
+package application_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/webhook"
+)
+
+const webhookSecret = "whsec-0123456789abcdef"
+
+// receiver counts requests with valid signature and responds with status.
+func receiver(t *testing.T, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		if r.Header.Get(webhook.HeaderSignature) != "sha256="+webhook.Sign(webhookSecret, timestamp, body) {
+			t.Error("delivery has invalid signature")
+		}
+		received.Add(1)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+// dispatchDeposit adds delivery of deposit message for new webhook of url.
+func dispatchDeposit(t *testing.T, store *accounts.AccountStorage, url string) string {
+	t.Helper()
+	ctx := context.Background()
+	service := application.NewWebhookService(store)
+	w, err := service.CreateWebhook(ctx, application.CreateWebhookCommand{
+		URL:    url,
+		Events: []account.EventType{account.EventDeposited},
+		Secret: webhookSecret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = application.NewWebhookDispatcher(store).Publish(ctx, application.OutboxMessage{
+		Id:        1,
+		AccountId: 1,
+		Type:      account.EventDeposited,
+		Payload:   []byte(`{"currency":"USD","amount":"10.00","balance":"10.00"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w.Id
+}
+
+func deliveryOf(t *testing.T, store *accounts.AccountStorage, webhookId string) application.WebhookDelivery {
+	t.Helper()
+	deliveries, err := store.ListWebhookDeliveries(context.Background(), application.DeliveryFilter{WebhookId: webhookId, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("webhook has %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+func sendDue(t *testing.T, sender *application.WebhookSender) {
+	t.Helper()
+	if _, err := sender.SendDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookSenderDelivered(t *testing.T) {
+	server, received := receiver(t, http.StatusOK)
+	store := accounts.NewInMemory()
+	webhookId := dispatchDeposit(t, store, server.URL)
+	sender := application.NewWebhookSender(
+		store,
+		webhook.NewClient(time.Second),
+		application.WebhookRetryPolicy{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: time.Minute},
+		time.Second,
+		10,
+	)
+
+	sendDue(t, sender)
+	delivery := deliveryOf(t, store, webhookId)
+	if delivery.Status != application.DeliveryDelivered || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusOK {
+		t.Fatalf("delivery %+v isn't delivered with one attempt", delivery)
+	}
+	sendDue(t, sender)
+	if got := received.Load(); got != 1 {
+		t.Fatalf("receiver got %d requests, want 1", got)
+	}
+}
+
+func TestWebhookSenderBackoff(t *testing.T) {
+	const (
+		backoff     = 10 * time.Millisecond
+		maxBackoff  = 25 * time.Millisecond
+		maxAttempts = 4
+	)
+	server, received := receiver(t, http.StatusServiceUnavailable)
+	store := accounts.NewInMemory()
+	webhookId := dispatchDeposit(t, store, server.URL)
+	sender := application.NewWebhookSender(
+		store,
+		webhook.NewClient(time.Second),
+		application.WebhookRetryPolicy{MaxAttempts: maxAttempts, Backoff: backoff, MaxBackoff: maxBackoff},
+		time.Second,
+		10,
+	)
+
+	// delay doubles after every failed attempt up to max backoff
+	wantDelays := []time.Duration{backoff, 2 * backoff, maxBackoff}
+	for attempt, want := range wantDelays {
+		sendDue(t, sender)
+		delivery := deliveryOf(t, store, webhookId)
+		if delivery.Status != application.DeliveryPending || delivery.Attempts != attempt+1 {
+			t.Fatalf("attempt %d: delivery %+v", attempt+1, delivery)
+		}
+		if delivery.LastStatusCode != http.StatusServiceUnavailable || delivery.LastError == "" {
+			t.Fatalf("attempt %d: failure isn't saved: %+v", attempt+1, delivery)
+		}
+		if got := delivery.NextAttemptAt.Sub(delivery.LastAttemptAt); got != want {
+			t.Fatalf("attempt %d: delay %v, want %v", attempt+1, got, want)
+		}
+
+		// delivery isn't sent before delay
+		sendDue(t, sender)
+		if got := received.Load(); got != int32(attempt+1) {
+			t.Fatalf("attempt %d: receiver got %d requests before delay", attempt+1, got)
+		}
+		time.Sleep(time.Until(delivery.NextAttemptAt))
+	}
+
+	sendDue(t, sender)
+	delivery := deliveryOf(t, store, webhookId)
+	if delivery.Status != application.DeliveryDead || delivery.Attempts != maxAttempts {
+		t.Fatalf("delivery %+v isn't dead after %d attempts", delivery, maxAttempts)
+	}
+	time.Sleep(maxBackoff)
+	sendDue(t, sender)
+	if got := received.Load(); got != maxAttempts {
+		t.Fatalf("receiver got %d requests, want %d", got, maxAttempts)
+	}
+}
+
+func TestWebhookSenderLease(t *testing.T) {
+	store := accounts.NewInMemory()
+	webhookId := dispatchDeposit(t, store, "https://example.com/hook")
+	ctx := context.Background()
+	now := time.Now()
+
+	claimed, err := store.ClaimWebhookDeliveries(ctx, now, time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 1 || claimed[0].WebhookId != webhookId {
+		t.Fatalf("claimed %+v, want delivery of webhook", claimed)
+	}
+	// other sender doesn't get leased delivery
+	again, err := store.ClaimWebhookDeliveries(ctx, now.Add(time.Second), time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("leased delivery is claimed again: %+v", again)
+	}
+	// sender crashed, delivery is claimed after lease
+	expired, err := store.ClaimWebhookDeliveries(ctx, now.Add(time.Minute+time.Second), time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 1 || expired[0].Id != claimed[0].Id {
+		t.Fatalf("claimed %+v after lease, want delivery %s", expired, claimed[0].Id)
+	}
+}
+
+func TestLowBalanceCrossing(t *testing.T) {
+	const threshold = "50"
+	tests := []struct {
+		name      string
+		eventType account.EventType
+		accountId int64
+		amount    string
+		balance   string
+		wantLow   bool
+	}{
+		{name: "withdrawal crosses threshold", eventType: account.EventWithdrawn, amount: "10.00", balance: "45.00", wantLow: true},
+		{name: "withdrawal from threshold", eventType: account.EventWithdrawn, amount: "10.00", balance: "40.00", wantLow: true},
+		{name: "withdrawal to threshold", eventType: account.EventWithdrawn, amount: "10.00", balance: "50.00"},
+		{name: "balance already below", eventType: account.EventWithdrawn, amount: "10.00", balance: "30.00"},
+		{name: "balance stays above", eventType: account.EventWithdrawn, amount: "10.00", balance: "60.00"},
+		{name: "sent transfer", eventType: account.EventTransferSent, amount: "10.00", balance: "45.00", wantLow: true},
+		{name: "captured hold", eventType: account.EventHoldCaptured, amount: "10.00", balance: "45.00", wantLow: true},
+		{name: "deposit", eventType: account.EventDeposited, amount: "10.00", balance: "45.00"},
+		{name: "other account", eventType: account.EventWithdrawn, accountId: 2, amount: "10.00", balance: "45.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := accounts.NewInMemory()
+			limit := account.MustParseMoney(threshold)
+			w, err := application.NewWebhookService(store).CreateWebhook(ctx, application.CreateWebhookCommand{
+				URL:                 "https://example.com/hook",
+				Events:              []account.EventType{application.EventBalanceLow},
+				AccountId:           1,
+				LowBalanceThreshold: &limit,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			accountId := tt.accountId
+			if accountId == 0 {
+				accountId = 1
+			}
+			payload, err := json.Marshal(map[string]string{"currency": "USD", "amount": tt.amount, "balance": tt.balance})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = application.NewWebhookDispatcher(store).Publish(ctx, application.OutboxMessage{
+				Id:        1,
+				AccountId: accountId,
+				Type:      tt.eventType,
+				Payload:   payload,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			deliveries, err := store.ListWebhookDeliveries(ctx, application.DeliveryFilter{WebhookId: w.Id, Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.wantLow {
+				if len(deliveries) != 0 {
+					t.Fatalf("unexpected deliveries %+v", deliveries)
+				}
+				return
+			}
+			if len(deliveries) != 1 || deliveries[0].Event != application.EventBalanceLow {
+				t.Fatalf("deliveries %+v, want one of %s", deliveries, application.EventBalanceLow)
+			}
+			var body struct {
+				AccountId int64 `json:"account_id"`
+				Payload   struct {
+					Balance   account.Money `json:"balance"`
+					Threshold account.Money `json:"threshold"`
+				} `json:"payload"`
+			}
+			if err := json.Unmarshal(deliveries[0].Body, &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.AccountId != accountId || !body.Payload.Balance.Equal(account.MustParseMoney(tt.balance)) ||
+				!body.Payload.Threshold.Equal(limit) {
+				t.Fatalf("unexpected body %s", deliveries[0].Body)
+			}
+		})
+	}
+}
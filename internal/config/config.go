@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
@@ -15,11 +16,177 @@ type DatabaseConfig struct {
 	Password string `env:"DB_PASSWORD"`
 }
 
+type AcquirerKind string
+
+const (
+	// AcquirerRepository locks accounts by strategy of postgres repository.
+	AcquirerRepository AcquirerKind = "repository"
+	// AcquirerAdvisory uses pg_advisory_xact_lock, safe for multiple instances.
+	AcquirerAdvisory AcquirerKind = "advisory"
+	// AcquirerMemory serializes access only within one process.
+	AcquirerMemory AcquirerKind = "memory"
+)
+
+type ConcurrencyConfig struct {
+	Acquirer AcquirerKind `env:"ACQUIRER" env-default:"repository"`
+	// AdvisoryMaxWait limits waiting for advisory lock, zero means until request is done
+	AdvisoryMaxWait time.Duration `env:"ADVISORY_LOCK_MAX_WAIT" env-default:"5s"`
+	// Strategy is pessimistic, serializable or optimistic
+	Strategy    string        `env:"DB_CONCURRENCY_STRATEGY" env-default:"pessimistic"`
+	LockNoWait  bool          `env:"DB_LOCK_NOWAIT" env-default:"false"`
+	LockTimeout time.Duration `env:"DB_LOCK_TIMEOUT" env-default:"0s"`
+	MaxRetries  int           `env:"DB_MAX_RETRIES" env-default:"5"`
+}
+
 type WebServerConfig struct {
 	Host string `env:"APP_HOST"`
 	Port int    `env:"APP_PORT"`
 }
 
+type GRPCServerConfig struct {
+	Enabled bool   `env:"GRPC_ENABLED" env-default:"true"`
+	Host    string `env:"GRPC_HOST"`
+	Port    int    `env:"GRPC_PORT" env-default:"9090"`
+}
+
+type IdempotencyConfig struct {
+	KeyRetention    time.Duration `env:"IDEMPOTENCY_KEY_RETENTION" env-default:"24h"`
+	CleanupInterval time.Duration `env:"IDEMPOTENCY_CLEANUP_INTERVAL" env-default:"10m"`
+}
+
+type HoldsConfig struct {
+	TTL           time.Duration `env:"HOLD_TTL" env-default:"168h"`
+	SweepInterval time.Duration `env:"HOLDS_SWEEP_INTERVAL" env-default:"1m"`
+}
+
+type AccountStore string
+
+const (
+	// AccountStoreState overwrites state of account in place.
+	AccountStoreState AccountStore = "state"
+	// AccountStoreEvents appends domain events of account, state is kept as read model.
+	AccountStoreEvents AccountStore = "events"
+)
+
+type AccountsConfig struct {
+	FrozenAcceptDeposits bool `env:"FROZEN_ACCOUNTS_ACCEPT_DEPOSITS" env-default:"true"`
+	// Store of events requires postgres storage backend
+	Store         AccountStore `env:"ACCOUNT_STORE" env-default:"state"`
+	SnapshotEvery int64        `env:"ACCOUNT_SNAPSHOT_EVERY" env-default:"100"`
+}
+
+type ExchangeProvider string
+
+const (
+	ExchangeProviderPostgres ExchangeProvider = "postgres"
+	ExchangeProviderStatic   ExchangeProvider = "static"
+)
+
+type ExchangeConfig struct {
+	// Provider is chosen by storage backend if empty
+	Provider  ExchangeProvider `env:"EXCHANGE_RATES_PROVIDER"`
+	RatesFile string           `env:"EXCHANGE_RATES_FILE"`
+	// Spread is fraction of market rate kept on conversion, e.g. 0.005
+	Spread   string `env:"EXCHANGE_SPREAD" env-default:"0"`
+	Rounding string `env:"EXCHANGE_ROUNDING" env-default:"half_even"`
+}
+
+type StorageBackend string
+
+const (
+	StoragePostgres StorageBackend = "postgres"
+	// StorageMemory keeps data in process, it doesn't need database.
+	StorageMemory StorageBackend = "memory"
+	// StorageSQLite keeps data in embedded database file.
+	StorageSQLite StorageBackend = "sqlite"
+)
+
+type SQLiteConfig struct {
+	Path string `env:"SQLITE_PATH" env-default:"bank.db"`
+	// BusyTimeout limits waiting for write lock of database
+	BusyTimeout time.Duration `env:"SQLITE_BUSY_TIMEOUT" env-default:"5s"`
+}
+
+// MemoryConfig makes memory storage durable when DataDir is set.
+type MemoryConfig struct {
+	DataDir string `env:"MEMORY_DATA_DIR"`
+	// Fsync is always, batch or interval
+	Fsync            string        `env:"MEMORY_FSYNC" env-default:"always"`
+	FsyncInterval    time.Duration `env:"MEMORY_FSYNC_INTERVAL" env-default:"100ms"`
+	SnapshotInterval time.Duration `env:"MEMORY_SNAPSHOT_INTERVAL" env-default:"5m"`
+}
+
+type OutboxPublisher string
+
+const (
+	// OutboxPublisherNone delivers messages only to webhooks.
+	OutboxPublisherNone   OutboxPublisher = "none"
+	OutboxPublisherStdout OutboxPublisher = "stdout"
+	OutboxPublisherFile   OutboxPublisher = "file"
+	OutboxPublisherHTTP   OutboxPublisher = "http"
+)
+
+type OutboxConfig struct {
+	Publisher     OutboxPublisher `env:"OUTBOX_PUBLISHER" env-default:"none"`
+	File          string          `env:"OUTBOX_FILE" env-default:"outbox.jsonl"`
+	URL           string          `env:"OUTBOX_HTTP_URL"`
+	HTTPTimeout   time.Duration   `env:"OUTBOX_HTTP_TIMEOUT" env-default:"5s"`
+	RelayInterval time.Duration   `env:"OUTBOX_RELAY_INTERVAL" env-default:"1s"`
+	BatchSize     int             `env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+	MaxAttempts   int             `env:"OUTBOX_MAX_ATTEMPTS" env-default:"10"`
+	Backoff       time.Duration   `env:"OUTBOX_BACKOFF" env-default:"1s"`
+	MaxBackoff    time.Duration   `env:"OUTBOX_MAX_BACKOFF" env-default:"10m"`
+}
+
+type WebhooksConfig struct {
+	Timeout     time.Duration `env:"WEBHOOK_TIMEOUT" env-default:"10s"`
+	MaxAttempts int           `env:"WEBHOOK_MAX_ATTEMPTS" env-default:"8"`
+	Backoff     time.Duration `env:"WEBHOOK_BACKOFF" env-default:"10s"`
+	MaxBackoff  time.Duration `env:"WEBHOOK_MAX_BACKOFF" env-default:"1h"`
+	// SendInterval is period of checking due deliveries.
+	SendInterval time.Duration `env:"WEBHOOK_SEND_INTERVAL" env-default:"1s"`
+	BatchSize    int           `env:"WEBHOOK_BATCH_SIZE" env-default:"100"`
+}
+
+type StreamConfig struct {
+	// PollInterval is period of reading journal of streamed accounts.
+	PollInterval time.Duration `env:"STREAM_POLL_INTERVAL" env-default:"250ms"`
+	// StallTimeout drops subscriber not reading events, it resumes after reconnect.
+	StallTimeout time.Duration `env:"STREAM_STALL_TIMEOUT" env-default:"30s"`
+	// AllowedOrigins are origins of browser pages allowed to open WebSocket stream
+	// besides origin of API itself, "*" allows any origin.
+	AllowedOrigins []string `env:"STREAM_ALLOWED_ORIGINS" env-separator:","`
+}
+
+// AuthConfig requires API key (X-API-Key header) or bearer token for HTTP and gRPC APIs when enabled.
+type AuthConfig struct {
+	Enabled bool `env:"AUTH_ENABLED" env-default:"true"`
+	// BootstrapKey is admin key which isn't stored, it is used to issue the first keys.
+	BootstrapKey string `env:"AUTH_BOOTSTRAP_KEY"`
+	JWT          JWTConfig
+}
+
+// JWTConfig accepts bearer tokens when HMAC keys or JWKS file are set.
+// All keys are accepted, so key is rotated by adding new one before tokens are signed by it
+// and removing old one after its tokens expire.
+type JWTConfig struct {
+	// HMACKeys are "kid:secret" of HS256 tokens, secret without kid verifies tokens without kid.
+	HMACKeys []string `env:"JWT_HMAC_KEYS" env-separator:","`
+	// JWKSFile has RS256, ES256 and HS256 keys, it is reloaded when changed.
+	JWKSFile           string        `env:"JWT_JWKS_FILE"`
+	JWKSReloadInterval time.Duration `env:"JWT_JWKS_RELOAD_INTERVAL" env-default:"1m"`
+	// Issuer and Audience are checked when set.
+	Issuer   string `env:"JWT_ISSUER"`
+	Audience string `env:"JWT_AUDIENCE"`
+	// AccountsClaim lists ids of accounts available to subject of token.
+	AccountsClaim string        `env:"JWT_ACCOUNTS_CLAIM" env-default:"accounts"`
+	Leeway        time.Duration `env:"JWT_LEEWAY" env-default:"30s"`
+}
+
+func (c JWTConfig) Enabled() bool {
+	return len(c.HMACKeys) != 0 || c.JWKSFile != ""
+}
+
 type Env string
 
 const (
@@ -28,9 +195,22 @@ const (
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Server   WebServerConfig
-	Env      Env `env:"APP_ENV" env-default:"dev"`
+	StorageBackend StorageBackend `env:"STORAGE_BACKEND" env-default:"postgres"`
+	Database       DatabaseConfig
+	Memory         MemoryConfig
+	SQLite         SQLiteConfig
+	Concurrency    ConcurrencyConfig
+	Server         WebServerConfig
+	GRPC           GRPCServerConfig
+	Idempotency    IdempotencyConfig
+	Exchange       ExchangeConfig
+	Holds          HoldsConfig
+	Accounts       AccountsConfig
+	Outbox         OutboxConfig
+	Webhooks       WebhooksConfig
+	Stream         StreamConfig
+	Auth           AuthConfig
+	Env            Env `env:"APP_ENV" env-default:"dev"`
 }
 
 var cfg Config
@@ -0,0 +1,48 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNegativeCreditLimit = errors.New("negative credit limit")
+	// ErrCreditLimitInUse returned when new limit doesn't cover already used credit.
+	ErrCreditLimitInUse = errors.New("credit limit is less than used credit")
+)
+
+func (a *Account) CreditLimit() Money {
+	return a.creditLimit
+}
+
+// CreditHeadroom is unused part of credit limit.
+func (a *Account) CreditHeadroom() Money {
+	available := a.AvailableBalance()
+	if available.LessThan(a.creditLimit) {
+		return available
+	}
+	return a.creditLimit
+}
+
+// SetCreditLimit changes limit of negative balance. Limit can't be lowered
+// below credit already used by balance and holds.
+func (a *Account) SetCreditLimit(limit Money) error {
+	if a.status == StatusClosed {
+		return ErrAccountClosed
+	}
+	if limit.IsNegative() {
+		return ErrNegativeCreditLimit
+	}
+	if err := a.currency.validate(limit); err != nil {
+		return err
+	}
+	limit = a.currency.Normalize(limit)
+
+	used := a.creditLimit.Sub(a.CreditHeadroom())
+	if limit.LessThan(used) {
+		return fmt.Errorf("%w: used %s", ErrCreditLimitInUse, used)
+	}
+	a.creditLimit = limit
+	a.emit(Event{Type: EventCreditLimitSet, Amount: limit})
+	return nil
+}
@@ -0,0 +1,69 @@
+package account
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithdrawIntoCredit(t *testing.T) {
+	a := NewAccount(1, "USD", MustParseMoney("20"), MustParseMoney("50"), "")
+	if got := a.CreditHeadroom().String(); got != "50.00" {
+		t.Fatalf("headroom of positive balance %s, want 50.00", got)
+	}
+
+	if err := a.Withdraw(MustParseMoney("60")); err != nil {
+		t.Fatal(err)
+	}
+	checkBalances(t, &a, "-40.00", "10.00")
+	if got := a.CreditHeadroom().String(); got != "10.00" {
+		t.Fatalf("headroom %s, want 10.00", got)
+	}
+
+	if err := a.Withdraw(MustParseMoney("10.01")); !errors.Is(err, ErrNotEnoughBalance) {
+		t.Fatalf("withdraw over credit limit: want ErrNotEnoughBalance, got %v", err)
+	}
+	if err := a.Withdraw(MustParseMoney("10")); err != nil {
+		t.Fatalf("withdraw of whole credit limit: %v", err)
+	}
+	checkBalances(t, &a, "-50.00", "0.00")
+}
+
+func TestSetCreditLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		balance   string
+		limit     string
+		hold      string
+		status    Status
+		newLimit  string
+		wantErr   error
+		wantLimit string
+	}{
+		{name: "raise", balance: "0", limit: "0", newLimit: "100", wantLimit: "100.00"},
+		{name: "lower unused", balance: "10", limit: "100", newLimit: "0", wantLimit: "0.00"},
+		{name: "lower to used", balance: "-40", limit: "50", newLimit: "40", wantLimit: "40.00"},
+		{name: "lower below used", balance: "-40", limit: "50", newLimit: "39.99", wantErr: ErrCreditLimitInUse, wantLimit: "50.00"},
+		{name: "lower below used by hold", balance: "0", limit: "50", hold: "30", newLimit: "20", wantErr: ErrCreditLimitInUse, wantLimit: "50.00"},
+		{name: "hold covered by balance", balance: "30", limit: "50", hold: "30", newLimit: "0", wantLimit: "0.00"},
+		{name: "negative", balance: "0", limit: "0", newLimit: "-1", wantErr: ErrNegativeCreditLimit, wantLimit: "0.00"},
+		{name: "excess precision", balance: "0", limit: "0", newLimit: "1.001", wantErr: ErrExcessPrecision, wantLimit: "0.00"},
+		{name: "frozen account", balance: "0", limit: "0", status: StatusFrozen, newLimit: "10", wantLimit: "10.00"},
+		{name: "closed account", balance: "0", limit: "0", status: StatusClosed, newLimit: "10", wantErr: ErrAccountClosed, wantLimit: "0.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var holds []Hold
+			if tt.hold != "" {
+				holds = append(holds, RestoreHold("h1", 1, MustParseMoney(tt.hold), Money{}, HoldActive, time.Time{}, time.Time{}))
+			}
+			a := NewAccount(1, "USD", MustParseMoney(tt.balance), MustParseMoney(tt.limit), tt.status, holds...)
+			if err := a.SetCreditLimit(MustParseMoney(tt.newLimit)); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			if got := a.CreditLimit().String(); got != tt.wantLimit {
+				t.Fatalf("credit limit %s, want %s", got, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventAccountOpened    EventType = "account_opened"
+	EventDeposited        EventType = "deposited"
+	EventWithdrawn        EventType = "withdrawn"
+	EventTransferSent     EventType = "transfer_sent"
+	EventTransferReceived EventType = "transfer_received"
+	EventHoldPlaced       EventType = "hold_placed"
+	EventHoldCaptured     EventType = "hold_captured"
+	EventHoldReleased     EventType = "hold_released"
+	EventHoldExpired      EventType = "hold_expired"
+	EventStatusChanged    EventType = "status_changed"
+	EventCreditLimitSet   EventType = "credit_limit_set"
+)
+
+var ErrUnknownEventType = errors.New("unknown event type")
+
+func ParseEventType(s string) (EventType, error) {
+	switch t := EventType(s); t {
+	case EventAccountOpened, EventDeposited, EventWithdrawn, EventTransferSent, EventTransferReceived,
+		EventHoldPlaced, EventHoldCaptured, EventHoldReleased, EventHoldExpired,
+		EventStatusChanged, EventCreditLimitSet:
+		return t, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownEventType, s)
+	}
+}
+
+// Event is fact about account emitted by its methods.
+// Applying all events of account in order gives its current state.
+type Event struct {
+	Type      EventType
+	AccountId int64
+	// Currency is set for EventAccountOpened.
+	Currency Currency
+	// Amount is moved money, captured amount of hold or new credit limit.
+	Amount Money
+	// Transfer is set for transfer events.
+	Transfer *Transfer
+	// HoldId is set for hold events.
+	HoldId string
+	// ExpiresAt is set for EventHoldPlaced.
+	ExpiresAt time.Time
+	// From, To and Reason are set for EventStatusChanged.
+	From   Status
+	To     Status
+	Reason string
+	// OccurredAt is set for hold events, it is time passed to hold methods.
+	OccurredAt time.Time
+}
+
+// OpenAccount creates account with pending EventAccountOpened.
+func OpenAccount(id int64, currency Currency) Account {
+	a := NewAccount(id, currency, currency.Zero(), currency.Zero(), StatusActive)
+	a.emit(Event{Type: EventAccountOpened, Currency: currency})
+	return a
+}
+
+// Events returns events emitted since account was loaded or last ClearChanges.
+func (a *Account) Events() []Event {
+	return a.events
+}
+
+// Version is number of events applied to account, events returned
+// by Events get next versions. Storages without events keep it zero.
+func (a *Account) Version() int64 {
+	return a.version
+}
+
+// SetVersion is used by event store for account restored from snapshot.
+func (a *Account) SetVersion(version int64) {
+	a.version = version
+}
+
+// Apply replays stored events, they aren't validated as already happened.
+func (a *Account) Apply(events ...Event) error {
+	for _, e := range events {
+		if err := a.applyEvent(e); err != nil {
+			return err
+		}
+		a.version++
+	}
+	return nil
+}
+
+func (a *Account) applyEvent(e Event) error {
+	switch e.Type {
+	case EventAccountOpened:
+		a.id = e.AccountId
+		a.currency = e.Currency
+		a.balance = e.Currency.Zero()
+		a.creditLimit = e.Currency.Zero()
+		a.status = StatusActive
+	case EventDeposited, EventTransferReceived:
+		a.balance = a.balance.Add(e.Amount)
+	case EventWithdrawn, EventTransferSent:
+		a.balance = a.balance.Sub(e.Amount)
+	case EventHoldPlaced:
+		a.holds = append(slices.Clip(a.holds), Hold{
+			id:        e.HoldId,
+			accountId: a.id,
+			amount:    e.Amount,
+			captured:  a.currency.Zero(),
+			status:    HoldActive,
+			expiresAt: e.ExpiresAt,
+			createdAt: e.OccurredAt,
+		})
+	case EventHoldCaptured:
+		a.removeHold(e.HoldId)
+		a.balance = a.balance.Sub(e.Amount)
+	case EventHoldReleased, EventHoldExpired:
+		a.removeHold(e.HoldId)
+	case EventStatusChanged:
+		a.status = e.To
+	case EventCreditLimitSet:
+		a.creditLimit = e.Amount
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownEventType, e.Type)
+	}
+	return nil
+}
+
+func (a *Account) emit(e Event) {
+	e.AccountId = a.id
+	a.events = append(a.events, e)
+}
+
+func (a *Account) removeHold(id string) {
+	a.holds = slices.DeleteFunc(slices.Clone(a.holds), func(h Hold) bool {
+		return h.id == id
+	})
+}
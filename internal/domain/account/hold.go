@@ -0,0 +1,192 @@
+package account
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "active"
+	HoldCaptured HoldStatus = "captured"
+	HoldReleased HoldStatus = "released"
+	HoldExpired  HoldStatus = "expired"
+)
+
+var (
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldExpired        = errors.New("hold is expired")
+	ErrCaptureExceedsHold = errors.New("capture amount exceeds hold")
+)
+
+// Hold reserves money of account. Reserved amount reduces available balance,
+// but ledger balance changes only when hold is captured.
+type Hold struct {
+	id        string
+	accountId int64
+	amount    Money
+	captured  Money
+	status    HoldStatus
+	expiresAt time.Time
+	createdAt time.Time
+}
+
+// RestoreHold creates hold from storage.
+func RestoreHold(
+	id string,
+	accountId int64,
+	amount Money,
+	captured Money,
+	status HoldStatus,
+	expiresAt time.Time,
+	createdAt time.Time,
+) Hold {
+	return Hold{
+		id:        id,
+		accountId: accountId,
+		amount:    amount,
+		captured:  captured,
+		status:    status,
+		expiresAt: expiresAt,
+		createdAt: createdAt,
+	}
+}
+
+func (h Hold) Id() string           { return h.id }
+func (h Hold) AccountId() int64     { return h.accountId }
+func (h Hold) Amount() Money        { return h.amount }
+func (h Hold) Captured() Money      { return h.captured }
+func (h Hold) Status() HoldStatus   { return h.status }
+func (h Hold) ExpiresAt() time.Time { return h.expiresAt }
+func (h Hold) CreatedAt() time.Time { return h.createdAt }
+
+func (h Hold) isExpired(now time.Time) bool {
+	return !now.Before(h.expiresAt)
+}
+
+// AvailableBalance is amount which can be spent: ledger balance with credit limit
+// and without amount reserved by active holds.
+func (a *Account) AvailableBalance() Money {
+	available := a.balance.Add(a.creditLimit)
+	for _, h := range a.holds {
+		available = available.Sub(h.amount)
+	}
+	return available
+}
+
+// Holds returns active holds.
+func (a *Account) Holds() []Hold {
+	return a.holds
+}
+
+// HoldChanges returns holds created or changed since last ClearChanges.
+func (a *Account) HoldChanges() []Hold {
+	return a.holdChanges
+}
+
+// PlaceHold reserves amount until expiresAt.
+func (a *Account) PlaceHold(id string, amount Money, now time.Time, expiresAt time.Time) (Hold, error) {
+	if err := a.checkSpend(); err != nil {
+		return Hold{}, err
+	}
+	if err := a.validateAmount(amount); err != nil {
+		return Hold{}, err
+	}
+	amount = a.currency.Normalize(amount)
+
+	if a.AvailableBalance().LessThan(amount) {
+		return Hold{}, ErrNotEnoughBalance
+	}
+
+	h := Hold{
+		id:        id,
+		accountId: a.id,
+		amount:    amount,
+		captured:  a.currency.Zero(),
+		status:    HoldActive,
+		expiresAt: expiresAt,
+		createdAt: now,
+	}
+	// clip forces copy, so account copies never share holds
+	a.holds = append(slices.Clip(a.holds), h)
+	a.holdChanges = append(a.holdChanges, h)
+	a.emit(Event{Type: EventHoldPlaced, Amount: amount, HoldId: id, ExpiresAt: expiresAt, OccurredAt: now})
+	return h, nil
+}
+
+// CaptureHold withdraws amount (up to reserved one) from ledger balance,
+// rest of hold is released.
+func (a *Account) CaptureHold(id string, amount Money, now time.Time) (Hold, error) {
+	if err := a.checkSpend(); err != nil {
+		return Hold{}, err
+	}
+	h, err := a.activeHold(id)
+	if err != nil {
+		return Hold{}, err
+	}
+	if h.isExpired(now) {
+		return Hold{}, ErrHoldExpired
+	}
+	if err := a.validateAmount(amount); err != nil {
+		return Hold{}, err
+	}
+	amount = a.currency.Normalize(amount)
+	if h.amount.LessThan(amount) {
+		return Hold{}, ErrCaptureExceedsHold
+	}
+
+	h.status = HoldCaptured
+	h.captured = amount
+	a.closeHold(h)
+	// reserved money is guaranteed by hold, so balance is not checked
+	a.balance = a.balance.Sub(amount)
+	a.recordHold(OperationHoldCapture, amount, h.id)
+	a.emit(Event{Type: EventHoldCaptured, Amount: amount, HoldId: h.id, OccurredAt: now})
+	return h, nil
+}
+
+func (a *Account) ReleaseHold(id string) (Hold, error) {
+	h, err := a.activeHold(id)
+	if err != nil {
+		return Hold{}, err
+	}
+
+	h.status = HoldReleased
+	a.closeHold(h)
+	a.emit(Event{Type: EventHoldReleased, HoldId: h.id})
+	return h, nil
+}
+
+// ExpireHolds closes active holds expired at now and returns them.
+func (a *Account) ExpireHolds(now time.Time) []Hold {
+	var expired []Hold
+	for _, h := range a.holds {
+		if h.isExpired(now) {
+			h.status = HoldExpired
+			expired = append(expired, h)
+		}
+	}
+	for _, h := range expired {
+		a.closeHold(h)
+		a.emit(Event{Type: EventHoldExpired, HoldId: h.id, OccurredAt: now})
+	}
+	return expired
+}
+
+// activeHold returns copy of active hold. Closed holds are not found.
+func (a *Account) activeHold(id string) (Hold, error) {
+	for _, h := range a.holds {
+		if h.id == id {
+			return h, nil
+		}
+	}
+	return Hold{}, ErrHoldNotFound
+}
+
+// closeHold removes hold from active and saves its final state.
+func (a *Account) closeHold(h Hold) {
+	a.removeHold(h.id)
+	a.holdChanges = append(a.holdChanges, h)
+}
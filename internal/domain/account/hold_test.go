@@ -0,0 +1,235 @@
+package account
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var holdNow = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func checkBalances(t *testing.T, a *Account, ledger, available string) {
+	t.Helper()
+	if got := a.GetBalance().String(); got != ledger {
+		t.Errorf("ledger balance %s, want %s", got, ledger)
+	}
+	if got := a.AvailableBalance().String(); got != available {
+		t.Errorf("available balance %s, want %s", got, available)
+	}
+}
+
+func TestHoldLifecycle(t *testing.T) {
+	expiresAt := holdNow.Add(time.Hour)
+	tests := []struct {
+		name          string
+		steps         func(a *Account) error
+		wantErr       error
+		wantLedger    string
+		wantAvailable string
+		wantHolds     int
+	}{
+		{
+			name: "place",
+			steps: func(a *Account) error {
+				_, err := a.PlaceHold("h1", MustParseMoney("30"), holdNow, expiresAt)
+				return err
+			},
+			wantLedger: "100.00", wantAvailable: "70.00", wantHolds: 1,
+		},
+		{
+			name: "place over available",
+			steps: func(a *Account) error {
+				if _, err := a.PlaceHold("h1", MustParseMoney("80"), holdNow, expiresAt); err != nil {
+					return err
+				}
+				_, err := a.PlaceHold("h2", MustParseMoney("30"), holdNow, expiresAt)
+				return err
+			},
+			wantErr:    ErrNotEnoughBalance,
+			wantLedger: "100.00", wantAvailable: "20.00", wantHolds: 1,
+		},
+		{
+			name: "capture greater than hold",
+			steps: func(a *Account) error {
+				if _, err := a.PlaceHold("h1", MustParseMoney("30"), holdNow, expiresAt); err != nil {
+					return err
+				}
+				_, err := a.CaptureHold("h1", MustParseMoney("30.01"), holdNow)
+				return err
+			},
+			wantErr:    ErrCaptureExceedsHold,
+			wantLedger: "100.00", wantAvailable: "70.00", wantHolds: 1,
+		},
+		{
+			name: "full capture",
+			steps: func(a *Account) error {
+				if _, err := a.PlaceHold("h1", MustParseMoney("30"), holdNow, expiresAt); err != nil {
+					return err
+				}
+				_, err := a.CaptureHold("h1", MustParseMoney("30"), holdNow)
+				return err
+			},
+			wantLedger: "70.00", wantAvailable: "70.00", wantHolds: 0,
+		},
+		{
+			name: "partial capture releases rest",
+			steps: func(a *Account) error {
+				if _, err := a.PlaceHold("h1", MustParseMoney("30"), holdNow, expiresAt); err != nil {
+					return err
+				}
+				_, err := a.CaptureHold("h1", MustParseMoney("20"), holdNow)
+				return err
+			},
+			wantLedger: "80.00", wantAvailable: "80.00", wantHolds: 0,
+		},
+		{
+			name: "capture closed hold",
+			steps: func(a *Account) error {
+				if _, err := a.PlaceHold("h1", MustParseMoney("30"), holdNow, expiresAt); err != nil {
+					return err
+				}
+				if _, err := a.CaptureHold("h1", MustParseMoney("20"), holdNow); err != nil {
+					return err
+				}
+				_, err := a.CaptureHold("h1", MustParseMoney("10"), holdNow)
+				return err
+			},
+			wantErr:    ErrHoldNotFound,
+			wantLedger: "80.00", wantAvailable: "80.00", wantHolds: 0,
+		},
+		{
+			name: "release",
+			steps: func(a *Account) error {
+				if _, err := a.PlaceHold("h1", MustParseMoney("30"), holdNow, expiresAt); err != nil {
+					return err
+				}
+				_, err := a.ReleaseHold("h1")
+				return err
+			},
+			wantLedger: "100.00", wantAvailable: "100.00", wantHolds: 0,
+		},
+		{
+			name: "release twice",
+			steps: func(a *Account) error {
+				if _, err := a.PlaceHold("h1", MustParseMoney("30"), holdNow, expiresAt); err != nil {
+					return err
+				}
+				if _, err := a.ReleaseHold("h1"); err != nil {
+					return err
+				}
+				_, err := a.ReleaseHold("h1")
+				return err
+			},
+			wantErr:    ErrHoldNotFound,
+			wantLedger: "100.00", wantAvailable: "100.00", wantHolds: 0,
+		},
+		{
+			name: "capture after expiry",
+			steps: func(a *Account) error {
+				if _, err := a.PlaceHold("h1", MustParseMoney("30"), holdNow, expiresAt); err != nil {
+					return err
+				}
+				_, err := a.CaptureHold("h1", MustParseMoney("30"), expiresAt)
+				return err
+			},
+			wantErr:    ErrHoldExpired,
+			wantLedger: "100.00", wantAvailable: "70.00", wantHolds: 1,
+		},
+		{
+			name: "withdraw of held money",
+			steps: func(a *Account) error {
+				if _, err := a.PlaceHold("h1", MustParseMoney("30"), holdNow, expiresAt); err != nil {
+					return err
+				}
+				return a.Withdraw(MustParseMoney("80"))
+			},
+			wantErr:    ErrNotEnoughBalance,
+			wantLedger: "100.00", wantAvailable: "70.00", wantHolds: 1,
+		},
+		{
+			name: "place on frozen account",
+			steps: func(a *Account) error {
+				if _, err := a.ChangeStatus(StatusFrozen, "test"); err != nil {
+					return err
+				}
+				_, err := a.PlaceHold("h1", MustParseMoney("30"), holdNow, expiresAt)
+				return err
+			},
+			wantErr:    ErrAccountFrozen,
+			wantLedger: "100.00", wantAvailable: "100.00", wantHolds: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAccount(1, "USD", MustParseMoney("100"), Money{}, "")
+			if err := tt.steps(&a); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			checkBalances(t, &a, tt.wantLedger, tt.wantAvailable)
+			if got := len(a.Holds()); got != tt.wantHolds {
+				t.Errorf("%d active holds, want %d", got, tt.wantHolds)
+			}
+		})
+	}
+}
+
+func TestCaptureHoldState(t *testing.T) {
+	a := NewAccount(1, "USD", MustParseMoney("100"), Money{}, "")
+	if _, err := a.PlaceHold("h1", MustParseMoney("30"), holdNow, holdNow.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	h, err := a.CaptureHold("h1", MustParseMoney("20"), holdNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Status() != HoldCaptured || h.Amount().String() != "30.00" || h.Captured().String() != "20.00" {
+		t.Fatalf("captured hold is %s %s of %s", h.Status(), h.Captured(), h.Amount())
+	}
+	changes := a.HoldChanges()
+	if len(changes) != 2 || changes[0].Status() != HoldActive || changes[1].Status() != HoldCaptured {
+		t.Fatalf("hold changes aren't recorded: %+v", changes)
+	}
+}
+
+func TestExpireHolds(t *testing.T) {
+	a := NewAccount(1, "USD", MustParseMoney("100"), Money{}, "")
+	if _, err := a.PlaceHold("short", MustParseMoney("20"), holdNow, holdNow.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.PlaceHold("long", MustParseMoney("30"), holdNow, holdNow.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	checkBalances(t, &a, "100.00", "50.00")
+
+	if expired := a.ExpireHolds(holdNow.Add(time.Minute - time.Nanosecond)); len(expired) != 0 {
+		t.Fatalf("holds are expired before deadline: %+v", expired)
+	}
+
+	// hold is expired at its deadline
+	expired := a.ExpireHolds(holdNow.Add(time.Minute))
+	if len(expired) != 1 || expired[0].Id() != "short" || expired[0].Status() != HoldExpired {
+		t.Fatalf("want expired hold short, got %+v", expired)
+	}
+	checkBalances(t, &a, "100.00", "70.00")
+
+	expired = a.ExpireHolds(holdNow.Add(2 * time.Hour))
+	if len(expired) != 1 || expired[0].Id() != "long" {
+		t.Fatalf("want expired hold long, got %+v", expired)
+	}
+	checkBalances(t, &a, "100.00", "100.00")
+	if len(a.Holds()) != 0 {
+		t.Fatalf("expired holds stay active: %+v", a.Holds())
+	}
+}
+
+func TestHoldUsesCreditLimit(t *testing.T) {
+	a := NewAccount(1, "USD", MustParseMoney("10"), MustParseMoney("50"), "")
+	if _, err := a.PlaceHold("h1", MustParseMoney("40"), holdNow, holdNow.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	checkBalances(t, &a, "10.00", "20.00")
+	if _, err := a.CaptureHold("h1", MustParseMoney("40"), holdNow); err != nil {
+		t.Fatal(err)
+	}
+	checkBalances(t, &a, "-30.00", "20.00")
+}
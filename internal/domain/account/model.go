@@ -1,16 +1,51 @@
 package account
 
-import "errors"
-
-type Money float64
+import (
+	"errors"
+	"fmt"
+)
 
 type Account struct {
-	id      int64
-	balance float64
+	id       int64
+	currency Currency
+	balance  Money
+	// creditLimit is how far balance may go below zero.
+	creditLimit Money
+	status      Status
+
+	// holds contains only active holds
+	holds []Hold
+
+	operations    []Operation
+	holdChanges   []Hold
+	statusChanges []StatusChange
+
+	// version counts applied events, see Event.
+	version int64
+	events  []Event
 }
 
-func NewAccount(id int64, balance float64) Account {
-	return Account{id: id, balance: balance}
+// NewAccount creates account from storage, holds must be active.
+// Empty status means StatusActive.
+func NewAccount(
+	id int64,
+	currency Currency,
+	balance Money,
+	creditLimit Money,
+	status Status,
+	holds ...Hold,
+) Account {
+	if status == "" {
+		status = StatusActive
+	}
+	return Account{
+		id:          id,
+		currency:    currency,
+		balance:     currency.Normalize(balance),
+		creditLimit: currency.Normalize(creditLimit),
+		status:      status,
+		holds:       holds,
+	}
 }
 
 func (a *Account) Id() int64 {
@@ -23,38 +58,82 @@ var (
 	ErrNotEnoughBalance = errors.New("not enough balance")
 )
 
-func (a *Account) GetBalance() float64 {
+func (a *Account) GetBalance() Money {
 	return a.balance
 }
 
-func (a *Account) Deposit(amount float64) error {
+func (a *Account) Currency() Currency {
+	return a.currency
+}
+
+// CheckCurrency returns ErrCurrencyMismatch if operation currency differs from account.
+// Empty currency means currency of account.
+func (a *Account) CheckCurrency(currency Currency) error {
+	if currency != "" && currency != a.currency {
+		return fmt.Errorf("%w: account in %s, operation in %s", ErrCurrencyMismatch, a.currency, currency)
+	}
+	return nil
+}
+
+func (a *Account) Deposit(amount Money) error {
+	if err := a.deposit(amount); err != nil {
+		return err
+	}
+	amount = a.currency.Normalize(amount)
+	a.record(OperationDeposit, amount)
+	a.emit(Event{Type: EventDeposited, Amount: amount})
+	return nil
+}
+
+func (a *Account) Withdraw(amount Money) error {
+	if err := a.withdraw(amount); err != nil {
+		return err
+	}
+	amount = a.currency.Normalize(amount)
+	a.record(OperationWithdrawal, amount)
+	a.emit(Event{Type: EventWithdrawn, Amount: amount})
+	return nil
+}
+
+func (a *Account) deposit(amount Money) error {
+	if err := a.checkReceive(); err != nil {
+		return err
+	}
 	if err := a.validateAmount(amount); err != nil {
 		return err
 	}
 
-	a.balance += amount
+	a.balance = a.balance.Add(a.currency.Normalize(amount))
 	return nil
 }
 
-func (a *Account) Withdraw(amount float64) error {
+func (a *Account) withdraw(amount Money) error {
+	if err := a.checkSpend(); err != nil {
+		return err
+	}
 	if err := a.validateAmount(amount); err != nil {
 		return err
 	}
+	amount = a.currency.Normalize(amount)
 
-	if a.balance < amount {
+	if a.AvailableBalance().LessThan(amount) {
 		return ErrNotEnoughBalance
 	}
-	a.balance -= amount
+	a.balance = a.balance.Sub(amount)
 	return nil
 }
 
-func (a *Account) validateAmount(amount float64) error {
-	if amount == 0 {
+func (a *Account) validateAmount(amount Money) error {
+	return validateAmount(amount, a.currency)
+}
+
+func validateAmount(amount Money, currency Currency) error {
+	if amount.IsZero() {
 		return ErrZeroAmount
 	}
 
-	if amount < 0 {
+	if amount.IsNegative() {
 		return ErrNegativeAmount
 	}
-	return nil
+	return currency.validate(amount)
 }
@@ -0,0 +1,140 @@
+package account
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/shopspring/decimal"
+)
+
+// MaxMoneyScale is max count of digits after decimal point that money can hold.
+// Precision of particular currency is checked by account.
+const MaxMoneyScale = 4
+
+var (
+	ErrInvalidMoney    = errors.New("invalid money format")
+	ErrExcessPrecision = errors.New("amount has excess precision")
+)
+
+// moneyPattern allows only plain decimal notation, without exponent and sign "+".
+var moneyPattern = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// Money is exact fixed-point amount. Zero value is valid zero amount.
+type Money struct {
+	d decimal.Decimal
+}
+
+func ParseMoney(s string) (Money, error) {
+	if !moneyPattern.MatchString(s) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	if !d.Equal(d.Truncate(MaxMoneyScale)) {
+		return Money{}, fmt.Errorf("%w: %q", ErrExcessPrecision, s)
+	}
+	return Money{d: d}, nil
+}
+
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Add(o Money) Money {
+	return Money{d: m.d.Add(o.d)}
+}
+
+func (m Money) Sub(o Money) Money {
+	return Money{d: m.d.Sub(o.d)}
+}
+
+func (m Money) Neg() Money {
+	return Money{d: m.d.Neg()}
+}
+
+// Cmp returns -1 if m < o, 0 if m == o and +1 if m > o.
+func (m Money) Cmp(o Money) int {
+	return m.d.Cmp(o.d)
+}
+
+func (m Money) Equal(o Money) bool {
+	return m.d.Equal(o.d)
+}
+
+func (m Money) LessThan(o Money) bool {
+	return m.d.LessThan(o.d)
+}
+
+func (m Money) IsZero() bool {
+	return m.d.IsZero()
+}
+
+func (m Money) IsNegative() bool {
+	return m.d.IsNegative()
+}
+
+// String keeps digits after point as is, e.g. "10.50" and "100".
+func (m Money) String() string {
+	if exp := m.d.Exponent(); exp < 0 {
+		return m.d.StringFixed(-exp)
+	}
+	return m.d.StringFixed(0)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON accepts only string-encoded amounts, so
+// amount never passes through float.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: amount must be a string", ErrInvalidMoney)
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner for numeric columns.
+func (m *Money) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case int64:
+		*m = Money{d: decimal.NewFromInt(v)}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported source %T", ErrInvalidMoney, src)
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	*m = Money{d: d}
+	return nil
+}
+
+// Value implements driver.Valuer, money passed to database as numeric text.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
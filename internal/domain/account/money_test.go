@@ -0,0 +1,151 @@
+package account
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr error
+	}{
+		{in: "10", want: "10"},
+		{in: "10.50", want: "10.50"},
+		{in: "0.0001", want: "0.0001"},
+		{in: "-5.25", want: "-5.25"},
+		{in: "007.10", want: "7.10"},
+		{in: "12345678901234567890.1234", want: "12345678901234567890.1234"},
+
+		{in: "+5", wantErr: ErrInvalidMoney},
+		{in: "--5", wantErr: ErrInvalidMoney},
+		{in: ".5", wantErr: ErrInvalidMoney},
+		{in: "5.", wantErr: ErrInvalidMoney},
+		{in: "-.5", wantErr: ErrInvalidMoney},
+		{in: "1e3", wantErr: ErrInvalidMoney},
+		{in: "1E-2", wantErr: ErrInvalidMoney},
+		{in: "1.5e2", wantErr: ErrInvalidMoney},
+		{in: "", wantErr: ErrInvalidMoney},
+		{in: " 1", wantErr: ErrInvalidMoney},
+		{in: "1,5", wantErr: ErrInvalidMoney},
+		{in: "NaN", wantErr: ErrInvalidMoney},
+		{in: "0x10", wantErr: ErrInvalidMoney},
+
+		// MaxMoneyScale digits after point are allowed, trailing zeros don't count
+		{in: "1.12345", wantErr: ErrExcessPrecision},
+		{in: "0.00001", wantErr: ErrExcessPrecision},
+		{in: "1.123400", want: "1.123400"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMoney(tt.in)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("want %v, got %v (%s)", tt.wantErr, err, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Fatalf("want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	for _, s := range []string{"0", "10.50", "-0.0001", "100"} {
+		data, err := json.Marshal(MustParseMoney(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `"`+s+`"` {
+			t.Fatalf("%s is marshaled to %s, want string", s, data)
+		}
+		var got Money
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != s {
+			t.Fatalf("round-trip of %s gives %s", s, got)
+		}
+	}
+
+	var m Money
+	for _, data := range []string{`10.5`, `null`, `"1e2"`, `"1.00001"`} {
+		if err := json.Unmarshal([]byte(data), &m); err == nil {
+			t.Errorf("%s is accepted as money", data)
+		}
+	}
+}
+
+func TestMoneyScanValue(t *testing.T) {
+	for _, s := range []string{"0", "10.50", "-7.1234"} {
+		value, err := MustParseMoney(s).Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromString, fromBytes Money
+		if err := fromString.Scan(value); err != nil {
+			t.Fatal(err)
+		}
+		if err := fromBytes.Scan([]byte(value.(string))); err != nil {
+			t.Fatal(err)
+		}
+		if fromString.String() != s || fromBytes.String() != s {
+			t.Fatalf("round-trip of %s gives %s and %s", s, fromString, fromBytes)
+		}
+	}
+
+	var m Money
+	if err := m.Scan(int64(42)); err != nil || m.String() != "42" {
+		t.Fatalf("scan of int64: %s, %v", m, err)
+	}
+	if err := m.Scan(nil); err != nil || !m.IsZero() {
+		t.Fatalf("scan of NULL: %s, %v", m, err)
+	}
+	if err := m.Scan(1.5); !errors.Is(err, ErrInvalidMoney) {
+		t.Fatalf("scan of float: want ErrInvalidMoney, got %v", err)
+	}
+	if err := m.Scan("abc"); !errors.Is(err, ErrInvalidMoney) {
+		t.Fatalf("scan of invalid text: want ErrInvalidMoney, got %v", err)
+	}
+}
+
+func TestCurrencyPrecision(t *testing.T) {
+	tests := []struct {
+		currency Currency
+		amount   string
+		fits     bool
+		zero     string
+	}{
+		{currency: "USD", amount: "1.01", fits: true, zero: "0.00"},
+		{currency: "USD", amount: "1.001", fits: false, zero: "0.00"},
+		{currency: "JPY", amount: "100", fits: true, zero: "0"},
+		{currency: "JPY", amount: "100.5", fits: false, zero: "0"},
+		{currency: "BHD", amount: "1.001", fits: true, zero: "0.000"},
+		{currency: "CLF", amount: "1.0001", fits: true, zero: "0.0000"},
+	}
+	for _, tt := range tests {
+		amount := MustParseMoney(tt.amount)
+		if got := tt.currency.Fits(amount); got != tt.fits {
+			t.Errorf("%s fits %s: %v, want %v", tt.amount, tt.currency, got, tt.fits)
+		}
+		if got := tt.currency.Zero().String(); got != tt.zero {
+			t.Errorf("zero of %s is %s, want %s", tt.currency, got, tt.zero)
+		}
+	}
+	if got := Currency("USD").Normalize(MustParseMoney("5")).String(); got != "5.00" {
+		t.Errorf("normalized 5 USD is %s", got)
+	}
+	if _, err := ParseCurrency("usd"); err != nil {
+		t.Errorf("lower case code: %v", err)
+	}
+	if _, err := ParseCurrency("XXX"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("unknown code: want ErrUnknownCurrency, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+)
+
+type OperationType string
+
+const (
+	OperationDeposit     OperationType = "deposit"
+	OperationWithdrawal  OperationType = "withdrawal"
+	OperationTransferIn  OperationType = "transfer_in"
+	OperationTransferOut OperationType = "transfer_out"
+	OperationHoldCapture OperationType = "hold_capture"
+)
+
+var ErrUnknownOperationType = errors.New("unknown operation type")
+
+func ParseOperationType(s string) (OperationType, error) {
+	switch t := OperationType(s); t {
+	case OperationDeposit, OperationWithdrawal, OperationTransferIn, OperationTransferOut, OperationHoldCapture:
+		return t, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownOperationType, s)
+	}
+}
+
+// Operation is a balance change made on account, which is not persisted yet.
+type Operation struct {
+	Type         OperationType
+	Amount       Money
+	BalanceAfter Money
+	// Transfer is set for transfer operations.
+	Transfer *Transfer
+	// HoldId is set for capture of hold.
+	HoldId string
+}
+
+// Operations returns changes made since account was loaded or last ClearChanges.
+func (a *Account) Operations() []Operation {
+	return a.operations
+}
+
+// ClearChanges must be called after operations, hold and status changes were persisted.
+// Version moves past persisted events.
+func (a *Account) ClearChanges() {
+	a.version += int64(len(a.events))
+	a.events = nil
+	a.operations = nil
+	a.holdChanges = nil
+	a.statusChanges = nil
+}
+
+func (a *Account) record(t OperationType, amount Money) {
+	a.operations = append(a.operations, Operation{
+		Type:         t,
+		Amount:       amount,
+		BalanceAfter: a.balance,
+	})
+}
+
+func (a *Account) recordTransfer(t OperationType, amount Money, transfer Transfer) {
+	a.operations = append(a.operations, Operation{
+		Type:         t,
+		Amount:       amount,
+		BalanceAfter: a.balance,
+		Transfer:     &transfer,
+	})
+}
+
+func (a *Account) recordHold(t OperationType, amount Money, holdId string) {
+	a.operations = append(a.operations, Operation{
+		Type:         t,
+		Amount:       amount,
+		BalanceAfter: a.balance,
+		HoldId:       holdId,
+	})
+}
@@ -0,0 +1,93 @@
+package account
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// rateScale is count of digits after point kept for inverted and applied rates.
+const rateScale = 10
+
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// Rate is exact decimal exchange rate or spread fraction.
+type Rate struct {
+	d decimal.Decimal
+}
+
+var OneRate = Rate{d: decimal.NewFromInt(1)}
+
+func ParseRate(s string) (Rate, error) {
+	if !moneyPattern.MatchString(s) {
+		return Rate{}, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return Rate{}, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	return Rate{d: d}, nil
+}
+
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (r Rate) IsPositive() bool {
+	return r.d.IsPositive()
+}
+
+func (r Rate) IsZero() bool {
+	return r.d.IsZero()
+}
+
+// Inverse returns 1/r rounded to rateScale digits. Rate must be positive.
+func (r Rate) Inverse() Rate {
+	return Rate{d: decimal.NewFromInt(1).DivRound(r.d, rateScale)}
+}
+
+func (r Rate) Equal(o Rate) bool {
+	return r.d.Equal(o.d)
+}
+
+func (r Rate) String() string {
+	return r.d.String()
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: rate must be a string", ErrInvalidRate)
+	}
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner for numeric columns.
+func (r *Rate) Scan(src any) error {
+	var m Money
+	if err := m.Scan(src); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRate, err)
+	}
+	*r = Rate{d: m.d}
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
@@ -0,0 +1,127 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Status string
+
+const (
+	StatusActive Status = "active"
+	// StatusFrozen blocks outgoing money, deposits depend on StatusPolicy.
+	StatusFrozen Status = "frozen"
+	// StatusClosed is terminal, closed account rejects all operations.
+	StatusClosed Status = "closed"
+)
+
+var (
+	ErrUnknownStatus           = errors.New("unknown account status")
+	ErrAccountFrozen           = errors.New("account is frozen")
+	ErrAccountClosed           = errors.New("account is closed")
+	ErrInvalidStatusTransition = errors.New("invalid account status transition")
+	ErrNonZeroBalance          = errors.New("balance must be zero to close account")
+	ErrActiveHolds             = errors.New("account has active holds")
+	ErrReasonRequired          = errors.New("reason of status change is required")
+)
+
+func ParseStatus(s string) (Status, error) {
+	switch st := Status(s); st {
+	case StatusActive, StatusFrozen, StatusClosed:
+		return st, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownStatus, s)
+	}
+}
+
+func (s Status) String() string {
+	return string(s)
+}
+
+// StatusPolicy defines rules which differ between deployments.
+type StatusPolicy struct {
+	FrozenAcceptsDeposits bool
+}
+
+// StatusChange is audit record of changing status.
+type StatusChange struct {
+	From   Status
+	To     Status
+	Reason string
+}
+
+func (a *Account) Status() Status {
+	return a.status
+}
+
+// StatusChanges returns changes made since last ClearChanges.
+func (a *Account) StatusChanges() []StatusChange {
+	return a.statusChanges
+}
+
+// CheckDeposit returns error if account can't receive money with policy.
+func (a *Account) CheckDeposit(policy StatusPolicy) error {
+	switch a.status {
+	case StatusClosed:
+		return ErrAccountClosed
+	case StatusFrozen:
+		if !policy.FrozenAcceptsDeposits {
+			return ErrAccountFrozen
+		}
+	}
+	return nil
+}
+
+// ChangeStatus moves account to status. Closed account can't be reopened,
+// closing requires zero balance and no active holds.
+func (a *Account) ChangeStatus(to Status, reason string) (StatusChange, error) {
+	if _, err := ParseStatus(string(to)); err != nil {
+		return StatusChange{}, err
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return StatusChange{}, ErrReasonRequired
+	}
+	if a.status == StatusClosed {
+		return StatusChange{}, ErrAccountClosed
+	}
+	if a.status == to {
+		return StatusChange{}, fmt.Errorf("%w: account is already %s", ErrInvalidStatusTransition, to)
+	}
+
+	if to == StatusClosed {
+		if !a.balance.IsZero() {
+			return StatusChange{}, ErrNonZeroBalance
+		}
+		if len(a.holds) != 0 {
+			return StatusChange{}, ErrActiveHolds
+		}
+	}
+
+	change := StatusChange{From: a.status, To: to, Reason: reason}
+	a.status = to
+	a.statusChanges = append(a.statusChanges, change)
+	a.emit(Event{Type: EventStatusChanged, From: change.From, To: change.To, Reason: reason})
+	return change, nil
+}
+
+// checkReceive rejects incoming money only for closed accounts,
+// policy of frozen ones checked by CheckDeposit.
+func (a *Account) checkReceive() error {
+	if a.status == StatusClosed {
+		return ErrAccountClosed
+	}
+	return nil
+}
+
+// checkSpend rejects outgoing money of not active accounts.
+func (a *Account) checkSpend() error {
+	switch a.status {
+	case StatusFrozen:
+		return ErrAccountFrozen
+	case StatusClosed:
+		return ErrAccountClosed
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package account
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChangeStatusTransitions(t *testing.T) {
+	statuses := []Status{StatusActive, StatusFrozen, StatusClosed}
+	// allowed[from][to] of account with zero balance and without holds
+	allowed := map[Status]map[Status]error{
+		StatusActive: {StatusActive: ErrInvalidStatusTransition, StatusFrozen: nil, StatusClosed: nil},
+		StatusFrozen: {StatusActive: nil, StatusFrozen: ErrInvalidStatusTransition, StatusClosed: nil},
+		StatusClosed: {StatusActive: ErrAccountClosed, StatusFrozen: ErrAccountClosed, StatusClosed: ErrAccountClosed},
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			t.Run(string(from)+"->"+string(to), func(t *testing.T) {
+				a := NewAccount(1, "USD", Money{}, Money{}, from)
+				change, err := a.ChangeStatus(to, "test")
+				wantErr := allowed[from][to]
+				if !errors.Is(err, wantErr) {
+					t.Fatalf("want %v, got %v", wantErr, err)
+				}
+				if wantErr != nil {
+					if a.Status() != from || len(a.StatusChanges()) != 0 {
+						t.Fatalf("rejected transition changed status to %s", a.Status())
+					}
+					return
+				}
+				if a.Status() != to {
+					t.Fatalf("status %s, want %s", a.Status(), to)
+				}
+				want := StatusChange{From: from, To: to, Reason: "test"}
+				if change != want || len(a.StatusChanges()) != 1 || a.StatusChanges()[0] != want {
+					t.Fatalf("change %+v isn't recorded, changes %+v", change, a.StatusChanges())
+				}
+			})
+		}
+	}
+}
+
+func TestChangeStatusErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance string
+		hold    bool
+		to      Status
+		reason  string
+		wantErr error
+	}{
+		{name: "missing reason", balance: "0", to: StatusFrozen, reason: "", wantErr: ErrReasonRequired},
+		{name: "blank reason", balance: "0", to: StatusFrozen, reason: "  \t", wantErr: ErrReasonRequired},
+		{name: "unknown status", balance: "0", to: "suspended", reason: "test", wantErr: ErrUnknownStatus},
+		{name: "close with positive balance", balance: "0.01", to: StatusClosed, reason: "test", wantErr: ErrNonZeroBalance},
+		{name: "close with negative balance", balance: "-0.01", to: StatusClosed, reason: "test", wantErr: ErrNonZeroBalance},
+		{name: "close with active hold", balance: "0", hold: true, to: StatusClosed, reason: "test", wantErr: ErrActiveHolds},
+		{name: "freeze with active hold", balance: "0", hold: true, to: StatusFrozen, reason: "test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var holds []Hold
+			if tt.hold {
+				holds = append(holds, RestoreHold("h1", 1, MustParseMoney("10"), Money{}, HoldActive, time.Time{}, time.Time{}))
+			}
+			a := NewAccount(1, "USD", MustParseMoney(tt.balance), MustParseMoney("10"), StatusActive, holds...)
+			if _, err := a.ChangeStatus(tt.to, tt.reason); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckDeposit(t *testing.T) {
+	tests := []struct {
+		status  Status
+		policy  StatusPolicy
+		wantErr error
+	}{
+		{status: StatusActive, policy: StatusPolicy{}},
+		{status: StatusFrozen, policy: StatusPolicy{FrozenAcceptsDeposits: false}, wantErr: ErrAccountFrozen},
+		{status: StatusFrozen, policy: StatusPolicy{FrozenAcceptsDeposits: true}},
+		{status: StatusClosed, policy: StatusPolicy{FrozenAcceptsDeposits: true}, wantErr: ErrAccountClosed},
+	}
+	for _, tt := range tests {
+		a := NewAccount(1, "USD", Money{}, Money{}, tt.status)
+		if err := a.CheckDeposit(tt.policy); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s with %+v: want %v, got %v", tt.status, tt.policy, tt.wantErr, err)
+		}
+	}
+}
+
+func TestStatusOperations(t *testing.T) {
+	tests := []struct {
+		status          Status
+		wantDepositErr  error
+		wantWithdrawErr error
+	}{
+		{status: StatusActive},
+		// deposit policy of frozen account is checked by CheckDeposit
+		{status: StatusFrozen, wantWithdrawErr: ErrAccountFrozen},
+		{status: StatusClosed, wantDepositErr: ErrAccountClosed, wantWithdrawErr: ErrAccountClosed},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			a := NewAccount(1, "USD", MustParseMoney("100"), Money{}, tt.status)
+			if err := a.Deposit(MustParseMoney("10")); !errors.Is(err, tt.wantDepositErr) {
+				t.Errorf("deposit: want %v, got %v", tt.wantDepositErr, err)
+			}
+			if err := a.Withdraw(MustParseMoney("10")); !errors.Is(err, tt.wantWithdrawErr) {
+				t.Errorf("withdraw: want %v, got %v", tt.wantWithdrawErr, err)
+			}
+		})
+	}
+}
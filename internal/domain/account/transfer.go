@@ -0,0 +1,149 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+type RoundingMode string
+
+const (
+	RoundHalfEven RoundingMode = "half_even"
+	RoundHalfUp   RoundingMode = "half_up"
+	RoundDown     RoundingMode = "down"
+	RoundUp       RoundingMode = "up"
+)
+
+var (
+	ErrUnknownRoundingMode = errors.New("unknown rounding mode")
+	ErrInvalidSpread       = errors.New("spread must be in range [0, 1)")
+)
+
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch m := RoundingMode(s); m {
+	case RoundHalfEven, RoundHalfUp, RoundDown, RoundUp:
+		return m, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownRoundingMode, s)
+	}
+}
+
+func (m RoundingMode) round(d decimal.Decimal, places int32) decimal.Decimal {
+	switch m {
+	case RoundHalfUp:
+		return d.Round(places)
+	case RoundDown:
+		return d.RoundDown(places)
+	case RoundUp:
+		return d.RoundUp(places)
+	default:
+		return d.RoundBank(places)
+	}
+}
+
+// ConversionPolicy defines how amount converted to other currency.
+type ConversionPolicy struct {
+	// Spread is fraction of market rate kept by bank, e.g. 0.005 is 0.5%.
+	Spread   Rate
+	Rounding RoundingMode
+}
+
+func (p ConversionPolicy) Validate() error {
+	if p.Spread.d.IsNegative() || p.Spread.d.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return ErrInvalidSpread
+	}
+	if _, err := ParseRoundingMode(string(p.Rounding)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Transfer describes money movement between accounts.
+// For transfers in the same currency Credit equals Debit and Rate is one.
+type Transfer struct {
+	FromAccountId  int64
+	ToAccountId    int64
+	Debit          Money
+	DebitCurrency  Currency
+	Credit         Money
+	CreditCurrency Currency
+	// Rate applied to Debit, it is MarketRate reduced by Spread.
+	Rate       Rate
+	MarketRate Rate
+	Spread     Rate
+}
+
+// Party is account taking part in transfer.
+type Party interface {
+	Id() int64
+	Currency() Currency
+}
+
+// NewTransfer calculates credited amount with policy. Market rate used only if currencies differ.
+func NewTransfer(from, to Party, amount Money, marketRate Rate, policy ConversionPolicy) (Transfer, error) {
+	if err := validateAmount(amount, from.Currency()); err != nil {
+		return Transfer{}, err
+	}
+	debit := from.Currency().Normalize(amount)
+
+	t := Transfer{
+		FromAccountId:  from.Id(),
+		ToAccountId:    to.Id(),
+		Debit:          debit,
+		DebitCurrency:  from.Currency(),
+		Credit:         debit,
+		CreditCurrency: to.Currency(),
+		Rate:           OneRate,
+		MarketRate:     OneRate,
+	}
+	if from.Currency() == to.Currency() {
+		return t, nil
+	}
+
+	if !marketRate.IsPositive() {
+		return Transfer{}, fmt.Errorf("%w: %s", ErrInvalidRate, marketRate)
+	}
+	applied := marketRate.d.Mul(decimal.NewFromInt(1).Sub(policy.Spread.d)).Round(rateScale)
+	credit := policy.Rounding.round(debit.d.Mul(applied), to.Currency().MinorUnits())
+
+	t.Credit = Money{d: credit}
+	t.Rate = Rate{d: applied}
+	t.MarketRate = marketRate
+	t.Spread = policy.Spread
+	return t, nil
+}
+
+// SendTransfer withdraws debited amount of transfer.
+func (a *Account) SendTransfer(t Transfer) error {
+	if err := a.checkTransfer(t.FromAccountId, t.DebitCurrency); err != nil {
+		return err
+	}
+	if err := a.withdraw(t.Debit); err != nil {
+		return err
+	}
+	a.recordTransfer(OperationTransferOut, t.Debit, t)
+	a.emit(Event{Type: EventTransferSent, Amount: t.Debit, Transfer: &t})
+	return nil
+}
+
+// ReceiveTransfer deposits credited amount of transfer.
+func (a *Account) ReceiveTransfer(t Transfer) error {
+	if err := a.checkTransfer(t.ToAccountId, t.CreditCurrency); err != nil {
+		return err
+	}
+	if err := a.deposit(t.Credit); err != nil {
+		return err
+	}
+	a.recordTransfer(OperationTransferIn, t.Credit, t)
+	a.emit(Event{Type: EventTransferReceived, Amount: t.Credit, Transfer: &t})
+	return nil
+}
+
+func (a *Account) checkTransfer(accountId int64, currency Currency) error {
+	if accountId != a.id {
+		return fmt.Errorf("transfer is not related to account %d", a.id)
+	}
+	return a.CheckCurrency(currency)
+}
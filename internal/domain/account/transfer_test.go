@@ -0,0 +1,203 @@
+package account
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewTransfer(t *testing.T) {
+	tests := []struct {
+		name       string
+		from, to   Currency
+		amount     string
+		marketRate string
+		policy     ConversionPolicy
+		wantDebit  string
+		wantCredit string
+		wantRate   string
+	}{
+		{
+			name: "same currency ignores rate and spread",
+			from: "USD", to: "USD", amount: "100", marketRate: "0.9",
+			policy:    ConversionPolicy{Spread: MustParseRate("0.01"), Rounding: RoundHalfEven},
+			wantDebit: "100.00", wantCredit: "100.00", wantRate: "1",
+		},
+		{
+			name: "market rate without spread",
+			from: "USD", to: "EUR", amount: "100", marketRate: "0.9",
+			policy:    ConversionPolicy{Rounding: RoundHalfEven},
+			wantDebit: "100.00", wantCredit: "90.00", wantRate: "0.9",
+		},
+		{
+			name: "spread reduces rate",
+			from: "USD", to: "EUR", amount: "100", marketRate: "0.9",
+			policy:    ConversionPolicy{Spread: MustParseRate("0.01"), Rounding: RoundHalfEven},
+			wantDebit: "100.00", wantCredit: "89.10", wantRate: "0.891",
+		},
+		{
+			name: "half even rounds tie to even",
+			from: "USD", to: "EUR", amount: "0.05", marketRate: "0.5",
+			policy:    ConversionPolicy{Rounding: RoundHalfEven},
+			wantDebit: "0.05", wantCredit: "0.02", wantRate: "0.5",
+		},
+		{
+			name: "half up rounds tie up",
+			from: "USD", to: "EUR", amount: "0.05", marketRate: "0.5",
+			policy:    ConversionPolicy{Rounding: RoundHalfUp},
+			wantDebit: "0.05", wantCredit: "0.03", wantRate: "0.5",
+		},
+		{
+			name: "half even and half up agree on odd tie",
+			from: "USD", to: "EUR", amount: "0.15", marketRate: "0.5",
+			policy:    ConversionPolicy{Rounding: RoundHalfEven},
+			wantDebit: "0.15", wantCredit: "0.08", wantRate: "0.5",
+		},
+		{
+			name: "down",
+			from: "USD", to: "EUR", amount: "0.15", marketRate: "0.5",
+			policy:    ConversionPolicy{Rounding: RoundDown},
+			wantDebit: "0.15", wantCredit: "0.07", wantRate: "0.5",
+		},
+		{
+			name: "up",
+			from: "USD", to: "EUR", amount: "0.13", marketRate: "0.5",
+			policy:    ConversionPolicy{Rounding: RoundUp},
+			wantDebit: "0.13", wantCredit: "0.07", wantRate: "0.5",
+		},
+		{
+			name: "zero minor units half even",
+			from: "USD", to: "JPY", amount: "1", marketRate: "150.5",
+			policy:    ConversionPolicy{Rounding: RoundHalfEven},
+			wantDebit: "1.00", wantCredit: "150", wantRate: "150.5",
+		},
+		{
+			name: "zero minor units half up",
+			from: "USD", to: "JPY", amount: "1", marketRate: "150.5",
+			policy:    ConversionPolicy{Rounding: RoundHalfUp},
+			wantDebit: "1.00", wantCredit: "151", wantRate: "150.5",
+		},
+		{
+			name: "three minor units half even",
+			from: "USD", to: "BHD", amount: "1", marketRate: "0.3765",
+			policy:    ConversionPolicy{Rounding: RoundHalfEven},
+			wantDebit: "1.00", wantCredit: "0.376", wantRate: "0.3765",
+		},
+		{
+			name: "three minor units half up",
+			from: "USD", to: "BHD", amount: "1", marketRate: "0.3765",
+			policy:    ConversionPolicy{Rounding: RoundHalfUp},
+			wantDebit: "1.00", wantCredit: "0.377", wantRate: "0.3765",
+		},
+		{
+			name: "from zero minor units",
+			from: "JPY", to: "USD", amount: "1000", marketRate: "0.0066",
+			policy:    ConversionPolicy{Rounding: RoundHalfEven},
+			wantDebit: "1000", wantCredit: "6.60", wantRate: "0.0066",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := NewAccount(1, tt.from, Money{}, Money{}, "")
+			to := NewAccount(2, tt.to, Money{}, Money{}, "")
+			transfer, err := NewTransfer(&from, &to, MustParseMoney(tt.amount), MustParseRate(tt.marketRate), tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := transfer.Debit.String(); got != tt.wantDebit {
+				t.Errorf("debit %s, want %s", got, tt.wantDebit)
+			}
+			if got := transfer.Credit.String(); got != tt.wantCredit {
+				t.Errorf("credit %s, want %s", got, tt.wantCredit)
+			}
+			if got := transfer.Rate.String(); got != tt.wantRate {
+				t.Errorf("rate %s, want %s", got, tt.wantRate)
+			}
+			if transfer.DebitCurrency != tt.from || transfer.CreditCurrency != tt.to {
+				t.Errorf("currencies %s -> %s, want %s -> %s",
+					transfer.DebitCurrency, transfer.CreditCurrency, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestNewTransferErrors(t *testing.T) {
+	usd := NewAccount(1, "USD", Money{}, Money{}, "")
+	eur := NewAccount(2, "EUR", Money{}, Money{}, "")
+	jpy := NewAccount(3, "JPY", Money{}, Money{}, "")
+	policy := ConversionPolicy{Rounding: RoundHalfEven}
+
+	tests := []struct {
+		name     string
+		from, to *Account
+		amount   string
+		rate     string
+		wantErr  error
+	}{
+		{name: "zero amount", from: &usd, to: &eur, amount: "0", rate: "0.9", wantErr: ErrZeroAmount},
+		{name: "negative amount", from: &usd, to: &eur, amount: "-1", rate: "0.9", wantErr: ErrNegativeAmount},
+		{name: "excess precision of debit currency", from: &jpy, to: &usd, amount: "1.5", rate: "0.0066", wantErr: ErrExcessPrecision},
+		{name: "zero rate", from: &usd, to: &eur, amount: "1", rate: "0", wantErr: ErrInvalidRate},
+		{name: "negative rate", from: &usd, to: &eur, amount: "1", rate: "-0.9", wantErr: ErrInvalidRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTransfer(tt.from, tt.to, MustParseMoney(tt.amount), MustParseRate(tt.rate), policy)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConversionPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ConversionPolicy
+		wantErr error
+	}{
+		{name: "valid", policy: ConversionPolicy{Spread: MustParseRate("0.005"), Rounding: RoundHalfUp}},
+		{name: "negative spread", policy: ConversionPolicy{Spread: MustParseRate("-0.1"), Rounding: RoundHalfUp}, wantErr: ErrInvalidSpread},
+		{name: "whole spread", policy: ConversionPolicy{Spread: MustParseRate("1"), Rounding: RoundHalfUp}, wantErr: ErrInvalidSpread},
+		{name: "unknown rounding", policy: ConversionPolicy{Rounding: "banker"}, wantErr: ErrUnknownRoundingMode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTransferBetweenAccounts(t *testing.T) {
+	from := NewAccount(1, "USD", MustParseMoney("100"), Money{}, "")
+	to := NewAccount(2, "EUR", Money{}, Money{}, "")
+	transfer, err := NewTransfer(&from, &to, MustParseMoney("10"), MustParseRate("0.9"), ConversionPolicy{Rounding: RoundHalfEven})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := to.SendTransfer(transfer); err == nil {
+		t.Fatal("transfer is sent from other account")
+	}
+	if err := from.SendTransfer(transfer); err != nil {
+		t.Fatal(err)
+	}
+	if err := to.ReceiveTransfer(transfer); err != nil {
+		t.Fatal(err)
+	}
+	if got := from.GetBalance().String(); got != "90.00" {
+		t.Errorf("sender balance %s, want 90.00", got)
+	}
+	if got := to.GetBalance().String(); got != "9.00" {
+		t.Errorf("receiver balance %s, want 9.00", got)
+	}
+
+	big, err := NewTransfer(&from, &to, MustParseMoney("1000"), MustParseRate("0.9"), ConversionPolicy{Rounding: RoundHalfEven})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := from.SendTransfer(big); !errors.Is(err, ErrNotEnoughBalance) {
+		t.Fatalf("want ErrNotEnoughBalance, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package acquire
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+// advisoryNamespace separates account locks from other advisory locks of database.
+const advisoryNamespace uint32 = 0x62616e6b // "bank"
+
+// advisoryKey is bigint key of account lock, namespace and full id are hashed into it.
+// Keys of different accounts may collide, it makes them contend but never deadlock:
+// advisory lock is reentrant within transaction and pairs are locked in order of keys.
+func advisoryKey(id int64) int64 {
+	var b [12]byte
+	binary.BigEndian.PutUint32(b[:4], advisoryNamespace)
+	binary.BigEndian.PutUint64(b[4:], uint64(id))
+	h := fnv.New64a()
+	h.Write(b[:])
+	return int64(h.Sum64())
+}
+
+type TxBeginner interface {
+	BeginFunc(ctx context.Context, f func(pgx.Tx) error) error
+}
+
+// TxStorageFunc returns storage working inside transaction tx.
+type TxStorageFunc func(tx pgx.Tx) AccountStorage
+
+// AdvisoryAcquirer serializes access to account between all instances of service
+// with pg_advisory_xact_lock. Lock is released with transaction, so account
+// changes are saved in the same transaction.
+type AdvisoryAcquirer struct {
+	db      TxBeginner
+	storage TxStorageFunc
+	metrics *LockWaitMetrics
+	// maxWait limits waiting for lock if context has no earlier deadline
+	maxWait time.Duration
+}
+
+// NewAdvisoryAcquirer creates acquirer, zero maxWait means waiting until context is done.
+// Metrics may be nil.
+func NewAdvisoryAcquirer(
+	db TxBeginner,
+	storage TxStorageFunc,
+	metrics *LockWaitMetrics,
+	maxWait time.Duration,
+) *AdvisoryAcquirer {
+	return &AdvisoryAcquirer{
+		db:      db,
+		storage: storage,
+		metrics: metrics,
+		maxWait: maxWait,
+	}
+}
+
+const advisoryOpPrefix = "acquire.Advisory."
+
+func (aa *AdvisoryAcquirer) Acquire(ctx context.Context, id int64, fn application.AccountProcessFunc) error {
+	const op = advisoryOpPrefix + "Acquire"
+
+	err := aa.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := aa.lock(ctx, tx, advisoryKey(id)); err != nil {
+			return err
+		}
+
+		storage := aa.storage(tx)
+		a, err := storage.GetAccountById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := fn(&a); err != nil {
+			return err
+		}
+		return storage.SaveAccounts(ctx, a)
+	})
+	return mapAdvisoryError(op, err)
+}
+
+func (aa *AdvisoryAcquirer) AcquirePair(ctx context.Context, firstId, secondId int64, fn application.PairProcessFunc) error {
+	const op = advisoryOpPrefix + "AcquirePair"
+	if firstId == secondId {
+		return fmt.Errorf("%s:%w", op, application.ErrSameAccount)
+	}
+
+	err := aa.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		// locks taken in order of keys, so opposite pairs cannot deadlock
+		firstKey, secondKey := advisoryKey(firstId), advisoryKey(secondId)
+		if err := aa.lock(ctx, tx, min(firstKey, secondKey)); err != nil {
+			return err
+		}
+		if err := aa.lock(ctx, tx, max(firstKey, secondKey)); err != nil {
+			return err
+		}
+
+		storage := aa.storage(tx)
+		first, err := storage.GetAccountById(ctx, firstId)
+		if err != nil {
+			return err
+		}
+		second, err := storage.GetAccountById(ctx, secondId)
+		if err != nil {
+			return err
+		}
+		if err := fn(&first, &second); err != nil {
+			return err
+		}
+		return storage.SaveAccounts(ctx, first, second)
+	})
+	return mapAdvisoryError(op, err)
+}
+
+// lock waits for advisory lock of key. Waiting is limited by deadline of ctx
+// through lock_timeout, so timeout doesn't break connection.
+func (aa *AdvisoryAcquirer) lock(ctx context.Context, tx pgx.Tx, key int64) error {
+	if timeout, ok := aa.waitTimeout(ctx); ok {
+		if timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+		_, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL lock_timeout = %d", max(timeout.Milliseconds(), 1)))
+		if err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, key)
+	aa.metrics.Observe(time.Since(start), err)
+	return err
+}
+
+func (aa *AdvisoryAcquirer) waitTimeout(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if aa.maxWait > 0 {
+		if limit := time.Now().Add(aa.maxWait); !ok || limit.Before(deadline) {
+			deadline, ok = limit, true
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
+
+// mapAdvisoryError replaces lock timeouts and deadlocks with application.ErrAccountBusy.
+// Deadlock is possible with row locks taken by storage in other transactions.
+func mapAdvisoryError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) &&
+		(pgErr.Code == pgerrcode.LockNotAvailable || pgErr.Code == pgerrcode.DeadlockDetected) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%s:%w", op, application.ErrAccountBusy)
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package acquire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+func TestAdvisoryKey(t *testing.T) {
+	if advisoryKey(42) != advisoryKey(42) {
+		t.Fatal("key of account isn't stable")
+	}
+	// ids differing only in high bits must not share lock
+	for _, pair := range [][2]int64{
+		{1, 1 + 1<<32},
+		{7, 7 + 1<<40},
+		{1, 2},
+	} {
+		if advisoryKey(pair[0]) == advisoryKey(pair[1]) {
+			t.Errorf("accounts %d and %d have the same key", pair[0], pair[1])
+		}
+	}
+}
+
+func TestMapAdvisoryError(t *testing.T) {
+	other := &pgconn.PgError{Code: pgerrcode.UniqueViolation}
+	tests := []struct {
+		name     string
+		err      error
+		wantBusy bool
+	}{
+		{name: "lock timeout", err: &pgconn.PgError{Code: pgerrcode.LockNotAvailable}, wantBusy: true},
+		{name: "deadlock", err: fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: pgerrcode.DeadlockDetected}), wantBusy: true},
+		{name: "deadline", err: context.DeadlineExceeded, wantBusy: true},
+		{name: "other", err: other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mapAdvisoryError("op", tt.err)
+			if busy := errors.Is(err, application.ErrAccountBusy); busy != tt.wantBusy {
+				t.Fatalf("busy %v, want %v: %v", busy, tt.wantBusy, err)
+			}
+			if !tt.wantBusy && !errors.Is(err, tt.err) {
+				t.Fatalf("error %v is replaced by %v", tt.err, err)
+			}
+		})
+	}
+	if err := mapAdvisoryError("op", nil); err != nil {
+		t.Fatalf("nil error is mapped to %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package acquire
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+const lockShards = 64
+
+// lockEntry exists while account is held or awaited.
+type lockEntry struct {
+	// refs counts holder and waiters, entry is evicted when it drops to zero
+	refs int
+	held bool
+	// waiters are woken in FIFO order, lock is handed over to woken waiter
+	waiters []chan struct{}
+
+	// account is last saved state, nil if not loaded yet.
+	// Only holder of lock accesses it.
+	account *account.Account
+}
+
+type lockShard struct {
+	mu      sync.Mutex
+	entries map[int64]*lockEntry
+}
+
+// lockManager is per-account mutex with fair waiting and eviction of idle accounts.
+type lockManager struct {
+	shards [lockShards]lockShard
+}
+
+func newLockManager() *lockManager {
+	m := &lockManager{}
+	for i := range m.shards {
+		m.shards[i].entries = make(map[int64]*lockEntry)
+	}
+	return m
+}
+
+func (m *lockManager) shard(id int64) *lockShard {
+	return &m.shards[uint64(id)%lockShards]
+}
+
+// lock waits until account is free or ctx is done.
+func (m *lockManager) lock(ctx context.Context, id int64) (*lockEntry, error) {
+	// done context never takes lock, even free one
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := m.shard(id)
+	s.mu.Lock()
+	e, ok := s.entries[id]
+	if !ok {
+		e = &lockEntry{}
+		s.entries[id] = e
+	}
+	e.refs++
+	if !e.held {
+		e.held = true
+		s.mu.Unlock()
+		return e, nil
+	}
+
+	wake := make(chan struct{})
+	e.waiters = append(e.waiters, wake)
+	s.mu.Unlock()
+
+	select {
+	case <-wake:
+		return e, nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	if i := slices.Index(e.waiters, wake); i >= 0 {
+		e.waiters = slices.Delete(e.waiters, i, i+1)
+		e.refs--
+		s.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	s.mu.Unlock()
+
+	// lock was handed over concurrently with cancellation, pass it further
+	m.unlock(id, e)
+	return nil, ctx.Err()
+}
+
+// unlock hands lock over to the first waiter or evicts idle entry.
+func (m *lockManager) unlock(id int64, e *lockEntry) {
+	s := m.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.refs--
+	if len(e.waiters) != 0 {
+		wake := e.waiters[0]
+		e.waiters = slices.Delete(e.waiters, 0, 1)
+		close(wake)
+		return
+	}
+
+	e.held = false
+	if e.refs == 0 {
+		delete(s.entries, id)
+	}
+}
@@ -0,0 +1,228 @@
+package acquire
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitQueued waits until n waiters of id are queued.
+func waitQueued(t *testing.T, m *lockManager, id int64, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s := m.shard(id)
+		s.mu.Lock()
+		e, ok := s.entries[id]
+		queued := ok && len(e.waiters) == n
+		s.mu.Unlock()
+		if queued {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%d waiters of account %d aren't queued", n, id)
+}
+
+func entriesCount(m *lockManager) int {
+	count := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		count += len(s.entries)
+		s.mu.Unlock()
+	}
+	return count
+}
+
+func TestLockFIFO(t *testing.T) {
+	const id, waiters = 1, 10
+	m := newLockManager()
+	ctx := context.Background()
+
+	holder, err := m.lock(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			e, err := m.lock(ctx, id)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			m.unlock(id, e)
+		}(i)
+		// the next waiter is queued after this one
+		waitQueued(t, m, id, i+1)
+	}
+
+	m.unlock(id, holder)
+	wg.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("waiters took lock in order %v, want FIFO", order)
+		}
+	}
+	if len(order) != waiters {
+		t.Fatalf("%d of %d waiters took lock", len(order), waiters)
+	}
+}
+
+func TestLockEviction(t *testing.T) {
+	m := newLockManager()
+	ctx := context.Background()
+
+	e, err := m.lock(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entriesCount(m); got != 1 {
+		t.Fatalf("held account has %d entries, want 1", got)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e, err := m.lock(ctx, 1)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		m.unlock(1, e)
+	}()
+	waitQueued(t, m, 1, 1)
+
+	m.unlock(1, e)
+	<-done
+	if got := entriesCount(m); got != 0 {
+		t.Fatalf("idle accounts have %d entries, want evicted", got)
+	}
+}
+
+func TestLockCancelledWaiter(t *testing.T) {
+	const id = 1
+	m := newLockManager()
+	ctx := context.Background()
+
+	holder, err := m.lock(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := m.lock(cancelCtx, id)
+		cancelled <- err
+	}()
+	waitQueued(t, m, id, 1)
+
+	next := make(chan *lockEntry, 1)
+	go func() {
+		e, err := m.lock(ctx, id)
+		if err != nil {
+			t.Error(err)
+		}
+		next <- e
+	}()
+	waitQueued(t, m, id, 2)
+
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled waiter: want context.Canceled, got %v", err)
+	}
+	waitQueued(t, m, id, 1)
+
+	// lock skips cancelled waiter
+	m.unlock(id, holder)
+	select {
+	case e := <-next:
+		m.unlock(id, e)
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock isn't handed over to waiter after cancelled one")
+	}
+	if got := entriesCount(m); got != 0 {
+		t.Fatalf("idle accounts have %d entries, want evicted", got)
+	}
+}
+
+// TestLockHandoverRacesCancel releases lock concurrently with cancellation of waiter,
+// lock handed over to cancelled waiter must be passed further.
+func TestLockHandoverRacesCancel(t *testing.T) {
+	const id = 1
+	m := newLockManager()
+	ctx := context.Background()
+
+	for i := 0; i < 200; i++ {
+		holder, err := m.lock(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancelled := make(chan *lockEntry, 1)
+		go func() {
+			e, err := m.lock(cancelCtx, id)
+			if err != nil {
+				e = nil
+			}
+			cancelled <- e
+		}()
+		waitQueued(t, m, id, 1)
+
+		next := make(chan *lockEntry, 1)
+		go func() {
+			e, err := m.lock(ctx, id)
+			if err != nil {
+				t.Error(err)
+			}
+			next <- e
+		}()
+		waitQueued(t, m, id, 2)
+
+		go cancel()
+		m.unlock(id, holder)
+
+		// cancelled waiter may still win lock if it was woken first
+		if e := <-cancelled; e != nil {
+			m.unlock(id, e)
+		}
+		select {
+		case e := <-next:
+			m.unlock(id, e)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("iteration %d: lock is lost after cancelled waiter", i)
+		}
+		if got := entriesCount(m); got != 0 {
+			t.Fatalf("iteration %d: idle accounts have %d entries, want evicted", i, got)
+		}
+	}
+}
+
+func TestLockDoneContext(t *testing.T) {
+	m := newLockManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := m.lock(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+	if got := entriesCount(m); got != 0 {
+		t.Fatalf("done context left %d entries", got)
+	}
+}
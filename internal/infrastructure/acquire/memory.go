@@ -2,7 +2,8 @@ package acquire
 
 import (
 	"context"
-	"sync"
+	"errors"
+	"fmt"
 
 	"github.com/vitaliy-ukiru/bank-service/internal/application"
 	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
@@ -10,59 +11,121 @@ import (
 
 type AccountStorage interface {
 	GetAccountById(ctx context.Context, id int64) (account.Account, error)
-	SaveAccount(ctx context.Context, acc account.Account) error
+	// SaveAccounts saves all accounts atomically.
+	SaveAccounts(ctx context.Context, accs ...account.Account) error
 }
 
+// InMemoryAcquirer serializes access to accounts within one process.
+// State of account is cached while it is held or awaited, idle accounts are evicted.
 type InMemoryAcquirer struct {
 	storage AccountStorage
-
-	rw                  sync.RWMutex
-	accountInProcessing map[int64]chan account.Account
+	locks   *lockManager
 }
 
 func NewInMemoryAcquirer(storage AccountStorage) *InMemoryAcquirer {
 	return &InMemoryAcquirer{
-		storage:             storage,
-		accountInProcessing: make(map[int64]chan account.Account),
+		storage: storage,
+		locks:   newLockManager(),
 	}
 }
 
-func (im *InMemoryAcquirer) addToProcessing(a account.Account) chan account.Account {
-	ch := make(chan account.Account, 1) // buffer for stop processing without wait
-	im.rw.Lock()
-	im.accountInProcessing[a.Id()] = ch
-	defer im.rw.Unlock()
-	return ch
+const memoryOpPrefix = "acquire.InMemory."
+
+func (im *InMemoryAcquirer) Acquire(ctx context.Context, id int64, fn application.AccountProcessFunc) error {
+	const op = memoryOpPrefix + "Acquire"
+
+	e, err := im.locks.lock(ctx, id)
+	if err != nil {
+		return waitError(op, err)
+	}
+	defer im.locks.unlock(id, e)
+
+	a, err := im.load(ctx, id, e)
+	if err != nil {
+		return err
+	}
+
+	// work with copy, so failed fn or save leaves cached account untouched
+	changed := a
+	if err := fn(&changed); err != nil {
+		return err
+	}
+	if err := im.storage.SaveAccounts(ctx, changed); err != nil {
+		return err
+	}
+
+	changed.ClearChanges()
+	e.account = &changed
+	return nil
 }
 
-func (im *InMemoryAcquirer) Acquire(ctx context.Context, id int64, fn application.AccountProcessFunc) (err error) {
-	im.rw.RLock()
-	ch, ok := im.accountInProcessing[id]
-	im.rw.RUnlock()
-	var a account.Account
-	if ok {
-		// waiting for free account or exit as done context
-		select {
-		case a = <-ch:
-		case <-ctx.Done():
-			return ctx.Err()
-		}
-	} else {
-		// get account from storage
-		// and add to processing
-		a, err = im.storage.GetAccountById(ctx, id)
-		if err != nil {
-			return err
-		}
-		ch = im.addToProcessing(a)
-	}
-
-	defer func() { ch <- a }() // push account as free in any way
-
-	err = fn(&a)
+func (im *InMemoryAcquirer) AcquirePair(ctx context.Context, firstId, secondId int64, fn application.PairProcessFunc) error {
+	const op = memoryOpPrefix + "AcquirePair"
+	if firstId == secondId {
+		return application.ErrSameAccount
+	}
+
+	// take accounts in order of id, so opposite pairs cannot deadlock
+	lowId, highId := min(firstId, secondId), max(firstId, secondId)
+
+	low, err := im.locks.lock(ctx, lowId)
+	if err != nil {
+		return waitError(op, err)
+	}
+	defer im.locks.unlock(lowId, low)
+
+	high, err := im.locks.lock(ctx, highId)
+	if err != nil {
+		return waitError(op, err)
+	}
+	defer im.locks.unlock(highId, high)
+
+	firstEntry, secondEntry := low, high
+	if firstId != lowId {
+		firstEntry, secondEntry = high, low
+	}
+
+	first, err := im.load(ctx, firstId, firstEntry)
+	if err != nil {
+		return err
+	}
+	second, err := im.load(ctx, secondId, secondEntry)
 	if err != nil {
 		return err
 	}
-	return im.storage.SaveAccount(ctx, a)
 
+	// work with copies, so failed fn or save leaves both cached accounts untouched
+	if err := fn(&first, &second); err != nil {
+		return err
+	}
+	if err := im.storage.SaveAccounts(ctx, first, second); err != nil {
+		return err
+	}
+
+	first.ClearChanges()
+	second.ClearChanges()
+	firstEntry.account, secondEntry.account = &first, &second
+	return nil
+}
+
+// load returns copy of cached account, reading it from storage on first access.
+// Must be called by holder of e.
+func (im *InMemoryAcquirer) load(ctx context.Context, id int64, e *lockEntry) (account.Account, error) {
+	if e.account != nil {
+		return *e.account, nil
+	}
+	a, err := im.storage.GetAccountById(ctx, id)
+	if err != nil {
+		return account.Account{}, err
+	}
+	e.account = &a
+	return a, nil
+}
+
+// waitError reports timeout of waiting as application.ErrAccountBusy.
+func waitError(op string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%s:%w", op, application.ErrAccountBusy)
+	}
+	return fmt.Errorf("%s:%w", op, err)
 }
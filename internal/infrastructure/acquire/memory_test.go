@@ -0,0 +1,240 @@
+package acquire
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+var (
+	errFn   = errors.New("fn failed")
+	errSave = errors.New("save failed")
+	// errReleased discards changes of holdAccount
+	errReleased = errors.New("released")
+)
+
+// fakeStorage counts reads and fails saves while failSaves is positive.
+type fakeStorage struct {
+	mu        sync.Mutex
+	accounts  map[int64]account.Account
+	reads     int
+	failSaves int
+}
+
+func newFakeStorage(accs ...account.Account) *fakeStorage {
+	s := &fakeStorage{accounts: make(map[int64]account.Account)}
+	for _, a := range accs {
+		s.accounts[a.Id()] = a
+	}
+	return s
+}
+
+func (s *fakeStorage) GetAccountById(_ context.Context, id int64) (account.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reads++
+	a, ok := s.accounts[id]
+	if !ok {
+		return account.Account{}, application.ErrAccountNotFound
+	}
+	return a, nil
+}
+
+func (s *fakeStorage) SaveAccounts(_ context.Context, accs ...account.Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failSaves > 0 {
+		s.failSaves--
+		return errSave
+	}
+	for _, a := range accs {
+		s.accounts[a.Id()] = a
+	}
+	return nil
+}
+
+func (s *fakeStorage) readCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reads
+}
+
+func testAccount(id int64, balance string) account.Account {
+	return account.NewAccount(id, account.DefaultCurrency, account.MustParseMoney(balance), account.Money{}, "")
+}
+
+// holdAccount holds account by Acquire until returned func is called.
+func holdAccount(t *testing.T, im *InMemoryAcquirer, id int64) (release func()) {
+	t.Helper()
+	locked, unlock, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		err := im.Acquire(context.Background(), id, func(application.BankAccount) error {
+			close(locked)
+			<-unlock
+			return errReleased
+		})
+		if !errors.Is(err, errReleased) {
+			t.Error(err)
+		}
+	}()
+	<-locked
+	return func() {
+		close(unlock)
+		<-done
+	}
+}
+
+func balanceOf(t *testing.T, im *InMemoryAcquirer, id int64) string {
+	t.Helper()
+	var balance string
+	err := im.Acquire(context.Background(), id, func(a application.BankAccount) error {
+		balance = a.GetBalance().String()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return balance
+}
+
+func TestInMemoryAcquirerRollback(t *testing.T) {
+	tests := []struct {
+		name      string
+		fnErr     error
+		failSaves int
+		wantErr   error
+	}{
+		{name: "fn fails", fnErr: errFn, wantErr: errFn},
+		{name: "save fails", failSaves: 1, wantErr: errSave},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newFakeStorage(testAccount(1, "100"))
+			im := NewInMemoryAcquirer(storage)
+
+			// account stays cached while it is held or awaited
+			release := holdAccount(t, im, 1)
+
+			failed := make(chan error, 1)
+			go func() {
+				failed <- im.Acquire(context.Background(), 1, func(a application.BankAccount) error {
+					if err := a.Withdraw(account.MustParseMoney("30")); err != nil {
+						return err
+					}
+					return tt.fnErr
+				})
+			}()
+			waitQueued(t, im.locks, 1, 1)
+
+			balance := make(chan string, 1)
+			go func() {
+				balance <- balanceOf(t, im, 1)
+			}()
+			waitQueued(t, im.locks, 1, 2)
+
+			storage.mu.Lock()
+			storage.failSaves = tt.failSaves
+			storage.mu.Unlock()
+			release()
+
+			if err := <-failed; !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+			if got := <-balance; got != "100.00" {
+				t.Fatalf("failed operation changed cached balance to %s", got)
+			}
+			if reads := storage.readCount(); reads != 1 {
+				t.Fatalf("account is read %d times, want once while cached", reads)
+			}
+		})
+	}
+}
+
+func TestInMemoryAcquirerPairRollback(t *testing.T) {
+	storage := newFakeStorage(testAccount(1, "100"), testAccount(2, "100"))
+	im := NewInMemoryAcquirer(storage)
+
+	release := holdAccount(t, im, 2)
+	failed := make(chan error, 1)
+	go func() {
+		failed <- im.AcquirePair(context.Background(), 2, 1, func(first, second application.BankAccount) error {
+			if err := first.Withdraw(account.MustParseMoney("30")); err != nil {
+				return err
+			}
+			return second.Deposit(account.MustParseMoney("30"))
+		})
+	}()
+	// pair holds account 1 and waits for account 2
+	waitQueued(t, im.locks, 2, 1)
+
+	balance := make(chan string, 1)
+	go func() {
+		balance <- balanceOf(t, im, 1)
+	}()
+	waitQueued(t, im.locks, 1, 1)
+
+	storage.mu.Lock()
+	storage.failSaves = 1
+	storage.mu.Unlock()
+	release()
+
+	if err := <-failed; !errors.Is(err, errSave) {
+		t.Fatalf("want %v, got %v", errSave, err)
+	}
+	if got := <-balance; got != "100.00" {
+		t.Fatalf("failed pair changed cached balance of account 1 to %s", got)
+	}
+	if got := balanceOf(t, im, 2); got != "100.00" {
+		t.Fatalf("failed pair changed balance of account 2 to %s", got)
+	}
+	// account 1 is read by pair and cached for waiter, account 2 is read by holder and again after eviction
+	if reads := storage.readCount(); reads != 3 {
+		t.Fatalf("accounts are read %d times, want 3", reads)
+	}
+}
+
+func TestInMemoryAcquirerEviction(t *testing.T) {
+	storage := newFakeStorage(testAccount(1, "100"))
+	im := NewInMemoryAcquirer(storage)
+
+	err := im.Acquire(context.Background(), 1, func(a application.BankAccount) error {
+		return a.Deposit(account.MustParseMoney("10"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entriesCount(im.locks); got != 0 {
+		t.Fatalf("idle account isn't evicted, %d entries", got)
+	}
+	// evicted account is read again with saved changes
+	if got := balanceOf(t, im, 1); got != "110.00" {
+		t.Fatalf("want balance 110.00, got %s", got)
+	}
+	if reads := storage.readCount(); reads != 2 {
+		t.Fatalf("account is read %d times, want again after eviction", reads)
+	}
+}
+
+func TestInMemoryAcquirerBusy(t *testing.T) {
+	storage := newFakeStorage(testAccount(1, "100"))
+	im := NewInMemoryAcquirer(storage)
+
+	release := holdAccount(t, im, 1)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := im.Acquire(ctx, 1, func(application.BankAccount) error {
+		t.Error("fn is called without lock")
+		return nil
+	})
+	if !errors.Is(err, application.ErrAccountBusy) {
+		t.Fatalf("want ErrAccountBusy, got %v", err)
+	}
+}
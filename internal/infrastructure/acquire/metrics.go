@@ -0,0 +1,82 @@
+package acquire
+
+import (
+	"sync"
+	"time"
+)
+
+// lockWaitBuckets are upper bounds of wait time histogram.
+var lockWaitBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	25 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// LockWaitMetrics collects time spent waiting for account locks.
+// Nil metrics ignore observations.
+type LockWaitMetrics struct {
+	mu       sync.Mutex
+	count    int64
+	failures int64
+	total    time.Duration
+	max      time.Duration
+	buckets  []int64
+}
+
+func NewLockWaitMetrics() *LockWaitMetrics {
+	return &LockWaitMetrics{buckets: make([]int64, len(lockWaitBuckets)+1)}
+}
+
+// Observe records wait, err is error of lock attempt.
+func (m *LockWaitMetrics) Observe(wait time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.count++
+	if err != nil {
+		m.failures++
+	}
+	m.total += wait
+	m.max = max(m.max, wait)
+
+	i := 0
+	for i < len(lockWaitBuckets) && wait > lockWaitBuckets[i] {
+		i++
+	}
+	m.buckets[i]++
+}
+
+type LockWaitSnapshot struct {
+	Count    int64 `json:"count"`
+	Failures int64 `json:"failures"`
+	TotalMs  int64 `json:"total_ms"`
+	MaxMs    int64 `json:"max_ms"`
+	// Buckets maps upper bound of wait to count of waits, last bucket is "+Inf"
+	Buckets map[string]int64 `json:"buckets"`
+}
+
+func (m *LockWaitMetrics) Snapshot() LockWaitSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buckets := make(map[string]int64, len(m.buckets))
+	for i, bound := range lockWaitBuckets {
+		buckets[bound.String()] = m.buckets[i]
+	}
+	buckets["+Inf"] = m.buckets[len(lockWaitBuckets)]
+
+	return LockWaitSnapshot{
+		Count:    m.count,
+		Failures: m.failures,
+		TotalMs:  m.total.Milliseconds(),
+		MaxMs:    m.max.Milliseconds(),
+		Buckets:  buckets,
+	}
+}
@@ -0,0 +1,60 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgtype/pgxtype"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+// PostgresProvider reads rates from exchange_rates table.
+// If only opposite pair is stored, its inverse is used.
+type PostgresProvider struct {
+	conn pgxtype.Querier
+}
+
+func NewPostgresProvider(conn pgxtype.Querier) *PostgresProvider {
+	return &PostgresProvider{conn: conn}
+}
+
+const opPrefix = "exchange."
+
+func (p *PostgresProvider) GetRate(ctx context.Context, from, to account.Currency) (account.Rate, error) {
+	const op = opPrefix + "Postgres.GetRate"
+
+	rows, err := p.conn.Query(ctx,
+		`SELECT base_currency, rate FROM exchange_rates
+		WHERE (base_currency=$1 AND quote_currency=$2) OR (base_currency=$2 AND quote_currency=$1)`,
+		string(from),
+		string(to),
+	)
+	if err != nil {
+		return account.Rate{}, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	rates := make(map[account.Currency]account.Rate, 2)
+	for rows.Next() {
+		var (
+			base string
+			rate account.Rate
+		)
+		if err := rows.Scan(&base, &rate); err != nil {
+			return account.Rate{}, fmt.Errorf("%s:%w", op, err)
+		}
+		rates[account.Currency(base)] = rate
+	}
+	if err := rows.Err(); err != nil {
+		return account.Rate{}, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if rate, ok := rates[from]; ok {
+		return rate, nil
+	}
+	if rate, ok := rates[to]; ok {
+		return rate.Inverse(), nil
+	}
+	return account.Rate{}, fmt.Errorf("%s:%w: %s/%s", op, application.ErrExchangeRateNotFound, from, to)
+}
@@ -0,0 +1,82 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+type pair struct {
+	base  account.Currency
+	quote account.Currency
+}
+
+// StaticProvider serves rates loaded once from file.
+// If only opposite pair is set, its inverse is used.
+type StaticProvider struct {
+	rates map[pair]account.Rate
+}
+
+// StaticRate is element of rates file:
+//
+//	{"rates": [{"base": "USD", "quote": "EUR", "rate": "0.92"}]}
+type StaticRate struct {
+	Base  string       `json:"base"`
+	Quote string       `json:"quote"`
+	Rate  account.Rate `json:"rate"`
+}
+
+func NewStaticProvider(rates []StaticRate) (*StaticProvider, error) {
+	p := &StaticProvider{rates: make(map[pair]account.Rate, len(rates))}
+	for _, r := range rates {
+		base, err := account.ParseCurrency(r.Base)
+		if err != nil {
+			return nil, err
+		}
+		quote, err := account.ParseCurrency(r.Quote)
+		if err != nil {
+			return nil, err
+		}
+		if !r.Rate.IsPositive() {
+			return nil, fmt.Errorf("%w: %s/%s must be positive", account.ErrInvalidRate, base, quote)
+		}
+		p.rates[pair{base, quote}] = r.Rate
+	}
+	return p, nil
+}
+
+func LoadStaticFile(path string) (*StaticProvider, error) {
+	const op = opPrefix + "LoadStaticFile"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	var file struct {
+		Rates []StaticRate `json:"rates"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	p, err := NewStaticProvider(file.Rates)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return p, nil
+}
+
+func (p *StaticProvider) GetRate(_ context.Context, from, to account.Currency) (account.Rate, error) {
+	if rate, ok := p.rates[pair{from, to}]; ok {
+		return rate, nil
+	}
+	if rate, ok := p.rates[pair{to, from}]; ok {
+		return rate.Inverse(), nil
+	}
+	return account.Rate{}, fmt.Errorf("%w: %s/%s", application.ErrExchangeRateNotFound, from, to)
+}
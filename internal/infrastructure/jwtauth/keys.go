@@ -0,0 +1,170 @@
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Algorithms of accepted tokens, every key verifies tokens of single algorithm.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// MinHMACKeyLength is minimal length of HS256 secret in bytes.
+const MinHMACKeyLength = 32
+
+var (
+	ErrWeakHMACKey     = fmt.Errorf("hmac key must have at least %d bytes", MinHMACKeyLength)
+	ErrUnsupportedKey  = errors.New("unsupported key")
+	ErrDuplicateKeyId  = errors.New("duplicate key id")
+	ErrInvalidKeyValue = errors.New("invalid key value")
+)
+
+// key verifies signatures of alg, material is []byte, *rsa.PublicKey or *ecdsa.PublicKey.
+type key struct {
+	id       string
+	alg      string
+	material any
+}
+
+// parseHMACKey parses "kid:secret", secret without colon has no kid.
+func parseHMACKey(raw string) (key, error) {
+	id, secret, ok := strings.Cut(raw, ":")
+	if !ok {
+		id, secret = "", raw
+	}
+	if len(secret) < MinHMACKeyLength {
+		return key{}, ErrWeakHMACKey
+	}
+	return key{id: id, alg: AlgHS256, material: []byte(secret)}, nil
+}
+
+// jwk is JSON Web Key of RSA, EC (P-256) or oct type.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// N and E are RSA public key.
+	N string `json:"n"`
+	E string `json:"e"`
+	// Crv, X and Y are EC public key.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// K is oct secret.
+	K string `json:"k"`
+}
+
+// parseJWKS parses {"keys": [...]}, keys used for encryption are skipped.
+func parseJWKS(data []byte) ([]key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make([]key, 0, len(set.Keys))
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		parsed, err := k.parse()
+		if err != nil {
+			return nil, fmt.Errorf("key %d (kid %q): %w", i, k.Kid, err)
+		}
+		keys = append(keys, parsed)
+	}
+	return keys, nil
+}
+
+func (k jwk) parse() (key, error) {
+	var (
+		alg      string
+		material any
+		err      error
+	)
+	switch k.Kty {
+	case "RSA":
+		alg = AlgRS256
+		material, err = k.rsaKey()
+	case "EC":
+		alg = AlgES256
+		material, err = k.ecKey()
+	case "oct":
+		alg = AlgHS256
+		var secret []byte
+		if secret, err = decodeSegment(k.K); err == nil && len(secret) < MinHMACKeyLength {
+			err = ErrWeakHMACKey
+		}
+		material = secret
+	default:
+		return key{}, fmt.Errorf("%w: kty %q", ErrUnsupportedKey, k.Kty)
+	}
+	if err != nil {
+		return key{}, err
+	}
+	if k.Alg != "" && k.Alg != alg {
+		return key{}, fmt.Errorf("%w: alg %q of %s key", ErrUnsupportedKey, k.Alg, k.Kty)
+	}
+	return key{id: k.Kid, alg: alg, material: material}, nil
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeSegment(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeSegment(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) < 256 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		// shorter modulus than 2048 bits is weak
+		return nil, ErrInvalidKeyValue
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func (k jwk) ecKey() (*ecdsa.PublicKey, error) {
+	if k.Crv != "P-256" {
+		return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, k.Crv)
+	}
+	x, err := decodeSegment(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeSegment(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	pub := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+	// conversion checks that point is on curve
+	if _, err := pub.ECDH(); err != nil {
+		return nil, ErrInvalidKeyValue
+	}
+	return pub, nil
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil || len(b) == 0 {
+		return nil, ErrInvalidKeyValue
+	}
+	return b, nil
+}
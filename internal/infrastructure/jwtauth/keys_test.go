@@ -0,0 +1,103 @@
+package jwtauth
+
+import (
+	"crypto/elliptic"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseHMACKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantId  string
+		wantErr error
+	}{
+		{name: "with kid", raw: "k1:" + hmacSecret, wantId: "k1"},
+		{name: "without kid", raw: hmacSecret},
+		{name: "short secret", raw: "k1:" + hmacSecret[1:], wantErr: ErrWeakHMACKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := parseHMACKey(tt.raw)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && (k.id != tt.wantId || k.alg != AlgHS256 || string(k.material.([]byte)) != hmacSecret) {
+				t.Fatalf("unexpected key %+v", k)
+			}
+		})
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey := generateRSAKey(t, 2048)
+	weakRSAKey := generateRSAKey(t, 1024)
+	ecKey := generateECKey(t, elliptic.P256())
+	p384Key := generateECKey(t, elliptic.P384())
+
+	offCurve := ecJWK("ec", &ecKey.PublicKey)
+	offCurve.X, offCurve.Y = offCurve.Y, offCurve.X
+	encryption := rsaJWK("enc", &rsaKey.PublicKey)
+	encryption.Use = "enc"
+	mismatchedAlg := rsaJWK("rsa", &rsaKey.PublicKey)
+	mismatchedAlg.Alg = AlgHS256
+
+	tests := []struct {
+		name     string
+		keys     []jwk
+		wantAlgs []string
+		wantErr  error
+	}{
+		{name: "RSA", keys: []jwk{rsaJWK("rsa", &rsaKey.PublicKey)}, wantAlgs: []string{AlgRS256}},
+		{name: "EC P-256", keys: []jwk{ecJWK("ec", &ecKey.PublicKey)}, wantAlgs: []string{AlgES256}},
+		{name: "oct", keys: []jwk{{Kty: "oct", Kid: "oct", K: segment([]byte(hmacSecret))}}, wantAlgs: []string{AlgHS256}},
+		{name: "encryption key is skipped", keys: []jwk{encryption, ecJWK("ec", &ecKey.PublicKey)}, wantAlgs: []string{AlgES256}},
+		{name: "RSA under 2048 bits", keys: []jwk{rsaJWK("rsa", &weakRSAKey.PublicKey)}, wantErr: ErrInvalidKeyValue},
+		{name: "EC P-384", keys: []jwk{ecJWK("ec", &p384Key.PublicKey)}, wantErr: ErrUnsupportedKey},
+		{name: "EC point not on curve", keys: []jwk{offCurve}, wantErr: ErrInvalidKeyValue},
+		{name: "short oct", keys: []jwk{{Kty: "oct", K: segment([]byte(hmacSecret[1:]))}}, wantErr: ErrWeakHMACKey},
+		{name: "alg of other type", keys: []jwk{mismatchedAlg}, wantErr: ErrUnsupportedKey},
+		{name: "unsupported type", keys: []jwk{{Kty: "OKP"}}, wantErr: ErrUnsupportedKey},
+		{name: "invalid encoding", keys: []jwk{{Kty: "RSA", N: "!", E: "AQAB"}}, wantErr: ErrInvalidKeyValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(map[string][]jwk{"keys": tt.keys})
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys, err := parseJWKS(data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want error %v, got %v", tt.wantErr, err)
+			}
+			if len(keys) != len(tt.wantAlgs) {
+				t.Fatalf("parsed %d keys, want %d", len(keys), len(tt.wantAlgs))
+			}
+			for i, k := range keys {
+				if k.alg != tt.wantAlgs[i] {
+					t.Errorf("key %d has alg %s, want %s", i, k.alg, tt.wantAlgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckKeyIds(t *testing.T) {
+	k1, err := parseHMACKey("k1:" + hmacSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noKid, err := parseHMACKey(hmacSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkKeyIds([]key{k1, noKid, noKid}); err != nil {
+		t.Fatalf("keys without kid are rejected: %v", err)
+	}
+	if err := checkKeyIds([]key{k1, noKid, k1}); !errors.Is(err, ErrDuplicateKeyId) {
+		t.Fatalf("want ErrDuplicateKeyId, got %v", err)
+	}
+}
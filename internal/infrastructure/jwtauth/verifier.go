@@ -0,0 +1,264 @@
+// Package jwtauth verifies bearer tokens (JWT) of end users and maps their claims to account scoped principals.
+package jwtauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/config"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+const opPrefix = "jwtauth."
+
+var (
+	errNoKey         = errors.New("no key verifies token")
+	errInvalidClaims = errors.New("invalid claims")
+)
+
+// defaultScopes are scopes of token without "scope" claim.
+var defaultScopes = []application.Scope{
+	application.ScopeRead,
+	application.ScopeDeposit,
+	application.ScopeWithdraw,
+}
+
+// Verifier checks signature by configured keys and validates exp, nbf, iss and aud claims.
+// Keys of JWKS file are replaced by Run when file is changed.
+type Verifier struct {
+	cfg      config.JWTConfig
+	hmacKeys []key
+	parser   *jwt.Parser
+	// now is current time of validation, it is replaced by tests
+	now func() time.Time
+
+	mu       sync.RWMutex
+	fileKeys []key
+	modTime  time.Time
+}
+
+func New(cfg config.JWTConfig) (*Verifier, error) {
+	const op = opPrefix + "New"
+
+	v := &Verifier{cfg: cfg, now: time.Now}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgES256}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithTimeFunc(func() time.Time { return v.now() }),
+		// account ids don't lose precision
+		jwt.WithJSONNumber(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+	for i, raw := range cfg.HMACKeys {
+		k, err := parseHMACKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s:hmac key %d: %w", op, i, err)
+		}
+		v.hmacKeys = append(v.hmacKeys, k)
+	}
+	if cfg.JWKSFile != "" {
+		if _, err := v.reload(); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+	}
+	if err := checkKeyIds(v.keys()); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return v, nil
+}
+
+// checkKeyIds rejects keys with the same kid, token with the kid would be verified by any of them.
+func checkKeyIds(keys []key) error {
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k.id == "" {
+			continue
+		}
+		if seen[k.id] {
+			return fmt.Errorf("%w: %q", ErrDuplicateKeyId, k.id)
+		}
+		seen[k.id] = true
+	}
+	return nil
+}
+
+func (v *Verifier) keys() []key {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return append(slices.Clone(v.hmacKeys), v.fileKeys...)
+}
+
+// reload reads JWKS file if it is changed since previous read.
+func (v *Verifier) reload() (changed bool, err error) {
+	info, err := os.Stat(v.cfg.JWKSFile)
+	if err != nil {
+		return false, err
+	}
+	v.mu.RLock()
+	modTime := v.modTime
+	v.mu.RUnlock()
+	if info.ModTime().Equal(modTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(v.cfg.JWKSFile)
+	if err != nil {
+		return false, err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return false, fmt.Errorf("jwks file: %w", err)
+	}
+	if err := checkKeyIds(append(slices.Clone(v.hmacKeys), keys...)); err != nil {
+		return false, err
+	}
+
+	v.mu.Lock()
+	v.fileKeys, v.modTime = keys, info.ModTime()
+	v.mu.Unlock()
+	return true, nil
+}
+
+// Run reloads JWKS file until ctx is done, invalid file doesn't replace loaded keys.
+func (v *Verifier) Run(ctx context.Context) {
+	const op = "JWKSReloader"
+	if v.cfg.JWKSFile == "" {
+		return
+	}
+	log := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(v.cfg.JWKSReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := v.reload()
+		if err != nil {
+			log.Error(op, "fail reload jwks file", err, logging.String("path", v.cfg.JWKSFile))
+			continue
+		}
+		if changed {
+			v.mu.RLock()
+			count := len(v.fileKeys)
+			v.mu.RUnlock()
+			log.Info(op, "jwks file reloaded", logging.Int64("keys", int64(count)))
+		}
+	}
+}
+
+// VerifyToken returns principal operating on accounts of AccountsClaim.
+// Reason of rejection is wrapped into application.ErrUnauthenticated.
+func (v *Verifier) VerifyToken(_ context.Context, token string) (application.Principal, error) {
+	const op = opPrefix + "VerifyToken"
+
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.keyfunc); err != nil {
+		return application.Principal{}, fmt.Errorf("%s:%w: %v", op, application.ErrUnauthenticated, err)
+	}
+	principal, err := v.principal(claims)
+	if err != nil {
+		return application.Principal{}, fmt.Errorf("%s:%w: %v", op, application.ErrUnauthenticated, err)
+	}
+	return principal, nil
+}
+
+// keyfunc returns keys of algorithm of token, token without kid is checked by all of them.
+// Keys of other algorithms are never used, so public key can't verify HS256 token as secret.
+func (v *Verifier) keyfunc(token *jwt.Token) (any, error) {
+	alg := token.Method.Alg()
+	kid, _ := token.Header["kid"].(string)
+
+	var set jwt.VerificationKeySet
+	for _, k := range v.keys() {
+		if k.alg != alg || (kid != "" && k.id != kid) {
+			continue
+		}
+		set.Keys = append(set.Keys, k.material)
+	}
+	if len(set.Keys) == 0 {
+		return nil, errNoKey
+	}
+	return set, nil
+}
+
+func (v *Verifier) principal(claims jwt.MapClaims) (application.Principal, error) {
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return application.Principal{}, fmt.Errorf("%w: sub is required", errInvalidClaims)
+	}
+	accountIds, err := accountIdsClaim(claims[v.cfg.AccountsClaim])
+	if err != nil {
+		return application.Principal{}, fmt.Errorf("%w: %s: %v", errInvalidClaims, v.cfg.AccountsClaim, err)
+	}
+
+	scopes := defaultScopes
+	if raw, ok := claims["scope"].(string); ok {
+		scopes = nil
+		for _, s := range strings.Fields(raw) {
+			// tokens of end users never have admin scope
+			if scope, err := application.ParseScope(s); err == nil && scope != application.ScopeAdmin {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	return application.Principal{
+		Name:          sub,
+		Scopes:        scopes,
+		Subject:       sub,
+		AccountScoped: true,
+		AccountIds:    accountIds,
+	}, nil
+}
+
+// accountIdsClaim parses id or array of ids, ids are numbers or numeric strings.
+// Missing claim means no accounts.
+func accountIdsClaim(raw any) ([]int64, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	values, ok := raw.([]any)
+	if !ok {
+		values = []any{raw}
+	}
+
+	ids := make([]int64, 0, len(values))
+	for _, value := range values {
+		var s string
+		switch v := value.(type) {
+		case json.Number:
+			s = v.String()
+		case string:
+			s = v
+		default:
+			return nil, fmt.Errorf("unexpected value %v", value)
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid account id %q", s)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,344 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/config"
+)
+
+const (
+	hmacSecret = "0123456789abcdef0123456789abcdef"
+	issuer     = "https://issuer.example.com"
+	audience   = "bank-service"
+	leeway     = 30 * time.Second
+)
+
+// testNow is current time of verifier of tests.
+var testNow = time.Unix(1700000000, 0)
+
+func generateRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func generateECKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func segment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, k *rsa.PublicKey) jwk {
+	return jwk{Kty: "RSA", Kid: kid, N: segment(k.N.Bytes()), E: segment(big.NewInt(int64(k.E)).Bytes())}
+}
+
+func ecJWK(kid string, k *ecdsa.PublicKey) jwk {
+	size := (k.Curve.Params().BitSize + 7) / 8
+	return jwk{Kty: "EC", Kid: kid, Crv: k.Curve.Params().Name, X: segment(k.X.FillBytes(make([]byte, size))), Y: segment(k.Y.FillBytes(make([]byte, size)))}
+}
+
+// writeJWKS replaces JWKS file, modification time is changed so reload reads it.
+func writeJWKS(t *testing.T, path string, modTime time.Time, keys ...jwk) {
+	t.Helper()
+	data, err := json.Marshal(map[string][]jwk{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newVerifier(t *testing.T, cfg config.JWTConfig) *Verifier {
+	t.Helper()
+	if cfg.AccountsClaim == "" {
+		cfg.AccountsClaim = "accounts"
+	}
+	v, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+// validClaims are accepted by verifier of testConfig.
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":      "alice",
+		"iss":      issuer,
+		"aud":      audience,
+		"iat":      testNow.Unix(),
+		"exp":      testNow.Add(time.Hour).Unix(),
+		"accounts": []int64{1, 2},
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims, k any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestVerifyToken(t *testing.T) {
+	rsaKey := generateRSAKey(t, 2048)
+	ecKey := generateECKey(t, elliptic.P256())
+	otherRSAKey := generateRSAKey(t, 2048)
+	rsaPublic, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksFile, testNow, rsaJWK("rsa", &rsaKey.PublicKey), ecJWK("ec", &ecKey.PublicKey))
+	v := newVerifier(t, config.JWTConfig{
+		HMACKeys: []string{"hmac:" + hmacSecret},
+		JWKSFile: jwksFile,
+		Issuer:   issuer,
+		Audience: audience,
+		Leeway:   leeway,
+	})
+
+	with := func(name string, value any) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	unsigned := func(claims jwt.MapClaims) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "HS256", token: sign(t, jwt.SigningMethodHS256, "hmac", validClaims(), []byte(hmacSecret)), valid: true},
+		{name: "RS256", token: sign(t, jwt.SigningMethodRS256, "rsa", validClaims(), rsaKey), valid: true},
+		{name: "ES256", token: sign(t, jwt.SigningMethodES256, "ec", validClaims(), ecKey), valid: true},
+		{name: "empty kid tries keys of alg", token: sign(t, jwt.SigningMethodRS256, "", validClaims(), rsaKey), valid: true},
+		{name: "empty kid without key of alg", token: sign(t, jwt.SigningMethodRS256, "", validClaims(), otherRSAKey)},
+		{name: "kid of other key", token: sign(t, jwt.SigningMethodRS256, "rsa", validClaims(), otherRSAKey)},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodRS256, "missing", validClaims(), rsaKey)},
+		{name: "kid of key of other alg", token: sign(t, jwt.SigningMethodRS256, "ec", validClaims(), rsaKey)},
+		{name: "HS256 signed by RSA public key", token: sign(t, jwt.SigningMethodHS256, "rsa", validClaims(), rsaPublic)},
+		{name: "HS256 without kid signed by RSA public key", token: sign(t, jwt.SigningMethodHS256, "", validClaims(), rsaPublic)},
+		{name: "alg none", token: unsigned(validClaims())},
+		{name: "not allowed alg", token: sign(t, jwt.SigningMethodHS512, "hmac", validClaims(), []byte(hmacSecret))},
+		{name: "expired", token: sign(t, jwt.SigningMethodHS256, "hmac", with("exp", testNow.Add(-time.Minute).Unix()), []byte(hmacSecret))},
+		{name: "expired within leeway", token: sign(t, jwt.SigningMethodHS256, "hmac", with("exp", testNow.Add(-leeway/2).Unix()), []byte(hmacSecret)), valid: true},
+		{name: "without exp", token: sign(t, jwt.SigningMethodHS256, "hmac", with("exp", nil), []byte(hmacSecret))},
+		{name: "not before", token: sign(t, jwt.SigningMethodHS256, "hmac", with("nbf", testNow.Add(time.Minute).Unix()), []byte(hmacSecret))},
+		{name: "not before within leeway", token: sign(t, jwt.SigningMethodHS256, "hmac", with("nbf", testNow.Add(leeway/2).Unix()), []byte(hmacSecret)), valid: true},
+		{name: "wrong issuer", token: sign(t, jwt.SigningMethodHS256, "hmac", with("iss", "https://other.example.com"), []byte(hmacSecret))},
+		{name: "without issuer", token: sign(t, jwt.SigningMethodHS256, "hmac", with("iss", nil), []byte(hmacSecret))},
+		{name: "wrong audience", token: sign(t, jwt.SigningMethodHS256, "hmac", with("aud", "other-service"), []byte(hmacSecret))},
+		{name: "audience in list", token: sign(t, jwt.SigningMethodHS256, "hmac", with("aud", []string{"other-service", audience}), []byte(hmacSecret)), valid: true},
+		{name: "without sub", token: sign(t, jwt.SigningMethodHS256, "hmac", with("sub", nil), []byte(hmacSecret))},
+		{name: "invalid account id", token: sign(t, jwt.SigningMethodHS256, "hmac", with("accounts", []any{1, "x"}), []byte(hmacSecret))},
+		{name: "malformed", token: "not.a.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := v.VerifyToken(context.Background(), tt.token)
+			if !tt.valid {
+				if !errors.Is(err, application.ErrUnauthenticated) {
+					t.Fatalf("want ErrUnauthenticated, got %v (principal %+v)", err, p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Subject != "alice" || !p.AccountScoped {
+				t.Fatalf("unexpected principal %+v", p)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenAccounts(t *testing.T) {
+	v := newVerifier(t, config.JWTConfig{HMACKeys: []string{hmacSecret}, AccountsClaim: "bank_accounts"})
+
+	tests := []struct {
+		name    string
+		claim   any
+		allowed []int64
+		denied  []int64
+	}{
+		{name: "array of numbers", claim: []any{1, 9007199254740993}, allowed: []int64{1, 9007199254740993}, denied: []int64{2, 9007199254740992}},
+		{name: "array of strings", claim: []any{"3", "4"}, allowed: []int64{3, 4}, denied: []int64{1}},
+		{name: "single id", claim: 5, allowed: []int64{5}, denied: []int64{1}},
+		{name: "missing claim", denied: []int64{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			delete(claims, "accounts")
+			if tt.claim != nil {
+				claims["bank_accounts"] = tt.claim
+			}
+			p, err := v.VerifyToken(context.Background(), sign(t, jwt.SigningMethodHS256, "", claims, []byte(hmacSecret)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := application.ContextWithPrincipal(context.Background(), p)
+			for _, id := range tt.allowed {
+				if err := application.AuthorizeAccount(ctx, id); err != nil {
+					t.Errorf("account %d: %v", id, err)
+				}
+			}
+			for _, id := range tt.denied {
+				if err := application.AuthorizeAccount(ctx, id); !errors.Is(err, application.ErrPermissionDenied) {
+					t.Errorf("account %d: want ErrPermissionDenied, got %v", id, err)
+				}
+			}
+			if err := application.AuthorizeAllAccounts(ctx); !errors.Is(err, application.ErrPermissionDenied) {
+				t.Errorf("token may operate on all accounts: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenScopes(t *testing.T) {
+	v := newVerifier(t, config.JWTConfig{HMACKeys: []string{hmacSecret}})
+
+	tests := []struct {
+		name  string
+		scope any
+		want  []application.Scope
+	}{
+		{name: "default", want: defaultScopes},
+		{name: "admin is removed", scope: "read admin withdraw", want: []application.Scope{application.ScopeRead, application.ScopeWithdraw}},
+		{name: "only admin", scope: "admin", want: nil},
+		{name: "unknown scope is ignored", scope: "read transfer:all", want: []application.Scope{application.ScopeRead}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			if tt.scope != nil {
+				claims["scope"] = tt.scope
+			}
+			p, err := v.VerifyToken(context.Background(), sign(t, jwt.SigningMethodHS256, "", claims, []byte(hmacSecret)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(p.Scopes, tt.want) {
+				t.Fatalf("scopes %v, want %v", p.Scopes, tt.want)
+			}
+			if p.Allows(application.ScopeAdmin) {
+				t.Fatal("token has admin scope")
+			}
+		})
+	}
+}
+
+func TestVerifierReload(t *testing.T) {
+	oldKey, newKey := generateRSAKey(t, 2048), generateRSAKey(t, 2048)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksFile, testNow, rsaJWK("old", &oldKey.PublicKey))
+	v := newVerifier(t, config.JWTConfig{JWKSFile: jwksFile})
+
+	verify := func(kid string, k *rsa.PrivateKey) error {
+		_, err := v.VerifyToken(context.Background(), sign(t, jwt.SigningMethodRS256, kid, validClaims(), k))
+		return err
+	}
+	reload := func(wantChanged bool) {
+		t.Helper()
+		changed, err := v.reload()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != wantChanged {
+			t.Fatalf("reload changed keys: %v, want %v", changed, wantChanged)
+		}
+	}
+
+	if err := verify("old", oldKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify("new", newKey); err == nil {
+		t.Fatal("token of key missing in file is accepted")
+	}
+
+	// new key is published before it signs tokens
+	writeJWKS(t, jwksFile, testNow.Add(time.Minute), rsaJWK("old", &oldKey.PublicKey), rsaJWK("new", &newKey.PublicKey))
+	reload(true)
+	reload(false)
+	if err := verify("old", oldKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify("new", newKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// old key is retired
+	writeJWKS(t, jwksFile, testNow.Add(2*time.Minute), rsaJWK("new", &newKey.PublicKey))
+	reload(true)
+	if err := verify("old", oldKey); err == nil {
+		t.Fatal("token of retired key is accepted")
+	}
+	if err := verify("new", newKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// invalid file doesn't replace loaded keys
+	if err := os.WriteFile(jwksFile, []byte(`{"keys": [`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(jwksFile, testNow.Add(3*time.Minute), testNow.Add(3*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.reload(); err == nil || !strings.Contains(err.Error(), "jwks file") {
+		t.Fatalf("want error of jwks file, got %v", err)
+	}
+	if err := verify("new", newKey); err != nil {
+		t.Fatal(err)
+	}
+}
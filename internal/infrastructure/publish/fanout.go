@@ -0,0 +1,28 @@
+package publish
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+// Fanout publishes message to every publisher. Message is published again
+// to all of them if one fails, so publishers must tolerate duplicates.
+type Fanout struct {
+	publishers []application.Publisher
+}
+
+func NewFanout(publishers ...application.Publisher) *Fanout {
+	return &Fanout{publishers: publishers}
+}
+
+func (f *Fanout) Publish(ctx context.Context, message application.OutboxMessage) error {
+	var errs []error
+	for _, p := range f.publishers {
+		if err := p.Publish(ctx, message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
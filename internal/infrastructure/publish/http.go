@@ -0,0 +1,55 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+var ErrUnexpectedStatus = errors.New("unexpected response status")
+
+// HTTPPublisher posts every message to URL, any 2xx response means delivery.
+type HTTPPublisher struct {
+	client *http.Client
+	url    string
+}
+
+func NewHTTPPublisher(url string, timeout time.Duration) *HTTPPublisher {
+	return &HTTPPublisher{client: &http.Client{Timeout: timeout}, url: url}
+}
+
+func (p *HTTPPublisher) Publish(ctx context.Context, message application.OutboxMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", strconv.FormatInt(message.Id, 10))
+	req.Header.Set("X-Event-Type", string(message.Type))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// drained body lets connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+	return nil
+}
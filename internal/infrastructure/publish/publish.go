@@ -0,0 +1,2 @@
+// Package publish delivers outbox messages to other services.
+package publish
@@ -0,0 +1,57 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+// WriterPublisher writes messages as JSON lines.
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+// OpenFilePublisher appends messages to file, they are synced before Publish returns.
+func OpenFilePublisher(path string) (*WriterPublisher, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterPublisher(f), nil
+}
+
+func (p *WriterPublisher) Publish(ctx context.Context, message application.OutboxMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if f, ok := p.w.(*os.File); ok && f != os.Stdout && f != os.Stderr {
+		return f.Sync()
+	}
+	return nil
+}
+
+// Close closes underlying writer if it is closer.
+func (p *WriterPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.w.(io.Closer); ok && p.w != os.Stdout && p.w != os.Stderr {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+const apiKeyColumns = `id, name, scopes, secret_hash, previous_secret_hash, previous_expires_at,
+	created_at, rotated_at, revoked_at`
+
+func (r *Repository) SaveAPIKey(ctx context.Context, key application.APIKey) error {
+	const op = opPrefix + "SaveAPIKey"
+
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+	_, err := r.conn.Exec(ctx,
+		`INSERT INTO api_keys(`+apiKeyColumns+`)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET name=excluded.name, scopes=excluded.scopes,
+			secret_hash=excluded.secret_hash, previous_secret_hash=excluded.previous_secret_hash,
+			previous_expires_at=excluded.previous_expires_at, rotated_at=excluded.rotated_at,
+			revoked_at=excluded.revoked_at`,
+		key.Id,
+		key.Name,
+		scopes,
+		key.SecretHash,
+		key.PreviousSecretHash,
+		nullTime(key.PreviousExpiresAt),
+		key.CreatedAt,
+		nullTime(key.RotatedAt),
+		nullTime(key.RevokedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+func (r *Repository) GetAPIKey(ctx context.Context, id string) (application.APIKey, error) {
+	const op = opPrefix + "GetAPIKey"
+
+	row := r.conn.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id=$1`, id)
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return key, fmt.Errorf("%s:%w", op, application.ErrAPIKeyNotFound)
+		}
+		return key, fmt.Errorf("%s:%w", op, err)
+	}
+	return key, nil
+}
+
+func (r *Repository) ListAPIKeys(ctx context.Context) ([]application.APIKey, error) {
+	const op = opPrefix + "ListAPIKeys"
+
+	rows, err := r.conn.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []application.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return keys, nil
+}
+
+func scanAPIKey(row rowScanner) (application.APIKey, error) {
+	var (
+		key               application.APIKey
+		scopes            []string
+		previousHash      sql.NullString
+		previousExpiresAt sql.NullTime
+		rotatedAt         sql.NullTime
+		revokedAt         sql.NullTime
+	)
+	err := row.Scan(
+		&key.Id,
+		&key.Name,
+		&scopes,
+		&key.SecretHash,
+		&previousHash,
+		&previousExpiresAt,
+		&key.CreatedAt,
+		&rotatedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return application.APIKey{}, err
+	}
+	for _, scope := range scopes {
+		key.Scopes = append(key.Scopes, application.Scope(scope))
+	}
+	key.PreviousSecretHash = previousHash.String
+	key.PreviousExpiresAt = previousExpiresAt.Time
+	key.RotatedAt = rotatedAt.Time
+	key.RevokedAt = revokedAt.Time
+	return key, nil
+}
+
+// nullTime returns nil for zero time.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,151 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+// Strategy defines how Acquire isolates concurrent changes of one account.
+type Strategy string
+
+const (
+	// StrategyPessimistic locks account row with SELECT ... FOR UPDATE.
+	StrategyPessimistic Strategy = "pessimistic"
+	// StrategySerializable runs Acquire in SERIALIZABLE transaction
+	// and retries it on serialization failures. Isolation level can't be changed
+	// inside outer transaction (WithTx), so nested Acquire locks row pessimistically.
+	StrategySerializable Strategy = "serializable"
+	// StrategyOptimistic reads account without lock and saves it only
+	// if version of account is unchanged, otherwise Acquire is retried.
+	StrategyOptimistic Strategy = "optimistic"
+)
+
+var (
+	ErrUnknownStrategy = errors.New("unknown concurrency strategy")
+	// ErrSerializableUnsupported returned if connection can't begin transaction with isolation level.
+	ErrSerializableUnsupported = errors.New("connection doesn't support serializable transactions")
+)
+
+func ParseStrategy(s string) (Strategy, error) {
+	switch st := Strategy(s); st {
+	case StrategyPessimistic, StrategySerializable, StrategyOptimistic:
+		return st, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownStrategy, s)
+	}
+}
+
+// Concurrency configures Repository as application.Acquirer.
+type Concurrency struct {
+	Strategy Strategy
+	// NoWait makes row locks fail immediately if account is locked.
+	NoWait bool
+	// LockTimeout limits waiting for row lock, zero means no limit.
+	LockTimeout time.Duration
+	// MaxRetries bounds retries of serialization failures and version conflicts.
+	MaxRetries int
+}
+
+var DefaultConcurrency = Concurrency{
+	Strategy:   StrategyPessimistic,
+	MaxRetries: 5,
+}
+
+// retryDelay is base of linear backoff between retries.
+const retryDelay = 10 * time.Millisecond
+
+var errVersionConflict = errors.New("account version changed concurrently")
+
+// rowLock is locking clause of account select.
+func (c Concurrency) rowLock() string {
+	if c.NoWait {
+		return " FOR UPDATE NOWAIT"
+	}
+	return " FOR UPDATE"
+}
+
+// setLockTimeout applies LockTimeout to current transaction.
+func (r *Repository) setLockTimeout(ctx context.Context) error {
+	if r.concurrency.LockTimeout <= 0 {
+		return nil
+	}
+	_, err := r.conn.Exec(ctx,
+		fmt.Sprintf("SET LOCAL lock_timeout = %d", r.concurrency.LockTimeout.Milliseconds()),
+	)
+	return err
+}
+
+type txOptionsBeginner interface {
+	BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error
+}
+
+// beginSerializable begins SERIALIZABLE transaction. Savepoint of outer transaction
+// keeps isolation level of outer one, so it isn't used as fallback.
+func (r *Repository) beginSerializable(ctx context.Context, f func(pgx.Tx) error) error {
+	beginner, ok := r.conn.(txOptionsBeginner)
+	if !ok {
+		return ErrSerializableUnsupported
+	}
+	return beginner.BeginTxFunc(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable}, f)
+}
+
+// strategy returns Strategy of Acquire. Repository in outer transaction can't
+// change isolation level, so serializable strategy is replaced by pessimistic.
+func (r *Repository) strategy() Strategy {
+	if r.nested() && r.concurrency.Strategy == StrategySerializable {
+		return StrategyPessimistic
+	}
+	return r.concurrency.Strategy
+}
+
+// nested reports whether repository is bound to outer transaction.
+func (r *Repository) nested() bool {
+	_, ok := r.conn.(pgx.Tx)
+	return ok
+}
+
+// retryable reports whether Acquire can be repeated after err.
+// Transaction of repository can't be repeated inside outer one.
+func (r *Repository) retryable(err error) bool {
+	if r.nested() {
+		return false
+	}
+	if errors.Is(err, errVersionConflict) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgerrcode.SerializationFailure || pgErr.Code == pgerrcode.DeadlockDetected
+	}
+	return false
+}
+
+// mapLockError replaces errors of waiting for locks with application.ErrAccountBusy.
+func mapLockError(op string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.LockNotAvailable {
+		return fmt.Errorf("%s:%w", op, application.ErrAccountBusy)
+	}
+	return err
+}
+
+// backoff sleeps before next attempt, jitter spreads concurrent retries.
+func backoff(ctx context.Context, attempt int) error {
+	delay := time.Duration(attempt+1)*retryDelay + time.Duration(rand.Int63n(int64(retryDelay)))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,240 @@
+package accounts_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/pg"
+)
+
+// testDB connects to migrated database of TEST_DATABASE_URL, test is skipped without it.
+func testDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL isn't set")
+	}
+	db, err := pg.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+func newAccount(t *testing.T, repo *accounts.Repository) int64 {
+	t.Helper()
+	id, err := repo.NewAccount(context.Background(), account.DefaultCurrency)
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	return id
+}
+
+// lockRow holds row lock of account until test ends.
+func lockRow(t *testing.T, db *pgxpool.Pool, id int64) {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = tx.Rollback(ctx) })
+	if _, err := tx.Exec(ctx, `SELECT id FROM accounts WHERE id=$1 FOR UPDATE`, id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func deposit(amount string) application.AccountProcessFunc {
+	return func(a application.BankAccount) error {
+		return a.Deposit(account.MustParseMoney(amount))
+	}
+}
+
+func balance(t *testing.T, repo *accounts.Repository, id int64) string {
+	t.Helper()
+	a, err := repo.GetAccountById(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.GetBalance().String()
+}
+
+func TestPessimisticNoWait(t *testing.T) {
+	db := testDB(t)
+	repo := accounts.NewRepository(db, accounts.Concurrency{
+		Strategy: accounts.StrategyPessimistic,
+		NoWait:   true,
+	})
+	id := newAccount(t, repo)
+	other := newAccount(t, repo)
+	lockRow(t, db, id)
+
+	start := time.Now()
+	err := repo.Acquire(context.Background(), id, deposit("10"))
+	if !errors.Is(err, application.ErrAccountBusy) {
+		t.Fatalf("acquire of locked account: want ErrAccountBusy, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("NOWAIT acquire waited %s", elapsed)
+	}
+
+	err = repo.AcquirePair(context.Background(), other, id, func(first, second application.BankAccount) error {
+		return nil
+	})
+	if !errors.Is(err, application.ErrAccountBusy) {
+		t.Fatalf("acquire of pair with locked account: want ErrAccountBusy, got %v", err)
+	}
+}
+
+func TestPessimisticLockTimeout(t *testing.T) {
+	const lockTimeout = 100 * time.Millisecond
+	db := testDB(t)
+	repo := accounts.NewRepository(db, accounts.Concurrency{
+		Strategy:    accounts.StrategyPessimistic,
+		LockTimeout: lockTimeout,
+	})
+	id := newAccount(t, repo)
+	lockRow(t, db, id)
+
+	start := time.Now()
+	err := repo.Acquire(context.Background(), id, deposit("10"))
+	elapsed := time.Since(start)
+	if !errors.Is(err, application.ErrAccountBusy) {
+		t.Fatalf("acquire of locked account: want ErrAccountBusy, got %v", err)
+	}
+	if elapsed < lockTimeout || elapsed > 10*lockTimeout {
+		t.Errorf("acquire waited %s, want about lock timeout %s", elapsed, lockTimeout)
+	}
+}
+
+func TestSerializableRetry(t *testing.T) {
+	db := testDB(t)
+	repo := accounts.NewRepository(db, accounts.Concurrency{
+		Strategy:   accounts.StrategySerializable,
+		MaxRetries: 3,
+	})
+	id := newAccount(t, repo)
+
+	attempts := 0
+	err := repo.Acquire(context.Background(), id, func(a application.BankAccount) error {
+		attempts++
+		if attempts == 1 {
+			// concurrent update of read row fails the first transaction with serialization failure
+			if _, err := db.Exec(context.Background(), `UPDATE accounts SET balance=balance WHERE id=$1`, id); err != nil {
+				return err
+			}
+		}
+		return a.Deposit(account.MustParseMoney("10"))
+	})
+	if err != nil {
+		t.Fatalf("acquire isn't retried after serialization failure: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("want 2 attempts, got %d", attempts)
+	}
+	if got := balance(t, repo, id); got != "10.00" {
+		t.Errorf("want balance 10.00 after single deposit, got %s", got)
+	}
+}
+
+func TestOptimisticRetry(t *testing.T) {
+	db := testDB(t)
+	repo := accounts.NewRepository(db, accounts.Concurrency{
+		Strategy:   accounts.StrategyOptimistic,
+		MaxRetries: 3,
+	})
+	id := newAccount(t, repo)
+
+	attempts := 0
+	err := repo.Acquire(context.Background(), id, func(a application.BankAccount) error {
+		attempts++
+		if attempts == 1 {
+			bumpVersion(t, db, id)
+		}
+		return a.Deposit(account.MustParseMoney("10"))
+	})
+	if err != nil {
+		t.Fatalf("acquire isn't retried after version conflict: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("want 2 attempts, got %d", attempts)
+	}
+	if got := balance(t, repo, id); got != "10.00" {
+		t.Errorf("want balance 10.00 after single deposit, got %s", got)
+	}
+}
+
+func TestOptimisticRetryExhaustion(t *testing.T) {
+	const maxRetries = 2
+	db := testDB(t)
+	repo := accounts.NewRepository(db, accounts.Concurrency{
+		Strategy:   accounts.StrategyOptimistic,
+		MaxRetries: maxRetries,
+	})
+	id := newAccount(t, repo)
+
+	attempts := 0
+	err := repo.Acquire(context.Background(), id, func(a application.BankAccount) error {
+		attempts++
+		// every attempt conflicts with concurrent change
+		bumpVersion(t, db, id)
+		return a.Deposit(account.MustParseMoney("10"))
+	})
+	if !errors.Is(err, application.ErrAccountBusy) {
+		t.Fatalf("want ErrAccountBusy after retries, got %v", err)
+	}
+	if attempts != maxRetries+1 {
+		t.Errorf("want %d attempts, got %d", maxRetries+1, attempts)
+	}
+	if got := balance(t, repo, id); got != "0.00" {
+		t.Errorf("conflicting attempts changed balance to %s", got)
+	}
+}
+
+// bumpVersion changes version of account outside of transaction of repository.
+func bumpVersion(t *testing.T, db *pgxpool.Pool, id int64) {
+	t.Helper()
+	err := db.BeginFunc(context.Background(), func(tx pgx.Tx) error {
+		_, err := tx.Exec(context.Background(), `UPDATE accounts SET version=version+1 WHERE id=$1`, id)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSerializableNestedLocksRow(t *testing.T) {
+	db := testDB(t)
+	repo := accounts.NewRepository(db, accounts.Concurrency{
+		Strategy:   accounts.StrategySerializable,
+		MaxRetries: 3,
+	})
+	id := newAccount(t, repo)
+	ctx := context.Background()
+
+	err := db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		return repo.WithTx(tx).Acquire(ctx, id, func(a application.BankAccount) error {
+			// serializable isolation can't be set in outer transaction, so row must be locked
+			_, err := db.Exec(ctx, `SELECT id FROM accounts WHERE id=$1 FOR UPDATE NOWAIT`, id)
+			if err == nil {
+				t.Error("nested serializable acquire doesn't lock row")
+			}
+			return a.Deposit(account.MustParseMoney("10"))
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := balance(t, repo, id); got != "10.00" {
+		t.Errorf("want balance 10.00, got %s", got)
+	}
+}
@@ -0,0 +1,60 @@
+package accounts_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+)
+
+// TestBalanceCreditLimitCheck checks that database rejects balance below credit limit
+// even if domain checks are bypassed.
+func TestBalanceCreditLimitCheck(t *testing.T) {
+	db := testDB(t)
+	repo := accounts.NewRepository(db, accounts.DefaultConcurrency)
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		balance     string
+		creditLimit string
+		wantErr     bool
+	}{
+		{name: "positive balance", balance: "10", creditLimit: "0"},
+		{name: "whole credit used", balance: "-50", creditLimit: "50"},
+		{name: "below credit limit", balance: "-50.01", creditLimit: "50", wantErr: true},
+		{name: "negative without credit", balance: "-0.01", creditLimit: "0", wantErr: true},
+		{name: "negative credit limit", balance: "0", creditLimit: "-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := newAccount(t, repo)
+			_, err := db.Exec(ctx, `UPDATE accounts SET balance=$2, credit_limit=$3 WHERE id=$1`,
+				id, tt.balance, tt.creditLimit)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) || pgErr.Code != pgerrcode.CheckViolation {
+				t.Fatalf("want check violation, got %v", err)
+			}
+		})
+	}
+
+	// repository save is checked too
+	id := newAccount(t, repo)
+	acc := account.NewAccount(id, account.DefaultCurrency, account.MustParseMoney("-20"), account.MustParseMoney("10"), "")
+	if err := repo.SaveAccounts(ctx, acc); err == nil {
+		t.Fatal("account with balance below credit limit is saved")
+	}
+	if got := balance(t, repo, id); got != "0.00" {
+		t.Fatalf("rejected save changed balance to %s", got)
+	}
+}
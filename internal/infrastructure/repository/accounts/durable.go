@@ -0,0 +1,223 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/wal"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+const (
+	walFile      = "wal.log"
+	snapshotFile = "snapshot.json"
+)
+
+type durability struct {
+	dir string
+	log *wal.Log
+	// snapshotMu serializes snapshots, they run under read lock of storage
+	snapshotMu sync.Mutex
+}
+
+type memorySnapshot struct {
+	// Seq is the last write-ahead log record included in snapshot.
+	Seq   uint64       `json:"seq"`
+	State memoryChange `json:"state"`
+}
+
+// OpenDurableInMemory restores storage from snapshot and write-ahead log in dir.
+// Every change is appended to the log before it is applied. Reads wait until
+// applied changes are durable by sync policy of opts:
+//   - wal.SyncAlways: change is fsynced before it is applied, reads never wait.
+//   - wal.SyncBatch: change is applied before fsync, reads wait for fsync of it,
+//     so neither writer nor reader observes change which may be lost on crash.
+//   - wal.SyncInterval: reads don't wait, changes of last interval may be seen
+//     by readers and writers and still be lost on crash.
+//
+// Failed write or fsync of the log is fatal: storage rejects all following
+// reads and writes, state must be restored by reopening storage.
+func OpenDurableInMemory(dir string, opts wal.Options) (*AccountStorage, error) {
+	const op = "accounts.OpenDurableInMemory"
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	a := NewInMemory()
+	snapshot, err := readSnapshot(filepath.Join(dir, snapshotFile))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	a.apply(snapshot.State)
+
+	log, err := wal.Open(filepath.Join(dir, walFile), opts, snapshot.Seq, func(data []byte) error {
+		var change memoryChange
+		if err := json.Unmarshal(data, &change); err != nil {
+			return err
+		}
+		a.apply(change)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	a.durability = &durability{dir: dir, log: log}
+	return a, nil
+}
+
+func readSnapshot(path string) (memorySnapshot, error) {
+	var snapshot memorySnapshot
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return snapshot, err
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("decode snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// commit must be called under write lock. Change is logged before it is applied,
+// returned sequence number is passed to waitDurable after lock is released.
+// Applied change isn't read until it is durable, see rlock.
+func (a *AccountStorage) commit(change memoryChange) (uint64, error) {
+	if a.durability == nil {
+		a.apply(change)
+		return 0, nil
+	}
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return 0, err
+	}
+	seq, err := a.durability.log.Append(data)
+	if err != nil {
+		return 0, err
+	}
+	a.apply(change)
+	return seq, nil
+}
+
+func (a *AccountStorage) waitDurable(seq uint64) error {
+	if a.durability == nil || seq == 0 {
+		return nil
+	}
+	return a.durability.log.WaitDurable(seq)
+}
+
+// rlock takes read lock once all applied changes are durable. Writers apply
+// changes under write lock, so nothing else becomes visible while lock is held.
+// Lock isn't held if error is returned.
+func (a *AccountStorage) rlock() error {
+	a.rw.RLock()
+	if a.durability == nil {
+		return nil
+	}
+	if err := a.durability.log.WaitDurable(a.durability.log.LastSeq()); err != nil {
+		a.rw.RUnlock()
+		return err
+	}
+	return nil
+}
+
+// Snapshot writes whole state to disk and truncates write-ahead log.
+// It is no-op for storage without durability.
+func (a *AccountStorage) Snapshot() error {
+	const op = "accounts.AccountStorage.Snapshot"
+	d := a.durability
+	if d == nil {
+		return nil
+	}
+	d.snapshotMu.Lock()
+	defer d.snapshotMu.Unlock()
+
+	// read lock keeps writers out, so log has no records after snapshot,
+	// and snapshot doesn't include changes which failed to be durable
+	if err := a.rlock(); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	defer a.rw.RUnlock()
+
+	data, err := json.Marshal(memorySnapshot{
+		Seq:   d.log.LastSeq(),
+		State: a.state(),
+	})
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := writeFileAtomic(filepath.Join(d.dir, snapshotFile), data); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := d.log.Reset(); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+// RunSnapshots takes snapshot every interval until ctx is done.
+func (a *AccountStorage) RunSnapshots(ctx context.Context, interval time.Duration) {
+	const op = "AccountStorage.RunSnapshots"
+	log := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := a.Snapshot(); err != nil {
+			log.Error(op, "fail take snapshot", err)
+		}
+	}
+}
+
+// Close takes final snapshot and closes write-ahead log.
+func (a *AccountStorage) Close() error {
+	if a.durability == nil {
+		return nil
+	}
+	return errors.Join(a.Snapshot(), a.durability.log.Close())
+}
+
+// writeFileAtomic replaces file, so crash leaves either old or new content.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,243 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/wal"
+)
+
+func openDurable(t *testing.T, dir string, opts wal.Options) *AccountStorage {
+	t.Helper()
+	a, err := OpenDurableInMemory(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// crashed storage isn't closed by test, log file is closed without snapshot
+	t.Cleanup(func() { a.durability.log.Close() })
+	return a
+}
+
+func depositTo(t *testing.T, a *AccountStorage, id int64, amount string) {
+	t.Helper()
+	ctx := context.Background()
+	acc, err := a.GetAccountById(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := acc.Deposit(account.MustParseMoney(amount)); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SaveAccounts(ctx, acc); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkAccount checks balance and count of journal entries of account.
+func checkAccount(t *testing.T, a *AccountStorage, id int64, balance string, entries int) {
+	t.Helper()
+	ctx := context.Background()
+	acc, err := a.GetAccountById(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := acc.GetBalance().String(); got != balance {
+		t.Errorf("account %d: balance %s, want %s", id, got, balance)
+	}
+	journal, err := a.ListJournalEntries(ctx, application.JournalFilter{AccountId: id, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journal) != entries {
+		t.Errorf("account %d: %d journal entries, want %d", id, len(journal), entries)
+	}
+}
+
+// writeSnapshot writes snapshot like Snapshot, but doesn't reset log,
+// as if process crashed between them.
+func writeSnapshot(t *testing.T, a *AccountStorage) {
+	t.Helper()
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	data, err := json.Marshal(memorySnapshot{
+		Seq:   a.durability.log.LastSeq(),
+		State: a.state(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(filepath.Join(a.durability.dir, snapshotFile), data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDurableRecovery(t *testing.T) {
+	opts := wal.Options{Sync: wal.SyncAlways}
+	tests := []struct {
+		name string
+		// crash runs before reopening storage of dir
+		crash       func(t *testing.T, a *AccountStorage, dir string)
+		wantBalance string
+		wantEntries int
+	}{
+		{
+			name:        "log without snapshot",
+			crash:       func(*testing.T, *AccountStorage, string) {},
+			wantBalance: "30.00", wantEntries: 2,
+		},
+		{
+			name: "torn tail record",
+			crash: func(t *testing.T, a *AccountStorage, dir string) {
+				path := filepath.Join(dir, walFile)
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Truncate(path, info.Size()-1); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantBalance: "10.00", wantEntries: 1,
+		},
+		{
+			name: "snapshot and log",
+			crash: func(t *testing.T, a *AccountStorage, dir string) {
+				if err := a.Snapshot(); err != nil {
+					t.Fatal(err)
+				}
+				depositTo(t, a, 1, "5")
+			},
+			wantBalance: "35.00", wantEntries: 3,
+		},
+		{
+			name: "crash between snapshot and reset",
+			crash: func(t *testing.T, a *AccountStorage, dir string) {
+				// records of snapshot stay in log and must not be applied twice
+				writeSnapshot(t, a)
+			},
+			wantBalance: "30.00", wantEntries: 2,
+		},
+		{
+			name: "crash between snapshot and reset with later records",
+			crash: func(t *testing.T, a *AccountStorage, dir string) {
+				writeSnapshot(t, a)
+				depositTo(t, a, 1, "5")
+			},
+			wantBalance: "35.00", wantEntries: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			a := openDurable(t, dir, opts)
+			id, err := a.NewAccount(context.Background(), account.DefaultCurrency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			depositTo(t, a, id, "10")
+			depositTo(t, a, id, "20")
+			tt.crash(t, a, dir)
+
+			restored := openDurable(t, dir, opts)
+			checkAccount(t, restored, id, tt.wantBalance, tt.wantEntries)
+
+			// restored storage continues ids and log
+			depositTo(t, restored, id, "1")
+			next, err := restored.NewAccount(context.Background(), account.DefaultCurrency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next != id+1 {
+				t.Fatalf("next account id %d, want %d", next, id+1)
+			}
+		})
+	}
+}
+
+func TestDurableGroupCommit(t *testing.T) {
+	const accounts = 10
+	for _, opts := range []wal.Options{
+		{Sync: wal.SyncBatch},
+		{Sync: wal.SyncInterval, Interval: time.Millisecond},
+	} {
+		t.Run(string(opts.Sync), func(t *testing.T) {
+			dir := t.TempDir()
+			a := openDurable(t, dir, opts)
+			ctx := context.Background()
+			ids := make([]int64, accounts)
+			for i := range ids {
+				id, err := a.NewAccount(ctx, account.DefaultCurrency)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids[i] = id
+			}
+
+			var wg sync.WaitGroup
+			for _, id := range ids {
+				wg.Add(1)
+				go func(id int64) {
+					defer wg.Done()
+					for i := 0; i < 5; i++ {
+						acc, err := a.GetAccountById(ctx, id)
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						if err := acc.Deposit(account.MustParseMoney("1")); err != nil {
+							t.Error(err)
+							return
+						}
+						if err := a.SaveAccounts(ctx, acc); err != nil {
+							t.Error(err)
+							return
+						}
+					}
+				}(id)
+			}
+			wg.Wait()
+
+			// closed log is fsynced with every policy
+			if err := a.durability.log.Close(); err != nil {
+				t.Fatal(err)
+			}
+			restored := openDurable(t, dir, opts)
+			for _, id := range ids {
+				checkAccount(t, restored, id, "5.00", 5)
+			}
+		})
+	}
+}
+
+func TestDurableFailureIsFatal(t *testing.T) {
+	a := openDurable(t, t.TempDir(), wal.Options{Sync: wal.SyncBatch})
+	ctx := context.Background()
+	id, err := a.NewAccount(ctx, account.DefaultCurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// closed log fails like broken one
+	if err := a.durability.log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.GetAccountById(ctx, id); err == nil {
+		t.Fatal("storage with broken log serves reads")
+	}
+	if _, err := a.NewAccount(ctx, account.DefaultCurrency); err == nil {
+		t.Fatal("storage with broken log accepts writes")
+	}
+	if err := a.Snapshot(); err == nil {
+		t.Fatal("storage with broken log takes snapshot")
+	}
+	if _, err := a.ListAccounts(ctx, application.AccountFilter{Limit: 10}); err == nil {
+		t.Fatal("storage with broken log lists accounts")
+	}
+}
@@ -0,0 +1,388 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
+)
+
+// EventStore persists accounts as streams of domain events with optimistic
+// concurrency on stream version. Tables of Repository are updated in the same
+// transaction as read model, so journal, holds and balance queries don't replay streams.
+type EventStore struct {
+	conn       Connection
+	projection *Repository
+	// snapshotEvery is number of events between snapshots of account, zero disables them.
+	snapshotEvery int64
+	maxRetries    int
+}
+
+func NewEventStore(conn Connection, snapshotEvery int64, maxRetries int) *EventStore {
+	return &EventStore{
+		conn:          conn,
+		projection:    NewRepository(conn, DefaultConcurrency),
+		snapshotEvery: snapshotEvery,
+		maxRetries:    maxRetries,
+	}
+}
+
+const eventStoreOpPrefix = "repo.EventStore."
+
+// accountSnapshot is state of account at version of snapshot.
+type accountSnapshot struct {
+	Currency    account.Currency `json:"currency"`
+	Balance     account.Money    `json:"balance"`
+	CreditLimit account.Money    `json:"credit_limit"`
+	Status      account.Status   `json:"status"`
+	Holds       []holdState      `json:"holds"`
+}
+
+func (es *EventStore) NewAccount(ctx context.Context, currency account.Currency) (int64, error) {
+	const op = eventStoreOpPrefix + "NewAccount"
+
+	var accountId int64
+	err := es.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		// projection row allocates id of stream
+		row := tx.QueryRow(ctx,
+			`INSERT INTO accounts(balance, currency) VALUES(0, $1) RETURNING id`,
+			string(currency),
+		)
+		if err := row.Scan(&accountId); err != nil {
+			return err
+		}
+		wrapped := es.with(tx)
+		opened := account.OpenAccount(accountId, currency)
+		if err := wrapped.appendEvents(ctx, opened); err != nil {
+			return err
+		}
+		if err := wrapped.projection.saveOutbox(ctx, opened); err != nil {
+			return err
+		}
+		return wrapped.projection.saveIdempotencyRecord(ctx, application.OperationResult{AccountId: accountId})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+	return accountId, nil
+}
+
+// GetAccountById replays events of account after its last snapshot.
+func (es *EventStore) GetAccountById(ctx context.Context, id int64) (account.Account, error) {
+	const op = eventStoreOpPrefix + "GetAccountById"
+
+	acc, err := es.loadAccount(ctx, id)
+	if err != nil {
+		return account.Account{}, fmt.Errorf("%s:%w", op, err)
+	}
+	return acc, nil
+}
+
+// ReadAccount returns account from read model, it may lag behind
+// only for account saved concurrently.
+func (es *EventStore) ReadAccount(ctx context.Context, id int64) (account.Account, error) {
+	return es.projection.GetAccountById(ctx, id)
+}
+
+func (es *EventStore) loadAccount(ctx context.Context, id int64) (account.Account, error) {
+	acc, found, err := es.loadSnapshot(ctx, id)
+	if err != nil {
+		return account.Account{}, err
+	}
+
+	rows, err := es.conn.Query(ctx,
+		`SELECT version, data FROM account_events WHERE account_id=$1 AND version>$2 ORDER BY version`,
+		id,
+		acc.Version(),
+	)
+	if err != nil {
+		return account.Account{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			version int64
+			data    []byte
+			event   account.Event
+		)
+		if err := rows.Scan(&version, &data); err != nil {
+			return account.Account{}, err
+		}
+		if version != acc.Version()+1 {
+			return account.Account{}, fmt.Errorf("event stream of account %d has gap before version %d", id, version)
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return account.Account{}, fmt.Errorf("decode event %d of account %d: %w", version, id, err)
+		}
+		if err := acc.Apply(event); err != nil {
+			return account.Account{}, err
+		}
+		found = true
+	}
+	if err := rows.Err(); err != nil {
+		return account.Account{}, err
+	}
+
+	if !found {
+		// account created before event sourcing has no stream yet
+		acc, _, err := es.projection.loadAccount(ctx, id, "")
+		return acc, err
+	}
+	return acc, nil
+}
+
+// loadSnapshot returns empty account at version zero if account has no snapshot.
+func (es *EventStore) loadSnapshot(ctx context.Context, id int64) (account.Account, bool, error) {
+	row := es.conn.QueryRow(ctx,
+		`SELECT version, state FROM account_snapshots WHERE account_id=$1`,
+		id,
+	)
+	var (
+		version int64
+		data    []byte
+	)
+	if err := row.Scan(&version, &data); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return account.Account{}, false, nil
+		}
+		return account.Account{}, false, err
+	}
+
+	var snapshot accountSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return account.Account{}, false, fmt.Errorf("decode snapshot of account %d: %w", id, err)
+	}
+	holds := make([]account.Hold, 0, len(snapshot.Holds))
+	for _, h := range snapshot.Holds {
+		holds = append(holds, h.hold())
+	}
+	acc := account.NewAccount(id, snapshot.Currency, snapshot.Balance, snapshot.CreditLimit, snapshot.Status, holds...)
+	acc.SetVersion(version)
+	return acc, true, nil
+}
+
+func (es *EventStore) SaveAccount(ctx context.Context, acc account.Account) error {
+	return es.SaveAccounts(ctx, acc)
+}
+
+// SaveAccounts appends events of accounts and updates read model atomically.
+// Returns errVersionConflict if stream of account was appended after it was loaded.
+func (es *EventStore) SaveAccounts(ctx context.Context, accs ...account.Account) error {
+	const op = eventStoreOpPrefix + "SaveAccounts"
+	if len(accs) == 0 {
+		return nil
+	}
+
+	err := es.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		wrapped := es.with(tx)
+		// concurrent saves of the same accounts wait here in order of id instead of deadlocking
+		ids := make([]int64, len(accs))
+		for i, acc := range accs {
+			ids[i] = acc.Id()
+		}
+		if _, err := tx.Exec(ctx, `SELECT id FROM accounts WHERE id = ANY($1) ORDER BY id FOR UPDATE`, ids); err != nil {
+			return err
+		}
+
+		for _, acc := range accs {
+			if err := wrapped.appendEvents(ctx, acc); err != nil {
+				return err
+			}
+		}
+		return wrapped.projection.SaveAccounts(ctx, accs...)
+	})
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+func (es *EventStore) appendEvents(ctx context.Context, acc account.Account) error {
+	events := acc.Events()
+	if len(events) == 0 {
+		return nil
+	}
+
+	version := acc.Version()
+	if version == 0 && events[0].Type != account.EventAccountOpened {
+		// read model still has state before these events
+		legacy, _, err := es.projection.loadAccount(ctx, acc.Id(), "")
+		if err != nil {
+			return err
+		}
+		if err := es.saveSnapshot(ctx, legacy, 0); err != nil {
+			return err
+		}
+	}
+
+	reqId := requestid.FromContext(ctx)
+	for i, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		tag, err := es.conn.Exec(ctx,
+			`INSERT INTO account_events(account_id, version, type, data, request_id)
+			VALUES ($1, $2, $3, $4, NULLIF($5, '')) ON CONFLICT (account_id, version) DO NOTHING`,
+			acc.Id(),
+			version+int64(i)+1,
+			string(event.Type),
+			string(data),
+			reqId,
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return errVersionConflict
+		}
+	}
+
+	next := version + int64(len(events))
+	if es.snapshotEvery > 0 && next/es.snapshotEvery > version/es.snapshotEvery {
+		return es.saveSnapshot(ctx, acc, next)
+	}
+	return nil
+}
+
+// saveSnapshot never replaces newer snapshot.
+func (es *EventStore) saveSnapshot(ctx context.Context, acc account.Account, version int64) error {
+	snapshot := accountSnapshot{
+		Currency:    acc.Currency(),
+		Balance:     acc.GetBalance(),
+		CreditLimit: acc.CreditLimit(),
+		Status:      acc.Status(),
+		Holds:       make([]holdState, 0, len(acc.Holds())),
+	}
+	for _, h := range acc.Holds() {
+		snapshot.Holds = append(snapshot.Holds, holdStateOf(h))
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	_, err = es.conn.Exec(ctx,
+		`INSERT INTO account_snapshots(account_id, version, state) VALUES ($1, $2, $3)
+		ON CONFLICT (account_id) DO UPDATE
+			SET version = excluded.version, state = excluded.state, created_at = now()
+			WHERE account_snapshots.version < excluded.version`,
+		acc.Id(),
+		version,
+		string(data),
+	)
+	return err
+}
+
+// Acquire loads account without locks and saves it if its stream
+// wasn't appended meanwhile, otherwise it is retried up to maxRetries.
+func (es *EventStore) Acquire(ctx context.Context, id int64, fn application.AccountProcessFunc) error {
+	const op = eventStoreOpPrefix + "Acquire"
+
+	return es.retry(ctx, op, func() error {
+		a, err := es.GetAccountById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := fn(&a); err != nil {
+			return err
+		}
+		return es.SaveAccount(ctx, a)
+	})
+}
+
+func (es *EventStore) AcquirePair(ctx context.Context, firstId, secondId int64, fn application.PairProcessFunc) error {
+	const op = eventStoreOpPrefix + "AcquirePair"
+	if firstId == secondId {
+		return fmt.Errorf("%s:%w", op, application.ErrSameAccount)
+	}
+
+	return es.retry(ctx, op, func() error {
+		first, err := es.GetAccountById(ctx, firstId)
+		if err != nil {
+			return err
+		}
+		second, err := es.GetAccountById(ctx, secondId)
+		if err != nil {
+			return err
+		}
+		if err := fn(&first, &second); err != nil {
+			return err
+		}
+		return es.SaveAccounts(ctx, first, second)
+	})
+}
+
+func (es *EventStore) retry(ctx context.Context, op string, f func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		if !es.projection.retryable(err) {
+			return mapLockError(op, err)
+		}
+		if attempt >= es.maxRetries {
+			return fmt.Errorf("%s:%w", op, application.ErrAccountBusy)
+		}
+		if err := backoff(ctx, attempt); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+	}
+}
+
+func (es *EventStore) ListJournalEntries(ctx context.Context, filter application.JournalFilter) ([]application.JournalEntry, error) {
+	return es.projection.ListJournalEntries(ctx, filter)
+}
+
+// ListAccounts reads accounts from read model.
+func (es *EventStore) ListAccounts(ctx context.Context, filter application.AccountFilter) ([]account.Account, error) {
+	return es.projection.ListAccounts(ctx, filter)
+}
+
+func (es *EventStore) GetIdempotencyRecord(ctx context.Context, key string) (application.IdempotencyRecord, error) {
+	return es.projection.GetIdempotencyRecord(ctx, key)
+}
+
+func (es *EventStore) DeleteIdempotencyRecords(ctx context.Context, createdBefore time.Time) (int64, error) {
+	return es.projection.DeleteIdempotencyRecords(ctx, createdBefore)
+}
+
+func (es *EventStore) AccountsWithExpiredHolds(ctx context.Context, now time.Time) ([]int64, error) {
+	return es.projection.AccountsWithExpiredHolds(ctx, now)
+}
+
+func (es *EventStore) ClaimOutbox(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]application.OutboxMessage, error) {
+	return es.projection.ClaimOutbox(ctx, now, lease, limit)
+}
+
+func (es *EventStore) CompleteOutbox(ctx context.Context, result application.OutboxResult) error {
+	return es.projection.CompleteOutbox(ctx, result)
+}
+
+// WithTx returns event store working inside tx, so other acquirers
+// can save accounts in transaction holding their lock.
+func (es *EventStore) WithTx(tx pgx.Tx) *EventStore {
+	return es.with(tx)
+}
+
+func (es *EventStore) with(tx pgx.Tx) *EventStore {
+	return &EventStore{
+		conn:          tx,
+		projection:    es.projection.with(tx),
+		snapshotEvery: es.snapshotEvery,
+		maxRetries:    es.maxRetries,
+	}
+}
@@ -0,0 +1,105 @@
+package accounts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+// loadHolds returns active holds of accounts grouped by account id.
+func (r *Repository) loadHolds(ctx context.Context, ids ...int64) (map[int64][]account.Hold, error) {
+	const op = opPrefix + "loadHolds"
+
+	rows, err := r.conn.Query(ctx,
+		`SELECT id, account_id, amount, captured_amount, status, expires_at, created_at
+		FROM holds WHERE account_id = ANY($1) AND status = $2 ORDER BY created_at, id`,
+		ids,
+		string(account.HoldActive),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	holds := make(map[int64][]account.Hold, len(ids))
+	for rows.Next() {
+		var (
+			id        string
+			accountId int64
+			amount    account.Money
+			captured  account.Money
+			status    string
+			expiresAt time.Time
+			createdAt time.Time
+		)
+		if err := rows.Scan(&id, &accountId, &amount, &captured, &status, &expiresAt, &createdAt); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		holds[accountId] = append(holds[accountId], account.RestoreHold(
+			id,
+			accountId,
+			amount,
+			captured,
+			account.HoldStatus(status),
+			expiresAt,
+			createdAt,
+		))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return holds, nil
+}
+
+func (r *Repository) saveHolds(ctx context.Context, acc account.Account) error {
+	for _, h := range acc.HoldChanges() {
+		_, err := r.conn.Exec(ctx,
+			`INSERT INTO holds(id, account_id, amount, captured_amount, status, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (id) DO UPDATE
+				SET captured_amount = excluded.captured_amount,
+					status = excluded.status,
+					updated_at = now()`,
+			h.Id(),
+			h.AccountId(),
+			h.Amount(),
+			h.Captured(),
+			string(h.Status()),
+			h.ExpiresAt(),
+			h.CreatedAt(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) AccountsWithExpiredHolds(ctx context.Context, now time.Time) ([]int64, error) {
+	const op = opPrefix + "AccountsWithExpiredHolds"
+
+	rows, err := r.conn.Query(ctx,
+		`SELECT DISTINCT account_id FROM holds WHERE status = $1 AND expires_at <= $2`,
+		string(account.HoldActive),
+		now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,72 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+func (r *Repository) GetIdempotencyRecord(ctx context.Context, key string) (application.IdempotencyRecord, error) {
+	const op = opPrefix + "GetIdempotencyRecord"
+
+	row := r.conn.QueryRow(ctx,
+		`SELECT key, fingerprint, response_status, response_body, created_at
+		FROM idempotency_keys WHERE key=$1`,
+		key,
+	)
+
+	var record application.IdempotencyRecord
+	err := row.Scan(
+		&record.Key,
+		&record.Fingerprint,
+		&record.Response.Status,
+		&record.Response.Body,
+		&record.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return record, fmt.Errorf("%s:%w", op, application.ErrIdempotencyRecordNotFound)
+		}
+		return record, fmt.Errorf("%s:%w", op, err)
+	}
+	return record, nil
+}
+
+func (r *Repository) DeleteIdempotencyRecords(ctx context.Context, createdBefore time.Time) (int64, error) {
+	const op = opPrefix + "DeleteIdempotencyRecords"
+
+	tag, err := r.conn.Exec(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, createdBefore)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// saveIdempotencyRecord must be called inside transaction of operation.
+func (r *Repository) saveIdempotencyRecord(ctx context.Context, result application.OperationResult) error {
+	record, err := application.IdempotencyRecordFromContext(ctx, result)
+	if err != nil || record == nil {
+		return err
+	}
+
+	tag, err := r.conn.Exec(ctx,
+		`INSERT INTO idempotency_keys(key, fingerprint, response_status, response_body, created_at)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (key) DO NOTHING`,
+		record.Key,
+		record.Fingerprint,
+		record.Response.Status,
+		record.Response.Body,
+		record.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return application.ErrIdempotencyKeyConflict
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
+)
+
+func (r *Repository) appendJournal(ctx context.Context, acc account.Account) error {
+	reqId := requestid.FromContext(ctx)
+	for _, operation := range acc.Operations() {
+		var (
+			counterpartyId  any
+			counterAmount   any
+			counterCurrency any
+			rate            any
+			marketRate      any
+			spread          any
+			holdId          any
+		)
+		if t := operation.Transfer; t != nil {
+			// counterparty columns describe opposite side of transfer
+			if t.FromAccountId == acc.Id() {
+				counterpartyId, counterAmount, counterCurrency = t.ToAccountId, t.Credit, string(t.CreditCurrency)
+			} else {
+				counterpartyId, counterAmount, counterCurrency = t.FromAccountId, t.Debit, string(t.DebitCurrency)
+			}
+			rate, marketRate, spread = t.Rate, t.MarketRate, t.Spread
+		}
+		if operation.HoldId != "" {
+			holdId = operation.HoldId
+		}
+
+		_, err := r.conn.Exec(ctx,
+			`INSERT INTO transactions(account_id, type, amount, balance_after, request_id,
+				counterparty_account_id, counter_amount, counter_currency, exchange_rate, market_rate, spread, hold_id)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12)`,
+			acc.Id(),
+			string(operation.Type),
+			operation.Amount,
+			operation.BalanceAfter,
+			reqId,
+			counterpartyId,
+			counterAmount,
+			counterCurrency,
+			rate,
+			marketRate,
+			spread,
+			holdId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) ListJournalEntries(ctx context.Context, filter application.JournalFilter) ([]application.JournalEntry, error) {
+	const op = opPrefix + "ListJournalEntries"
+
+	var currency string
+	row := r.conn.QueryRow(ctx, `SELECT currency FROM accounts WHERE id=$1`, filter.AccountId)
+	if err := row.Scan(&currency); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s:%w", op, application.ErrAccountNotFound)
+		}
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	query, args := buildJournalQuery(filter)
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	var entries []application.JournalEntry
+	for rows.Next() {
+		var (
+			entry           application.JournalEntry
+			opType          string
+			requestId       pgtype.Text
+			counterpartyId  pgtype.Int8
+			counterAmount   account.Money
+			counterCurrency pgtype.Text
+			transfer        account.Transfer
+			holdId          pgtype.Text
+		)
+		err := rows.Scan(
+			&entry.Id,
+			&entry.AccountId,
+			&opType,
+			&entry.Amount,
+			&entry.BalanceAfter,
+			&requestId,
+			&entry.CreatedAt,
+			&counterpartyId,
+			&counterAmount,
+			&counterCurrency,
+			&transfer.Rate,
+			&transfer.MarketRate,
+			&transfer.Spread,
+			&holdId,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		entry.Type = account.OperationType(opType)
+		entry.Currency = account.Currency(currency)
+		entry.RequestId = requestId.String
+		entry.HoldId = holdId.String
+
+		if counterpartyId.Status == pgtype.Present {
+			own := transferSide{entry.AccountId, entry.Amount, entry.Currency}
+			other := transferSide{counterpartyId.Int, counterAmount, account.Currency(counterCurrency.String)}
+			if entry.Type == account.OperationTransferIn {
+				own, other = other, own
+			}
+			transfer.FromAccountId, transfer.Debit, transfer.DebitCurrency = own.accountId, own.amount, own.currency
+			transfer.ToAccountId, transfer.Credit, transfer.CreditCurrency = other.accountId, other.amount, other.currency
+			entry.Transfer = &transfer
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return entries, nil
+}
+
+type transferSide struct {
+	accountId int64
+	amount    account.Money
+	currency  account.Currency
+}
+
+func journalOrder(filter application.JournalFilter) string {
+	if filter.OldestFirst {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+func buildJournalQuery(filter application.JournalFilter) (string, []any) {
+	var sb strings.Builder
+	sb.WriteString(`SELECT id, account_id, type, amount, balance_after, request_id, created_at,
+		counterparty_account_id, counter_amount, counter_currency, exchange_rate, market_rate, spread, hold_id
+		FROM transactions WHERE account_id=$1`)
+	args := []any{filter.AccountId}
+
+	addCondition := func(cond string, arg any) {
+		args = append(args, arg)
+		sb.WriteString(" AND ")
+		sb.WriteString(fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.BeforeId != 0 {
+		addCondition("id < $%d", filter.BeforeId)
+	}
+	if filter.AfterId != 0 {
+		addCondition("id > $%d", filter.AfterId)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To)
+	}
+	if len(filter.Types) != 0 {
+		types := make([]string, len(filter.Types))
+		for i, t := range filter.Types {
+			types[i] = string(t)
+		}
+		addCondition("type = ANY($%d)", types)
+	}
+
+	args = append(args, filter.Limit)
+	sb.WriteString(fmt.Sprintf(" ORDER BY id %s LIMIT $%d", journalOrder(filter), len(args)))
+	return sb.String(), args
+}
@@ -1,48 +1,448 @@
 package accounts
 
 import (
+	"cmp"
 	"context"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/vitaliy-ukiru/bank-service/internal/application"
 	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
 )
 
 type AccountStorage struct {
 	rw       sync.RWMutex
 	id       int64
-	accounts map[int64]float64
+	accounts map[int64]memoryAccount
+
+	entryId int64
+	journal map[int64][]application.JournalEntry
+
+	// holds keeps holds of all statuses by id
+	holds map[string]account.Hold
+
+	statusChanges map[int64][]memoryStatusChange
+
+	idempotency map[string]application.IdempotencyRecord
+
+	outboxId int64
+	// outbox keeps unpublished messages in order of id
+	outbox []memoryOutboxMessage
+
+	webhooks map[string]application.Webhook
+	// deliveries keeps deliveries of all webhooks by id
+	deliveries map[string]application.WebhookDelivery
+
+	apiKeys map[string]application.APIKey
+
+	// durability is set for storage opened with OpenDurableInMemory.
+	durability *durability
 }
 
 func NewInMemory() *AccountStorage {
 	return &AccountStorage{
-		accounts: make(map[int64]float64),
+		accounts: make(map[int64]memoryAccount),
+		journal:  make(map[int64][]application.JournalEntry),
+		holds:    make(map[string]account.Hold),
+
+		statusChanges: make(map[int64][]memoryStatusChange),
+
+		idempotency: make(map[string]application.IdempotencyRecord),
+
+		webhooks:   make(map[string]application.Webhook),
+		deliveries: make(map[string]application.WebhookDelivery),
+
+		apiKeys: make(map[string]application.APIKey),
 	}
 }
 
 func (a *AccountStorage) GetAccountById(ctx context.Context, id int64) (account.Account, error) {
-	a.rw.RLock()
+	if err := a.rlock(); err != nil {
+		return account.Account{}, err
+	}
 	defer a.rw.RUnlock()
-	balance, ok := a.accounts[id]
+	acc, ok := a.accounts[id]
 	if !ok {
 		return account.Account{}, application.ErrAccountNotFound
 	}
 
-	return account.NewAccount(id, balance), nil
+	return account.NewAccount(
+		id,
+		acc.Currency,
+		acc.Balance,
+		acc.CreditLimit,
+		acc.Status,
+		a.activeHolds(id)...,
+	), nil
+}
+
+func (a *AccountStorage) ListAccounts(ctx context.Context, filter application.AccountFilter) ([]account.Account, error) {
+	if err := a.rlock(); err != nil {
+		return nil, err
+	}
+	defer a.rw.RUnlock()
+
+	// ids are allocated sequentially, so accounts are walked in order of id
+	var accs []account.Account
+	for id := filter.AfterId + 1; id <= a.id && len(accs) < filter.Limit; id++ {
+		acc, ok := a.accounts[id]
+		if !ok || (filter.Status != "" && acc.Status != filter.Status) {
+			continue
+		}
+		accs = append(accs, account.NewAccount(
+			id,
+			acc.Currency,
+			acc.Balance,
+			acc.CreditLimit,
+			acc.Status,
+			a.activeHolds(id)...,
+		))
+	}
+	return accs, nil
+}
+
+// activeHolds must be called under lock.
+func (a *AccountStorage) activeHolds(accountId int64) []account.Hold {
+	var holds []account.Hold
+	for _, h := range a.holds {
+		if h.AccountId() == accountId && h.Status() == account.HoldActive {
+			holds = append(holds, h)
+		}
+	}
+	slices.SortFunc(holds, func(x, y account.Hold) int {
+		return x.CreatedAt().Compare(y.CreatedAt())
+	})
+	return holds
+}
+
+func (a *AccountStorage) AccountsWithExpiredHolds(ctx context.Context, now time.Time) ([]int64, error) {
+	if err := a.rlock(); err != nil {
+		return nil, err
+	}
+	defer a.rw.RUnlock()
+	var ids []int64
+	for _, h := range a.holds {
+		if h.Status() == account.HoldActive && !now.Before(h.ExpiresAt()) && !slices.Contains(ids, h.AccountId()) {
+			ids = append(ids, h.AccountId())
+		}
+	}
+	return ids, nil
 }
 
 func (a *AccountStorage) SaveAccount(ctx context.Context, acc account.Account) error {
+	return a.SaveAccounts(ctx, acc)
+}
+
+// SaveAccounts stores balances, statuses, holds, journal and idempotency record atomically.
+func (a *AccountStorage) SaveAccounts(ctx context.Context, accs ...account.Account) error {
+	if len(accs) == 0 {
+		return nil
+	}
+
+	record, err := application.IdempotencyRecordFromContext(ctx, application.OperationResultOf(accs...))
+	if err != nil {
+		return err
+	}
+
 	a.rw.Lock()
+	seq, err := a.saveAccounts(ctx, record, accs)
 	a.rw.Unlock()
-	a.accounts[acc.Id()] = acc.GetBalance()
+	if err != nil {
+		return err
+	}
+	return a.waitDurable(seq)
+}
+
+// saveAccounts must be called under write lock.
+func (a *AccountStorage) saveAccounts(ctx context.Context, record *application.IdempotencyRecord, accs []account.Account) (uint64, error) {
+	if err := a.checkIdempotencyRecord(record); err != nil {
+		return 0, err
+	}
+
+	var change memoryChange
+	if record != nil {
+		change.Idempotency = append(change.Idempotency, *record)
+	}
+
+	reqId := requestid.FromContext(ctx)
+	now := time.Now()
+	entryId := a.entryId
+	for _, acc := range accs {
+		change.Accounts = append(change.Accounts, memoryAccount{
+			Id:          acc.Id(),
+			Currency:    acc.Currency(),
+			Balance:     acc.GetBalance(),
+			CreditLimit: acc.CreditLimit(),
+			Status:      acc.Status(),
+		})
+		for _, sc := range acc.StatusChanges() {
+			change.StatusChanges = append(change.StatusChanges, memoryStatusChange{
+				AccountId: acc.Id(),
+				From:      sc.From,
+				To:        sc.To,
+				Reason:    sc.Reason,
+				RequestId: reqId,
+				CreatedAt: now,
+			})
+		}
+		for _, h := range acc.HoldChanges() {
+			change.Holds = append(change.Holds, holdStateOf(h))
+		}
+		if err := a.appendOutbox(&change, reqId, now, acc); err != nil {
+			return 0, err
+		}
+
+		for _, operation := range acc.Operations() {
+			entryId++
+			change.Entries = append(change.Entries, application.JournalEntry{
+				Id:           entryId,
+				AccountId:    acc.Id(),
+				Type:         operation.Type,
+				Currency:     acc.Currency(),
+				Amount:       operation.Amount,
+				BalanceAfter: operation.BalanceAfter,
+				RequestId:    reqId,
+				CreatedAt:    now,
+				Transfer:     operation.Transfer,
+				HoldId:       operation.HoldId,
+			})
+		}
+	}
+	return a.commit(change)
+}
+
+func (a *AccountStorage) NewAccount(ctx context.Context, currency account.Currency) (int64, error) {
+	a.rw.Lock()
+	id, seq, err := a.newAccount(ctx, currency)
+	a.rw.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	return id, a.waitDurable(seq)
+}
+
+// newAccount must be called under write lock.
+func (a *AccountStorage) newAccount(ctx context.Context, currency account.Currency) (int64, uint64, error) {
+	id := a.id + 1
+
+	record, err := application.IdempotencyRecordFromContext(
+		ctx,
+		application.OperationResult{AccountId: id},
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := a.checkIdempotencyRecord(record); err != nil {
+		return 0, 0, err
+	}
+
+	change := memoryChange{
+		Accounts: []memoryAccount{{
+			Id:          id,
+			Currency:    currency,
+			Balance:     currency.Zero(),
+			CreditLimit: currency.Zero(),
+			Status:      account.StatusActive,
+		}},
+	}
+	if record != nil {
+		change.Idempotency = append(change.Idempotency, *record)
+	}
+	err = a.appendOutbox(&change, requestid.FromContext(ctx), time.Now(), account.OpenAccount(id, currency))
+	if err != nil {
+		return 0, 0, err
+	}
+	seq, err := a.commit(change)
+	return id, seq, err
+}
+
+func (a *AccountStorage) ListJournalEntries(ctx context.Context, filter application.JournalFilter) ([]application.JournalEntry, error) {
+	if err := a.rlock(); err != nil {
+		return nil, err
+	}
+	defer a.rw.RUnlock()
+	if _, ok := a.accounts[filter.AccountId]; !ok {
+		return nil, application.ErrAccountNotFound
+	}
+
+	// entries appended in order of id, so walk from the end for newest first
+	// and from the first one after AfterId for oldest first
+	journal := a.journal[filter.AccountId]
+	if filter.AfterId != 0 {
+		journal = journal[sort.Search(len(journal), func(i int) bool {
+			return journal[i].Id > filter.AfterId
+		}):]
+	}
+	var entries []application.JournalEntry
+	for n := 0; n < len(journal) && len(entries) < filter.Limit; n++ {
+		entry := journal[len(journal)-1-n]
+		if filter.OldestFirst {
+			entry = journal[n]
+		}
+		if filter.BeforeId != 0 && entry.Id >= filter.BeforeId {
+			continue
+		}
+		if !filter.From.IsZero() && entry.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !entry.CreatedAt.Before(filter.To) {
+			continue
+		}
+		if len(filter.Types) != 0 && !slices.Contains(filter.Types, entry.Type) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (a *AccountStorage) GetIdempotencyRecord(ctx context.Context, key string) (application.IdempotencyRecord, error) {
+	if err := a.rlock(); err != nil {
+		return application.IdempotencyRecord{}, err
+	}
+	defer a.rw.RUnlock()
+	record, ok := a.idempotency[key]
+	if !ok {
+		return record, application.ErrIdempotencyRecordNotFound
+	}
+	return record, nil
+}
+
+func (a *AccountStorage) DeleteIdempotencyRecords(ctx context.Context, createdBefore time.Time) (int64, error) {
+	a.rw.Lock()
+	var deleted int64
+	for _, record := range a.idempotency {
+		if record.CreatedAt.Before(createdBefore) {
+			deleted++
+		}
+	}
+	var (
+		seq uint64
+		err error
+	)
+	if deleted != 0 {
+		seq, err = a.commit(memoryChange{IdempotencyBefore: createdBefore})
+	}
+	a.rw.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	return deleted, a.waitDurable(seq)
+}
+
+// appendOutbox adds messages of account events to change, must be called under write lock.
+func (a *AccountStorage) appendOutbox(change *memoryChange, reqId string, now time.Time, acc account.Account) error {
+	messages, err := application.OutboxMessagesOf(acc)
+	if err != nil {
+		return err
+	}
+	id := max(a.outboxId, change.OutboxId)
+	for _, message := range messages {
+		id++
+		message.Id = id
+		message.RequestId = reqId
+		message.CreatedAt = now
+		change.Outbox = append(change.Outbox, message)
+	}
+	change.OutboxId = id
 	return nil
 }
 
-func (a *AccountStorage) NewAccount(ctx context.Context) (int64, error) {
+// ClaimOutbox leases messages under write lock, so concurrent claims
+// don't lease messages of one account.
+func (a *AccountStorage) ClaimOutbox(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]application.OutboxMessage, error) {
 	a.rw.Lock()
+	var (
+		messages []application.OutboxMessage
+		change   memoryChange
+		// blocked accounts have earlier message which isn't due
+		blocked = make(map[int64]bool)
+	)
+	for _, m := range a.outbox {
+		if len(messages) == limit {
+			break
+		}
+		if !m.deadAt.IsZero() || blocked[m.AccountId] {
+			continue
+		}
+		if m.availableAt.After(now) {
+			blocked[m.AccountId] = true
+			continue
+		}
+		m.availableAt = now.Add(lease)
+		messages = append(messages, m.OutboxMessage)
+		change.OutboxStates = append(change.OutboxStates, m.state())
+	}
+	var (
+		seq uint64
+		err error
+	)
+	if len(messages) != 0 {
+		seq, err = a.commit(change)
+	}
 	a.rw.Unlock()
-	a.id++
-	id := a.id
-	a.accounts[id] = 0
-	return id, nil
+	if err != nil {
+		return nil, err
+	}
+	return messages, a.waitDurable(seq)
+}
+
+func (a *AccountStorage) CompleteOutbox(ctx context.Context, result application.OutboxResult) error {
+	now := time.Now()
+	change := memoryChange{OutboxPublished: result.Published}
+
+	a.rw.Lock()
+	for _, id := range result.Released {
+		if m, ok := a.outboxMessage(id); ok {
+			m.availableAt = now
+			change.OutboxStates = append(change.OutboxStates, m.state())
+		}
+	}
+	for _, f := range result.Failed {
+		if m, ok := a.outboxMessage(f.Id); ok {
+			m.Attempts = f.Attempts
+			m.lastError = f.Error
+			m.availableAt = f.RetryAt
+			if f.Dead {
+				m.deadAt = now
+			}
+			change.OutboxStates = append(change.OutboxStates, m.state())
+		}
+	}
+	seq, err := a.commit(change)
+	a.rw.Unlock()
+	if err != nil {
+		return err
+	}
+	return a.waitDurable(seq)
+}
+
+// outboxMessage must be called under lock.
+func (a *AccountStorage) outboxMessage(id int64) (memoryOutboxMessage, bool) {
+	i, ok := slices.BinarySearchFunc(a.outbox, id, func(m memoryOutboxMessage, id int64) int {
+		return cmp.Compare(m.Id, id)
+	})
+	if !ok {
+		return memoryOutboxMessage{}, false
+	}
+	return a.outbox[i], true
+}
+
+// checkIdempotencyRecord must be called under lock.
+func (a *AccountStorage) checkIdempotencyRecord(record *application.IdempotencyRecord) error {
+	if record == nil {
+		return nil
+	}
+	if _, ok := a.idempotency[record.Key]; ok {
+		return application.ErrIdempotencyKeyConflict
+	}
+	return nil
 }
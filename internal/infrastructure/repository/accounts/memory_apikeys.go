@@ -0,0 +1,50 @@
+package accounts
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+func (a *AccountStorage) SaveAPIKey(ctx context.Context, key application.APIKey) error {
+	a.rw.Lock()
+	seq, err := a.commit(memoryChange{APIKeys: []application.APIKey{key}})
+	a.rw.Unlock()
+	if err != nil {
+		return err
+	}
+	return a.waitDurable(seq)
+}
+
+func (a *AccountStorage) GetAPIKey(ctx context.Context, id string) (application.APIKey, error) {
+	if err := a.rlock(); err != nil {
+		return application.APIKey{}, err
+	}
+	defer a.rw.RUnlock()
+	key, ok := a.apiKeys[id]
+	if !ok {
+		return application.APIKey{}, application.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (a *AccountStorage) ListAPIKeys(ctx context.Context) ([]application.APIKey, error) {
+	if err := a.rlock(); err != nil {
+		return nil, err
+	}
+	keys := make([]application.APIKey, 0, len(a.apiKeys))
+	for _, k := range a.apiKeys {
+		keys = append(keys, k)
+	}
+	a.rw.RUnlock()
+
+	slices.SortFunc(keys, func(x, y application.APIKey) int {
+		if c := x.CreatedAt.Compare(y.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Id, y.Id)
+	})
+	return keys, nil
+}
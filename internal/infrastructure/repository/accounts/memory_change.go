@@ -0,0 +1,211 @@
+package accounts
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+// memoryChange is atomic change of AccountStorage. It is applied the same way
+// when made and when replayed from write-ahead log, snapshot is one big change.
+type memoryChange struct {
+	Accounts      []memoryAccount                 `json:"accounts,omitempty"`
+	Entries       []application.JournalEntry      `json:"entries,omitempty"`
+	Holds         []holdState                     `json:"holds,omitempty"`
+	StatusChanges []memoryStatusChange            `json:"status_changes,omitempty"`
+	Idempotency   []application.IdempotencyRecord `json:"idempotency,omitempty"`
+	// IdempotencyBefore deletes idempotency records created before it.
+	IdempotencyBefore time.Time                   `json:"idempotency_before,omitempty"`
+	Outbox            []application.OutboxMessage `json:"outbox,omitempty"`
+	OutboxPublished   []int64                     `json:"outbox_published,omitempty"`
+	// OutboxStates replaces publishing state of messages by id.
+	OutboxStates []outboxState `json:"outbox_states,omitempty"`
+	// OutboxId keeps ids of messages growing after all of them are published.
+	OutboxId int64 `json:"outbox_id,omitempty"`
+
+	Webhooks []application.Webhook `json:"webhooks,omitempty"`
+	// DeletedWebhooks deletes webhooks with their deliveries.
+	DeletedWebhooks []string `json:"deleted_webhooks,omitempty"`
+	// Deliveries adds or replaces deliveries by id.
+	Deliveries []application.WebhookDelivery `json:"deliveries,omitempty"`
+
+	// APIKeys adds or replaces keys by id.
+	APIKeys []application.APIKey `json:"api_keys,omitempty"`
+}
+
+type memoryAccount struct {
+	Id          int64            `json:"id"`
+	Currency    account.Currency `json:"currency"`
+	Balance     account.Money    `json:"balance"`
+	CreditLimit account.Money    `json:"credit_limit"`
+	Status      account.Status   `json:"status"`
+}
+
+type holdState struct {
+	Id        string             `json:"id"`
+	AccountId int64              `json:"account_id"`
+	Amount    account.Money      `json:"amount"`
+	Captured  account.Money      `json:"captured"`
+	Status    account.HoldStatus `json:"status"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+func holdStateOf(h account.Hold) holdState {
+	return holdState{
+		Id:        h.Id(),
+		AccountId: h.AccountId(),
+		Amount:    h.Amount(),
+		Captured:  h.Captured(),
+		Status:    h.Status(),
+		ExpiresAt: h.ExpiresAt(),
+		CreatedAt: h.CreatedAt(),
+	}
+}
+
+func (h holdState) hold() account.Hold {
+	return account.RestoreHold(h.Id, h.AccountId, h.Amount, h.Captured, h.Status, h.ExpiresAt, h.CreatedAt)
+}
+
+// memoryOutboxMessage is unpublished message with state of publishing.
+type memoryOutboxMessage struct {
+	application.OutboxMessage
+	// availableAt is end of lease or time of retry, zero for new message
+	availableAt time.Time
+	lastError   string
+	deadAt      time.Time
+}
+
+type outboxState struct {
+	Id          int64     `json:"id"`
+	Attempts    int       `json:"attempts"`
+	AvailableAt time.Time `json:"available_at"`
+	LastError   string    `json:"last_error,omitempty"`
+	DeadAt      time.Time `json:"dead_at,omitempty"`
+}
+
+func (m memoryOutboxMessage) state() outboxState {
+	return outboxState{
+		Id:          m.Id,
+		Attempts:    m.Attempts,
+		AvailableAt: m.availableAt,
+		LastError:   m.lastError,
+		DeadAt:      m.deadAt,
+	}
+}
+
+type memoryStatusChange struct {
+	AccountId int64          `json:"account_id"`
+	From      account.Status `json:"from"`
+	To        account.Status `json:"to"`
+	Reason    string         `json:"reason"`
+	RequestId string         `json:"request_id"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// apply must be called under write lock. Change is validated before,
+// so apply never fails and replay gives the same state.
+func (a *AccountStorage) apply(change memoryChange) {
+	for _, acc := range change.Accounts {
+		a.accounts[acc.Id] = acc
+		a.id = max(a.id, acc.Id)
+	}
+	for _, entry := range change.Entries {
+		a.journal[entry.AccountId] = append(a.journal[entry.AccountId], entry)
+		a.entryId = max(a.entryId, entry.Id)
+	}
+	for _, h := range change.Holds {
+		a.holds[h.Id] = h.hold()
+	}
+	for _, sc := range change.StatusChanges {
+		a.statusChanges[sc.AccountId] = append(a.statusChanges[sc.AccountId], sc)
+	}
+	for _, record := range change.Idempotency {
+		a.idempotency[record.Key] = record
+	}
+	for _, message := range change.Outbox {
+		a.outbox = append(a.outbox, memoryOutboxMessage{OutboxMessage: message})
+		a.outboxId = max(a.outboxId, message.Id)
+	}
+	a.outboxId = max(a.outboxId, change.OutboxId)
+	for _, st := range change.OutboxStates {
+		i, ok := slices.BinarySearchFunc(a.outbox, st.Id, func(m memoryOutboxMessage, id int64) int {
+			return cmp.Compare(m.Id, id)
+		})
+		if !ok {
+			continue
+		}
+		m := &a.outbox[i]
+		m.Attempts = st.Attempts
+		m.availableAt = st.AvailableAt
+		m.lastError = st.LastError
+		m.deadAt = st.DeadAt
+	}
+	if len(change.OutboxPublished) != 0 {
+		a.outbox = slices.DeleteFunc(a.outbox, func(message memoryOutboxMessage) bool {
+			return slices.Contains(change.OutboxPublished, message.Id)
+		})
+	}
+	for _, w := range change.Webhooks {
+		a.webhooks[w.Id] = w
+	}
+	for _, d := range change.Deliveries {
+		a.deliveries[d.Id] = d
+	}
+	for _, k := range change.APIKeys {
+		a.apiKeys[k.Id] = k
+	}
+	for _, id := range change.DeletedWebhooks {
+		delete(a.webhooks, id)
+		for deliveryId, d := range a.deliveries {
+			if d.WebhookId == id {
+				delete(a.deliveries, deliveryId)
+			}
+		}
+	}
+	if !change.IdempotencyBefore.IsZero() {
+		for key, record := range a.idempotency {
+			if record.CreatedAt.Before(change.IdempotencyBefore) {
+				delete(a.idempotency, key)
+			}
+		}
+	}
+}
+
+// state returns whole storage as one change, must be called under lock.
+func (a *AccountStorage) state() memoryChange {
+	var state memoryChange
+	for _, acc := range a.accounts {
+		state.Accounts = append(state.Accounts, acc)
+	}
+	for _, journal := range a.journal {
+		state.Entries = append(state.Entries, journal...)
+	}
+	for _, h := range a.holds {
+		state.Holds = append(state.Holds, holdStateOf(h))
+	}
+	for _, changes := range a.statusChanges {
+		state.StatusChanges = append(state.StatusChanges, changes...)
+	}
+	for _, record := range a.idempotency {
+		state.Idempotency = append(state.Idempotency, record)
+	}
+	for _, m := range a.outbox {
+		state.Outbox = append(state.Outbox, m.OutboxMessage)
+		state.OutboxStates = append(state.OutboxStates, m.state())
+	}
+	state.OutboxId = a.outboxId
+	for _, w := range a.webhooks {
+		state.Webhooks = append(state.Webhooks, w)
+	}
+	for _, d := range a.deliveries {
+		state.Deliveries = append(state.Deliveries, d)
+	}
+	for _, k := range a.apiKeys {
+		state.APIKeys = append(state.APIKeys, k)
+	}
+	return state
+}
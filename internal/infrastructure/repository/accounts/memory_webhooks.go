@@ -0,0 +1,186 @@
+package accounts
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+func (a *AccountStorage) SaveWebhook(ctx context.Context, webhook application.Webhook) error {
+	a.rw.Lock()
+	seq, err := a.commit(memoryChange{Webhooks: []application.Webhook{webhook}})
+	a.rw.Unlock()
+	if err != nil {
+		return err
+	}
+	return a.waitDurable(seq)
+}
+
+func (a *AccountStorage) GetWebhook(ctx context.Context, id string) (application.Webhook, error) {
+	if err := a.rlock(); err != nil {
+		return application.Webhook{}, err
+	}
+	defer a.rw.RUnlock()
+	webhook, ok := a.webhooks[id]
+	if !ok {
+		return application.Webhook{}, application.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+func (a *AccountStorage) ListWebhooks(ctx context.Context) ([]application.Webhook, error) {
+	if err := a.rlock(); err != nil {
+		return nil, err
+	}
+	webhooks := make([]application.Webhook, 0, len(a.webhooks))
+	for _, w := range a.webhooks {
+		webhooks = append(webhooks, w)
+	}
+	a.rw.RUnlock()
+
+	slices.SortFunc(webhooks, func(x, y application.Webhook) int {
+		if c := x.CreatedAt.Compare(y.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Id, y.Id)
+	})
+	return webhooks, nil
+}
+
+func (a *AccountStorage) DeleteWebhook(ctx context.Context, id string) error {
+	a.rw.Lock()
+	if _, ok := a.webhooks[id]; !ok {
+		a.rw.Unlock()
+		return application.ErrWebhookNotFound
+	}
+	seq, err := a.commit(memoryChange{DeletedWebhooks: []string{id}})
+	a.rw.Unlock()
+	if err != nil {
+		return err
+	}
+	return a.waitDurable(seq)
+}
+
+func (a *AccountStorage) AddWebhookDeliveries(ctx context.Context, deliveries ...application.WebhookDelivery) error {
+	type deliveryKey struct {
+		webhookId string
+		messageId int64
+		event     string
+	}
+
+	a.rw.Lock()
+	added := make(map[deliveryKey]bool, len(a.deliveries))
+	for _, d := range a.deliveries {
+		added[deliveryKey{d.WebhookId, d.MessageId, string(d.Event)}] = true
+	}
+	var change memoryChange
+	for _, d := range deliveries {
+		key := deliveryKey{d.WebhookId, d.MessageId, string(d.Event)}
+		// deliveries of deleted webhooks are dropped like by foreign key
+		if _, ok := a.webhooks[d.WebhookId]; !ok || added[key] {
+			continue
+		}
+		added[key] = true
+		change.Deliveries = append(change.Deliveries, d)
+	}
+	var (
+		seq uint64
+		err error
+	)
+	if len(change.Deliveries) != 0 {
+		seq, err = a.commit(change)
+	}
+	a.rw.Unlock()
+	if err != nil {
+		return err
+	}
+	return a.waitDurable(seq)
+}
+
+func (a *AccountStorage) ClaimWebhookDeliveries(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]application.WebhookDelivery, error) {
+	a.rw.Lock()
+	var due []application.WebhookDelivery
+	for _, d := range a.deliveries {
+		if d.Status == application.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	slices.SortFunc(due, func(x, y application.WebhookDelivery) int {
+		return x.NextAttemptAt.Compare(y.NextAttemptAt)
+	})
+	due = due[:min(limit, len(due))]
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+	}
+	var (
+		seq uint64
+		err error
+	)
+	if len(due) != 0 {
+		seq, err = a.commit(memoryChange{Deliveries: due})
+	}
+	a.rw.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(due), a.waitDurable(seq)
+}
+
+func (a *AccountStorage) SaveWebhookDelivery(ctx context.Context, delivery application.WebhookDelivery) error {
+	a.rw.Lock()
+	if _, ok := a.deliveries[delivery.Id]; !ok {
+		a.rw.Unlock()
+		return application.ErrWebhookDeliveryNotFound
+	}
+	seq, err := a.commit(memoryChange{Deliveries: []application.WebhookDelivery{delivery}})
+	a.rw.Unlock()
+	if err != nil {
+		return err
+	}
+	return a.waitDurable(seq)
+}
+
+func (a *AccountStorage) GetWebhookDelivery(ctx context.Context, webhookId, id string) (application.WebhookDelivery, error) {
+	if err := a.rlock(); err != nil {
+		return application.WebhookDelivery{}, err
+	}
+	defer a.rw.RUnlock()
+	d, ok := a.deliveries[id]
+	if !ok || d.WebhookId != webhookId {
+		return application.WebhookDelivery{}, application.ErrWebhookDeliveryNotFound
+	}
+	return d, nil
+}
+
+func (a *AccountStorage) ListWebhookDeliveries(ctx context.Context, filter application.DeliveryFilter) ([]application.WebhookDelivery, error) {
+	if err := a.rlock(); err != nil {
+		return nil, err
+	}
+	var deliveries []application.WebhookDelivery
+	for _, d := range a.deliveries {
+		if d.WebhookId != filter.WebhookId {
+			continue
+		}
+		if filter.Status != "" && d.Status != filter.Status {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	a.rw.RUnlock()
+
+	slices.SortFunc(deliveries, func(x, y application.WebhookDelivery) int {
+		if c := y.CreatedAt.Compare(x.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Id, y.Id)
+	})
+	return deliveries[:min(filter.Limit, len(deliveries))], nil
+}
@@ -0,0 +1,152 @@
+package accounts
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
+)
+
+// Claim of relay holds advisory lock with two keys, their space doesn't overlap
+// with locks of accounts keyed by id.
+const (
+	outboxLockClass = 1
+	outboxLockId    = 1
+)
+
+func (r *Repository) saveOutbox(ctx context.Context, acc account.Account) error {
+	messages, err := application.OutboxMessagesOf(acc)
+	if err != nil {
+		return err
+	}
+
+	reqId := requestid.FromContext(ctx)
+	for _, message := range messages {
+		_, err := r.conn.Exec(ctx,
+			`INSERT INTO outbox(account_id, type, payload, request_id) VALUES ($1, $2, $3, NULLIF($4, ''))`,
+			message.AccountId,
+			string(message.Type),
+			string(message.Payload),
+			reqId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimOutbox holds advisory lock only while messages are leased, so claims of
+// several instances don't lease messages of one account concurrently.
+func (r *Repository) ClaimOutbox(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]application.OutboxMessage, error) {
+	const op = opPrefix + "ClaimOutbox"
+
+	var messages []application.OutboxMessage
+	err := r.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, outboxLockClass, outboxLockId)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx,
+			`UPDATE outbox SET available_at = $2
+			WHERE id IN (
+				SELECT o.id FROM outbox o
+				WHERE o.dead_at IS NULL AND o.available_at <= $1
+				AND NOT EXISTS (
+					SELECT 1 FROM outbox p
+					WHERE p.account_id = o.account_id AND p.id < o.id
+					AND p.dead_at IS NULL AND p.available_at > $1
+				)
+				ORDER BY o.id LIMIT $3
+			)
+			RETURNING id, account_id, type, payload, COALESCE(request_id, ''), created_at, attempts`,
+			now,
+			now.Add(lease),
+			limit,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				message     application.OutboxMessage
+				messageType string
+			)
+			err := rows.Scan(
+				&message.Id,
+				&message.AccountId,
+				&messageType,
+				&message.Payload,
+				&message.RequestId,
+				&message.CreatedAt,
+				&message.Attempts,
+			)
+			if err != nil {
+				return err
+			}
+			message.Type = account.EventType(messageType)
+			messages = append(messages, message)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	// RETURNING doesn't keep order of subquery
+	slices.SortFunc(messages, func(x, y application.OutboxMessage) int {
+		return cmp.Compare(x.Id, y.Id)
+	})
+	return messages, nil
+}
+
+func (r *Repository) CompleteOutbox(ctx context.Context, result application.OutboxResult) error {
+	const op = opPrefix + "CompleteOutbox"
+
+	err := r.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if len(result.Published) != 0 {
+			if _, err := tx.Exec(ctx, `DELETE FROM outbox WHERE id = ANY($1)`, result.Published); err != nil {
+				return err
+			}
+		}
+		if len(result.Released) != 0 {
+			if _, err := tx.Exec(ctx, `UPDATE outbox SET available_at = now() WHERE id = ANY($1)`, result.Released); err != nil {
+				return err
+			}
+		}
+		for _, f := range result.Failed {
+			var deadAt any
+			if f.Dead {
+				deadAt = time.Now()
+			}
+			_, err := tx.Exec(ctx,
+				`UPDATE outbox SET attempts=$2, last_error=NULLIF($3, ''), available_at=$4, dead_at=$5 WHERE id=$1`,
+				f.Id,
+				f.Attempts,
+				f.Error,
+				f.RetryAt,
+				deadAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/jackc/pgx/v4"
 	"github.com/vitaliy-ukiru/bank-service/internal/application"
 	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
 )
 
 type Connection interface {
@@ -17,21 +18,35 @@ type Connection interface {
 }
 
 type Repository struct {
-	conn Connection
+	conn        Connection
+	concurrency Concurrency
 }
 
-func NewRepository(conn Connection) *Repository {
-	return &Repository{conn: conn}
+func NewRepository(conn Connection, concurrency Concurrency) *Repository {
+	return &Repository{conn: conn, concurrency: concurrency}
 }
 
 const opPrefix = "repo.Postgres."
 
-func (r *Repository) NewAccount(ctx context.Context) (int64, error) {
+func (r *Repository) NewAccount(ctx context.Context, currency account.Currency) (int64, error) {
 	const op = opPrefix + "NewAccount"
 
-	row := r.conn.QueryRow(ctx, `INSERT INTO accounts(balance) VALUES(0) RETURNING id`)
 	var accountId int64
-	if err := row.Scan(&accountId); err != nil {
+	err := r.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx,
+			`INSERT INTO accounts(balance, currency) VALUES(0, $1) RETURNING id`,
+			string(currency),
+		)
+		if err := row.Scan(&accountId); err != nil {
+			return err
+		}
+		wrapped := r.with(tx)
+		if err := wrapped.saveOutbox(ctx, account.OpenAccount(accountId, currency)); err != nil {
+			return err
+		}
+		return wrapped.saveIdempotencyRecord(ctx, application.OperationResult{AccountId: accountId})
+	})
+	if err != nil {
 		return 0, fmt.Errorf("%s:%w", op, err)
 	}
 	return accountId, nil
@@ -40,56 +55,342 @@ func (r *Repository) NewAccount(ctx context.Context) (int64, error) {
 func (r *Repository) GetAccountById(ctx context.Context, id int64) (account.Account, error) {
 	const op = opPrefix + "GetAccountById"
 
-	row := r.conn.QueryRow(ctx, `SELECT id, balance FROM accounts WHERE id=$1`, id)
+	acc, _, err := r.loadAccount(ctx, id, "")
+	if err != nil {
+		return account.Account{}, fmt.Errorf("%s:%w", op, err)
+	}
+	return acc, nil
+}
+
+// loadAccount returns account with its version, lock is appended to select.
+func (r *Repository) loadAccount(ctx context.Context, id int64, lock string) (account.Account, int64, error) {
+	row := r.conn.QueryRow(ctx,
+		`SELECT id, currency, balance, credit_limit, status, version FROM accounts WHERE id=$1`+lock,
+		id,
+	)
 
 	var (
-		accountId int64
-		balance   float64
+		accountId   int64
+		currency    string
+		balance     account.Money
+		creditLimit account.Money
+		status      string
+		version     int64
 	)
-	if err := row.Scan(&accountId, &balance); err != nil {
+	if err := row.Scan(&accountId, &currency, &balance, &creditLimit, &status, &version); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return account.Account{}, fmt.Errorf("%s:%w", op, application.ErrAccountNotFound)
+			return account.Account{}, 0, application.ErrAccountNotFound
 		}
+		return account.Account{}, 0, err
+	}
 
-		return account.Account{}, fmt.Errorf("%s:%w", op, err)
+	holds, err := r.loadHolds(ctx, id)
+	if err != nil {
+		return account.Account{}, 0, err
 	}
+	return account.NewAccount(
+		id,
+		account.Currency(currency),
+		balance,
+		creditLimit,
+		account.Status(status),
+		holds[id]...,
+	), version, nil
+}
 
-	return account.NewAccount(id, balance), nil
+// ListAccounts returns accounts in order of id with their active holds.
+func (r *Repository) ListAccounts(ctx context.Context, filter application.AccountFilter) ([]account.Account, error) {
+	const op = opPrefix + "ListAccounts"
+
+	query := `SELECT id, currency, balance, credit_limit, status FROM accounts WHERE id>$1`
+	args := []any{filter.AfterId}
+	if filter.Status != "" {
+		query += ` AND status=$2`
+		args = append(args, string(filter.Status))
+	}
+	query += fmt.Sprintf(` ORDER BY id LIMIT %d`, filter.Limit)
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
 
+	var (
+		accs []account.Account
+		ids  []int64
+	)
+	for rows.Next() {
+		var (
+			id          int64
+			currency    string
+			balance     account.Money
+			creditLimit account.Money
+			status      string
+		)
+		if err := rows.Scan(&id, &currency, &balance, &creditLimit, &status); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		accs = append(accs, account.NewAccount(id, account.Currency(currency), balance, creditLimit, account.Status(status)))
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	rows.Close()
+	if len(accs) == 0 {
+		return nil, nil
+	}
+
+	holds, err := r.loadHolds(ctx, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	for i, acc := range accs {
+		if len(holds[acc.Id()]) != 0 {
+			accs[i] = account.NewAccount(acc.Id(), acc.Currency(), acc.GetBalance(), acc.CreditLimit(), acc.Status(), holds[acc.Id()]...)
+		}
+	}
+	return accs, nil
 }
+
 func (r *Repository) SaveAccount(ctx context.Context, acc account.Account) error {
-	const op = opPrefix + "SaveAccount"
+	return r.SaveAccounts(ctx, acc)
+}
 
-	_, err := r.conn.Exec(ctx, `UPDATE accounts SET balance=$2 WHERE id=$1`, acc.Id(), acc.GetBalance())
-	if err != nil {
+// SaveAccounts stores balances, statuses, hold changes and appends account operations to journal atomically.
+func (r *Repository) SaveAccounts(ctx context.Context, accs ...account.Account) error {
+	const op = opPrefix + "SaveAccounts"
+	if err := r.saveAccounts(ctx, nil, accs...); err != nil {
 		return fmt.Errorf("%s:%w", op, err)
 	}
 	return nil
 }
 
-func (r *Repository) Acquire(ctx context.Context, id int64, fn application.AccountProcessFunc) (err error) {
-	err = r.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+// saveAccounts returns errVersionConflict if version of account differs from one in versions.
+// Accounts missing in versions are saved unconditionally.
+func (r *Repository) saveAccounts(ctx context.Context, versions map[int64]int64, accs ...account.Account) error {
+	if len(accs) == 0 {
+		return nil
+	}
+
+	return r.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
 		wrapped := r.with(tx)
-		a, err := wrapped.GetAccountById(ctx, id)
-		if err != nil {
+		for _, acc := range accs {
+			query := `UPDATE accounts SET balance=$2, credit_limit=$3, status=$4, version=version+1 WHERE id=$1`
+			args := []any{acc.Id(), acc.GetBalance(), acc.CreditLimit(), string(acc.Status())}
+			version, checkVersion := versions[acc.Id()]
+			if checkVersion {
+				query += ` AND version=$5`
+				args = append(args, version)
+			}
+
+			tag, err := tx.Exec(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			if checkVersion && tag.RowsAffected() == 0 {
+				return errVersionConflict
+			}
+			if err := wrapped.saveStatusChanges(ctx, acc); err != nil {
+				return err
+			}
+			// holds saved first, journal references captured ones
+			if err := wrapped.saveHolds(ctx, acc); err != nil {
+				return err
+			}
+			if err := wrapped.appendJournal(ctx, acc); err != nil {
+				return err
+			}
+			if err := wrapped.saveOutbox(ctx, acc); err != nil {
+				return err
+			}
+		}
+		return wrapped.saveIdempotencyRecord(ctx, application.OperationResultOf(accs...))
+	})
+}
+
+// Acquire isolates changes of account according to Concurrency strategy.
+// Serialization failures and version conflicts are retried up to MaxRetries,
+// then ErrAccountBusy is returned.
+func (r *Repository) Acquire(ctx context.Context, id int64, fn application.AccountProcessFunc) error {
+	const op = opPrefix + "Acquire"
+
+	for attempt := 0; ; attempt++ {
+		err := r.acquire(ctx, id, fn)
+		if err == nil {
+			return nil
+		}
+		if !r.retryable(err) {
+			return mapLockError(op, err)
+		}
+		if attempt >= r.concurrency.MaxRetries {
+			return fmt.Errorf("%s:%w", op, application.ErrAccountBusy)
+		}
+		if err := backoff(ctx, attempt); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+	}
+}
+
+func (r *Repository) acquire(ctx context.Context, id int64, fn application.AccountProcessFunc) error {
+	switch r.strategy() {
+	case StrategySerializable:
+		return r.beginSerializable(ctx, func(tx pgx.Tx) error {
+			wrapped := r.with(tx)
+			a, err := wrapped.GetAccountById(ctx, id)
+			if err != nil {
+				return err
+			}
+			if err := fn(&a); err != nil {
+				return err
+			}
+			return wrapped.SaveAccount(ctx, a)
+		})
+
+	case StrategyOptimistic:
+		return r.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+			wrapped := r.with(tx)
+			a, version, err := wrapped.loadAccount(ctx, id, "")
+			if err != nil {
+				return err
+			}
+			if err := fn(&a); err != nil {
+				return err
+			}
+			return wrapped.saveAccounts(ctx, map[int64]int64{id: version}, a)
+		})
+
+	default:
+		return r.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+			wrapped := r.with(tx)
+			if err := wrapped.setLockTimeout(ctx); err != nil {
+				return err
+			}
+			a, _, err := wrapped.loadAccount(ctx, id, r.concurrency.rowLock())
+			if err != nil {
+				return err
+			}
+			if err := fn(&a); err != nil {
+				return err
+			}
+			return wrapped.SaveAccount(ctx, a)
+		})
+	}
+}
+
+func (r *Repository) AcquirePair(ctx context.Context, firstId, secondId int64, fn application.PairProcessFunc) error {
+	const op = opPrefix + "AcquirePair"
+	if firstId == secondId {
+		return fmt.Errorf("%s:%w", op, application.ErrSameAccount)
+	}
+
+	// pair is always locked pessimistically, NoWait and LockTimeout are respected
+	err := r.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		wrapped := r.with(tx)
+		if err := wrapped.setLockTimeout(ctx); err != nil {
 			return err
 		}
-		if err := fn(&a); err != nil {
+		// rows locked in order of id, so two opposite transfers cannot deadlock
+		locked, err := wrapped.lockAccounts(ctx, firstId, secondId)
+		if err != nil {
 			return err
 		}
-		if err := wrapped.SaveAccount(ctx, a); err != nil {
+
+		first, second := locked[firstId], locked[secondId]
+		if err := fn(&first, &second); err != nil {
 			return err
 		}
-		return nil
+		return wrapped.SaveAccounts(ctx, first, second)
 	})
+	return mapLockError(op, err)
+}
+
+func (r *Repository) lockAccounts(ctx context.Context, ids ...int64) (map[int64]account.Account, error) {
+	const op = opPrefix + "lockAccounts"
 
+	rows, err := r.conn.Query(ctx,
+		`SELECT id, currency, balance, credit_limit, status FROM accounts WHERE id = ANY($1) ORDER BY id`+
+			r.concurrency.rowLock(),
+		ids,
+	)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	result := make(map[int64]account.Account, len(ids))
+	for rows.Next() {
+		var (
+			accountId   int64
+			currency    string
+			balance     account.Money
+			creditLimit account.Money
+			status      string
+		)
+		if err := rows.Scan(&accountId, &currency, &balance, &creditLimit, &status); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		result[accountId] = account.NewAccount(
+			accountId,
+			account.Currency(currency),
+			balance,
+			creditLimit,
+			account.Status(status),
+		)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	for _, id := range ids {
+		if _, ok := result[id]; !ok {
+			return nil, fmt.Errorf("%s:%w", op, application.ErrAccountNotFound)
+		}
+	}
+
+	holds, err := r.loadHolds(ctx, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	for id, acc := range result {
+		result[id] = account.NewAccount(
+			id,
+			acc.Currency(),
+			acc.GetBalance(),
+			acc.CreditLimit(),
+			acc.Status(),
+			holds[id]...,
+		)
+	}
+	return result, nil
+}
+
+func (r *Repository) saveStatusChanges(ctx context.Context, acc account.Account) error {
+	reqId := requestid.FromContext(ctx)
+	for _, change := range acc.StatusChanges() {
+		_, err := r.conn.Exec(ctx,
+			`INSERT INTO account_status_changes(account_id, from_status, to_status, reason, request_id)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''))`,
+			acc.Id(),
+			string(change.From),
+			string(change.To),
+			change.Reason,
+			reqId,
+		)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
+}
 
+// WithTx returns repository working inside tx, so other acquirers
+// can save accounts in transaction holding their lock.
+func (r *Repository) WithTx(tx pgx.Tx) *Repository {
+	return r.with(tx)
 }
 
 func (r *Repository) with(tx pgx.Tx) *Repository {
-	return &Repository{conn: tx}
+	return &Repository{conn: tx, concurrency: r.concurrency}
 }
@@ -0,0 +1,367 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type sqliteQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SQLiteRepository stores accounts in embedded database file. Every write
+// transaction starts with BEGIN IMMEDIATE and takes write lock of whole database,
+// so Acquire needs no row locks or retries.
+type SQLiteRepository struct {
+	db   *sql.DB
+	conn sqliteQuerier
+	// busyTimeout is busy_timeout pragma of connections, it is shortened
+	// to deadline of context while write transaction starts.
+	busyTimeout time.Duration
+}
+
+func NewSQLiteRepository(db *sql.DB, busyTimeout time.Duration) *SQLiteRepository {
+	return &SQLiteRepository{db: db, conn: db, busyTimeout: busyTimeout}
+}
+
+const sqliteOpPrefix = "repo.SQLite."
+
+// immediate runs f in write transaction, nested calls join the outer one.
+func (r *SQLiteRepository) immediate(ctx context.Context, f func(r *SQLiteRepository) error) error {
+	if _, nested := r.conn.(*sql.Conn); nested {
+		return f(r)
+	}
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// sqlite doesn't stop waiting for lock when ctx is done
+	if timeout, ok := r.waitTimeout(ctx); ok {
+		if timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+		if err := setBusyTimeout(ctx, conn, timeout); err != nil {
+			return err
+		}
+		defer setBusyTimeout(context.Background(), conn, r.busyTimeout)
+	}
+
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return err
+	}
+	err = f(&SQLiteRepository{db: r.db, conn: conn, busyTimeout: r.busyTimeout})
+	if err == nil {
+		_, err = conn.ExecContext(ctx, `COMMIT`)
+	}
+	if err != nil {
+		// ctx may be already done, rollback must run anyway
+		if _, rbErr := conn.ExecContext(context.Background(), `ROLLBACK`); rbErr != nil {
+			// connection in unknown state must not return to pool
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		return err
+	}
+	return nil
+}
+
+// waitTimeout returns time to deadline of ctx if it is shorter than busy timeout.
+func (r *SQLiteRepository) waitTimeout(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	timeout := time.Until(deadline)
+	return timeout, timeout < r.busyTimeout
+}
+
+func setBusyTimeout(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA busy_timeout = %d", max(timeout.Milliseconds(), 1)))
+	return err
+}
+
+func (r *SQLiteRepository) NewAccount(ctx context.Context, currency account.Currency) (int64, error) {
+	const op = sqliteOpPrefix + "NewAccount"
+
+	var accountId int64
+	err := r.immediate(ctx, func(tx *SQLiteRepository) error {
+		row := tx.conn.QueryRowContext(ctx,
+			`INSERT INTO accounts(currency) VALUES (?) RETURNING id`,
+			string(currency),
+		)
+		if err := row.Scan(&accountId); err != nil {
+			return err
+		}
+		if err := tx.saveOutbox(ctx, account.OpenAccount(accountId, currency), time.Now()); err != nil {
+			return err
+		}
+		return tx.saveIdempotencyRecord(ctx, application.OperationResult{AccountId: accountId})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+	return accountId, nil
+}
+
+func (r *SQLiteRepository) GetAccountById(ctx context.Context, id int64) (account.Account, error) {
+	const op = sqliteOpPrefix + "GetAccountById"
+
+	row := r.conn.QueryRowContext(ctx,
+		`SELECT currency, balance, credit_limit, status FROM accounts WHERE id=?`,
+		id,
+	)
+	var (
+		currency    string
+		balance     account.Money
+		creditLimit account.Money
+		status      string
+	)
+	if err := row.Scan(&currency, &balance, &creditLimit, &status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return account.Account{}, fmt.Errorf("%s:%w", op, application.ErrAccountNotFound)
+		}
+		return account.Account{}, fmt.Errorf("%s:%w", op, err)
+	}
+
+	holds, err := r.loadHolds(ctx, id)
+	if err != nil {
+		return account.Account{}, fmt.Errorf("%s:%w", op, err)
+	}
+	return account.NewAccount(
+		id,
+		account.Currency(currency),
+		balance,
+		creditLimit,
+		account.Status(status),
+		holds[id]...,
+	), nil
+}
+
+// ListAccounts returns accounts in order of id with their active holds.
+func (r *SQLiteRepository) ListAccounts(ctx context.Context, filter application.AccountFilter) ([]account.Account, error) {
+	const op = sqliteOpPrefix + "ListAccounts"
+
+	query := `SELECT id, currency, balance, credit_limit, status FROM accounts WHERE id>?`
+	args := []any{filter.AfterId}
+	if filter.Status != "" {
+		query += ` AND status=?`
+		args = append(args, string(filter.Status))
+	}
+	query += ` ORDER BY id LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	var (
+		accs []account.Account
+		ids  []int64
+	)
+	for rows.Next() {
+		var (
+			id          int64
+			currency    string
+			balance     account.Money
+			creditLimit account.Money
+			status      string
+		)
+		if err := rows.Scan(&id, &currency, &balance, &creditLimit, &status); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		accs = append(accs, account.NewAccount(id, account.Currency(currency), balance, creditLimit, account.Status(status)))
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	rows.Close()
+	if len(accs) == 0 {
+		return nil, nil
+	}
+
+	holds, err := r.loadHolds(ctx, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	for i, acc := range accs {
+		if len(holds[acc.Id()]) != 0 {
+			accs[i] = account.NewAccount(acc.Id(), acc.Currency(), acc.GetBalance(), acc.CreditLimit(), acc.Status(), holds[acc.Id()]...)
+		}
+	}
+	return accs, nil
+}
+
+func (r *SQLiteRepository) SaveAccount(ctx context.Context, acc account.Account) error {
+	return r.SaveAccounts(ctx, acc)
+}
+
+// SaveAccounts stores balances, statuses, hold changes and appends account operations to journal atomically.
+func (r *SQLiteRepository) SaveAccounts(ctx context.Context, accs ...account.Account) error {
+	const op = sqliteOpPrefix + "SaveAccounts"
+	if len(accs) == 0 {
+		return nil
+	}
+
+	err := r.immediate(ctx, func(tx *SQLiteRepository) error {
+		now := time.Now()
+		for _, acc := range accs {
+			res, err := tx.conn.ExecContext(ctx,
+				`UPDATE accounts SET balance=?, credit_limit=?, status=?, version=version+1 WHERE id=?`,
+				acc.GetBalance(),
+				acc.CreditLimit(),
+				string(acc.Status()),
+				acc.Id(),
+			)
+			if err != nil {
+				return err
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return err
+			} else if n == 0 {
+				return application.ErrAccountNotFound
+			}
+			if err := tx.saveStatusChanges(ctx, acc, now); err != nil {
+				return err
+			}
+			// holds saved first, journal references captured ones
+			if err := tx.saveHolds(ctx, acc, now); err != nil {
+				return err
+			}
+			if err := tx.appendJournal(ctx, acc, now); err != nil {
+				return err
+			}
+			if err := tx.saveOutbox(ctx, acc, now); err != nil {
+				return err
+			}
+		}
+		return tx.saveIdempotencyRecord(ctx, application.OperationResultOf(accs...))
+	})
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+// Acquire holds write lock of database while fn runs. Waiting for lock
+// is bounded by busy timeout of connection, then ErrAccountBusy is returned.
+func (r *SQLiteRepository) Acquire(ctx context.Context, id int64, fn application.AccountProcessFunc) error {
+	const op = sqliteOpPrefix + "Acquire"
+
+	err := r.immediate(ctx, func(tx *SQLiteRepository) error {
+		a, err := tx.GetAccountById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := fn(&a); err != nil {
+			return err
+		}
+		return tx.SaveAccount(ctx, a)
+	})
+	return mapSQLiteError(op, err)
+}
+
+func (r *SQLiteRepository) AcquirePair(ctx context.Context, firstId, secondId int64, fn application.PairProcessFunc) error {
+	const op = sqliteOpPrefix + "AcquirePair"
+	if firstId == secondId {
+		return fmt.Errorf("%s:%w", op, application.ErrSameAccount)
+	}
+
+	err := r.immediate(ctx, func(tx *SQLiteRepository) error {
+		first, err := tx.GetAccountById(ctx, firstId)
+		if err != nil {
+			return err
+		}
+		second, err := tx.GetAccountById(ctx, secondId)
+		if err != nil {
+			return err
+		}
+		if err := fn(&first, &second); err != nil {
+			return err
+		}
+		return tx.SaveAccounts(ctx, first, second)
+	})
+	return mapSQLiteError(op, err)
+}
+
+// mapSQLiteError replaces errors of waiting for database lock with application.ErrAccountBusy.
+func mapSQLiteError(op string, err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY {
+		return fmt.Errorf("%s:%w", op, application.ErrAccountBusy)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%s:%w", op, application.ErrAccountBusy)
+	}
+	return err
+}
+
+func (r *SQLiteRepository) saveStatusChanges(ctx context.Context, acc account.Account, now time.Time) error {
+	reqId := requestid.FromContext(ctx)
+	for _, change := range acc.StatusChanges() {
+		_, err := r.conn.ExecContext(ctx,
+			`INSERT INTO account_status_changes(account_id, from_status, to_status, reason, request_id, created_at)
+			VALUES (?, ?, ?, ?, NULLIF(?, ''), ?)`,
+			acc.Id(),
+			string(change.From),
+			string(change.To),
+			change.Reason,
+			reqId,
+			sqliteTime(now),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteTimeLayout has fixed width in UTC, so stored times compare as text.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+// sqliteTime stores time as text of sqliteTimeLayout.
+type sqliteTime time.Time
+
+func (t sqliteTime) Value() (driver.Value, error) {
+	return time.Time(t).UTC().Format(sqliteTimeLayout), nil
+}
+
+func (t *sqliteTime) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("unsupported time source %T", src)
+	}
+	parsed, err := time.Parse(sqliteTimeLayout, s)
+	if err != nil {
+		return err
+	}
+	*t = sqliteTime(parsed)
+	return nil
+}
+
+// placeholders returns "?, ?, ?" for n arguments.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
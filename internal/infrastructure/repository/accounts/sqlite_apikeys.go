@@ -0,0 +1,132 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+func (r *SQLiteRepository) SaveAPIKey(ctx context.Context, key application.APIKey) error {
+	const op = sqliteOpPrefix + "SaveAPIKey"
+
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	_, err = r.conn.ExecContext(ctx,
+		`INSERT INTO api_keys(`+apiKeyColumns+`)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name=excluded.name, scopes=excluded.scopes,
+			secret_hash=excluded.secret_hash, previous_secret_hash=excluded.previous_secret_hash,
+			previous_expires_at=excluded.previous_expires_at, rotated_at=excluded.rotated_at,
+			revoked_at=excluded.revoked_at`,
+		key.Id,
+		key.Name,
+		string(scopes),
+		key.SecretHash,
+		key.PreviousSecretHash,
+		nullSQLiteTime(key.PreviousExpiresAt),
+		sqliteTime(key.CreatedAt),
+		nullSQLiteTime(key.RotatedAt),
+		nullSQLiteTime(key.RevokedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) GetAPIKey(ctx context.Context, id string) (application.APIKey, error) {
+	const op = sqliteOpPrefix + "GetAPIKey"
+
+	row := r.conn.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id=?`, id)
+	key, err := scanSQLiteAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return key, fmt.Errorf("%s:%w", op, application.ErrAPIKeyNotFound)
+		}
+		return key, fmt.Errorf("%s:%w", op, err)
+	}
+	return key, nil
+}
+
+func (r *SQLiteRepository) ListAPIKeys(ctx context.Context) ([]application.APIKey, error) {
+	const op = sqliteOpPrefix + "ListAPIKeys"
+
+	rows, err := r.conn.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []application.APIKey
+	for rows.Next() {
+		key, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return keys, nil
+}
+
+func scanSQLiteAPIKey(row rowScanner) (application.APIKey, error) {
+	var (
+		key               application.APIKey
+		scopes            string
+		previousHash      sql.NullString
+		previousExpiresAt sql.NullString
+		rotatedAt         sql.NullString
+		revokedAt         sql.NullString
+	)
+	err := row.Scan(
+		&key.Id,
+		&key.Name,
+		&scopes,
+		&key.SecretHash,
+		&previousHash,
+		&previousExpiresAt,
+		(*sqliteTime)(&key.CreatedAt),
+		&rotatedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return application.APIKey{}, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return application.APIKey{}, err
+	}
+	key.PreviousSecretHash = previousHash.String
+	for _, t := range []struct {
+		src sql.NullString
+		dst *time.Time
+	}{
+		{previousExpiresAt, &key.PreviousExpiresAt},
+		{rotatedAt, &key.RotatedAt},
+		{revokedAt, &key.RevokedAt},
+	} {
+		if !t.src.Valid {
+			continue
+		}
+		if *t.dst, err = time.Parse(sqliteTimeLayout, t.src.String); err != nil {
+			return application.APIKey{}, err
+		}
+	}
+	return key, nil
+}
+
+// nullSQLiteTime returns nil for zero time.
+func nullSQLiteTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return sqliteTime(t)
+}
@@ -0,0 +1,120 @@
+package accounts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+// loadHolds returns active holds of accounts grouped by account id.
+func (r *SQLiteRepository) loadHolds(ctx context.Context, ids ...int64) (map[int64][]account.Hold, error) {
+	const op = sqliteOpPrefix + "loadHolds"
+
+	args := make([]any, 0, len(ids)+1)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, string(account.HoldActive))
+
+	rows, err := r.conn.QueryContext(ctx,
+		`SELECT id, account_id, amount, captured_amount, status, expires_at, created_at
+		FROM holds WHERE account_id IN (`+placeholders(len(ids))+`) AND status = ? ORDER BY created_at, id`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	holds := make(map[int64][]account.Hold, len(ids))
+	for rows.Next() {
+		var (
+			id        string
+			accountId int64
+			amount    account.Money
+			captured  account.Money
+			status    string
+			expiresAt time.Time
+			createdAt time.Time
+		)
+		err := rows.Scan(
+			&id,
+			&accountId,
+			&amount,
+			&captured,
+			&status,
+			(*sqliteTime)(&expiresAt),
+			(*sqliteTime)(&createdAt),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		holds[accountId] = append(holds[accountId], account.RestoreHold(
+			id,
+			accountId,
+			amount,
+			captured,
+			account.HoldStatus(status),
+			expiresAt,
+			createdAt,
+		))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return holds, nil
+}
+
+func (r *SQLiteRepository) saveHolds(ctx context.Context, acc account.Account, now time.Time) error {
+	for _, h := range acc.HoldChanges() {
+		_, err := r.conn.ExecContext(ctx,
+			`INSERT INTO holds(id, account_id, amount, captured_amount, status, expires_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE
+				SET captured_amount = excluded.captured_amount,
+					status = excluded.status,
+					updated_at = excluded.updated_at`,
+			h.Id(),
+			h.AccountId(),
+			h.Amount(),
+			h.Captured(),
+			string(h.Status()),
+			sqliteTime(h.ExpiresAt()),
+			sqliteTime(h.CreatedAt()),
+			sqliteTime(now),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) AccountsWithExpiredHolds(ctx context.Context, now time.Time) ([]int64, error) {
+	const op = sqliteOpPrefix + "AccountsWithExpiredHolds"
+
+	rows, err := r.conn.QueryContext(ctx,
+		`SELECT DISTINCT account_id FROM holds WHERE status = ? AND expires_at <= ?`,
+		string(account.HoldActive),
+		sqliteTime(now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,80 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+func (r *SQLiteRepository) GetIdempotencyRecord(ctx context.Context, key string) (application.IdempotencyRecord, error) {
+	const op = sqliteOpPrefix + "GetIdempotencyRecord"
+
+	row := r.conn.QueryRowContext(ctx,
+		`SELECT key, fingerprint, response_status, response_body, created_at
+		FROM idempotency_keys WHERE key=?`,
+		key,
+	)
+
+	var record application.IdempotencyRecord
+	err := row.Scan(
+		&record.Key,
+		&record.Fingerprint,
+		&record.Response.Status,
+		&record.Response.Body,
+		(*sqliteTime)(&record.CreatedAt),
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return record, fmt.Errorf("%s:%w", op, application.ErrIdempotencyRecordNotFound)
+		}
+		return record, fmt.Errorf("%s:%w", op, err)
+	}
+	return record, nil
+}
+
+func (r *SQLiteRepository) DeleteIdempotencyRecords(ctx context.Context, createdBefore time.Time) (int64, error) {
+	const op = sqliteOpPrefix + "DeleteIdempotencyRecords"
+
+	res, err := r.conn.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at < ?`, sqliteTime(createdBefore))
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+	return deleted, nil
+}
+
+// saveIdempotencyRecord must be called inside transaction of operation.
+func (r *SQLiteRepository) saveIdempotencyRecord(ctx context.Context, result application.OperationResult) error {
+	record, err := application.IdempotencyRecordFromContext(ctx, result)
+	if err != nil || record == nil {
+		return err
+	}
+
+	res, err := r.conn.ExecContext(ctx,
+		`INSERT INTO idempotency_keys(key, fingerprint, response_status, response_body, created_at)
+		VALUES (?, ?, ?, ?, ?) ON CONFLICT (key) DO NOTHING`,
+		record.Key,
+		record.Fingerprint,
+		record.Response.Status,
+		record.Response.Body,
+		sqliteTime(record.CreatedAt),
+	)
+	if err != nil {
+		return err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return application.ErrIdempotencyKeyConflict
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
+)
+
+func (r *SQLiteRepository) appendJournal(ctx context.Context, acc account.Account, now time.Time) error {
+	reqId := requestid.FromContext(ctx)
+	for _, operation := range acc.Operations() {
+		var (
+			counterpartyId  any
+			counterAmount   any
+			counterCurrency any
+			rate            any
+			marketRate      any
+			spread          any
+			holdId          any
+		)
+		if t := operation.Transfer; t != nil {
+			// counterparty columns describe opposite side of transfer
+			if t.FromAccountId == acc.Id() {
+				counterpartyId, counterAmount, counterCurrency = t.ToAccountId, t.Credit, string(t.CreditCurrency)
+			} else {
+				counterpartyId, counterAmount, counterCurrency = t.FromAccountId, t.Debit, string(t.DebitCurrency)
+			}
+			rate, marketRate, spread = t.Rate, t.MarketRate, t.Spread
+		}
+		if operation.HoldId != "" {
+			holdId = operation.HoldId
+		}
+
+		_, err := r.conn.ExecContext(ctx,
+			`INSERT INTO transactions(account_id, type, amount, balance_after, request_id, created_at,
+				counterparty_account_id, counter_amount, counter_currency, exchange_rate, market_rate, spread, hold_id)
+			VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?)`,
+			acc.Id(),
+			string(operation.Type),
+			operation.Amount,
+			operation.BalanceAfter,
+			reqId,
+			sqliteTime(now),
+			counterpartyId,
+			counterAmount,
+			counterCurrency,
+			rate,
+			marketRate,
+			spread,
+			holdId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) ListJournalEntries(ctx context.Context, filter application.JournalFilter) ([]application.JournalEntry, error) {
+	const op = sqliteOpPrefix + "ListJournalEntries"
+
+	var currency string
+	row := r.conn.QueryRowContext(ctx, `SELECT currency FROM accounts WHERE id=?`, filter.AccountId)
+	if err := row.Scan(&currency); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s:%w", op, application.ErrAccountNotFound)
+		}
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+
+	query, args := buildSQLiteJournalQuery(filter)
+	rows, err := r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	var entries []application.JournalEntry
+	for rows.Next() {
+		var (
+			entry           application.JournalEntry
+			opType          string
+			requestId       sql.NullString
+			counterpartyId  sql.NullInt64
+			counterAmount   account.Money
+			counterCurrency sql.NullString
+			transfer        account.Transfer
+			holdId          sql.NullString
+		)
+		err := rows.Scan(
+			&entry.Id,
+			&entry.AccountId,
+			&opType,
+			&entry.Amount,
+			&entry.BalanceAfter,
+			&requestId,
+			(*sqliteTime)(&entry.CreatedAt),
+			&counterpartyId,
+			&counterAmount,
+			&counterCurrency,
+			&transfer.Rate,
+			&transfer.MarketRate,
+			&transfer.Spread,
+			&holdId,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		entry.Type = account.OperationType(opType)
+		entry.Currency = account.Currency(currency)
+		entry.RequestId = requestId.String
+		entry.HoldId = holdId.String
+
+		if counterpartyId.Valid {
+			own := transferSide{entry.AccountId, entry.Amount, entry.Currency}
+			other := transferSide{counterpartyId.Int64, counterAmount, account.Currency(counterCurrency.String)}
+			if entry.Type == account.OperationTransferIn {
+				own, other = other, own
+			}
+			transfer.FromAccountId, transfer.Debit, transfer.DebitCurrency = own.accountId, own.amount, own.currency
+			transfer.ToAccountId, transfer.Credit, transfer.CreditCurrency = other.accountId, other.amount, other.currency
+			entry.Transfer = &transfer
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return entries, nil
+}
+
+func buildSQLiteJournalQuery(filter application.JournalFilter) (string, []any) {
+	var sb strings.Builder
+	sb.WriteString(`SELECT id, account_id, type, amount, balance_after, request_id, created_at,
+		counterparty_account_id, counter_amount, counter_currency, exchange_rate, market_rate, spread, hold_id
+		FROM transactions WHERE account_id=?`)
+	args := []any{filter.AccountId}
+
+	if filter.BeforeId != 0 {
+		sb.WriteString(" AND id < ?")
+		args = append(args, filter.BeforeId)
+	}
+	if filter.AfterId != 0 {
+		sb.WriteString(" AND id > ?")
+		args = append(args, filter.AfterId)
+	}
+	if !filter.From.IsZero() {
+		sb.WriteString(" AND created_at >= ?")
+		args = append(args, sqliteTime(filter.From))
+	}
+	if !filter.To.IsZero() {
+		sb.WriteString(" AND created_at < ?")
+		args = append(args, sqliteTime(filter.To))
+	}
+	if len(filter.Types) != 0 {
+		sb.WriteString(" AND type IN (" + placeholders(len(filter.Types)) + ")")
+		for _, t := range filter.Types {
+			args = append(args, string(t))
+		}
+	}
+
+	sb.WriteString(" ORDER BY id " + journalOrder(filter) + " LIMIT ?")
+	args = append(args, filter.Limit)
+	return sb.String(), args
+}
@@ -0,0 +1,167 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
+)
+
+func (r *SQLiteRepository) saveOutbox(ctx context.Context, acc account.Account, now time.Time) error {
+	messages, err := application.OutboxMessagesOf(acc)
+	if err != nil {
+		return err
+	}
+
+	reqId := requestid.FromContext(ctx)
+	for _, message := range messages {
+		_, err := r.conn.ExecContext(ctx,
+			`INSERT INTO outbox(account_id, type, payload, request_id, created_at, available_at)
+			VALUES (?, ?, ?, NULLIF(?, ''), ?, ?)`,
+			message.AccountId,
+			string(message.Type),
+			string(message.Payload),
+			reqId,
+			sqliteTime(now),
+			sqliteTime(now),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimOutbox leases messages in write transaction, so concurrent claims
+// don't lease messages of one account.
+func (r *SQLiteRepository) ClaimOutbox(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]application.OutboxMessage, error) {
+	const op = sqliteOpPrefix + "ClaimOutbox"
+
+	var messages []application.OutboxMessage
+	err := r.immediate(ctx, func(tx *SQLiteRepository) error {
+		rows, err := tx.conn.QueryContext(ctx,
+			`SELECT o.id, o.account_id, o.type, o.payload, COALESCE(o.request_id, ''), o.created_at, o.attempts
+			FROM outbox o
+			WHERE o.dead_at IS NULL AND o.available_at <= ?1
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox p
+				WHERE p.account_id = o.account_id AND p.id < o.id
+				AND p.dead_at IS NULL AND p.available_at > ?1
+			)
+			ORDER BY o.id LIMIT ?2`,
+			sqliteTime(now),
+			limit,
+		)
+		if err != nil {
+			return err
+		}
+		messages, err = collectSQLiteOutbox(rows)
+		if err != nil {
+			return err
+		}
+
+		leaseEnd := sqliteTime(now.Add(lease))
+		for _, message := range messages {
+			_, err := tx.conn.ExecContext(ctx, `UPDATE outbox SET available_at=? WHERE id=?`, leaseEnd, message.Id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return messages, nil
+}
+
+func collectSQLiteOutbox(rows *sql.Rows) ([]application.OutboxMessage, error) {
+	defer rows.Close()
+	var messages []application.OutboxMessage
+	for rows.Next() {
+		var (
+			message     application.OutboxMessage
+			messageType string
+		)
+		err := rows.Scan(
+			&message.Id,
+			&message.AccountId,
+			&messageType,
+			&message.Payload,
+			&message.RequestId,
+			(*sqliteTime)(&message.CreatedAt),
+			&message.Attempts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		message.Type = account.EventType(messageType)
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (r *SQLiteRepository) CompleteOutbox(ctx context.Context, result application.OutboxResult) error {
+	const op = sqliteOpPrefix + "CompleteOutbox"
+
+	now := time.Now()
+	err := r.immediate(ctx, func(tx *SQLiteRepository) error {
+		if len(result.Published) != 0 {
+			_, err := tx.conn.ExecContext(ctx,
+				`DELETE FROM outbox WHERE id IN (`+placeholders(len(result.Published))+`)`,
+				int64Args(result.Published)...,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		if len(result.Released) != 0 {
+			_, err := tx.conn.ExecContext(ctx,
+				`UPDATE outbox SET available_at=? WHERE id IN (`+placeholders(len(result.Released))+`)`,
+				append([]any{sqliteTime(now)}, int64Args(result.Released)...)...,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		for _, f := range result.Failed {
+			var deadAt any
+			if f.Dead {
+				deadAt = sqliteTime(now)
+			}
+			_, err := tx.conn.ExecContext(ctx,
+				`UPDATE outbox SET attempts=?, last_error=NULLIF(?, ''), available_at=?, dead_at=? WHERE id=?`,
+				f.Attempts,
+				f.Error,
+				sqliteTime(f.RetryAt),
+				deadAt,
+				f.Id,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+func int64Args(ids []int64) []any {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
@@ -0,0 +1,333 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+func (r *SQLiteRepository) SaveWebhook(ctx context.Context, webhook application.Webhook) error {
+	const op = sqliteOpPrefix + "SaveWebhook"
+
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	var threshold any
+	if webhook.LowBalanceThreshold != nil {
+		threshold = *webhook.LowBalanceThreshold
+	}
+
+	_, err = r.conn.ExecContext(ctx,
+		`INSERT INTO webhooks(id, url, events, account_id, low_balance_threshold, secret, created_at)
+		VALUES (?, ?, ?, NULLIF(?, 0), ?, ?, ?)`,
+		webhook.Id,
+		webhook.URL,
+		string(events),
+		webhook.AccountId,
+		threshold,
+		webhook.Secret,
+		sqliteTime(webhook.CreatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) GetWebhook(ctx context.Context, id string) (application.Webhook, error) {
+	const op = sqliteOpPrefix + "GetWebhook"
+
+	row := r.conn.QueryRowContext(ctx,
+		`SELECT id, url, events, account_id, low_balance_threshold, secret, created_at FROM webhooks WHERE id=?`,
+		id,
+	)
+	webhook, err := scanSQLiteWebhook(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return webhook, fmt.Errorf("%s:%w", op, application.ErrWebhookNotFound)
+		}
+		return webhook, fmt.Errorf("%s:%w", op, err)
+	}
+	return webhook, nil
+}
+
+func (r *SQLiteRepository) ListWebhooks(ctx context.Context) ([]application.Webhook, error) {
+	const op = sqliteOpPrefix + "ListWebhooks"
+
+	rows, err := r.conn.QueryContext(ctx,
+		`SELECT id, url, events, account_id, low_balance_threshold, secret, created_at
+		FROM webhooks ORDER BY created_at, id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	var webhooks []application.Webhook
+	for rows.Next() {
+		webhook, err := scanSQLiteWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return webhooks, nil
+}
+
+func scanSQLiteWebhook(row rowScanner) (application.Webhook, error) {
+	var (
+		webhook   application.Webhook
+		events    string
+		accountId sql.NullInt64
+		threshold sql.NullString
+	)
+	err := row.Scan(
+		&webhook.Id,
+		&webhook.URL,
+		&events,
+		&accountId,
+		&threshold,
+		&webhook.Secret,
+		(*sqliteTime)(&webhook.CreatedAt),
+	)
+	if err != nil {
+		return application.Webhook{}, err
+	}
+	if err := json.Unmarshal([]byte(events), &webhook.Events); err != nil {
+		return application.Webhook{}, err
+	}
+	webhook.AccountId = accountId.Int64
+	if threshold.Valid {
+		money, err := account.ParseMoney(threshold.String)
+		if err != nil {
+			return application.Webhook{}, err
+		}
+		webhook.LowBalanceThreshold = &money
+	}
+	return webhook, nil
+}
+
+func (r *SQLiteRepository) DeleteWebhook(ctx context.Context, id string) error {
+	const op = sqliteOpPrefix + "DeleteWebhook"
+
+	res, err := r.conn.ExecContext(ctx, `DELETE FROM webhooks WHERE id=?`, id)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s:%w", op, application.ErrWebhookNotFound)
+	}
+	return nil
+}
+
+// AddWebhookDeliveries drops deliveries of deleted webhooks.
+func (r *SQLiteRepository) AddWebhookDeliveries(ctx context.Context, deliveries ...application.WebhookDelivery) error {
+	const op = sqliteOpPrefix + "AddWebhookDeliveries"
+
+	err := r.immediate(ctx, func(tx *SQLiteRepository) error {
+		for _, d := range deliveries {
+			_, err := tx.conn.ExecContext(ctx,
+				`INSERT INTO webhook_deliveries(id, webhook_id, message_id, event, body, status, next_attempt_at, created_at)
+				SELECT ?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8
+				WHERE EXISTS (SELECT 1 FROM webhooks WHERE id = ?2)
+				ON CONFLICT (webhook_id, message_id, event) DO NOTHING`,
+				d.Id,
+				d.WebhookId,
+				d.MessageId,
+				string(d.Event),
+				d.Body,
+				string(d.Status),
+				sqliteTime(d.NextAttemptAt),
+				sqliteTime(d.CreatedAt),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+const sqliteDeliveryColumns = `id, webhook_id, message_id, event, body, status, attempts,
+	next_attempt_at, last_attempt_at, last_status_code, last_error, created_at`
+
+func (r *SQLiteRepository) ClaimWebhookDeliveries(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]application.WebhookDelivery, error) {
+	const op = sqliteOpPrefix + "ClaimWebhookDeliveries"
+
+	var deliveries []application.WebhookDelivery
+	err := r.immediate(ctx, func(tx *SQLiteRepository) error {
+		rows, err := tx.conn.QueryContext(ctx,
+			`SELECT `+sqliteDeliveryColumns+` FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= ?
+			ORDER BY next_attempt_at LIMIT ?`,
+			sqliteTime(now),
+			limit,
+		)
+		if err != nil {
+			return err
+		}
+		deliveries, err = collectSQLiteDeliveries(rows)
+		if err != nil {
+			return err
+		}
+
+		leaseEnd := now.Add(lease)
+		for i := range deliveries {
+			deliveries[i].NextAttemptAt = leaseEnd
+			_, err := tx.conn.ExecContext(ctx,
+				`UPDATE webhook_deliveries SET next_attempt_at=? WHERE id=?`,
+				sqliteTime(leaseEnd),
+				deliveries[i].Id,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return deliveries, nil
+}
+
+func (r *SQLiteRepository) SaveWebhookDelivery(ctx context.Context, d application.WebhookDelivery) error {
+	const op = sqliteOpPrefix + "SaveWebhookDelivery"
+
+	var lastAttemptAt any
+	if !d.LastAttemptAt.IsZero() {
+		lastAttemptAt = sqliteTime(d.LastAttemptAt)
+	}
+	res, err := r.conn.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status=?, attempts=?, next_attempt_at=?,
+			last_attempt_at=?, last_status_code=NULLIF(?, 0), last_error=NULLIF(?, '')
+		WHERE id=?`,
+		string(d.Status),
+		d.Attempts,
+		sqliteTime(d.NextAttemptAt),
+		lastAttemptAt,
+		d.LastStatusCode,
+		d.LastError,
+		d.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s:%w", op, application.ErrWebhookDeliveryNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) GetWebhookDelivery(ctx context.Context, webhookId, id string) (application.WebhookDelivery, error) {
+	const op = sqliteOpPrefix + "GetWebhookDelivery"
+
+	row := r.conn.QueryRowContext(ctx,
+		`SELECT `+sqliteDeliveryColumns+` FROM webhook_deliveries WHERE webhook_id=? AND id=?`,
+		webhookId,
+		id,
+	)
+	d, err := scanSQLiteDelivery(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return d, fmt.Errorf("%s:%w", op, application.ErrWebhookDeliveryNotFound)
+		}
+		return d, fmt.Errorf("%s:%w", op, err)
+	}
+	return d, nil
+}
+
+func (r *SQLiteRepository) ListWebhookDeliveries(ctx context.Context, filter application.DeliveryFilter) ([]application.WebhookDelivery, error) {
+	const op = sqliteOpPrefix + "ListWebhookDeliveries"
+
+	rows, err := r.conn.QueryContext(ctx,
+		`SELECT `+sqliteDeliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id=? AND (? = '' OR status=?)
+		ORDER BY created_at DESC, id LIMIT ?`,
+		filter.WebhookId,
+		string(filter.Status),
+		string(filter.Status),
+		filter.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	deliveries, err := collectSQLiteDeliveries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return deliveries, nil
+}
+
+func collectSQLiteDeliveries(rows *sql.Rows) ([]application.WebhookDelivery, error) {
+	defer rows.Close()
+	var deliveries []application.WebhookDelivery
+	for rows.Next() {
+		d, err := scanSQLiteDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanSQLiteDelivery(row rowScanner) (application.WebhookDelivery, error) {
+	var (
+		d              application.WebhookDelivery
+		event, status  string
+		lastAttemptAt  sql.NullString
+		lastStatusCode sql.NullInt64
+		lastError      sql.NullString
+	)
+	err := row.Scan(
+		&d.Id,
+		&d.WebhookId,
+		&d.MessageId,
+		&event,
+		&d.Body,
+		&status,
+		&d.Attempts,
+		(*sqliteTime)(&d.NextAttemptAt),
+		&lastAttemptAt,
+		&lastStatusCode,
+		&lastError,
+		(*sqliteTime)(&d.CreatedAt),
+	)
+	if err != nil {
+		return application.WebhookDelivery{}, err
+	}
+	if lastAttemptAt.Valid {
+		if d.LastAttemptAt, err = time.Parse(sqliteTimeLayout, lastAttemptAt.String); err != nil {
+			return application.WebhookDelivery{}, err
+		}
+	}
+	d.Event = account.EventType(event)
+	d.Status = application.DeliveryStatus(status)
+	d.LastStatusCode = int(lastStatusCode.Int64)
+	d.LastError = lastError.String
+	return d, nil
+}
@@ -0,0 +1,312 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+const webhookColumns = `id, url, events, account_id, low_balance_threshold::text, secret, created_at`
+
+const deliveryColumns = `id, webhook_id, message_id, event, body, status, attempts,
+	next_attempt_at, last_attempt_at, last_status_code, last_error, created_at`
+
+// rowScanner is pgx.Row or pgx.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func (r *Repository) SaveWebhook(ctx context.Context, webhook application.Webhook) error {
+	const op = opPrefix + "SaveWebhook"
+
+	events := make([]string, len(webhook.Events))
+	for i, event := range webhook.Events {
+		events[i] = string(event)
+	}
+	var threshold any
+	if webhook.LowBalanceThreshold != nil {
+		threshold = *webhook.LowBalanceThreshold
+	}
+
+	_, err := r.conn.Exec(ctx,
+		`INSERT INTO webhooks(id, url, events, account_id, low_balance_threshold, secret, created_at)
+		VALUES ($1, $2, $3, NULLIF($4::integer, 0), $5, $6, $7)`,
+		webhook.Id,
+		webhook.URL,
+		events,
+		webhook.AccountId,
+		threshold,
+		webhook.Secret,
+		webhook.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+func (r *Repository) GetWebhook(ctx context.Context, id string) (application.Webhook, error) {
+	const op = opPrefix + "GetWebhook"
+
+	row := r.conn.QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id=$1`, id)
+	webhook, err := scanWebhook(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return webhook, fmt.Errorf("%s:%w", op, application.ErrWebhookNotFound)
+		}
+		return webhook, fmt.Errorf("%s:%w", op, err)
+	}
+	return webhook, nil
+}
+
+func (r *Repository) ListWebhooks(ctx context.Context) ([]application.Webhook, error) {
+	const op = opPrefix + "ListWebhooks"
+
+	rows, err := r.conn.Query(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	defer rows.Close()
+
+	var webhooks []application.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return webhooks, nil
+}
+
+func scanWebhook(row rowScanner) (application.Webhook, error) {
+	var (
+		webhook   application.Webhook
+		events    []string
+		accountId sql.NullInt64
+		threshold sql.NullString
+	)
+	err := row.Scan(
+		&webhook.Id,
+		&webhook.URL,
+		&events,
+		&accountId,
+		&threshold,
+		&webhook.Secret,
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		return application.Webhook{}, err
+	}
+	for _, event := range events {
+		webhook.Events = append(webhook.Events, account.EventType(event))
+	}
+	webhook.AccountId = accountId.Int64
+	if threshold.Valid {
+		money, err := account.ParseMoney(threshold.String)
+		if err != nil {
+			return application.Webhook{}, err
+		}
+		webhook.LowBalanceThreshold = &money
+	}
+	return webhook, nil
+}
+
+func (r *Repository) DeleteWebhook(ctx context.Context, id string) error {
+	const op = opPrefix + "DeleteWebhook"
+
+	tag, err := r.conn.Exec(ctx, `DELETE FROM webhooks WHERE id=$1`, id)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s:%w", op, application.ErrWebhookNotFound)
+	}
+	return nil
+}
+
+// AddWebhookDeliveries drops deliveries of deleted webhooks.
+func (r *Repository) AddWebhookDeliveries(ctx context.Context, deliveries ...application.WebhookDelivery) error {
+	const op = opPrefix + "AddWebhookDeliveries"
+
+	err := r.conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		for _, d := range deliveries {
+			_, err := tx.Exec(ctx,
+				`INSERT INTO webhook_deliveries(id, webhook_id, message_id, event, body, status, next_attempt_at, created_at)
+				SELECT $1, $2, $3, $4, $5, $6, $7, $8
+				WHERE EXISTS (SELECT 1 FROM webhooks WHERE id = $2)
+				ON CONFLICT (webhook_id, message_id, event) DO NOTHING`,
+				d.Id,
+				d.WebhookId,
+				d.MessageId,
+				string(d.Event),
+				d.Body,
+				string(d.Status),
+				d.NextAttemptAt,
+				d.CreatedAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries skips deliveries claimed by concurrent senders.
+func (r *Repository) ClaimWebhookDeliveries(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]application.WebhookDelivery, error) {
+	const op = opPrefix + "ClaimWebhookDeliveries"
+
+	rows, err := r.conn.Query(ctx,
+		`UPDATE webhook_deliveries SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns,
+		now,
+		now.Add(lease),
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	deliveries, err := collectDeliveries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return deliveries, nil
+}
+
+func (r *Repository) SaveWebhookDelivery(ctx context.Context, d application.WebhookDelivery) error {
+	const op = opPrefix + "SaveWebhookDelivery"
+
+	var lastAttemptAt any
+	if !d.LastAttemptAt.IsZero() {
+		lastAttemptAt = d.LastAttemptAt
+	}
+	tag, err := r.conn.Exec(ctx,
+		`UPDATE webhook_deliveries SET status=$2, attempts=$3, next_attempt_at=$4,
+			last_attempt_at=$5, last_status_code=NULLIF($6::integer, 0), last_error=NULLIF($7, '')
+		WHERE id=$1`,
+		d.Id,
+		string(d.Status),
+		d.Attempts,
+		d.NextAttemptAt,
+		lastAttemptAt,
+		d.LastStatusCode,
+		d.LastError,
+	)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s:%w", op, application.ErrWebhookDeliveryNotFound)
+	}
+	return nil
+}
+
+func (r *Repository) GetWebhookDelivery(ctx context.Context, webhookId, id string) (application.WebhookDelivery, error) {
+	const op = opPrefix + "GetWebhookDelivery"
+
+	row := r.conn.QueryRow(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE webhook_id=$1 AND id=$2`,
+		webhookId,
+		id,
+	)
+	d, err := scanDelivery(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return d, fmt.Errorf("%s:%w", op, application.ErrWebhookDeliveryNotFound)
+		}
+		return d, fmt.Errorf("%s:%w", op, err)
+	}
+	return d, nil
+}
+
+func (r *Repository) ListWebhookDeliveries(ctx context.Context, filter application.DeliveryFilter) ([]application.WebhookDelivery, error) {
+	const op = opPrefix + "ListWebhookDeliveries"
+
+	rows, err := r.conn.Query(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id=$1 AND ($2 = '' OR status=$2)
+		ORDER BY created_at DESC, id LIMIT $3`,
+		filter.WebhookId,
+		string(filter.Status),
+		filter.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	deliveries, err := collectDeliveries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return deliveries, nil
+}
+
+func collectDeliveries(rows pgx.Rows) ([]application.WebhookDelivery, error) {
+	defer rows.Close()
+	var deliveries []application.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanDelivery(row rowScanner) (application.WebhookDelivery, error) {
+	var (
+		d              application.WebhookDelivery
+		event, status  string
+		lastAttemptAt  sql.NullTime
+		lastStatusCode sql.NullInt64
+		lastError      sql.NullString
+	)
+	err := row.Scan(
+		&d.Id,
+		&d.WebhookId,
+		&d.MessageId,
+		&event,
+		&d.Body,
+		&status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&lastAttemptAt,
+		&lastStatusCode,
+		&lastError,
+		&d.CreatedAt,
+	)
+	if err != nil {
+		return application.WebhookDelivery{}, err
+	}
+	d.Event = account.EventType(event)
+	d.Status = application.DeliveryStatus(status)
+	d.LastAttemptAt = lastAttemptAt.Time
+	d.LastStatusCode = int(lastStatusCode.Int64)
+	d.LastError = lastError.String
+	return d, nil
+}
@@ -0,0 +1,88 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+// checkAPIKeys is skipped for storages keeping no api keys, like event store,
+// its keys are kept by state repository of the same database.
+func checkAPIKeys(ctx context.Context, b Backend) error {
+	store, ok := b.Storage.(application.APIKeyStore)
+	if !ok {
+		return nil
+	}
+
+	id := "conformance-" + randomId()
+	// times are truncated, storages may keep only microseconds
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	key := application.APIKey{
+		Id:         id,
+		Name:       "conformance",
+		Scopes:     []application.Scope{application.ScopeDeposit, application.ScopeRead},
+		SecretHash: "current-hash",
+		CreatedAt:  now,
+	}
+	if err := store.SaveAPIKey(ctx, key); err != nil {
+		return fmt.Errorf("save api key: %w", err)
+	}
+	got, err := store.GetAPIKey(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get api key: %w", err)
+	}
+	if !equalAPIKeys(got, key) {
+		return fmt.Errorf("loaded api key %+v, want %+v", got, key)
+	}
+
+	rotated := key
+	rotated.SecretHash = "rotated-hash"
+	rotated.PreviousSecretHash = key.SecretHash
+	rotated.PreviousExpiresAt = now.Add(time.Hour)
+	rotated.RotatedAt = now.Add(time.Second)
+	if err := store.SaveAPIKey(ctx, rotated); err != nil {
+		return fmt.Errorf("save rotated api key: %w", err)
+	}
+	if got, err = store.GetAPIKey(ctx, id); err != nil {
+		return fmt.Errorf("get api key: %w", err)
+	}
+	if !equalAPIKeys(got, rotated) {
+		return fmt.Errorf("loaded rotated api key %+v, want %+v", got, rotated)
+	}
+
+	revoked := rotated
+	revoked.PreviousSecretHash, revoked.PreviousExpiresAt = "", time.Time{}
+	revoked.RevokedAt = now.Add(2 * time.Second)
+	if err := store.SaveAPIKey(ctx, revoked); err != nil {
+		return fmt.Errorf("save revoked api key: %w", err)
+	}
+	if got, err = store.GetAPIKey(ctx, id); err != nil {
+		return fmt.Errorf("get api key: %w", err)
+	}
+	if !equalAPIKeys(got, revoked) {
+		return fmt.Errorf("loaded revoked api key %+v, want %+v", got, revoked)
+	}
+
+	keys, err := store.ListAPIKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("list api keys: %w", err)
+	}
+	if !slices.ContainsFunc(keys, func(k application.APIKey) bool { return k.Id == id }) {
+		return errors.New("saved api key isn't listed")
+	}
+	if _, err := store.GetAPIKey(ctx, "conformance-"+randomId()); !errors.Is(err, application.ErrAPIKeyNotFound) {
+		return fmt.Errorf("get unknown api key returned %v, want %v", err, application.ErrAPIKeyNotFound)
+	}
+	return nil
+}
+
+func equalAPIKeys(x, y application.APIKey) bool {
+	return x.Id == y.Id && x.Name == y.Name && slices.Equal(x.Scopes, y.Scopes) &&
+		x.SecretHash == y.SecretHash && x.PreviousSecretHash == y.PreviousSecretHash &&
+		x.PreviousExpiresAt.Equal(y.PreviousExpiresAt) && x.CreatedAt.Equal(y.CreatedAt) &&
+		x.RotatedAt.Equal(y.RotatedAt) && x.RevokedAt.Equal(y.RevokedAt)
+}
@@ -0,0 +1,459 @@
+package conformance
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+func checkNewAccount(ctx context.Context, b Backend) error {
+	first, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	second, err := newAccount(ctx, b, "EUR")
+	if err != nil {
+		return err
+	}
+	if first <= 0 || second <= 0 || first == second {
+		return fmt.Errorf("ids %d and %d must be positive and distinct", first, second)
+	}
+
+	acc, err := b.Storage.GetAccountById(ctx, second)
+	if err != nil {
+		return fmt.Errorf("get account: %w", err)
+	}
+	switch {
+	case acc.Id() != second:
+		return fmt.Errorf("loaded account %d, want %d", acc.Id(), second)
+	case acc.Currency() != "EUR":
+		return fmt.Errorf("currency is %s, want EUR", acc.Currency())
+	case !acc.GetBalance().IsZero() || !acc.CreditLimit().IsZero():
+		return fmt.Errorf("new account has balance %s and credit limit %s", acc.GetBalance(), acc.CreditLimit())
+	case acc.Status() != account.StatusActive:
+		return fmt.Errorf("status is %s, want %s", acc.Status(), account.StatusActive)
+	case len(acc.Holds()) != 0:
+		return fmt.Errorf("new account has %d holds", len(acc.Holds()))
+	}
+
+	entries, err := journal(ctx, b, second)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		return fmt.Errorf("new account has %d journal entries", len(entries))
+	}
+	return nil
+}
+
+func checkNotFound(ctx context.Context, b Backend) error {
+	existing, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	missing := existing + 1_000_000
+
+	if _, err := b.Storage.GetAccountById(ctx, missing); !errors.Is(err, application.ErrAccountNotFound) {
+		return fmt.Errorf("GetAccountById returned %v, want ErrAccountNotFound", err)
+	}
+	if _, err := b.Storage.ListJournalEntries(ctx, application.JournalFilter{AccountId: missing, Limit: 1}); !errors.Is(err, application.ErrAccountNotFound) {
+		return fmt.Errorf("ListJournalEntries returned %v, want ErrAccountNotFound", err)
+	}
+
+	called := false
+	err = b.Acquirer.Acquire(ctx, missing, func(application.BankAccount) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, application.ErrAccountNotFound) {
+		return fmt.Errorf("Acquire returned %v, want ErrAccountNotFound", err)
+	}
+	for _, ids := range [][2]int64{{existing, missing}, {missing, existing}} {
+		err = b.Acquirer.AcquirePair(ctx, ids[0], ids[1], func(application.BankAccount, application.BankAccount) error {
+			called = true
+			return nil
+		})
+		if !errors.Is(err, application.ErrAccountNotFound) {
+			return fmt.Errorf("AcquirePair(%d, %d) returned %v, want ErrAccountNotFound", ids[0], ids[1], err)
+		}
+	}
+	if called {
+		return errors.New("process func called for missing account")
+	}
+	return nil
+}
+
+func checkSaveAndLoad(ctx context.Context, b Backend) error {
+	id, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+
+	err = acquire(ctx, b, id, func(acc application.BankAccount) error {
+		if err := acc.Deposit(account.MustParseMoney("100.50")); err != nil {
+			return err
+		}
+		return acc.Withdraw(account.MustParseMoney("0.50"))
+	})
+	if err != nil {
+		return fmt.Errorf("acquire: %w", err)
+	}
+	if err := expectBalance(ctx, b, id, "100"); err != nil {
+		return err
+	}
+
+	// storage saves account loaded without acquirer as well
+	acc, err := b.Storage.GetAccountById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get account: %w", err)
+	}
+	if err := acc.Deposit(account.MustParseMoney("1")); err != nil {
+		return err
+	}
+	if err := b.Storage.SaveAccounts(ctx, acc); err != nil {
+		return fmt.Errorf("save account: %w", err)
+	}
+	if err := expectBalance(ctx, b, id, "101"); err != nil {
+		return err
+	}
+
+	entries, err := journal(ctx, b, id)
+	if err != nil {
+		return err
+	}
+	want := []struct {
+		opType       account.OperationType
+		amount       string
+		balanceAfter string
+	}{
+		{account.OperationDeposit, "1", "101"},
+		{account.OperationWithdrawal, "0.50", "100"},
+		{account.OperationDeposit, "100.50", "100.50"},
+	}
+	if len(entries) != len(want) {
+		return fmt.Errorf("journal has %d entries, want %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Type != w.opType || !e.Amount.Equal(account.MustParseMoney(w.amount)) ||
+			!e.BalanceAfter.Equal(account.MustParseMoney(w.balanceAfter)) {
+			return fmt.Errorf("journal entry %d is %s %s -> %s, want %s %s -> %s",
+				i, e.Type, e.Amount, e.BalanceAfter, w.opType, w.amount, w.balanceAfter)
+		}
+		if e.AccountId != id || e.Currency != "USD" {
+			return fmt.Errorf("journal entry %d belongs to account %d in %s", i, e.AccountId, e.Currency)
+		}
+		if i > 0 && e.Id >= entries[i-1].Id {
+			return errors.New("journal is not ordered newest first")
+		}
+	}
+
+	page, err := b.Storage.ListJournalEntries(ctx, application.JournalFilter{
+		AccountId: id,
+		BeforeId:  entries[0].Id,
+		Limit:     1,
+		Types:     []account.OperationType{account.OperationDeposit},
+	})
+	if err != nil {
+		return fmt.Errorf("list journal page: %w", err)
+	}
+	if len(page) != 1 || page[0].Id != entries[2].Id {
+		return fmt.Errorf("filtered page has %d entries, want only entry %d", len(page), entries[2].Id)
+	}
+
+	following, err := b.Storage.ListJournalEntries(ctx, application.JournalFilter{
+		AccountId:   id,
+		AfterId:     entries[2].Id,
+		OldestFirst: true,
+		Limit:       10,
+	})
+	if err != nil {
+		return fmt.Errorf("list journal after entry: %w", err)
+	}
+	if len(following) != 2 || following[0].Id != entries[1].Id || following[1].Id != entries[0].Id {
+		return fmt.Errorf("entries after %d aren't the next ones oldest first", entries[2].Id)
+	}
+	return nil
+}
+
+func checkAccountState(ctx context.Context, b Backend) error {
+	id, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	holdId := randomId()
+	err = acquire(ctx, b, id, func(acc application.BankAccount) error {
+		if err := acc.SetCreditLimit(account.MustParseMoney("50")); err != nil {
+			return err
+		}
+		if err := acc.Withdraw(account.MustParseMoney("20")); err != nil {
+			return err
+		}
+		_, err := acc.PlaceHold(holdId, account.MustParseMoney("10"), now, now.Add(time.Minute))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("acquire: %w", err)
+	}
+	err = acquire(ctx, b, id, func(acc application.BankAccount) error {
+		_, err := acc.ChangeStatus(account.StatusFrozen, "conformance check")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("change status: %w", err)
+	}
+
+	acc, err := b.Storage.GetAccountById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get account: %w", err)
+	}
+	switch {
+	case !acc.GetBalance().Equal(account.MustParseMoney("-20")):
+		return fmt.Errorf("balance is %s, want -20", acc.GetBalance())
+	case !acc.CreditLimit().Equal(account.MustParseMoney("50")):
+		return fmt.Errorf("credit limit is %s, want 50", acc.CreditLimit())
+	case acc.Status() != account.StatusFrozen:
+		return fmt.Errorf("status is %s, want %s", acc.Status(), account.StatusFrozen)
+	case !acc.AvailableBalance().Equal(account.MustParseMoney("20")):
+		return fmt.Errorf("available balance is %s, want 20", acc.AvailableBalance())
+	}
+	holds := acc.Holds()
+	if len(holds) != 1 || holds[0].Id() != holdId || holds[0].Status() != account.HoldActive {
+		return fmt.Errorf("account has %d holds, want active hold %s", len(holds), holdId)
+	}
+
+	expired, err := b.Storage.AccountsWithExpiredHolds(ctx, now.Add(2*time.Minute))
+	if err != nil {
+		return fmt.Errorf("accounts with expired holds: %w", err)
+	}
+	if !slices.Contains(expired, id) {
+		return errors.New("account with expired hold is not reported")
+	}
+	expired, err = b.Storage.AccountsWithExpiredHolds(ctx, now)
+	if err != nil {
+		return fmt.Errorf("accounts with expired holds: %w", err)
+	}
+	if slices.Contains(expired, id) {
+		return errors.New("account with unexpired hold is reported")
+	}
+
+	err = acquire(ctx, b, id, func(acc application.BankAccount) error {
+		_, err := acc.ReleaseHold(holdId)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("release hold: %w", err)
+	}
+	acc, err = b.Storage.GetAccountById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get account: %w", err)
+	}
+	if len(acc.Holds()) != 0 {
+		return errors.New("released hold is still active")
+	}
+	return nil
+}
+
+func checkListAccounts(ctx context.Context, b Backend) error {
+	var ids []int64
+	for _, currency := range []account.Currency{"USD", "EUR", "USD"} {
+		id, err := newAccount(ctx, b, currency)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	now := time.Now()
+	err := acquire(ctx, b, ids[1], func(acc application.BankAccount) error {
+		if err := acc.Deposit(account.MustParseMoney("30")); err != nil {
+			return err
+		}
+		if _, err := acc.PlaceHold(randomId(), account.MustParseMoney("10"), now, now.Add(time.Minute)); err != nil {
+			return err
+		}
+		_, err := acc.ChangeStatus(account.StatusFrozen, "conformance check")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("acquire: %w", err)
+	}
+
+	accs, err := b.Storage.ListAccounts(ctx, application.AccountFilter{AfterId: ids[0] - 1, Limit: 2})
+	if err != nil {
+		return fmt.Errorf("list accounts: %w", err)
+	}
+	if len(accs) != 2 || accs[0].Id() != ids[0] || accs[1].Id() != ids[1] {
+		return fmt.Errorf("listed %v, want accounts %v", accountIds(accs), ids[:2])
+	}
+	listed := accs[1]
+	switch {
+	case listed.Currency() != "EUR":
+		return fmt.Errorf("currency is %s, want EUR", listed.Currency())
+	case listed.Status() != account.StatusFrozen:
+		return fmt.Errorf("status is %s, want %s", listed.Status(), account.StatusFrozen)
+	case !listed.AvailableBalance().Equal(account.MustParseMoney("20")):
+		return fmt.Errorf("available balance is %s, want 20", listed.AvailableBalance())
+	}
+
+	accs, err = b.Storage.ListAccounts(ctx, application.AccountFilter{AfterId: ids[1], Limit: 1})
+	if err != nil {
+		return fmt.Errorf("list accounts: %w", err)
+	}
+	if len(accs) != 1 || accs[0].Id() != ids[2] {
+		return fmt.Errorf("listed %v after %d, want account %d", accountIds(accs), ids[1], ids[2])
+	}
+
+	accs, err = b.Storage.ListAccounts(ctx, application.AccountFilter{
+		AfterId: ids[0] - 1,
+		Status:  account.StatusFrozen,
+		Limit:   100,
+	})
+	if err != nil {
+		return fmt.Errorf("list accounts: %w", err)
+	}
+	found := accountIds(accs)
+	if !slices.Contains(found, ids[1]) || slices.Contains(found, ids[0]) || slices.Contains(found, ids[2]) {
+		return fmt.Errorf("listed frozen %v, want %d without %d and %d", found, ids[1], ids[0], ids[2])
+	}
+	return nil
+}
+
+func accountIds(accs []account.Account) []int64 {
+	ids := make([]int64, 0, len(accs))
+	for _, acc := range accs {
+		ids = append(ids, acc.Id())
+	}
+	return ids
+}
+
+func checkSameAccountPair(ctx context.Context, b Backend) error {
+	id, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	err = b.Acquirer.AcquirePair(ctx, id, id, func(application.BankAccount, application.BankAccount) error {
+		return nil
+	})
+	if !errors.Is(err, application.ErrSameAccount) {
+		return fmt.Errorf("AcquirePair returned %v, want ErrSameAccount", err)
+	}
+	return nil
+}
+
+func checkRollback(ctx context.Context, b Backend) error {
+	first, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	second, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	if err := deposit(ctx, b, first, "10"); err != nil {
+		return err
+	}
+
+	err = acquire(ctx, b, first, func(acc application.BankAccount) error {
+		if err := acc.Deposit(account.MustParseMoney("5")); err != nil {
+			return err
+		}
+		return errCheck
+	})
+	if !errors.Is(err, errCheck) {
+		return fmt.Errorf("Acquire returned %v, want error of process func", err)
+	}
+	err = acquirePair(ctx, b, first, second, func(from, to application.BankAccount) error {
+		if err := from.Withdraw(account.MustParseMoney("10")); err != nil {
+			return err
+		}
+		if err := to.Deposit(account.MustParseMoney("10")); err != nil {
+			return err
+		}
+		return errCheck
+	})
+	if !errors.Is(err, errCheck) {
+		return fmt.Errorf("AcquirePair returned %v, want error of process func", err)
+	}
+
+	if err := expectBalance(ctx, b, first, "10"); err != nil {
+		return err
+	}
+	if err := expectBalance(ctx, b, second, "0"); err != nil {
+		return err
+	}
+	for _, id := range []int64{first, second} {
+		entries, err := journal(ctx, b, id)
+		if err != nil {
+			return err
+		}
+		if id == first && len(entries) != 1 || id == second && len(entries) != 0 {
+			return fmt.Errorf("discarded operations of account %d are in journal", id)
+		}
+	}
+
+	// next acquire must see saved state, not one discarded
+	if err := deposit(ctx, b, first, "1"); err != nil {
+		return err
+	}
+	return expectBalance(ctx, b, first, "11")
+}
+
+func checkIdempotency(ctx context.Context, b Backend) error {
+	id, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+
+	key := "conformance-" + randomId()
+	idemCtx := application.WithIdempotency(ctx, application.Idempotency{
+		Key:         key,
+		Fingerprint: "deposit",
+		Render: func(result application.OperationResult) (application.IdempotentResponse, error) {
+			return application.IdempotentResponse{
+				Status: 200,
+				Body:   []byte(result.Balance.Balance.String()),
+			}, nil
+		},
+	})
+
+	if err := deposit(idemCtx, b, id, "7"); err != nil {
+		return fmt.Errorf("first deposit: %w", err)
+	}
+	record, err := b.Storage.GetIdempotencyRecord(ctx, key)
+	if err != nil {
+		return fmt.Errorf("get idempotency record: %w", err)
+	}
+	rendered, err := account.ParseMoney(string(record.Response.Body))
+	if err != nil || record.Fingerprint != "deposit" || record.Response.Status != 200 || !rendered.Equal(account.MustParseMoney("7")) {
+		return fmt.Errorf("record is %+v, want rendered after deposit", record)
+	}
+
+	err = deposit(idemCtx, b, id, "7")
+	if !errors.Is(err, application.ErrIdempotencyKeyConflict) {
+		return fmt.Errorf("second deposit returned %v, want ErrIdempotencyKeyConflict", err)
+	}
+	if err := expectBalance(ctx, b, id, "7"); err != nil {
+		return err
+	}
+
+	if _, err := b.Storage.GetIdempotencyRecord(ctx, "conformance-missing-"+randomId()); !errors.Is(err, application.ErrIdempotencyRecordNotFound) {
+		return fmt.Errorf("GetIdempotencyRecord returned %v, want ErrIdempotencyRecordNotFound", err)
+	}
+	return nil
+}
+
+func randomId() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
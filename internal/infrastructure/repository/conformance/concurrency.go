@@ -0,0 +1,244 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+const (
+	workers          = 8
+	opsPerWorker     = 10
+	lockWaitDeadline = 200 * time.Millisecond
+)
+
+// checkLinearizable runs concurrent deposits and withdrawals, withdrawals may fail only
+// for lack of money. Final balance must match successful operations and journal
+// must be one sequential history of them.
+func checkLinearizable(ctx context.Context, b Backend) error {
+	id, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg          sync.WaitGroup
+		deposits    atomic.Int64
+		withdrawals atomic.Int64
+		errs        = make(chan error, workers*opsPerWorker)
+	)
+	one := account.MustParseMoney("1")
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < opsPerWorker; i++ {
+				withdraw := (w+i)%2 == 1
+				err := acquire(ctx, b, id, func(acc application.BankAccount) error {
+					if withdraw {
+						return acc.Withdraw(one)
+					}
+					return acc.Deposit(one)
+				})
+				switch {
+				case err == nil && withdraw:
+					withdrawals.Add(1)
+				case err == nil:
+					deposits.Add(1)
+				case withdraw && errors.Is(err, account.ErrNotEnoughBalance):
+				default:
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return fmt.Errorf("concurrent operation: %w", err)
+	}
+
+	want := deposits.Load() - withdrawals.Load()
+	if err := expectBalance(ctx, b, id, fmt.Sprint(want)); err != nil {
+		return err
+	}
+
+	entries, err := journal(ctx, b, id)
+	if err != nil {
+		return err
+	}
+	if int64(len(entries)) != deposits.Load()+withdrawals.Load() {
+		return fmt.Errorf("journal has %d entries, want %d successful operations",
+			len(entries), deposits.Load()+withdrawals.Load())
+	}
+	slices.Reverse(entries)
+	balance := account.MustParseMoney("0")
+	for _, e := range entries {
+		switch e.Type {
+		case account.OperationDeposit:
+			balance = balance.Add(e.Amount)
+		case account.OperationWithdrawal:
+			balance = balance.Sub(e.Amount)
+		default:
+			return fmt.Errorf("unexpected journal entry %s", e.Type)
+		}
+		if !e.BalanceAfter.Equal(balance) {
+			return fmt.Errorf("journal entry %d has balance after %s, history gives %s: lost update",
+				e.Id, e.BalanceAfter, balance)
+		}
+		if balance.IsNegative() {
+			return fmt.Errorf("balance went negative at journal entry %d", e.Id)
+		}
+	}
+	return nil
+}
+
+// checkOppositeTransfers moves money both ways between two accounts,
+// pairs locked in different order must not deadlock or lose money.
+func checkOppositeTransfers(ctx context.Context, b Backend) error {
+	first, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	second, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	for _, id := range []int64{first, second} {
+		if err := deposit(ctx, b, id, "100"); err != nil {
+			return err
+		}
+	}
+
+	var (
+		wg   sync.WaitGroup
+		sent [2]atomic.Int64
+		errs = make(chan error, workers*opsPerWorker)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			from, to := first, second
+			if w%2 == 1 {
+				from, to = second, first
+			}
+			for i := 0; i < opsPerWorker; i++ {
+				err := acquirePair(ctx, b, from, to, func(src, dst application.BankAccount) error {
+					t, err := account.NewTransfer(src, dst, account.MustParseMoney("1"), account.OneRate, account.ConversionPolicy{})
+					if err != nil {
+						return err
+					}
+					if err := src.SendTransfer(t); err != nil {
+						return err
+					}
+					return dst.ReceiveTransfer(t)
+				})
+				if err != nil {
+					errs <- err
+					return
+				}
+				sent[w%2].Add(1)
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return fmt.Errorf("concurrent transfer: %w", err)
+	}
+
+	delta := sent[1].Load() - sent[0].Load()
+	if err := expectBalance(ctx, b, first, fmt.Sprint(100+delta)); err != nil {
+		return err
+	}
+	return expectBalance(ctx, b, second, fmt.Sprint(100-delta))
+}
+
+// checkCanceledContext requires canceled context to stop operation before anything is saved.
+func checkCanceledContext(ctx context.Context, b Backend) error {
+	id, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	other, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = b.Acquirer.Acquire(canceled, id, func(acc application.BankAccount) error {
+		return acc.Deposit(account.MustParseMoney("1"))
+	})
+	if !isContextError(err) {
+		return fmt.Errorf("Acquire with canceled context returned %v, want context error", err)
+	}
+	err = b.Acquirer.AcquirePair(canceled, id, other, func(first, second application.BankAccount) error {
+		return first.Deposit(account.MustParseMoney("1"))
+	})
+	if !isContextError(err) {
+		return fmt.Errorf("AcquirePair with canceled context returned %v, want context error", err)
+	}
+	return expectBalance(ctx, b, id, "0")
+}
+
+// checkLockWaitDeadline requires waiting for held account to end at deadline
+// of context with ErrAccountBusy or context error.
+func checkLockWaitDeadline(ctx context.Context, b Backend) error {
+	id, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	holderDone := make(chan error, 1)
+	go func() {
+		holderDone <- acquire(ctx, b, id, func(acc application.BankAccount) error {
+			close(locked)
+			<-release
+			return acc.Deposit(account.MustParseMoney("1"))
+		})
+	}()
+	select {
+	case <-locked:
+	case err := <-holderDone:
+		return fmt.Errorf("holder: %w", err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, lockWaitDeadline)
+	start := time.Now()
+	err = b.Acquirer.Acquire(waitCtx, id, func(acc application.BankAccount) error {
+		return acc.Deposit(account.MustParseMoney("100"))
+	})
+	waited := time.Since(start)
+	cancel()
+	close(release)
+
+	if err := <-holderDone; err != nil {
+		return fmt.Errorf("holder: %w", err)
+	}
+	if !isContextError(err) {
+		return fmt.Errorf("Acquire of held account returned %v, want ErrAccountBusy or context error", err)
+	}
+	if waited > 10*lockWaitDeadline {
+		return fmt.Errorf("Acquire waited %s with deadline %s", waited.Round(time.Millisecond), lockWaitDeadline)
+	}
+	return expectBalance(ctx, b, id, "1")
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, application.ErrAccountBusy)
+}
@@ -0,0 +1,133 @@
+// Package conformance checks contract of account storages and acquirers
+// relied on by application.AccountService. Every backend runs the same checks,
+// from go test or with cmd/conformance.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+// Storage is account storage under check.
+type Storage interface {
+	application.Repository
+	GetAccountById(ctx context.Context, id int64) (account.Account, error)
+	SaveAccounts(ctx context.Context, accs ...account.Account) error
+}
+
+// Backend is storage with acquirer saving into it.
+type Backend struct {
+	Storage  Storage
+	Acquirer application.Acquirer
+}
+
+// TB is part of testing.TB used by Run.
+type TB interface {
+	Helper()
+	Logf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+type Check struct {
+	Name string
+	Run  func(ctx context.Context, b Backend) error
+}
+
+// Checks are independent, each creates its own accounts, so backend may keep data of others.
+var Checks = []Check{
+	{Name: "new account", Run: checkNewAccount},
+	{Name: "account not found", Run: checkNotFound},
+	{Name: "save and load", Run: checkSaveAndLoad},
+	{Name: "status, credit limit and holds", Run: checkAccountState},
+	{Name: "list accounts", Run: checkListAccounts},
+	{Name: "same account pair", Run: checkSameAccountPair},
+	{Name: "rollback on error", Run: checkRollback},
+	{Name: "idempotency record", Run: checkIdempotency},
+	{Name: "outbox", Run: checkOutbox},
+	{Name: "outbox lease", Run: checkOutboxLease},
+	{Name: "outbox retry", Run: checkOutboxRetry},
+	{Name: "webhooks", Run: checkWebhooks},
+	{Name: "api keys", Run: checkAPIKeys},
+	{Name: "concurrent deposits and withdrawals", Run: checkLinearizable},
+	{Name: "concurrent opposite transfers", Run: checkOppositeTransfers},
+	{Name: "canceled context", Run: checkCanceledContext},
+	{Name: "lock wait deadline", Run: checkLockWaitDeadline},
+}
+
+// checkTimeout bounds every check, so deadlocked backend fails instead of hanging.
+const checkTimeout = 30 * time.Second
+
+// Run reports failed checks to t.
+func Run(t TB, b Backend) {
+	t.Helper()
+	for _, check := range Checks {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		start := time.Now()
+		err := check.Run(ctx, b)
+		cancel()
+		if err != nil {
+			t.Errorf("%s: %v", check.Name, err)
+			continue
+		}
+		t.Logf("%s: ok in %s", check.Name, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+var errCheck = errors.New("rollback requested by check")
+
+func newAccount(ctx context.Context, b Backend, currency account.Currency) (int64, error) {
+	id, err := b.Storage.NewAccount(ctx, currency)
+	if err != nil {
+		return 0, fmt.Errorf("new account: %w", err)
+	}
+	return id, nil
+}
+
+// acquire retries ErrAccountBusy, backends may return it under contention.
+func acquire(ctx context.Context, b Backend, id int64, fn application.AccountProcessFunc) error {
+	for {
+		err := b.Acquirer.Acquire(ctx, id, fn)
+		if !errors.Is(err, application.ErrAccountBusy) || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+func acquirePair(ctx context.Context, b Backend, firstId, secondId int64, fn application.PairProcessFunc) error {
+	for {
+		err := b.Acquirer.AcquirePair(ctx, firstId, secondId, fn)
+		if !errors.Is(err, application.ErrAccountBusy) || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+func deposit(ctx context.Context, b Backend, id int64, amount string) error {
+	return acquire(ctx, b, id, func(acc application.BankAccount) error {
+		return acc.Deposit(account.MustParseMoney(amount))
+	})
+}
+
+func expectBalance(ctx context.Context, b Backend, id int64, want string) error {
+	acc, err := b.Storage.GetAccountById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get account %d: %w", id, err)
+	}
+	if !acc.GetBalance().Equal(account.MustParseMoney(want)) {
+		return fmt.Errorf("balance of account %d is %s, want %s", id, acc.GetBalance(), want)
+	}
+	return nil
+}
+
+func journal(ctx context.Context, b Backend, id int64) ([]application.JournalEntry, error) {
+	entries, err := b.Storage.ListJournalEntries(ctx, application.JournalFilter{AccountId: id, Limit: 10000})
+	if err != nil {
+		return nil, fmt.Errorf("list journal of account %d: %w", id, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,120 @@
+package conformance_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/acquire"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/conformance"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/wal"
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/pg"
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/sqlite"
+)
+
+// sqliteMigrations is directory of SQLite migrations relative to this package.
+const sqliteMigrations = "../../../../migrations/sqlite"
+
+const (
+	sqliteBusyTimeout = 5 * time.Second
+	advisoryMaxWait   = 5 * time.Second
+)
+
+func TestMemory(t *testing.T) {
+	repo := accounts.NewInMemory()
+	conformance.Run(t, conformance.Backend{Storage: repo, Acquirer: acquire.NewInMemoryAcquirer(repo)})
+}
+
+func TestMemoryDurable(t *testing.T) {
+	repo, err := accounts.OpenDurableInMemory(filepath.Join(t.TempDir(), "memory"), wal.Options{Sync: wal.SyncBatch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	conformance.Run(t, conformance.Backend{Storage: repo, Acquirer: acquire.NewInMemoryAcquirer(repo)})
+}
+
+func TestSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bank.db")
+	m, err := migrate.New("file://"+sqliteMigrations, sqlite.MigrateConnString(path))
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	m.Close()
+
+	db, err := sqlite.New(context.Background(), sqlite.ConnString(path, sqliteBusyTimeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	repo := accounts.NewSQLiteRepository(db, sqliteBusyTimeout)
+
+	t.Run("repository", func(t *testing.T) {
+		conformance.Run(t, conformance.Backend{Storage: repo, Acquirer: repo})
+	})
+	t.Run("memory", func(t *testing.T) {
+		conformance.Run(t, conformance.Backend{Storage: repo, Acquirer: acquire.NewInMemoryAcquirer(repo)})
+	})
+}
+
+// TestPostgres runs checks against migrated database of TEST_DATABASE_URL, it is skipped without it.
+func TestPostgres(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL isn't set")
+	}
+	ctx := context.Background()
+	db, err := pg.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	for _, strategy := range []accounts.Strategy{
+		accounts.StrategyPessimistic,
+		accounts.StrategySerializable,
+		accounts.StrategyOptimistic,
+	} {
+		concurrency := accounts.DefaultConcurrency
+		concurrency.Strategy = strategy
+		repo := accounts.NewRepository(db, concurrency)
+		t.Run("repository/"+string(strategy), func(t *testing.T) {
+			conformance.Run(t, conformance.Backend{Storage: repo, Acquirer: repo})
+		})
+	}
+
+	repo := accounts.NewRepository(db, accounts.DefaultConcurrency)
+	advisory := acquire.NewAdvisoryAcquirer(db, func(tx pgx.Tx) acquire.AccountStorage {
+		return repo.WithTx(tx)
+	}, nil, advisoryMaxWait)
+	t.Run("advisory", func(t *testing.T) {
+		conformance.Run(t, conformance.Backend{Storage: repo, Acquirer: advisory})
+	})
+	t.Run("memory", func(t *testing.T) {
+		conformance.Run(t, conformance.Backend{Storage: repo, Acquirer: acquire.NewInMemoryAcquirer(repo)})
+	})
+
+	es := accounts.NewEventStore(db, 3, accounts.DefaultConcurrency.MaxRetries)
+	eventsAdvisory := acquire.NewAdvisoryAcquirer(db, func(tx pgx.Tx) acquire.AccountStorage {
+		return es.WithTx(tx)
+	}, nil, advisoryMaxWait)
+	t.Run("events/repository", func(t *testing.T) {
+		conformance.Run(t, conformance.Backend{Storage: es, Acquirer: es})
+	})
+	t.Run("events/advisory", func(t *testing.T) {
+		conformance.Run(t, conformance.Backend{Storage: es, Acquirer: eventsAdvisory})
+	})
+	t.Run("events/memory", func(t *testing.T) {
+		conformance.Run(t, conformance.Backend{Storage: es, Acquirer: acquire.NewInMemoryAcquirer(es)})
+	})
+}
@@ -0,0 +1,302 @@
+package conformance
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+func checkOutbox(ctx context.Context, b Backend) error {
+	first, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	second, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	if err := deposit(ctx, b, first, "10"); err != nil {
+		return err
+	}
+	err = acquire(ctx, b, first, func(acc application.BankAccount) error {
+		if err := acc.Deposit(account.MustParseMoney("5")); err != nil {
+			return err
+		}
+		return errCheck
+	})
+	if !errors.Is(err, errCheck) {
+		return fmt.Errorf("Acquire returned %v, want error of process func", err)
+	}
+	err = acquirePair(ctx, b, first, second, func(from, to application.BankAccount) error {
+		transfer := account.Transfer{
+			FromAccountId:  first,
+			ToAccountId:    second,
+			Debit:          account.MustParseMoney("4"),
+			DebitCurrency:  "USD",
+			Credit:         account.MustParseMoney("4"),
+			CreditCurrency: "USD",
+			Rate:           account.OneRate,
+			MarketRate:     account.OneRate,
+		}
+		if err := from.SendTransfer(transfer); err != nil {
+			return err
+		}
+		return to.ReceiveTransfer(transfer)
+	})
+	if err != nil {
+		return fmt.Errorf("transfer: %w", err)
+	}
+
+	ours := func(m application.OutboxMessage) bool {
+		return m.AccountId == first || m.AccountId == second
+	}
+	messages, err := relayAll(ctx, b, ours)
+	if err != nil {
+		return err
+	}
+	want := map[int64][]account.EventType{
+		first:  {account.EventAccountOpened, account.EventDeposited, account.EventTransferSent},
+		second: {account.EventAccountOpened, account.EventTransferReceived},
+	}
+	for id, types := range want {
+		var got []account.EventType
+		for _, m := range messages {
+			if m.AccountId == id {
+				got = append(got, m.Type)
+			}
+		}
+		if !slices.Equal(got, types) {
+			return fmt.Errorf("messages of account %d are %v, want %v", id, got, types)
+		}
+	}
+	for _, m := range messages {
+		var payload map[string]any
+		if err := json.Unmarshal(m.Payload, &payload); err != nil {
+			return fmt.Errorf("payload of message %d: %w", m.Id, err)
+		}
+		if m.Type == account.EventTransferSent && payload["to_account_id"] != float64(second) {
+			return fmt.Errorf("payload of transfer is %s", m.Payload)
+		}
+	}
+
+	// not acknowledged messages are passed again
+	again, err := relayAll(ctx, b, ours)
+	if err != nil {
+		return err
+	}
+	if !slices.EqualFunc(again, messages, func(x, y application.OutboxMessage) bool { return x.Id == y.Id }) {
+		return fmt.Errorf("not acknowledged messages weren't passed again")
+	}
+	if _, err := relayAll(ctx, b, func(application.OutboxMessage) bool { return false }); err != nil {
+		return err
+	}
+	left, err := relayAll(ctx, b, ours)
+	if err != nil {
+		return err
+	}
+	if len(left) != 0 {
+		return fmt.Errorf("%d acknowledged messages are passed again", len(left))
+	}
+	return nil
+}
+
+// outboxLease is lease of claims made by checks.
+const outboxLease = time.Minute
+
+// relayAll acknowledges all messages except kept ones, which are returned in order of id
+// and released. Messages of other checks are acknowledged too.
+func relayAll(ctx context.Context, b Backend, keep func(application.OutboxMessage) bool) ([]application.OutboxMessage, error) {
+	kept, err := claimOurs(ctx, b, time.Now(), keep)
+	if err != nil {
+		return nil, err
+	}
+	if len(kept) == 0 {
+		return nil, nil
+	}
+	if err := b.Storage.CompleteOutbox(ctx, application.OutboxResult{Released: messageIds(kept)}); err != nil {
+		return nil, fmt.Errorf("complete outbox: %w", err)
+	}
+	return kept, nil
+}
+
+// claimOurs claims messages due at now until messages of other checks are drained,
+// they are acknowledged. Claimed messages of ours are returned in order of id and stay leased.
+func claimOurs(
+	ctx context.Context,
+	b Backend,
+	now time.Time,
+	ours func(application.OutboxMessage) bool,
+) ([]application.OutboxMessage, error) {
+	const limit = 100
+	var claimed []application.OutboxMessage
+	for {
+		messages, err := b.Storage.ClaimOutbox(ctx, now, outboxLease, limit)
+		if err != nil {
+			return nil, fmt.Errorf("claim outbox: %w", err)
+		}
+		if !slices.IsSortedFunc(messages, func(x, y application.OutboxMessage) int { return cmp.Compare(x.Id, y.Id) }) {
+			return nil, errors.New("messages aren't ordered by id")
+		}
+		var result application.OutboxResult
+		for _, m := range messages {
+			if !ours(m) {
+				result.Published = append(result.Published, m.Id)
+				continue
+			}
+			if slices.ContainsFunc(claimed, func(c application.OutboxMessage) bool { return c.Id == m.Id }) {
+				return nil, fmt.Errorf("leased message %d is claimed again", m.Id)
+			}
+			claimed = append(claimed, m)
+		}
+		if len(result.Published) == 0 {
+			return claimed, nil
+		}
+		if err := b.Storage.CompleteOutbox(ctx, result); err != nil {
+			return nil, fmt.Errorf("complete outbox: %w", err)
+		}
+	}
+}
+
+func messageIds(messages []application.OutboxMessage) []int64 {
+	ids := make([]int64, len(messages))
+	for i, m := range messages {
+		ids[i] = m.Id
+	}
+	return ids
+}
+
+func accountMessages(id int64) func(application.OutboxMessage) bool {
+	return func(m application.OutboxMessage) bool { return m.AccountId == id }
+}
+
+// checkOutboxLease checks that leased messages aren't claimed again until lease ends,
+// so messages of crashed relay are published again.
+func checkOutboxLease(ctx context.Context, b Backend) error {
+	id, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	if err := deposit(ctx, b, id, "10"); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	ours := accountMessages(id)
+	claimed, err := claimOurs(ctx, b, now, ours)
+	if err != nil {
+		return err
+	}
+	if len(claimed) != 2 {
+		return fmt.Errorf("claimed %d messages of account, want 2", len(claimed))
+	}
+	again, err := claimOurs(ctx, b, now, ours)
+	if err != nil {
+		return err
+	}
+	if len(again) != 0 {
+		return fmt.Errorf("%d leased messages are claimed again", len(again))
+	}
+
+	// relay crashed without completing claim
+	redelivered, err := claimOurs(ctx, b, now.Add(outboxLease+time.Second), ours)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(messageIds(redelivered), messageIds(claimed)) {
+		return fmt.Errorf("messages %v are claimed after lease, want %v", messageIds(redelivered), messageIds(claimed))
+	}
+	return b.Storage.CompleteOutbox(ctx, application.OutboxResult{Published: messageIds(redelivered)})
+}
+
+// checkOutboxRetry checks that failed message blocks later messages of its account until
+// retry and doesn't block them once it is dead.
+func checkOutboxRetry(ctx context.Context, b Backend) error {
+	id, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	for _, amount := range []string{"1", "2"} {
+		if err := deposit(ctx, b, id, amount); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	ours := accountMessages(id)
+	claimed, err := claimOurs(ctx, b, now, ours)
+	if err != nil {
+		return err
+	}
+	if len(claimed) != 3 {
+		return fmt.Errorf("claimed %d messages of account, want 3", len(claimed))
+	}
+	retryAt := now.Add(time.Hour)
+	err = b.Storage.CompleteOutbox(ctx, application.OutboxResult{
+		Failed:   []application.OutboxFailure{{Id: claimed[0].Id, Attempts: 1, Error: "unavailable", RetryAt: retryAt}},
+		Released: messageIds(claimed[1:]),
+	})
+	if err != nil {
+		return err
+	}
+
+	// later messages wait for retry of failed one, messages of other accounts don't
+	blocked, err := claimOurs(ctx, b, now, ours)
+	if err != nil {
+		return err
+	}
+	if len(blocked) != 0 {
+		return fmt.Errorf("%d messages are claimed before retry of earlier failed message", len(blocked))
+	}
+	other, err := newAccount(ctx, b, "USD")
+	if err != nil {
+		return err
+	}
+	if err := deposit(ctx, b, other, "1"); err != nil {
+		return err
+	}
+	others, err := claimOurs(ctx, b, time.Now(), accountMessages(other))
+	if err != nil {
+		return err
+	}
+	if len(others) != 2 {
+		return fmt.Errorf("failed message of other account blocks %d messages", 2-len(others))
+	}
+	if err := b.Storage.CompleteOutbox(ctx, application.OutboxResult{Published: messageIds(others)}); err != nil {
+		return err
+	}
+
+	retried, err := claimOurs(ctx, b, retryAt, ours)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(messageIds(retried), messageIds(claimed)) {
+		return fmt.Errorf("messages %v are claimed at retry, want %v", messageIds(retried), messageIds(claimed))
+	}
+	if retried[0].Attempts != 1 {
+		return fmt.Errorf("failed message has %d attempts, want 1", retried[0].Attempts)
+	}
+	err = b.Storage.CompleteOutbox(ctx, application.OutboxResult{
+		Failed:   []application.OutboxFailure{{Id: claimed[0].Id, Attempts: 2, Error: "unavailable", RetryAt: retryAt, Dead: true}},
+		Released: messageIds(claimed[1:]),
+	})
+	if err != nil {
+		return err
+	}
+
+	// dead message is never claimed and doesn't block later ones
+	afterDead, err := claimOurs(ctx, b, retryAt.Add(time.Hour), ours)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(messageIds(afterDead), messageIds(claimed[1:])) {
+		return fmt.Errorf("messages %v are claimed after dead one, want %v", messageIds(afterDead), messageIds(claimed[1:]))
+	}
+	return b.Storage.CompleteOutbox(ctx, application.OutboxResult{Published: messageIds(afterDead)})
+}
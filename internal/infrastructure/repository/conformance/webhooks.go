@@ -0,0 +1,155 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+// checkWebhooks is skipped for storages keeping no webhooks, like event store,
+// its webhooks are kept by state repository of the same database.
+func checkWebhooks(ctx context.Context, b Backend) error {
+	store, ok := b.Storage.(application.WebhookStore)
+	if !ok {
+		return nil
+	}
+
+	id := "conformance-" + randomId()
+	threshold := account.MustParseMoney("50.5")
+	// times are truncated, storages may keep only microseconds
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	webhook := application.Webhook{
+		Id:                  id,
+		URL:                 "https://example.com/hook",
+		Events:              []account.EventType{application.EventBalanceLow, account.EventDeposited},
+		AccountId:           42,
+		LowBalanceThreshold: &threshold,
+		Secret:              "conformance-secret",
+		CreatedAt:           now,
+	}
+	if err := store.SaveWebhook(ctx, webhook); err != nil {
+		return fmt.Errorf("save webhook: %w", err)
+	}
+	got, err := store.GetWebhook(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get webhook: %w", err)
+	}
+	if got.URL != webhook.URL || !slices.Equal(got.Events, webhook.Events) || got.AccountId != webhook.AccountId ||
+		got.LowBalanceThreshold == nil || !got.LowBalanceThreshold.Equal(threshold) ||
+		got.Secret != webhook.Secret || !got.CreatedAt.Equal(now) {
+		return fmt.Errorf("loaded webhook %+v, want %+v", got, webhook)
+	}
+	webhooks, err := store.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("list webhooks: %w", err)
+	}
+	if !slices.ContainsFunc(webhooks, func(w application.Webhook) bool { return w.Id == id }) {
+		return errors.New("saved webhook isn't listed")
+	}
+
+	delivery := application.WebhookDelivery{
+		WebhookId:     id,
+		MessageId:     1,
+		Event:         account.EventDeposited,
+		Body:          []byte(`{"id":1}`),
+		Status:        application.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	first, second := delivery, delivery
+	first.Id = randomId()
+	second.Id = randomId()
+	if err := store.AddWebhookDeliveries(ctx, first); err != nil {
+		return fmt.Errorf("add delivery: %w", err)
+	}
+	// the same message is published again
+	if err := store.AddWebhookDeliveries(ctx, second); err != nil {
+		return fmt.Errorf("add delivery again: %w", err)
+	}
+	if _, err := store.GetWebhookDelivery(ctx, id, second.Id); !errors.Is(err, application.ErrWebhookDeliveryNotFound) {
+		return fmt.Errorf("delivery of the same message is added twice, get returned %v", err)
+	}
+
+	ours := func(deliveries []application.WebhookDelivery) []application.WebhookDelivery {
+		return slices.DeleteFunc(deliveries, func(d application.WebhookDelivery) bool { return d.WebhookId != id })
+	}
+	claimed, err := store.ClaimWebhookDeliveries(ctx, now.Add(time.Second), time.Minute, 1000)
+	if err != nil {
+		return fmt.Errorf("claim deliveries: %w", err)
+	}
+	claimed = ours(claimed)
+	if len(claimed) != 1 || claimed[0].Id != first.Id || string(claimed[0].Body) != string(first.Body) {
+		return fmt.Errorf("claimed %+v, want delivery %s", claimed, first.Id)
+	}
+	leased, err := store.ClaimWebhookDeliveries(ctx, now.Add(time.Second), time.Minute, 1000)
+	if err != nil {
+		return fmt.Errorf("claim deliveries: %w", err)
+	}
+	if leased = ours(leased); len(leased) != 0 {
+		return fmt.Errorf("delivery is claimed again before lease ends")
+	}
+	expired, err := store.ClaimWebhookDeliveries(ctx, now.Add(time.Second+time.Minute), time.Minute, 1000)
+	if err != nil {
+		return fmt.Errorf("claim deliveries: %w", err)
+	}
+	if expired = ours(expired); len(expired) != 1 || expired[0].Id != first.Id {
+		return fmt.Errorf("delivery of crashed sender isn't claimed after lease, claimed %+v", expired)
+	}
+
+	dead := claimed[0]
+	dead.Status = application.DeliveryDead
+	dead.Attempts = 3
+	dead.LastAttemptAt = now.Add(2 * time.Second)
+	dead.LastStatusCode = 500
+	dead.LastError = "unexpected response status"
+	if err := store.SaveWebhookDelivery(ctx, dead); err != nil {
+		return fmt.Errorf("save delivery: %w", err)
+	}
+	loaded, err := store.GetWebhookDelivery(ctx, id, dead.Id)
+	if err != nil {
+		return fmt.Errorf("get delivery: %w", err)
+	}
+	if loaded.Status != dead.Status || loaded.Attempts != dead.Attempts || !loaded.LastAttemptAt.Equal(dead.LastAttemptAt) ||
+		loaded.LastStatusCode != dead.LastStatusCode || loaded.LastError != dead.LastError {
+		return fmt.Errorf("loaded delivery %+v, want %+v", loaded, dead)
+	}
+	for status, want := range map[application.DeliveryStatus]int{
+		"":                            1,
+		application.DeliveryDead:      1,
+		application.DeliveryPending:   0,
+		application.DeliveryDelivered: 0,
+	} {
+		listed, err := store.ListWebhookDeliveries(ctx, application.DeliveryFilter{WebhookId: id, Status: status, Limit: 10})
+		if err != nil {
+			return fmt.Errorf("list deliveries: %w", err)
+		}
+		if len(listed) != want {
+			return fmt.Errorf("listed %d deliveries with status %q, want %d", len(listed), status, want)
+		}
+	}
+
+	if err := store.DeleteWebhook(ctx, id); err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	if _, err := store.GetWebhook(ctx, id); !errors.Is(err, application.ErrWebhookNotFound) {
+		return fmt.Errorf("get deleted webhook returned %v, want %v", err, application.ErrWebhookNotFound)
+	}
+	if _, err := store.GetWebhookDelivery(ctx, id, dead.Id); !errors.Is(err, application.ErrWebhookDeliveryNotFound) {
+		return fmt.Errorf("delivery of deleted webhook is kept, get returned %v", err)
+	}
+	if err := store.DeleteWebhook(ctx, id); !errors.Is(err, application.ErrWebhookNotFound) {
+		return fmt.Errorf("delete of deleted webhook returned %v, want %v", err, application.ErrWebhookNotFound)
+	}
+	if err := store.AddWebhookDeliveries(ctx, second); err != nil {
+		return fmt.Errorf("add delivery of deleted webhook: %w", err)
+	}
+	if _, err := store.GetWebhookDelivery(ctx, id, second.Id); !errors.Is(err, application.ErrWebhookDeliveryNotFound) {
+		return fmt.Errorf("delivery of deleted webhook is added, get returned %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+// Package storage opens account storage of backend chosen by config.
+package storage
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/config"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/acquire"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/exchange"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/wal"
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/pg"
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/sqlite"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+// Storage is repository with acquirer and rates provider of chosen backend.
+type Storage struct {
+	Repo   application.Repository
+	Locker application.Acquirer
+	Rates  application.ExchangeRateProvider
+	// Webhooks and APIKeys are kept beside accounts of backend
+	Webhooks application.WebhookStore
+	APIKeys  application.APIKeyStore
+	// Workers run in background until shutdown
+	Workers []func(ctx context.Context)
+	Close   func()
+}
+
+func New(ctx context.Context, cfg config.Config, log logging.Logger) (Storage, error) {
+	switch cfg.StorageBackend {
+	case config.StoragePostgres:
+		return newPostgresStorage(ctx, cfg, log)
+	case config.StorageMemory:
+		st, err := newMemoryStorage(cfg, log)
+		if err != nil {
+			return Storage{}, err
+		}
+		if cfg.Memory.DataDir == "" {
+			log.Info("InitStorage", "memory storage initialized, data is lost on restart")
+		} else {
+			log.Info("InitStorage", "durable memory storage initialized",
+				logging.String("dir", cfg.Memory.DataDir),
+				logging.String("fsync", cfg.Memory.Fsync),
+			)
+		}
+		return st, nil
+	case config.StorageSQLite:
+		st, err := newSQLiteStorage(ctx, cfg)
+		if err != nil {
+			return Storage{}, err
+		}
+		log.Info("InitStorage", "sqlite storage initialized",
+			logging.String("path", cfg.SQLite.Path),
+			logging.String("acquirer", string(cfg.Concurrency.Acquirer)),
+		)
+		return st, nil
+	default:
+		return Storage{}, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
+
+func newPostgresStorage(ctx context.Context, cfg config.Config, log logging.Logger) (Storage, error) {
+	db, err := pg.New(ctx, pg.ConnString(
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.Database,
+		cfg.Database.Host,
+		cfg.Database.Port,
+	))
+	if err != nil {
+		return Storage{}, fmt.Errorf("init postgres: %w", err)
+	}
+
+	if err := db.Ping(ctx); err != nil {
+		db.Close()
+		return Storage{}, fmt.Errorf("ping database: %w", err)
+	}
+
+	st, err := postgresStorage(cfg, db)
+	if err != nil {
+		db.Close()
+		return Storage{}, err
+	}
+	log.Info("InitStorage", "postgres storage initialized",
+		logging.String("acquirer", string(cfg.Concurrency.Acquirer)),
+		logging.String("account_store", string(cfg.Accounts.Store)),
+	)
+	return st, nil
+}
+
+func postgresStorage(cfg config.Config, db *pgxpool.Pool) (Storage, error) {
+	rates, err := newExchangeRateProvider(cfg.Exchange, config.ExchangeProviderPostgres, db)
+	if err != nil {
+		return Storage{}, fmt.Errorf("init exchange rates provider: %w", err)
+	}
+
+	concurrency, err := repositoryConcurrency(cfg.Concurrency)
+	if err != nil {
+		return Storage{}, fmt.Errorf("invalid concurrency config: %w", err)
+	}
+
+	var (
+		repo   postgresRepository
+		withTx acquire.TxStorageFunc
+		// state repository keeps webhooks and api keys for both account stores
+		webhooks = accounts.NewRepository(db, concurrency)
+	)
+	switch cfg.Accounts.Store {
+	case config.AccountStoreState:
+		repo = webhooks
+		withTx = func(tx pgx.Tx) acquire.AccountStorage {
+			return webhooks.WithTx(tx)
+		}
+	case config.AccountStoreEvents:
+		es := accounts.NewEventStore(db, cfg.Accounts.SnapshotEvery, cfg.Concurrency.MaxRetries)
+		repo = es
+		withTx = func(tx pgx.Tx) acquire.AccountStorage {
+			return es.WithTx(tx)
+		}
+	default:
+		return Storage{}, fmt.Errorf("unknown account store %q", cfg.Accounts.Store)
+	}
+
+	locker, err := newAcquirer(cfg.Concurrency, db, repo, withTx)
+	if err != nil {
+		return Storage{}, fmt.Errorf("init acquirer: %w", err)
+	}
+
+	return Storage{
+		Repo:     repo,
+		Locker:   locker,
+		Rates:    rates,
+		Webhooks: webhooks,
+		APIKeys:  webhooks,
+		Close:    db.Close,
+	}, nil
+}
+
+// newMemoryStorage keeps all data in process, only in-memory acquirer can be used.
+// With data dir changes are written to write-ahead log and recovered on start.
+func newMemoryStorage(cfg config.Config, log logging.Logger) (Storage, error) {
+	if cfg.Accounts.Store != config.AccountStoreState {
+		return Storage{}, fmt.Errorf("account store %q requires postgres storage backend", cfg.Accounts.Store)
+	}
+	switch cfg.Concurrency.Acquirer {
+	case config.AcquirerRepository, config.AcquirerMemory:
+	default:
+		return Storage{}, fmt.Errorf("acquirer %q requires postgres storage backend", cfg.Concurrency.Acquirer)
+	}
+
+	rates, err := newExchangeRateProvider(cfg.Exchange, config.ExchangeProviderStatic, nil)
+	if err != nil {
+		return Storage{}, fmt.Errorf("init exchange rates provider: %w", err)
+	}
+
+	if cfg.Memory.DataDir == "" {
+		repo := accounts.NewInMemory()
+		return Storage{
+			Repo:     repo,
+			Locker:   acquire.NewInMemoryAcquirer(repo),
+			Rates:    rates,
+			Webhooks: repo,
+			APIKeys:  repo,
+			Close:    func() {},
+		}, nil
+	}
+
+	policy, err := wal.ParseSyncPolicy(cfg.Memory.Fsync)
+	if err != nil {
+		return Storage{}, fmt.Errorf("invalid memory config: %w", err)
+	}
+	repo, err := accounts.OpenDurableInMemory(cfg.Memory.DataDir, wal.Options{
+		Sync:     policy,
+		Interval: cfg.Memory.FsyncInterval,
+	})
+	if err != nil {
+		return Storage{}, fmt.Errorf("recover memory storage: %w", err)
+	}
+
+	return Storage{
+		Repo:     repo,
+		Locker:   acquire.NewInMemoryAcquirer(repo),
+		Rates:    rates,
+		Webhooks: repo,
+		APIKeys:  repo,
+		Workers: []func(ctx context.Context){
+			func(ctx context.Context) {
+				repo.RunSnapshots(ctx, cfg.Memory.SnapshotInterval)
+			},
+		},
+		Close: func() {
+			if err := repo.Close(); err != nil {
+				log.Error("CloseStorage", "fail close memory storage", err)
+			}
+		},
+	}, nil
+}
+
+// newSQLiteStorage uses database file migrated by migrator, Acquire locks whole database
+// with BEGIN IMMEDIATE, memory acquirer serializes accounts within process before it.
+func newSQLiteStorage(ctx context.Context, cfg config.Config) (Storage, error) {
+	if cfg.Accounts.Store != config.AccountStoreState {
+		return Storage{}, fmt.Errorf("account store %q requires postgres storage backend", cfg.Accounts.Store)
+	}
+	switch cfg.Concurrency.Acquirer {
+	case config.AcquirerRepository, config.AcquirerMemory:
+	default:
+		return Storage{}, fmt.Errorf("acquirer %q requires postgres storage backend", cfg.Concurrency.Acquirer)
+	}
+
+	rates, err := newExchangeRateProvider(cfg.Exchange, config.ExchangeProviderStatic, nil)
+	if err != nil {
+		return Storage{}, fmt.Errorf("init exchange rates provider: %w", err)
+	}
+
+	db, err := sqlite.New(ctx, sqlite.ConnString(cfg.SQLite.Path, cfg.SQLite.BusyTimeout))
+	if err != nil {
+		return Storage{}, fmt.Errorf("open sqlite: %w", err)
+	}
+
+	repo := accounts.NewSQLiteRepository(db, cfg.SQLite.BusyTimeout)
+	var locker application.Acquirer = repo
+	if cfg.Concurrency.Acquirer == config.AcquirerMemory {
+		locker = acquire.NewInMemoryAcquirer(repo)
+	}
+	return Storage{
+		Repo:     repo,
+		Locker:   locker,
+		Rates:    rates,
+		Webhooks: repo,
+		APIKeys:  repo,
+		Close:    func() { db.Close() },
+	}, nil
+}
+
+// newExchangeRateProvider uses fallback if provider isn't set.
+// Static provider without file has no rates.
+func newExchangeRateProvider(
+	cfg config.ExchangeConfig,
+	fallback config.ExchangeProvider,
+	db *pgxpool.Pool,
+) (application.ExchangeRateProvider, error) {
+	provider := cfg.Provider
+	if provider == "" {
+		provider = fallback
+	}
+
+	switch provider {
+	case config.ExchangeProviderPostgres:
+		if db == nil {
+			return nil, fmt.Errorf("exchange rates provider %q requires postgres storage backend", provider)
+		}
+		return exchange.NewPostgresProvider(db), nil
+	case config.ExchangeProviderStatic:
+		if cfg.RatesFile == "" {
+			return exchange.NewStaticProvider(nil)
+		}
+		return exchange.LoadStaticFile(cfg.RatesFile)
+	default:
+		return nil, fmt.Errorf("unknown exchange rates provider %q", provider)
+	}
+}
+
+// postgresRepository is account store usable by every acquirer.
+type postgresRepository interface {
+	application.Repository
+	application.Acquirer
+	acquire.AccountStorage
+}
+
+func newAcquirer(
+	cfg config.ConcurrencyConfig,
+	db *pgxpool.Pool,
+	repo postgresRepository,
+	withTx acquire.TxStorageFunc,
+) (application.Acquirer, error) {
+	switch cfg.Acquirer {
+	case config.AcquirerRepository:
+		return repo, nil
+	case config.AcquirerAdvisory:
+		metrics := acquire.NewLockWaitMetrics()
+		expvar.Publish("account_lock_wait", expvar.Func(func() any {
+			return metrics.Snapshot()
+		}))
+		return acquire.NewAdvisoryAcquirer(db, withTx, metrics, cfg.AdvisoryMaxWait), nil
+	case config.AcquirerMemory:
+		return acquire.NewInMemoryAcquirer(repo), nil
+	default:
+		return nil, fmt.Errorf("unknown acquirer %q", cfg.Acquirer)
+	}
+}
+
+func repositoryConcurrency(cfg config.ConcurrencyConfig) (accounts.Concurrency, error) {
+	strategy, err := accounts.ParseStrategy(cfg.Strategy)
+	if err != nil {
+		return accounts.Concurrency{}, err
+	}
+	return accounts.Concurrency{
+		Strategy:    strategy,
+		NoWait:      cfg.LockNoWait,
+		LockTimeout: cfg.LockTimeout,
+		MaxRetries:  cfg.MaxRetries,
+	}, nil
+}
@@ -0,0 +1,326 @@
+// Package wal is append-only log of records with checksums.
+// Each record has sequence number, so records already included
+// in snapshot are skipped on replay.
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs every record before Append returns.
+	SyncAlways SyncPolicy = "always"
+	// SyncBatch fsyncs in WaitDurable, concurrent waiters share one fsync.
+	// Record is not durable between Append and return of WaitDurable.
+	SyncBatch SyncPolicy = "batch"
+	// SyncInterval fsyncs in background, records of last interval may be lost on crash
+	// after WaitDurable returned.
+	SyncInterval SyncPolicy = "interval"
+)
+
+var (
+	ErrUnknownSyncPolicy = errors.New("unknown wal sync policy")
+	ErrClosed            = errors.New("wal is closed")
+)
+
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch p := SyncPolicy(s); p {
+	case SyncAlways, SyncBatch, SyncInterval:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownSyncPolicy, s)
+	}
+}
+
+type Options struct {
+	Sync SyncPolicy
+	// Interval of background fsync for SyncInterval.
+	Interval time.Duration
+}
+
+// header is length, crc32 of payload and sequence number.
+const headerSize = 4 + 4 + 8
+
+// maxRecordSize protects replay from allocating garbage length.
+const maxRecordSize = 64 << 20
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// syncFile is replaced by tests to count and fail fsyncs.
+var syncFile = (*os.File).Sync
+
+type Log struct {
+	opts Options
+	file *os.File
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	seq  uint64
+	// synced is the last durable sequence number
+	synced  uint64
+	syncing bool
+	// err breaks log after failed write or fsync, so no record follows torn one
+	err error
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Open replays records with sequence number greater than after and opens log for appending.
+// Torn or corrupted tail is truncated.
+func Open(path string, opts Options, after uint64, replay func(data []byte) error) (*Log, error) {
+	if _, err := ParseSyncPolicy(string(opts.Sync)); err != nil {
+		return nil, err
+	}
+
+	_, statErr := os.Stat(path)
+	created := errors.Is(statErr, os.ErrNotExist)
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		if err := syncDir(filepath.Dir(path)); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	last, err := replayFile(file, after, replay)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	l := &Log{
+		opts:   opts,
+		file:   file,
+		seq:    max(last, after),
+		synced: max(last, after),
+	}
+	l.cond = sync.NewCond(&l.mu)
+
+	if opts.Sync == SyncInterval {
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.syncLoop()
+	}
+	return l, nil
+}
+
+// replayFile leaves file offset at the end of last valid record.
+func replayFile(file *os.File, after uint64, replay func(data []byte) error) (uint64, error) {
+	r := bufio.NewReader(file)
+	var (
+		offset int64
+		last   uint64
+		header [headerSize]byte
+	)
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			break
+		}
+		size := binary.LittleEndian.Uint32(header[0:4])
+		sum := binary.LittleEndian.Uint32(header[4:8])
+		seq := binary.LittleEndian.Uint64(header[8:16])
+		if size > maxRecordSize {
+			break
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			break
+		}
+		if crc32.Checksum(data, crcTable) != sum {
+			break
+		}
+
+		if seq > after {
+			if err := replay(data); err != nil {
+				return 0, fmt.Errorf("replay record %d: %w", seq, err)
+			}
+		}
+		last = seq
+		offset += headerSize + int64(size)
+	}
+
+	if err := file.Truncate(offset); err != nil {
+		return 0, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return last, nil
+}
+
+// Append writes record and returns its sequence number.
+// With SyncAlways record is durable on return, otherwise see WaitDurable.
+func (l *Log) Append(data []byte) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	seq := l.seq + 1
+	frame := make([]byte, headerSize+len(data))
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(len(data)))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.Checksum(data, crcTable))
+	binary.LittleEndian.PutUint64(frame[8:16], seq)
+	copy(frame[headerSize:], data)
+
+	if _, err := l.file.Write(frame); err != nil {
+		l.err = fmt.Errorf("write wal: %w", err)
+		return 0, l.err
+	}
+	l.seq = seq
+
+	if l.opts.Sync == SyncAlways {
+		if err := syncFile(l.file); err != nil {
+			l.err = fmt.Errorf("sync wal: %w", err)
+			return 0, l.err
+		}
+		l.synced = seq
+	}
+	return seq, nil
+}
+
+// WaitDurable blocks until record seq is fsynced. It doesn't wait with
+// SyncAlways and SyncInterval policies. Error of failed write or fsync
+// is returned by every following call, since log is broken.
+func (l *Log) WaitDurable(seq uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.opts.Sync != SyncBatch {
+		return l.err
+	}
+	for l.synced < seq {
+		if l.err != nil {
+			return l.err
+		}
+		if l.syncing {
+			l.cond.Wait()
+			continue
+		}
+		l.syncLocked()
+	}
+	return l.err
+}
+
+// syncLocked fsyncs all appended records, lock is released during fsync
+// so concurrent appends and waiters join the next one.
+func (l *Log) syncLocked() {
+	l.syncing = true
+	target := l.seq
+	l.mu.Unlock()
+	err := syncFile(l.file)
+	l.mu.Lock()
+	l.syncing = false
+	if err != nil && l.err == nil {
+		l.err = fmt.Errorf("sync wal: %w", err)
+	}
+	if err == nil {
+		l.synced = max(l.synced, target)
+	}
+	l.cond.Broadcast()
+}
+
+func (l *Log) syncLoop() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+		l.mu.Lock()
+		if l.err == nil && !l.syncing && l.synced < l.seq {
+			l.syncLocked()
+		}
+		l.mu.Unlock()
+	}
+}
+
+// LastSeq returns sequence number of last appended record.
+func (l *Log) LastSeq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq
+}
+
+// Reset removes all records after they were saved in snapshot.
+// Sequence numbers continue to grow.
+func (l *Log) Reset() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.syncing {
+		l.cond.Wait()
+	}
+	if l.err != nil {
+		return l.err
+	}
+
+	if err := l.file.Truncate(0); err != nil {
+		l.err = fmt.Errorf("truncate wal: %w", err)
+		return l.err
+	}
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		l.err = fmt.Errorf("seek wal: %w", err)
+		return l.err
+	}
+	if err := syncFile(l.file); err != nil {
+		l.err = fmt.Errorf("sync wal: %w", err)
+		return l.err
+	}
+	l.synced = l.seq
+	l.cond.Broadcast()
+	return nil
+}
+
+// Close fsyncs appended records and closes file. Repeated Close is no-op.
+func (l *Log) Close() error {
+	if l.stop != nil {
+		l.stopOnce.Do(func() {
+			close(l.stop)
+			<-l.done
+		})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.syncing {
+		l.cond.Wait()
+	}
+	if l.err == ErrClosed {
+		return nil
+	}
+
+	syncErr := syncFile(l.file)
+	closeErr := l.file.Close()
+	l.err = ErrClosed
+	l.cond.Broadcast()
+	return errors.Join(syncErr, closeErr)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,349 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubSync replaces fsync of log files until test ends.
+func stubSync(t *testing.T, sync func(f *os.File) error) {
+	t.Helper()
+	prev := syncFile
+	syncFile = sync
+	t.Cleanup(func() { syncFile = prev })
+}
+
+// openLog opens log of path and returns payloads of replayed records.
+func openLog(t *testing.T, path string, opts Options, after uint64) (*Log, []string) {
+	t.Helper()
+	var replayed []string
+	l, err := Open(path, opts, after, func(data []byte) error {
+		replayed = append(replayed, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l, replayed
+}
+
+// writeRecords writes records to new log of path and returns size of file after each record.
+func writeRecords(t *testing.T, path string, records ...string) []int64 {
+	t.Helper()
+	l, err := Open(path, Options{Sync: SyncAlways}, 0, func([]byte) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sizes []int64
+	for _, r := range records {
+		if _, err := l.Append([]byte(r)); err != nil {
+			t.Fatal(err)
+		}
+		info, err := l.file.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, info.Size())
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return sizes
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name  string
+		after uint64
+		// damage changes log file of records with sizes of file after each record
+		damage       func(t *testing.T, path string, sizes []int64)
+		wantReplayed []string
+		wantLastSeq  uint64
+	}{
+		{
+			name:         "all records",
+			wantReplayed: []string{"one", "two", "three"},
+			wantLastSeq:  3,
+		},
+		{
+			name:         "records included in snapshot are skipped",
+			after:        2,
+			wantReplayed: []string{"three"},
+			wantLastSeq:  3,
+		},
+		{
+			name:        "snapshot newer than log",
+			after:       5,
+			wantLastSeq: 5,
+		},
+		{
+			name: "torn header of tail record",
+			damage: func(t *testing.T, path string, sizes []int64) {
+				truncate(t, path, sizes[1]+headerSize-1)
+			},
+			wantReplayed: []string{"one", "two"},
+			wantLastSeq:  2,
+		},
+		{
+			name: "torn payload of tail record",
+			damage: func(t *testing.T, path string, sizes []int64) {
+				truncate(t, path, sizes[2]-1)
+			},
+			wantReplayed: []string{"one", "two"},
+			wantLastSeq:  2,
+		},
+		{
+			name: "crc mismatch in the middle",
+			damage: func(t *testing.T, path string, sizes []int64) {
+				// flip the first byte of payload of the second record
+				flipByte(t, path, sizes[0]+headerSize)
+			},
+			wantReplayed: []string{"one"},
+			wantLastSeq:  1,
+		},
+		{
+			name: "garbage length",
+			damage: func(t *testing.T, path string, sizes []int64) {
+				// the highest byte of length of the second record
+				flipByte(t, path, sizes[0]+3)
+			},
+			wantReplayed: []string{"one"},
+			wantLastSeq:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal.log")
+			sizes := writeRecords(t, path, "one", "two", "three")
+			if tt.damage != nil {
+				tt.damage(t, path, sizes)
+			}
+
+			l, replayed := openLog(t, path, Options{Sync: SyncAlways}, tt.after)
+			if !slices.Equal(replayed, tt.wantReplayed) {
+				t.Fatalf("replayed %q, want %q", replayed, tt.wantReplayed)
+			}
+			if got := l.LastSeq(); got != tt.wantLastSeq {
+				t.Fatalf("last seq %d, want %d", got, tt.wantLastSeq)
+			}
+
+			// invalid tail is truncated, so next record is readable after reopen
+			seq, err := l.Append([]byte("next"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seq != tt.wantLastSeq+1 {
+				t.Fatalf("next record has seq %d, want %d", seq, tt.wantLastSeq+1)
+			}
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+			_, replayed = openLog(t, path, Options{Sync: SyncAlways}, tt.after)
+			if want := append(slices.Clone(tt.wantReplayed), "next"); !slices.Equal(replayed, want) {
+				t.Fatalf("replayed after reopen %q, want %q", replayed, want)
+			}
+		})
+	}
+}
+
+func truncate(t *testing.T, path string, size int64) {
+	t.Helper()
+	if err := os.Truncate(path, size); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func flipByte(t *testing.T, path string, offset int64) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[offset] ^= 0xff
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplayError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	writeRecords(t, path, "one")
+	errReplay := errors.New("replay failed")
+	_, err := Open(path, Options{Sync: SyncAlways}, 0, func([]byte) error { return errReplay })
+	if !errors.Is(err, errReplay) {
+		t.Fatalf("want %v, got %v", errReplay, err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	l, _ := openLog(t, path, Options{Sync: SyncAlways}, 0)
+	for _, r := range []string{"one", "two"} {
+		if _, err := l.Append([]byte(r)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshotSeq := l.LastSeq()
+	if err := l.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Append([]byte("three")); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, replayed := openLog(t, path, Options{Sync: SyncAlways}, snapshotSeq)
+	if !slices.Equal(replayed, []string{"three"}) {
+		t.Fatalf("replayed %q after reset, want only record after it", replayed)
+	}
+	if got := l.LastSeq(); got != 3 {
+		t.Fatalf("sequence numbers don't continue after reset, last seq %d", got)
+	}
+}
+
+func TestSyncBatchGroupCommit(t *testing.T) {
+	const writers = 20
+	var syncs atomic.Int32
+	stubSync(t, func(f *os.File) error {
+		syncs.Add(1)
+		// slow fsync lets writers join the next one
+		time.Sleep(10 * time.Millisecond)
+		return f.Sync()
+	})
+
+	path := filepath.Join(t.TempDir(), "wal.log")
+	l, _ := openLog(t, path, Options{Sync: SyncBatch}, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			seq, err := l.Append([]byte(fmt.Sprint(i)))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := l.WaitDurable(seq); err != nil {
+				t.Error(err)
+				return
+			}
+			l.mu.Lock()
+			synced := l.synced
+			l.mu.Unlock()
+			if synced < seq {
+				t.Errorf("record %d isn't durable after WaitDurable, synced %d", seq, synced)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := syncs.Load(); got == 0 || got >= writers {
+		t.Fatalf("%d writers made %d fsyncs, want shared fsyncs", writers, got)
+	}
+}
+
+func TestSyncBatchAppendDoesNotSync(t *testing.T) {
+	var syncs atomic.Int32
+	stubSync(t, func(f *os.File) error {
+		syncs.Add(1)
+		return f.Sync()
+	})
+
+	path := filepath.Join(t.TempDir(), "wal.log")
+	l, _ := openLog(t, path, Options{Sync: SyncBatch}, 0)
+	seq, err := l.Append([]byte("one"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := syncs.Load(); got != 0 {
+		t.Fatalf("append made %d fsyncs, want none before WaitDurable", got)
+	}
+	if err := l.WaitDurable(seq); err != nil {
+		t.Fatal(err)
+	}
+	// record is already durable
+	if err := l.WaitDurable(seq); err != nil {
+		t.Fatal(err)
+	}
+	if got := syncs.Load(); got != 1 {
+		t.Fatalf("want one fsync, got %d", got)
+	}
+}
+
+func TestSyncInterval(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "wal.log")
+	l, _ := openLog(t, path, Options{Sync: SyncInterval, Interval: interval}, 0)
+
+	seq, err := l.Append([]byte("one"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// WaitDurable doesn't wait for background fsync
+	if err := l.WaitDurable(seq); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		l.mu.Lock()
+		synced := l.synced
+		l.mu.Unlock()
+		if synced >= seq {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("record isn't fsynced in background, synced %d", synced)
+		}
+		time.Sleep(interval)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("repeated close: %v", err)
+	}
+}
+
+func TestSyncFailureBreaksLog(t *testing.T) {
+	errSync := errors.New("fsync failed")
+	for _, policy := range []SyncPolicy{SyncAlways, SyncBatch} {
+		t.Run(string(policy), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal.log")
+			l, _ := openLog(t, path, Options{Sync: policy}, 0)
+			stubSync(t, func(*os.File) error { return errSync })
+
+			seq, err := l.Append([]byte("one"))
+			if err == nil {
+				err = l.WaitDurable(seq)
+			}
+			if !errors.Is(err, errSync) {
+				t.Fatalf("want %v, got %v", errSync, err)
+			}
+
+			// failure is returned by all following calls, even if fsync works again
+			syncFile = (*os.File).Sync
+			if _, err := l.Append([]byte("two")); !errors.Is(err, errSync) {
+				t.Fatalf("append after failed fsync: want %v, got %v", errSync, err)
+			}
+			if err := l.WaitDurable(0); !errors.Is(err, errSync) {
+				t.Fatalf("wait after failed fsync: want %v, got %v", errSync, err)
+			}
+			if err := l.Reset(); !errors.Is(err, errSync) {
+				t.Fatalf("reset after failed fsync: want %v, got %v", errSync, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package webhook sends deliveries of webhooks over HTTP.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+)
+
+const (
+	HeaderWebhookId  = "X-Webhook-Id"
+	HeaderDeliveryId = "X-Webhook-Delivery"
+	HeaderEvent      = "X-Webhook-Event"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	// HeaderSignature is "sha256=" followed by result of Sign.
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+var ErrUnexpectedStatus = errors.New("unexpected response status")
+
+// Sign returns hex encoded HMAC-SHA256 of timestamp, dot and body. Timestamp is
+// signed, so receivers can reject replayed deliveries by its age.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Client posts deliveries, every attempt is signed with its own timestamp.
+type Client struct {
+	client *http.Client
+}
+
+func NewClient(timeout time.Duration) *Client {
+	return &Client{client: &http.Client{
+		Timeout: timeout,
+		// redirect could send signed body to other host
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func (c *Client) Send(ctx context.Context, webhook application.Webhook, delivery application.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookId, webhook.Id)
+	req.Header.Set(HeaderDeliveryId, delivery.Id)
+	req.Header.Set(HeaderEvent, string(delivery.Event))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(webhook.Secret, timestamp, delivery.Body))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drained body lets connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
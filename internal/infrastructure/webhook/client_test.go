@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+func TestSign(t *testing.T) {
+	// computed independently: HMAC-SHA256 of "1700000000." followed by body
+	const want = "b5eed6f959c6e9d71443b819a0d243a1b61adb22c916a5fcc435fbfce3665fac"
+	got := Sign("whsec-0123456789abcdef", 1700000000, []byte(`{"id":1,"type":"deposited"}`))
+	if got != want {
+		t.Fatalf("signature %s, want %s", got, want)
+	}
+	if Sign("whsec-0123456789abcdef", 1700000001, []byte(`{"id":1,"type":"deposited"}`)) == want {
+		t.Fatal("timestamp isn't signed")
+	}
+}
+
+func TestClientSend(t *testing.T) {
+	webhook := application.Webhook{Id: "hook", Secret: "whsec-0123456789abcdef"}
+	delivery := application.WebhookDelivery{
+		Id:    "delivery",
+		Event: account.EventDeposited,
+		Body:  []byte(`{"id":1}`),
+	}
+
+	tests := []struct {
+		name     string
+		status   int
+		redirect bool
+		wantCode int
+		wantErr  error
+	}{
+		{name: "delivered", status: http.StatusNoContent, wantCode: http.StatusNoContent},
+		{name: "server error", status: http.StatusInternalServerError, wantCode: http.StatusInternalServerError, wantErr: ErrUnexpectedStatus},
+		{name: "redirect isn't followed", redirect: true, wantCode: http.StatusFound, wantErr: ErrUnexpectedStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var redirected bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/other" {
+					redirected = true
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != string(delivery.Body) {
+					t.Errorf("body %s, want %s", body, delivery.Body)
+				}
+				timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+				if err != nil {
+					t.Errorf("invalid timestamp: %v", err)
+				}
+				if got, want := r.Header.Get(HeaderSignature), "sha256="+Sign(webhook.Secret, timestamp, body); got != want {
+					t.Errorf("signature %s, want %s", got, want)
+				}
+				if r.Header.Get(HeaderWebhookId) != webhook.Id || r.Header.Get(HeaderDeliveryId) != delivery.Id ||
+					r.Header.Get(HeaderEvent) != string(delivery.Event) {
+					t.Errorf("unexpected headers %v", r.Header)
+				}
+				if tt.redirect {
+					http.Redirect(w, r, "/other", http.StatusFound)
+					return
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			webhook := webhook
+			webhook.URL = server.URL
+			code, err := NewClient(time.Second).Send(context.Background(), webhook, delivery)
+			if code != tt.wantCode {
+				t.Errorf("status code %d, want %d", code, tt.wantCode)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("want error %v, got %v", tt.wantErr, err)
+			}
+			if redirected {
+				t.Error("signed delivery is sent to redirect location")
+			}
+		})
+	}
+}
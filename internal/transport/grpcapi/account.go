@@ -0,0 +1,208 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/vitaliy-ukiru/bank-service/api/bankpb"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Usecase is part of controllers.Usecase served over gRPC.
+type Usecase interface {
+	CreateAccount(ctx context.Context, cmd application.CreateAccountCommand) (int64, error)
+	DepositBalance(ctx context.Context, cmd application.DepositBalanceCommand) error
+	WithdrawBalance(ctx context.Context, cmd application.WithdrawBalanceCommand) error
+	GetBalance(ctx context.Context, cmd application.GetBalanceCommand) (application.AccountBalance, error)
+	LookupIdempotentResponse(ctx context.Context, key, fingerprint string) (application.IdempotentResponse, bool, error)
+}
+
+type BalanceStreamer interface {
+	Subscribe(ctx context.Context, accountId int64, lastEventId int64) (*application.BalanceSubscription, error)
+}
+
+type AccountServer struct {
+	bankpb.UnimplementedAccountServiceServer
+	uc     Usecase
+	stream BalanceStreamer
+}
+
+func NewAccountServer(uc Usecase, stream BalanceStreamer) *AccountServer {
+	return &AccountServer{uc: uc, stream: stream}
+}
+
+func (a *AccountServer) CreateAccount(ctx context.Context, req *bankpb.CreateAccountRequest) (*bankpb.CreateAccountResponse, error) {
+	if err := application.AuthorizeAllAccounts(ctx); err != nil {
+		return nil, statusError(err)
+	}
+	currency, err := parseCurrency(req.GetCurrency())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	accountId, err := a.uc.CreateAccount(ctx, application.CreateAccountCommand{
+		Currency: currency,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &bankpb.CreateAccountResponse{Id: accountId}, nil
+}
+
+func (a *AccountServer) Deposit(ctx context.Context, req *bankpb.DepositRequest) (*bankpb.DepositResponse, error) {
+	if err := application.AuthorizeAccount(ctx, req.GetAccountId()); err != nil {
+		return nil, statusError(err)
+	}
+	amount, currency, err := parseAmount(req.GetAmount(), req.GetCurrency())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := &bankpb.DepositResponse{}
+	ctx, replayed, err := a.idempotency(ctx, req, resp)
+	if err != nil || replayed {
+		return resp, err
+	}
+
+	err = a.uc.DepositBalance(ctx, application.DepositBalanceCommand{
+		AccountId: req.GetAccountId(),
+		Amount:    amount,
+		Currency:  currency,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return resp, nil
+}
+
+func (a *AccountServer) Withdraw(ctx context.Context, req *bankpb.WithdrawRequest) (*bankpb.WithdrawResponse, error) {
+	if err := application.AuthorizeAccount(ctx, req.GetAccountId()); err != nil {
+		return nil, statusError(err)
+	}
+	amount, currency, err := parseAmount(req.GetAmount(), req.GetCurrency())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := &bankpb.WithdrawResponse{}
+	ctx, replayed, err := a.idempotency(ctx, req, resp)
+	if err != nil || replayed {
+		return resp, err
+	}
+
+	err = a.uc.WithdrawBalance(ctx, application.WithdrawBalanceCommand{
+		AccountId: req.GetAccountId(),
+		Amount:    amount,
+		Currency:  currency,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return resp, nil
+}
+
+func (a *AccountServer) GetBalance(ctx context.Context, req *bankpb.GetBalanceRequest) (*bankpb.Balance, error) {
+	if err := application.AuthorizeAccount(ctx, req.GetAccountId()); err != nil {
+		return nil, statusError(err)
+	}
+	balance, err := a.uc.GetBalance(ctx, application.GetBalanceCommand{
+		AccountId: req.GetAccountId(),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &bankpb.Balance{
+		Balance:          balance.Balance.String(),
+		AvailableBalance: balance.Available.String(),
+		CreditLimit:      balance.CreditLimit.String(),
+		CreditHeadroom:   balance.CreditHeadroom.String(),
+		Currency:         string(balance.Currency),
+		Status:           string(balance.Status),
+	}, nil
+}
+
+// WatchBalance ends with Unavailable when watcher doesn't keep up with entries
+// or server shuts down, watcher resumes with id of the last received entry.
+func (a *AccountServer) WatchBalance(req *bankpb.WatchBalanceRequest, srv bankpb.AccountService_WatchBalanceServer) error {
+	if req.GetLastEventId() < 0 {
+		return status.Error(codes.InvalidArgument, "invalid last event id")
+	}
+
+	ctx := srv.Context()
+	if err := application.AuthorizeAccount(ctx, req.GetAccountId()); err != nil {
+		return statusError(err)
+	}
+	sub, err := a.stream.Subscribe(ctx, req.GetAccountId(), req.GetLastEventId())
+	if err != nil {
+		return statusError(err)
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry, ok := <-sub.Events():
+			if !ok {
+				return status.Error(codes.Unavailable, "balance stream is closed, resume with last event id")
+			}
+			if err := srv.Send(transaction(entry)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func transaction(entry application.JournalEntry) *bankpb.Transaction {
+	return &bankpb.Transaction{
+		Id:           entry.Id,
+		AccountId:    entry.AccountId,
+		Type:         string(entry.Type),
+		Currency:     string(entry.Currency),
+		Amount:       entry.Amount.String(),
+		BalanceAfter: entry.BalanceAfter.String(),
+		RequestId:    entry.RequestId,
+		CreatedAt:    timestamppb.New(entry.CreatedAt),
+		Transfer:     transfer(entry.Transfer),
+		HoldId:       entry.HoldId,
+	}
+}
+
+// transfer returns nil for nil transfer.
+func transfer(t *account.Transfer) *bankpb.Transfer {
+	if t == nil {
+		return nil
+	}
+	return &bankpb.Transfer{
+		FromAccountId:  t.FromAccountId,
+		ToAccountId:    t.ToAccountId,
+		Debit:          t.Debit.String(),
+		DebitCurrency:  string(t.DebitCurrency),
+		Credit:         t.Credit.String(),
+		CreditCurrency: string(t.CreditCurrency),
+		Rate:           t.Rate.String(),
+		MarketRate:     t.MarketRate.String(),
+		Spread:         t.Spread.String(),
+	}
+}
+
+// parseCurrency returns empty currency for empty code.
+func parseCurrency(code string) (account.Currency, error) {
+	if code == "" {
+		return "", nil
+	}
+	return account.ParseCurrency(code)
+}
+
+func parseAmount(amount string, currency string) (account.Money, account.Currency, error) {
+	money, err := account.ParseMoney(amount)
+	if err != nil {
+		return account.Money{}, "", err
+	}
+	c, err := parseCurrency(currency)
+	if err != nil {
+		return account.Money{}, "", err
+	}
+	return money, c, nil
+}
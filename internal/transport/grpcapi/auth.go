@@ -0,0 +1,103 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/vitaliy-ukiru/bank-service/api/bankpb"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// apiKeyKey is metadata key of API key, the same as X-API-Key of HTTP API.
+	apiKeyKey = "x-api-key"
+	// authorizationKey is metadata key of "Bearer <token>".
+	authorizationKey = "authorization"
+)
+
+// Authenticator resolves API key or bearer token into principal,
+// it returns application.ErrUnauthenticated for invalid credentials.
+type Authenticator interface {
+	Authenticate(ctx context.Context, creds application.Credentials) (application.Principal, error)
+}
+
+// methodScopes are scopes of methods, the same as scopes of routes of HTTP API.
+var methodScopes = map[string]application.Scope{
+	bankpb.AccountService_CreateAccount_FullMethodName: application.ScopeDeposit,
+	bankpb.AccountService_Deposit_FullMethodName:       application.ScopeDeposit,
+	bankpb.AccountService_Withdraw_FullMethodName:      application.ScopeWithdraw,
+	bankpb.AccountService_GetBalance_FullMethodName:    application.ScopeRead,
+	bankpb.AccountService_WatchBalance_FullMethodName:  application.ScopeRead,
+}
+
+// publicServices are health checks and reflection, they don't require key.
+var publicServices = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.",
+}
+
+// authenticate puts principal on context of call, methods missing in methodScopes require admin scope.
+func authenticate(ctx context.Context, auth Authenticator, method string) (context.Context, error) {
+	for _, prefix := range publicServices {
+		if strings.HasPrefix(method, prefix) {
+			return ctx, nil
+		}
+	}
+	scope, ok := methodScopes[method]
+	if !ok {
+		scope = application.ScopeAdmin
+	}
+
+	var creds application.Credentials
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(apiKeyKey); len(values) != 0 {
+			creds.APIKey = values[0]
+		}
+		if values := md.Get(authorizationKey); len(values) != 0 {
+			scheme, token, ok := strings.Cut(values[0], " ")
+			if ok && strings.EqualFold(scheme, "Bearer") {
+				creds.BearerToken = strings.TrimSpace(token)
+			}
+		}
+	}
+	principal, err := auth.Authenticate(ctx, creds)
+	if errors.Is(err, application.ErrUnauthenticated) {
+		return ctx, status.Error(codes.Unauthenticated, application.ErrUnauthenticated.Error())
+	}
+	if err != nil {
+		return ctx, status.Error(codes.Internal, unknownError)
+	}
+
+	log := logging.FromContext(ctx).With(principal.LogAttr())
+	ctx = logging.Context(application.ContextWithPrincipal(ctx, principal), log)
+	if !principal.Allows(scope) {
+		return ctx, status.Errorf(codes.PermissionDenied, "%s: %s scope is required", application.ErrPermissionDenied, scope)
+	}
+	return ctx, nil
+}
+
+func unaryAuthInterceptor(auth Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, auth, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuthInterceptor(auth Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), auth, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
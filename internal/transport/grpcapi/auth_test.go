@@ -0,0 +1,117 @@
+package grpcapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vitaliy-ukiru/bank-service/api/bankpb"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// scopeAuthenticator treats API key or bearer token as name of the only scope of principal.
+type scopeAuthenticator struct{}
+
+func (scopeAuthenticator) Authenticate(_ context.Context, creds application.Credentials) (application.Principal, error) {
+	name := creds.APIKey
+	if name == "" {
+		name = creds.BearerToken
+	}
+	switch scope := application.Scope(name); scope {
+	case application.ScopeRead, application.ScopeDeposit, application.ScopeWithdraw, application.ScopeAdmin:
+		return application.Principal{KeyId: name, Name: name, Scopes: []application.Scope{scope}}, nil
+	}
+	return application.Principal{}, application.ErrUnauthenticated
+}
+
+func TestAuthInterceptorScopes(t *testing.T) {
+	tests := []struct {
+		method string
+		// scope is empty for public methods
+		scope application.Scope
+	}{
+		{bankpb.AccountService_CreateAccount_FullMethodName, application.ScopeDeposit},
+		{bankpb.AccountService_Deposit_FullMethodName, application.ScopeDeposit},
+		{bankpb.AccountService_Withdraw_FullMethodName, application.ScopeWithdraw},
+		{bankpb.AccountService_GetBalance_FullMethodName, application.ScopeRead},
+		{bankpb.AccountService_WatchBalance_FullMethodName, application.ScopeRead},
+		// methods without scope require admin
+		{"/bank.v1.AccountService/Unknown", application.ScopeAdmin},
+		{"/grpc.health.v1.Health/Check", ""},
+		{"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", ""},
+	}
+	credentials := []struct {
+		name string
+		md   metadata.MD
+		// scope of principal, empty for missing or invalid credentials
+		scope application.Scope
+	}{
+		{"without credentials", nil, ""},
+		{"invalid key", metadata.Pairs(apiKeyKey, "invalid"), ""},
+		{"read key", metadata.Pairs(apiKeyKey, "read"), application.ScopeRead},
+		{"deposit key", metadata.Pairs(apiKeyKey, "deposit"), application.ScopeDeposit},
+		{"withdraw token", metadata.Pairs(authorizationKey, "Bearer withdraw"), application.ScopeWithdraw},
+		{"admin token", metadata.Pairs(authorizationKey, "bearer admin"), application.ScopeAdmin},
+	}
+	interceptor := unaryAuthInterceptor(scopeAuthenticator{})
+	for _, tt := range tests {
+		for _, creds := range credentials {
+			t.Run(tt.method+" "+creds.name, func(t *testing.T) {
+				ctx := metadata.NewIncomingContext(context.Background(), creds.md)
+				var principal *application.Principal
+				_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method},
+					func(ctx context.Context, _ any) (any, error) {
+						if p, ok := application.PrincipalFromContext(ctx); ok {
+							principal = &p
+						}
+						return nil, nil
+					})
+
+				want := codes.OK
+				switch {
+				case tt.scope == "":
+				case creds.scope == "":
+					want = codes.Unauthenticated
+				case creds.scope != tt.scope && creds.scope != application.ScopeAdmin:
+					want = codes.PermissionDenied
+				}
+				if got := status.Code(err); got != want {
+					t.Fatalf("code %s, want %s: %v", got, want, err)
+				}
+				if want == codes.OK && tt.scope != "" && (principal == nil || principal.Name != string(creds.scope)) {
+					t.Fatalf("handler got principal %+v of %s", principal, creds.scope)
+				}
+			})
+		}
+	}
+}
+
+func TestStreamAuthInterceptor(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(apiKeyKey, "read"))
+	info := &grpc.StreamServerInfo{FullMethod: bankpb.AccountService_WatchBalance_FullMethodName}
+	var principal application.Principal
+	err := streamAuthInterceptor(scopeAuthenticator{})(nil, &contextStream{ctx: ctx}, info,
+		func(_ any, ss grpc.ServerStream) error {
+			principal, _ = application.PrincipalFromContext(ss.Context())
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.Name != "read" {
+		t.Fatalf("stream has principal %+v", principal)
+	}
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(apiKeyKey, "deposit"))
+	err = streamAuthInterceptor(scopeAuthenticator{})(nil, &contextStream{ctx: ctx}, info,
+		func(any, grpc.ServerStream) error {
+			t.Fatal("handler is called without scope")
+			return nil
+		})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("want PermissionDenied, got %v", err)
+	}
+}
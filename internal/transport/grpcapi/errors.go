@@ -0,0 +1,78 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const unknownError = "unknown error occurred"
+
+// statusError maps errors of service to status codes the same way
+// as HTTP API maps them to status codes of response.
+func statusError(err error) error {
+	msg := unknownError
+	if unwrapped := errors.Unwrap(err); unwrapped != nil {
+		msg = unwrapped.Error()
+	}
+
+	if errors.Is(err, application.ErrAccountNotFound) ||
+		errors.Is(err, account.ErrHoldNotFound) {
+		return status.Error(codes.NotFound, msg)
+	}
+
+	if errors.Is(err, account.ErrNegativeAmount) ||
+		errors.Is(err, account.ErrZeroAmount) ||
+		errors.Is(err, account.ErrInvalidMoney) ||
+		errors.Is(err, application.ErrSameAccount) ||
+		errors.Is(err, application.ErrInvalidCursor) ||
+		errors.Is(err, account.ErrUnknownCurrency) ||
+		errors.Is(err, account.ErrExcessPrecision) ||
+		errors.Is(err, account.ErrCaptureExceedsHold) ||
+		errors.Is(err, account.ErrUnknownStatus) ||
+		errors.Is(err, account.ErrReasonRequired) ||
+		errors.Is(err, account.ErrNegativeCreditLimit) ||
+		errors.Is(err, account.ErrCurrencyMismatch) ||
+		errors.Is(err, application.ErrIdempotencyKeyReused) {
+		return status.Error(codes.InvalidArgument, msg)
+	}
+
+	if errors.Is(err, account.ErrAccountFrozen) ||
+		errors.Is(err, account.ErrAccountClosed) ||
+		errors.Is(err, account.ErrNotEnoughBalance) ||
+		errors.Is(err, account.ErrHoldExpired) ||
+		errors.Is(err, account.ErrInvalidStatusTransition) ||
+		errors.Is(err, account.ErrNonZeroBalance) ||
+		errors.Is(err, account.ErrActiveHolds) ||
+		errors.Is(err, account.ErrCreditLimitInUse) ||
+		errors.Is(err, application.ErrExchangeRateNotFound) {
+		return status.Error(codes.FailedPrecondition, msg)
+	}
+
+	if errors.Is(err, application.ErrPermissionDenied) {
+		return status.Error(codes.PermissionDenied, msg)
+	}
+
+	// operation may succeed on retry, call with used key is replayed
+	if errors.Is(err, application.ErrAccountBusy) ||
+		errors.Is(err, application.ErrIdempotencyKeyConflict) {
+		return status.Error(codes.Aborted, msg)
+	}
+
+	if errors.Is(err, application.ErrStreamClosed) {
+		return status.Error(codes.Unavailable, msg)
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	return status.Error(codes.Internal, unknownError)
+}
@@ -0,0 +1,55 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{application.ErrAccountNotFound, codes.NotFound},
+		{account.ErrHoldNotFound, codes.NotFound},
+		{account.ErrZeroAmount, codes.InvalidArgument},
+		{account.ErrInvalidMoney, codes.InvalidArgument},
+		{account.ErrCurrencyMismatch, codes.InvalidArgument},
+		{application.ErrIdempotencyKeyReused, codes.InvalidArgument},
+		{account.ErrNotEnoughBalance, codes.FailedPrecondition},
+		{account.ErrAccountFrozen, codes.FailedPrecondition},
+		{account.ErrAccountClosed, codes.FailedPrecondition},
+		{application.ErrExchangeRateNotFound, codes.FailedPrecondition},
+		{application.ErrPermissionDenied, codes.PermissionDenied},
+		{application.ErrAccountBusy, codes.Aborted},
+		{application.ErrIdempotencyKeyConflict, codes.Aborted},
+		{application.ErrStreamClosed, codes.Unavailable},
+		{context.Canceled, codes.Canceled},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{errors.New("connection refused"), codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			// services wrap errors with operation
+			st := status.Convert(statusError(fmt.Errorf("%s: %w", "Operation", tt.err)))
+			if st.Code() != tt.want {
+				t.Fatalf("code %s, want %s", st.Code(), tt.want)
+			}
+			// message of unknown error isn't exposed
+			if tt.want == codes.Internal && st.Message() != unknownError {
+				t.Fatalf("internal error has message %q", st.Message())
+			}
+			if tt.want != codes.Internal && !strings.Contains(st.Message(), tt.err.Error()) {
+				t.Fatalf("message %q doesn't describe %q", st.Message(), tt.err.Error())
+			}
+		})
+	}
+}
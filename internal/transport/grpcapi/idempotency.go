@@ -0,0 +1,86 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// idempotencyKeyKey is metadata key of idempotency key, the same as Idempotency-Key of HTTP API.
+	idempotencyKeyKey = "idempotency-key"
+	// idempotentReplayedKey is header of replayed response, the same as Idempotent-Replayed of HTTP API.
+	idempotentReplayedKey   = "idempotent-replayed"
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotency prepares call with idempotency-key metadata, call must be authorized
+// and its request parsed before. Keys are scoped by principal like keys of HTTP API.
+// If response for the key is already stored, it is unmarshalled into resp and replayed is true.
+// Otherwise, returned context makes storage save resp together with changes of operation.
+func (a *AccountServer) idempotency(ctx context.Context, req, resp proto.Message) (_ context.Context, replayed bool, err error) {
+	var key string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(idempotencyKeyKey); len(values) != 0 {
+			key = values[0]
+		}
+	}
+	if key == "" {
+		return ctx, false, nil
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return ctx, false, status.Errorf(codes.InvalidArgument, "%s metadata is too long", idempotencyKeyKey)
+	}
+
+	fingerprint, err := callFingerprint(ctx, req)
+	if err != nil {
+		return ctx, false, status.Error(codes.Internal, unknownError)
+	}
+	stored, found, err := a.uc.LookupIdempotentResponse(ctx, key, fingerprint)
+	if err != nil {
+		return ctx, false, statusError(err)
+	}
+	if found {
+		if err := proto.Unmarshal(stored.Body, resp); err != nil {
+			return ctx, false, status.Error(codes.Internal, unknownError)
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(idempotentReplayedKey, "true"))
+		return ctx, true, nil
+	}
+
+	ctx = application.WithIdempotency(ctx, application.Idempotency{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Render: func(application.OperationResult) (application.IdempotentResponse, error) {
+			body, err := proto.Marshal(resp)
+			if err != nil {
+				return application.IdempotentResponse{}, err
+			}
+			return application.IdempotentResponse{Status: int(codes.OK), Body: body}, nil
+		},
+	})
+	return ctx, false, nil
+}
+
+// callFingerprint hashes full method and request, so key of HTTP request
+// or other method isn't replayed.
+func callFingerprint(ctx context.Context, req proto.Message) (string, error) {
+	method, _ := grpc.Method(ctx)
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
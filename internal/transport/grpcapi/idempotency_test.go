@@ -0,0 +1,121 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/api/bankpb"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/config"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/acquire"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/exchange"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves account service without authentication over in-memory connection.
+func newTestClient(t *testing.T) (bankpb.AccountServiceClient, *application.AccountService) {
+	t.Helper()
+	repo := accounts.NewInMemory()
+	rates, err := exchange.NewStaticProvider(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := application.NewAccountService(
+		acquire.NewInMemoryAcquirer(repo),
+		repo,
+		rates,
+		account.ConversionPolicy{Rounding: account.RoundHalfEven},
+		time.Hour,
+		account.StatusPolicy{},
+		time.Hour,
+	)
+	server := New(config.Config{}, NewAccountServer(service, nil), nil, logging.Discard())
+
+	lis := bufconn.Listen(1 << 20)
+	go server.srv.Serve(lis)
+	t.Cleanup(server.srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return bankpb.NewAccountServiceClient(conn), service
+}
+
+func TestWithdrawIdempotencyKey(t *testing.T) {
+	client, service := newTestClient(t)
+	ctx := context.Background()
+	id, err := service.CreateAccount(ctx, application.CreateAccountCommand{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.SetCreditLimit(ctx, application.SetCreditLimitCommand{
+		AccountId:   id,
+		CreditLimit: account.MustParseMoney("100"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withdraw := func(key, amount string) (metadata.MD, error) {
+		t.Helper()
+		ctx := ctx
+		if key != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, idempotencyKeyKey, key)
+		}
+		var header metadata.MD
+		_, err := client.Withdraw(ctx, &bankpb.WithdrawRequest{AccountId: id, Amount: amount}, grpc.Header(&header))
+		return header, err
+	}
+	balance := func() string {
+		t.Helper()
+		resp, err := client.GetBalance(ctx, &bankpb.GetBalanceRequest{AccountId: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.GetBalance()
+	}
+
+	header, err := withdraw("key", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(header.Get(idempotentReplayedKey)) != 0 {
+		t.Fatal("the first call is replayed")
+	}
+
+	header, err = withdraw("key", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get(idempotentReplayedKey); len(got) != 1 || got[0] != "true" {
+		t.Fatalf("repeated call isn't replayed, header %v", header)
+	}
+	if got := balance(); got != "-10.00" {
+		t.Fatalf("repeated call is applied, balance %s", got)
+	}
+
+	if _, err := withdraw("key", "20"); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("key reused with other amount: want InvalidArgument, got %v", err)
+	}
+	if _, err := withdraw("", "10"); err != nil {
+		t.Fatal(err)
+	}
+	if got := balance(); got != "-20.00" {
+		t.Fatalf("call without key isn't applied, balance %s", got)
+	}
+}
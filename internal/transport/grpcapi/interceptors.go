@@ -0,0 +1,91 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/labstack/gommon/random"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIdKey is metadata key of request id, the same as X-Request-Id of HTTP API.
+const requestIdKey = "x-request-id"
+
+// callContext wraps context of call with logger and request id like middlewares of HTTP API,
+// request id is taken from metadata or generated and sent back in header.
+func callContext(ctx context.Context, log logging.Logger) context.Context {
+	var reqId string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIdKey); len(values) != 0 {
+			reqId = values[0]
+		}
+	}
+	if reqId == "" {
+		reqId = random.String(32)
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIdKey, reqId))
+
+	ctx = logging.Context(ctx, log.With(logging.String("request_id", reqId)))
+	return requestid.Context(ctx, reqId)
+}
+
+// logCall logs server errors as warnings and other calls with debug level.
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelDebug
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+		level = slog.LevelWarn
+	}
+	logging.FromContext(ctx).ToStd().LogAttrs(ctx, level, "grpc call",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+	)
+}
+
+// recoverCall turns panic of handler into Internal error.
+func recoverCall(ctx context.Context, method string, err *error) {
+	if r := recover(); r != nil {
+		logging.FromContext(ctx).Error("RecoverGRPC", "handler panicked", fmt.Errorf("%v", r),
+			logging.String("method", method))
+		*err = status.Error(codes.Internal, unknownError)
+	}
+}
+
+func unaryInterceptor(log logging.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		start := time.Now()
+		ctx = callContext(ctx, log)
+		defer func() { logCall(ctx, info.FullMethod, start, err) }()
+		defer recoverCall(ctx, info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+func streamInterceptor(log logging.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		start := time.Now()
+		ctx := callContext(ss.Context(), log)
+		defer func() { logCall(ctx, info.FullMethod, start, err) }()
+		defer recoverCall(ctx, info.FullMethod, &err)
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream replaces context of stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
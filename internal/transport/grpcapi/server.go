@@ -0,0 +1,75 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/vitaliy-ukiru/bank-service/api/bankpb"
+	"github.com/vitaliy-ukiru/bank-service/internal/config"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type Server struct {
+	srv    *grpc.Server
+	health *health.Server
+	cfg    config.Config
+}
+
+// New creates server, auth is nil when authentication is disabled.
+func New(cfg config.Config, accountServer *AccountServer, auth Authenticator, logger logging.Logger) *Server {
+	unary := []grpc.UnaryServerInterceptor{unaryInterceptor(logger)}
+	stream := []grpc.StreamServerInterceptor{streamInterceptor(logger)}
+	if auth != nil {
+		unary = append(unary, unaryAuthInterceptor(auth))
+		stream = append(stream, streamAuthInterceptor(auth))
+	}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+	bankpb.RegisterAccountServiceServer(srv, accountServer)
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(srv, healthServer)
+	reflection.Register(srv)
+
+	return &Server{
+		srv:    srv,
+		health: healthServer,
+		cfg:    cfg,
+	}
+}
+
+// Start serves until Shutdown, it returns grpc.ErrServerStopped if Shutdown is called first.
+func (s *Server) Start() error {
+	lis, err := net.Listen("tcp", net.JoinHostPort(s.cfg.GRPC.Host, strconv.Itoa(s.cfg.GRPC.Port)))
+	if err != nil {
+		return err
+	}
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	s.health.SetServingStatus(bankpb.AccountService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return s.srv.Serve(lis)
+}
+
+// Shutdown waits for running calls until ctx is done, then closes them.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		return ctx.Err()
+	}
+}
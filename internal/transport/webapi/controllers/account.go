@@ -14,10 +14,19 @@ import (
 )
 
 type Usecase interface {
-	CreateAccount(ctx context.Context) (int64, error)
+	CreateAccount(ctx context.Context, cmd application.CreateAccountCommand) (int64, error)
 	DepositBalance(ctx context.Context, cmd application.DepositBalanceCommand) error
 	WithdrawBalance(ctx context.Context, cmd application.WithdrawBalanceCommand) error
-	GetBalance(ctx context.Context, cmd application.GetBalanceCommand) (float64, error)
+	GetBalance(ctx context.Context, cmd application.GetBalanceCommand) (application.AccountBalance, error)
+	Transfer(ctx context.Context, cmd application.TransferCommand) (account.Transfer, error)
+	ListTransactions(ctx context.Context, cmd application.ListTransactionsCommand) (application.TransactionsPage, error)
+	ListAccounts(ctx context.Context, cmd application.ListAccountsCommand) (application.AccountsPage, error)
+	PlaceHold(ctx context.Context, cmd application.PlaceHoldCommand) (account.Hold, error)
+	CaptureHold(ctx context.Context, cmd application.CaptureHoldCommand) (account.Hold, error)
+	ReleaseHold(ctx context.Context, cmd application.ReleaseHoldCommand) (account.Hold, error)
+	SetCreditLimit(ctx context.Context, cmd application.SetCreditLimitCommand) (application.AccountBalance, error)
+	ChangeStatus(ctx context.Context, cmd application.ChangeStatusCommand) (account.StatusChange, error)
+	LookupIdempotentResponse(ctx context.Context, key, fingerprint string) (application.IdempotentResponse, bool, error)
 }
 
 type AccountController struct {
@@ -34,35 +43,85 @@ func (a AccountController) Bind(e *echo.Echo) {
 	g.POST("/:id/deposit", a.Deposit)
 	g.POST("/:id/withdraw", a.Withdraw)
 	g.GET("/:id/balance", a.GetAccountBalance)
+	g.GET("/:id/transactions", a.ListTransactions)
+	g.POST("/:id/holds", a.PlaceHold)
+	g.POST("/:id/holds/:hold_id/capture", a.CaptureHold)
+	g.POST("/:id/holds/:hold_id/release", a.ReleaseHold)
+
+	e.POST("/transfers", a.Transfer)
+
+	admin := e.Group("/admin")
+	admin.GET("/accounts", a.ListAccounts)
+	admin.POST("/accounts/:id/status", a.ChangeStatus)
+	admin.PUT("/accounts/:id/credit-limit", a.SetCreditLimit)
 }
 
 const unknownError = "unknown error occurred"
 
 func (a AccountController) CreateAccount(c echo.Context) error {
-	ctx := c.Request().Context()
-	accountId, err := a.uc.CreateAccount(ctx)
+	respond := func(result application.OperationResult) (int, any) {
+		return http.StatusCreated, response.Ok(response.M{
+			"id": result.AccountId,
+		})
+	}
+
+	var req request.CreateAccountRequest
+	if err := bindRequest(c, &req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	currency, err := parseCurrency(req.Currency)
 	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error(err))
+	}
+
+	if err := application.AuthorizeAllAccounts(getContext(c)); err != nil {
 		return processError(c, err)
 	}
+	if handled, err := a.idempotency(c, respond); handled {
+		return err
+	}
 
-	return c.JSON(http.StatusCreated, response.Ok(response.M{
-		"id": accountId,
-	}))
+	ctx := getContext(c)
+	accountId, err := a.uc.CreateAccount(ctx, application.CreateAccountCommand{
+		Currency: currency,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+
+	return c.JSON(respond(application.OperationResult{AccountId: accountId}))
 }
 
 func (a AccountController) Deposit(c echo.Context) error {
 	var req request.DepositRequest
-	if err := c.Bind(&req); err != nil {
+	if err := bindRequest(c, &req); err != nil {
 		return c.JSON(
 			http.StatusUnprocessableEntity,
 			response.Error(fmt.Errorf("invalid request format: %w", err)),
 		)
 	}
 
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error(err))
+	}
+
+	if err := application.AuthorizeAccount(getContext(c), req.AccountId); err != nil {
+		return processError(c, err)
+	}
+	if handled, err := a.idempotency(c, okResponder); handled {
+		return err
+	}
+
 	ctx := getContext(c)
-	err := a.uc.DepositBalance(ctx, application.DepositBalanceCommand{
+	err = a.uc.DepositBalance(ctx, application.DepositBalanceCommand{
 		AccountId: req.AccountId,
 		Amount:    req.Amount,
+		Currency:  currency,
 	})
 	if err != nil {
 		return processError(c, err)
@@ -73,17 +132,30 @@ func (a AccountController) Deposit(c echo.Context) error {
 
 func (a AccountController) Withdraw(c echo.Context) error {
 	var req request.WithdrawRequest
-	if err := c.Bind(&req); err != nil {
+	if err := bindRequest(c, &req); err != nil {
 		return c.JSON(
 			http.StatusUnprocessableEntity,
 			response.Error(fmt.Errorf("invalid request format: %w", err)),
 		)
 	}
 
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error(err))
+	}
+
+	if err := application.AuthorizeAccount(getContext(c), req.AccountId); err != nil {
+		return processError(c, err)
+	}
+	if handled, err := a.idempotency(c, okResponder); handled {
+		return err
+	}
+
 	ctx := getContext(c)
-	err := a.uc.WithdrawBalance(ctx, application.WithdrawBalanceCommand{
+	err = a.uc.WithdrawBalance(ctx, application.WithdrawBalanceCommand{
 		AccountId: req.AccountId,
 		Amount:    req.Amount,
+		Currency:  currency,
 	})
 	if err != nil {
 		return processError(c, err)
@@ -97,11 +169,14 @@ func (a AccountController) GetAccountBalance(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(
 			http.StatusUnprocessableEntity,
-			response.Error(fmt.Errorf("invalid request format: %w", req)),
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
 		)
 	}
 
 	ctx := getContext(c)
+	if err := application.AuthorizeAccount(ctx, req.AccountId); err != nil {
+		return processError(c, err)
+	}
 	balance, err := a.uc.GetBalance(ctx, application.GetBalanceCommand{
 		AccountId: req.AccountId,
 	})
@@ -109,28 +184,256 @@ func (a AccountController) GetAccountBalance(c echo.Context) error {
 		return processError(c, err)
 	}
 
+	return c.JSON(http.StatusOK, response.Ok(balanceResponse(balance)))
+}
+
+func balanceResponse(balance application.AccountBalance) response.M {
+	return response.M{
+		"balance":           balance.Balance,
+		"available_balance": balance.Available,
+		"credit_limit":      balance.CreditLimit,
+		"credit_headroom":   balance.CreditHeadroom,
+		"currency":          balance.Currency,
+		"status":            balance.Status,
+	}
+}
+
+// ListAccounts is admin endpoint, accounts are listed oldest first.
+func (a AccountController) ListAccounts(c echo.Context) error {
+	var req request.ListAccountsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	ctx := getContext(c)
+	page, err := a.uc.ListAccounts(ctx, application.ListAccountsCommand{
+		Cursor: req.Cursor,
+		Limit:  req.Limit,
+		Status: account.Status(req.Status),
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+
+	items := make([]response.M, len(page.Items))
+	for i, summary := range page.Items {
+		items[i] = balanceResponse(summary.AccountBalance)
+		items[i]["id"] = summary.Id
+	}
+
+	return c.JSON(http.StatusOK, response.Ok(response.M{
+		"items":       items,
+		"next_cursor": page.NextCursor,
+	}))
+}
+
+func (a AccountController) Transfer(c echo.Context) error {
+	respond := func(result application.OperationResult) (int, any) {
+		return http.StatusOK, response.Ok(transferResponse(result.Transfer))
+	}
+
+	var req request.TransferRequest
+	if err := bindRequest(c, &req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error(err))
+	}
+
+	if err := application.AuthorizeAccount(getContext(c), req.FromAccountId); err != nil {
+		return processError(c, err)
+	}
+	if handled, err := a.idempotency(c, respond); handled {
+		return err
+	}
+
+	ctx := getContext(c)
+	transfer, err := a.uc.Transfer(ctx, application.TransferCommand{
+		FromAccountId: req.FromAccountId,
+		ToAccountId:   req.ToAccountId,
+		Amount:        req.Amount,
+		Currency:      currency,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+
+	return c.JSON(respond(application.OperationResult{
+		AccountId: transfer.FromAccountId,
+		Transfer:  &transfer,
+	}))
+}
+
+func (a AccountController) ListTransactions(c echo.Context) error {
+	var req request.ListTransactionsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	types := make([]account.OperationType, 0, len(req.Types))
+	for _, raw := range req.Types {
+		t, err := account.ParseOperationType(raw)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, response.Error(err))
+		}
+		types = append(types, t)
+	}
+
+	ctx := getContext(c)
+	if err := application.AuthorizeAccount(ctx, req.AccountId); err != nil {
+		return processError(c, err)
+	}
+	page, err := a.uc.ListTransactions(ctx, application.ListTransactionsCommand{
+		AccountId: req.AccountId,
+		Cursor:    req.Cursor,
+		Limit:     req.Limit,
+		From:      req.From,
+		To:        req.To,
+		Types:     types,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+
+	items := make([]response.M, len(page.Items))
+	for i, entry := range page.Items {
+		items[i] = transactionResponse(entry)
+	}
+
 	return c.JSON(http.StatusOK, response.Ok(response.M{
-		"balance": balance,
+		"items":       items,
+		"next_cursor": page.NextCursor,
 	}))
 }
 
+func transactionResponse(entry application.JournalEntry) response.M {
+	return response.M{
+		"id":            entry.Id,
+		"account_id":    entry.AccountId,
+		"type":          entry.Type,
+		"currency":      entry.Currency,
+		"amount":        entry.Amount,
+		"balance_after": entry.BalanceAfter,
+		"request_id":    entry.RequestId,
+		"created_at":    entry.CreatedAt,
+		"transfer":      transferResponse(entry.Transfer),
+		"hold_id":       entry.HoldId,
+	}
+}
+
+// transferResponse returns nil for nil transfer.
+func transferResponse(t *account.Transfer) response.M {
+	if t == nil {
+		return nil
+	}
+	return response.M{
+		"from_account_id": t.FromAccountId,
+		"to_account_id":   t.ToAccountId,
+		"debit":           t.Debit,
+		"debit_currency":  t.DebitCurrency,
+		"credit":          t.Credit,
+		"credit_currency": t.CreditCurrency,
+		"rate":            t.Rate,
+		"market_rate":     t.MarketRate,
+		"spread":          t.Spread,
+	}
+}
+
+// parseCurrency returns empty currency for empty code.
+func parseCurrency(code string) (account.Currency, error) {
+	if code == "" {
+		return "", nil
+	}
+	return account.ParseCurrency(code)
+}
+
 func getContext(c echo.Context) context.Context {
 	return c.Request().Context()
 }
 
 func processError(c echo.Context, err error) error {
 	resp := response.Error(errors.Unwrap(err))
-	if errors.Is(err, application.ErrAccountNotFound) {
+	resp.Code = errorCode(err)
+	if errors.Is(err, application.ErrAccountNotFound) ||
+		errors.Is(err, account.ErrHoldNotFound) ||
+		errors.Is(err, application.ErrWebhookNotFound) ||
+		errors.Is(err, application.ErrWebhookDeliveryNotFound) ||
+		errors.Is(err, application.ErrAPIKeyNotFound) {
 		return c.JSON(http.StatusNotFound, resp)
 	}
 
-	if errors.Is(err, account.ErrNegativeAmount) || errors.Is(err, account.ErrZeroAmount) {
+	if errors.Is(err, account.ErrNegativeAmount) ||
+		errors.Is(err, account.ErrZeroAmount) ||
+		errors.Is(err, application.ErrSameAccount) ||
+		errors.Is(err, application.ErrInvalidCursor) ||
+		errors.Is(err, account.ErrUnknownCurrency) ||
+		errors.Is(err, account.ErrExcessPrecision) ||
+		errors.Is(err, account.ErrCaptureExceedsHold) ||
+		errors.Is(err, account.ErrUnknownStatus) ||
+		errors.Is(err, account.ErrReasonRequired) ||
+		errors.Is(err, account.ErrNegativeCreditLimit) ||
+		errors.Is(err, application.ErrInvalidWebhookURL) ||
+		errors.Is(err, application.ErrWebhookEventsRequired) ||
+		errors.Is(err, application.ErrWeakWebhookSecret) ||
+		errors.Is(err, application.ErrLowBalanceThresholdRequired) ||
+		errors.Is(err, application.ErrUnknownScope) ||
+		errors.Is(err, application.ErrAPIKeyNameRequired) ||
+		errors.Is(err, application.ErrAPIKeyScopesRequired) ||
+		errors.Is(err, application.ErrNegativeGracePeriod) {
 		return c.JSON(http.StatusBadRequest, resp)
 	}
 
-	if errors.Is(err, account.ErrNotEnoughBalance) {
+	if errors.Is(err, account.ErrAccountFrozen) ||
+		errors.Is(err, account.ErrAccountClosed) ||
+		errors.Is(err, application.ErrPermissionDenied) {
+		return c.JSON(http.StatusForbidden, resp)
+	}
+
+	if errors.Is(err, account.ErrNotEnoughBalance) ||
+		errors.Is(err, account.ErrHoldExpired) ||
+		errors.Is(err, account.ErrInvalidStatusTransition) ||
+		errors.Is(err, account.ErrNonZeroBalance) ||
+		errors.Is(err, account.ErrActiveHolds) ||
+		errors.Is(err, account.ErrCreditLimitInUse) ||
+		errors.Is(err, application.ErrAccountBusy) ||
+		errors.Is(err, application.ErrIdempotencyKeyConflict) ||
+		errors.Is(err, application.ErrAPIKeyRevoked) {
 		return c.JSON(http.StatusConflict, resp)
 	}
 
+	if errors.Is(err, application.ErrStreamClosed) {
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+
+	if errors.Is(err, application.ErrIdempotencyKeyReused) ||
+		errors.Is(err, account.ErrCurrencyMismatch) ||
+		errors.Is(err, application.ErrExchangeRateNotFound) {
+		return c.JSON(http.StatusUnprocessableEntity, resp)
+	}
+
 	return c.JSON(500, response.Fail(unknownError))
 }
+
+// errorCode returns code of errors which clients handle, e.g. retry, empty for others.
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, application.ErrAccountBusy):
+		return response.CodeAccountBusy
+	case errors.Is(err, account.ErrNotEnoughBalance):
+		return response.CodeInsufficientFunds
+	case errors.Is(err, application.ErrIdempotencyKeyConflict):
+		return response.CodeIdempotencyKeyConflict
+	}
+	return ""
+}
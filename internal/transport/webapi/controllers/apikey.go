@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/request"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/response"
+)
+
+type APIKeyUsecase interface {
+	IssueAPIKey(ctx context.Context, cmd application.IssueAPIKeyCommand) (application.IssuedAPIKey, error)
+	RotateAPIKey(ctx context.Context, cmd application.RotateAPIKeyCommand) (application.IssuedAPIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) (application.APIKey, error)
+	GetAPIKey(ctx context.Context, id string) (application.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]application.APIKey, error)
+}
+
+type APIKeyController struct {
+	uc APIKeyUsecase
+}
+
+func NewAPIKeyController(uc APIKeyUsecase) *APIKeyController {
+	return &APIKeyController{uc: uc}
+}
+
+func (a APIKeyController) Bind(e *echo.Echo) {
+	g := e.Group("/admin/api-keys")
+	g.POST("", a.IssueAPIKey)
+	g.GET("", a.ListAPIKeys)
+	g.GET("/:id", a.GetAPIKey)
+	g.POST("/:id/rotate", a.RotateAPIKey)
+	g.POST("/:id/revoke", a.RevokeAPIKey)
+}
+
+// IssueAPIKey returns the only copy of key, only its hash is stored.
+func (a APIKeyController) IssueAPIKey(c echo.Context) error {
+	var req request.IssueAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	scopes := make([]application.Scope, 0, len(req.Scopes))
+	for _, raw := range req.Scopes {
+		scope, err := application.ParseScope(raw)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, response.Error(err))
+		}
+		scopes = append(scopes, scope)
+	}
+
+	ctx := getContext(c)
+	issued, err := a.uc.IssueAPIKey(ctx, application.IssueAPIKeyCommand{
+		Name:   req.Name,
+		Scopes: scopes,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+	return c.JSON(http.StatusCreated, response.Ok(issuedAPIKeyResponse(issued)))
+}
+
+func (a APIKeyController) ListAPIKeys(c echo.Context) error {
+	ctx := getContext(c)
+	keys, err := a.uc.ListAPIKeys(ctx)
+	if err != nil {
+		return processError(c, err)
+	}
+
+	items := make([]response.M, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, apiKeyResponse(key))
+	}
+	return c.JSON(http.StatusOK, response.Ok(response.M{
+		"api_keys": items,
+	}))
+}
+
+func (a APIKeyController) GetAPIKey(c echo.Context) error {
+	var req request.APIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	ctx := getContext(c)
+	key, err := a.uc.GetAPIKey(ctx, req.KeyId)
+	if err != nil {
+		return processError(c, err)
+	}
+	return c.JSON(http.StatusOK, response.Ok(apiKeyResponse(key)))
+}
+
+// RotateAPIKey returns new key, previous one is accepted during grace period.
+func (a APIKeyController) RotateAPIKey(c echo.Context) error {
+	var req request.RotateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	var grace time.Duration
+	if req.GracePeriod != "" {
+		var err error
+		if grace, err = time.ParseDuration(req.GracePeriod); err != nil {
+			return c.JSON(http.StatusBadRequest, response.Error(fmt.Errorf("invalid grace period: %w", err)))
+		}
+	}
+
+	ctx := getContext(c)
+	issued, err := a.uc.RotateAPIKey(ctx, application.RotateAPIKeyCommand{
+		Id:          req.KeyId,
+		GracePeriod: grace,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+	return c.JSON(http.StatusOK, response.Ok(issuedAPIKeyResponse(issued)))
+}
+
+func (a APIKeyController) RevokeAPIKey(c echo.Context) error {
+	var req request.APIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	ctx := getContext(c)
+	key, err := a.uc.RevokeAPIKey(ctx, req.KeyId)
+	if err != nil {
+		return processError(c, err)
+	}
+	return c.JSON(http.StatusOK, response.Ok(apiKeyResponse(key)))
+}
+
+func apiKeyResponse(key application.APIKey) response.M {
+	resp := response.M{
+		"id":         key.Id,
+		"name":       key.Name,
+		"scopes":     key.Scopes,
+		"created_at": key.CreatedAt,
+	}
+	if !key.RotatedAt.IsZero() {
+		resp["rotated_at"] = key.RotatedAt
+	}
+	if key.PreviousSecretHash != "" {
+		resp["previous_expires_at"] = key.PreviousExpiresAt
+	}
+	if key.Revoked() {
+		resp["revoked_at"] = key.RevokedAt
+	}
+	return resp
+}
+
+func issuedAPIKeyResponse(issued application.IssuedAPIKey) response.M {
+	resp := apiKeyResponse(issued.APIKey)
+	resp["key"] = issued.Token
+	return resp
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/request"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/response"
+)
+
+// SetCreditLimit is admin endpoint for approving overdraft.
+func (a AccountController) SetCreditLimit(c echo.Context) error {
+	respond := func(result application.OperationResult) (int, any) {
+		return http.StatusOK, response.Ok(balanceResponse(result.Balance))
+	}
+
+	var req request.SetCreditLimitRequest
+	if err := bindRequest(c, &req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error(err))
+	}
+
+	if handled, err := a.idempotency(c, respond); handled {
+		return err
+	}
+
+	ctx := getContext(c)
+	balance, err := a.uc.SetCreditLimit(ctx, application.SetCreditLimitCommand{
+		AccountId:   req.AccountId,
+		CreditLimit: req.CreditLimit,
+		Currency:    currency,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+
+	return c.JSON(respond(application.OperationResult{AccountId: req.AccountId, Balance: balance}))
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/request"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/response"
+)
+
+func holdResponder(status int) responder {
+	return func(result application.OperationResult) (int, any) {
+		return status, response.Ok(holdResponse(result.Hold))
+	}
+}
+
+func (a AccountController) PlaceHold(c echo.Context) error {
+	respond := holdResponder(http.StatusCreated)
+
+	var req request.PlaceHoldRequest
+	if err := bindRequest(c, &req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error(err))
+	}
+
+	if err := application.AuthorizeAccount(getContext(c), req.AccountId); err != nil {
+		return processError(c, err)
+	}
+	if handled, err := a.idempotency(c, respond); handled {
+		return err
+	}
+
+	ctx := getContext(c)
+	hold, err := a.uc.PlaceHold(ctx, application.PlaceHoldCommand{
+		AccountId: req.AccountId,
+		Amount:    req.Amount,
+		Currency:  currency,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+
+	return c.JSON(respond(application.OperationResult{AccountId: req.AccountId, Hold: &hold}))
+}
+
+func (a AccountController) CaptureHold(c echo.Context) error {
+	respond := holdResponder(http.StatusOK)
+
+	var req request.CaptureHoldRequest
+	if err := bindRequest(c, &req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	if err := application.AuthorizeAccount(getContext(c), req.AccountId); err != nil {
+		return processError(c, err)
+	}
+	if handled, err := a.idempotency(c, respond); handled {
+		return err
+	}
+
+	ctx := getContext(c)
+	hold, err := a.uc.CaptureHold(ctx, application.CaptureHoldCommand{
+		AccountId: req.AccountId,
+		HoldId:    req.HoldId,
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+
+	return c.JSON(respond(application.OperationResult{AccountId: req.AccountId, Hold: &hold}))
+}
+
+func (a AccountController) ReleaseHold(c echo.Context) error {
+	respond := holdResponder(http.StatusOK)
+
+	var req request.ReleaseHoldRequest
+	if err := bindRequest(c, &req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	if err := application.AuthorizeAccount(getContext(c), req.AccountId); err != nil {
+		return processError(c, err)
+	}
+	if handled, err := a.idempotency(c, respond); handled {
+		return err
+	}
+
+	ctx := getContext(c)
+	hold, err := a.uc.ReleaseHold(ctx, application.ReleaseHoldCommand{
+		AccountId: req.AccountId,
+		HoldId:    req.HoldId,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+
+	return c.JSON(respond(application.OperationResult{AccountId: req.AccountId, Hold: &hold}))
+}
+
+// holdResponse returns nil for nil hold.
+func holdResponse(h *account.Hold) response.M {
+	if h == nil {
+		return nil
+	}
+	return response.M{
+		"id":              h.Id(),
+		"account_id":      h.AccountId(),
+		"amount":          h.Amount(),
+		"captured_amount": h.Captured(),
+		"status":          h.Status(),
+		"expires_at":      h.ExpiresAt(),
+		"created_at":      h.CreatedAt(),
+	}
+}
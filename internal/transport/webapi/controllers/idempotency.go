@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/response"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// responder builds response of successful operation.
+type responder func(result application.OperationResult) (int, any)
+
+func okResponder(application.OperationResult) (int, any) {
+	return http.StatusOK, response.OkStatus
+}
+
+// idempotency prepares request with Idempotency-Key header, request must be authorized
+// and bound by bindRequest before. Keys are scoped by principal of request.
+// If response for the key already stored, it written to client and handled is true.
+// Otherwise, request context receives application.Idempotency, so response
+// will be stored together with changes of operation.
+func (a AccountController) idempotency(c echo.Context, respond responder) (handled bool, err error) {
+	key := c.Request().Header.Get(HeaderIdempotencyKey)
+	if key == "" {
+		return false, nil
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return true, c.JSON(
+			http.StatusBadRequest,
+			response.Fail(fmt.Sprintf("%s header is too long", HeaderIdempotencyKey)),
+		)
+	}
+
+	fingerprint, err := requestFingerprint(c)
+	if err != nil {
+		return true, err
+	}
+
+	ctx := getContext(c)
+	stored, found, err := a.uc.LookupIdempotentResponse(ctx, key, fingerprint)
+	if err != nil {
+		return true, processError(c, err)
+	}
+	if found {
+		c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+		return true, c.JSONBlob(stored.Status, stored.Body)
+	}
+
+	ctx = application.WithIdempotency(ctx, application.Idempotency{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Render: func(result application.OperationResult) (application.IdempotentResponse, error) {
+			status, payload := respond(result)
+			body, err := json.Marshal(payload)
+			if err != nil {
+				return application.IdempotentResponse{}, err
+			}
+			return application.IdempotentResponse{Status: status, Body: body}, nil
+		},
+	})
+	c.SetRequest(c.Request().WithContext(ctx))
+	return false, nil
+}
+
+// bindRequest binds req keeping body for requestFingerprint.
+func bindRequest(c echo.Context, req any) error {
+	r := c.Request()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	err = c.Bind(req)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return err
+}
+
+// requestFingerprint hashes method, path and body. Body is restored for other readers.
+func requestFingerprint(c echo.Context) (string, error) {
+	req := c.Request()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+	h.Write([]byte(req.Method))
+	h.Write([]byte{' '})
+	h.Write([]byte(req.URL.Path))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/request"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/response"
+)
+
+// ChangeStatus is admin endpoint for freezing, unfreezing and closing accounts.
+func (a AccountController) ChangeStatus(c echo.Context) error {
+	respond := func(result application.OperationResult) (int, any) {
+		return http.StatusOK, response.Ok(statusChangeResponse(result.AccountId, result.StatusChange))
+	}
+
+	var req request.ChangeStatusRequest
+	if err := bindRequest(c, &req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	status, err := account.ParseStatus(req.Status)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error(err))
+	}
+
+	if handled, err := a.idempotency(c, respond); handled {
+		return err
+	}
+
+	ctx := getContext(c)
+	change, err := a.uc.ChangeStatus(ctx, application.ChangeStatusCommand{
+		AccountId: req.AccountId,
+		Status:    status,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+
+	return c.JSON(respond(application.OperationResult{AccountId: req.AccountId, StatusChange: &change}))
+}
+
+// statusChangeResponse returns nil for nil change.
+func statusChangeResponse(accountId int64, change *account.StatusChange) response.M {
+	if change == nil {
+		return nil
+	}
+	return response.M{
+		"account_id":      accountId,
+		"status":          change.To,
+		"previous_status": change.From,
+		"reason":          change.Reason,
+	}
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/request"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/response"
+	"golang.org/x/net/websocket"
+)
+
+type BalanceStreamer interface {
+	Subscribe(ctx context.Context, accountId int64, lastEventId int64) (*application.BalanceSubscription, error)
+}
+
+type StreamController struct {
+	stream BalanceStreamer
+	// allowedOrigins of WebSocket clients besides origin of API, "*" allows any
+	allowedOrigins []string
+}
+
+func NewStreamController(stream BalanceStreamer, allowedOrigins []string) *StreamController {
+	return &StreamController{stream: stream, allowedOrigins: allowedOrigins}
+}
+
+func (s StreamController) Bind(e *echo.Echo) {
+	g := e.Group("/accounts")
+	g.GET("/:id/stream", s.StreamEvents)
+	g.GET("/:id/stream/ws", s.StreamWebSocket)
+}
+
+// ssePingInterval keeps idle connection open through proxies.
+const ssePingInterval = 15 * time.Second
+
+// StreamEvents sends balance changes as Server-Sent Events with journal entry id as event id,
+// so EventSource resumes after reconnect by Last-Event-ID header. Changes aren't pushed on
+// commit, journal is polled by BalanceStream, so they are delayed up to its interval.
+func (s StreamController) StreamEvents(c echo.Context) error {
+	sub, err := s.subscribe(c)
+	if sub == nil {
+		return err
+	}
+	defer sub.Close()
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	ping := time.NewTicker(ssePingInterval)
+	defer ping.Stop()
+	ctx := getContext(c)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return nil
+			}
+		case entry, ok := <-sub.Events():
+			if !ok {
+				// client reconnects and resumes
+				return nil
+			}
+			data, err := json.Marshal(transactionResponse(entry))
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: balance\ndata: %s\n\n", entry.Id, data); err != nil {
+				return nil
+			}
+		}
+		w.Flush()
+	}
+}
+
+// StreamWebSocket sends balance changes as JSON messages, client resumes
+// with last_event_id query parameter set to id of the last message.
+// Messages come from polled journal like events of StreamEvents.
+func (s StreamController) StreamWebSocket(c echo.Context) error {
+	// browsers don't apply CORS to WebSocket, page of other origin could read
+	// stream with credentials of user
+	if !s.originAllowed(c.Request()) {
+		return c.JSON(http.StatusForbidden, response.Fail("origin isn't allowed"))
+	}
+	sub, err := s.subscribe(c)
+	if sub == nil {
+		return err
+	}
+	defer sub.Close()
+
+	// origin is checked above, handshake accepts it
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		// client sends nothing, reading detects closed connection
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var discard string
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+		}()
+
+		ctx := getContext(c)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-closed:
+				return
+			case entry, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+				if err := websocket.JSON.Send(ws, transactionResponse(entry)); err != nil {
+					return
+				}
+			}
+		}
+	}}.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// originAllowed accepts clients without Origin header, they aren't browsers,
+// and browser pages of the API host or allowed origins.
+func (s StreamController) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || slices.Contains(s.allowedOrigins, "*") || slices.Contains(s.allowedOrigins, origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// subscribe writes error response and returns nil subscription if request is rejected.
+func (s StreamController) subscribe(c echo.Context) (*application.BalanceSubscription, error) {
+	var req request.StreamBalanceRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+	if header := c.Request().Header.Get("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			return nil, c.JSON(
+				http.StatusBadRequest,
+				response.Error(fmt.Errorf("invalid Last-Event-ID header: %w", err)),
+			)
+		}
+		req.LastEventId = id
+	}
+
+	ctx := getContext(c)
+	if err := application.AuthorizeAccount(ctx, req.AccountId); err != nil {
+		return nil, processError(c, err)
+	}
+	sub, err := s.stream.Subscribe(ctx, req.AccountId, req.LastEventId)
+	if err != nil {
+		return nil, processError(c, err)
+	}
+	return sub, nil
+}
@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/request"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/response"
+)
+
+type WebhookUsecase interface {
+	CreateWebhook(ctx context.Context, cmd application.CreateWebhookCommand) (application.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (application.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]application.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	ListWebhookDeliveries(ctx context.Context, cmd application.ListWebhookDeliveriesCommand) ([]application.WebhookDelivery, error)
+	RetryWebhookDelivery(ctx context.Context, cmd application.RetryWebhookDeliveryCommand) (application.WebhookDelivery, error)
+}
+
+type WebhookController struct {
+	uc WebhookUsecase
+}
+
+func NewWebhookController(uc WebhookUsecase) *WebhookController {
+	return &WebhookController{uc: uc}
+}
+
+func (w WebhookController) Bind(e *echo.Echo) {
+	g := e.Group("/webhooks")
+	g.POST("", w.CreateWebhook)
+	g.GET("", w.ListWebhooks)
+	g.GET("/:id", w.GetWebhook)
+	g.DELETE("/:id", w.DeleteWebhook)
+	g.GET("/:id/deliveries", w.ListDeliveries)
+	g.POST("/:id/deliveries/:delivery_id/retry", w.RetryDelivery)
+}
+
+// CreateWebhook is the only endpoint returning secret of webhook.
+func (w WebhookController) CreateWebhook(c echo.Context) error {
+	var req request.CreateWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	events := make([]account.EventType, 0, len(req.Events))
+	for _, e := range req.Events {
+		event, err := application.ParseWebhookEvent(e)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, response.Error(err))
+		}
+		events = append(events, event)
+	}
+
+	ctx := getContext(c)
+	webhook, err := w.uc.CreateWebhook(ctx, application.CreateWebhookCommand{
+		URL:                 req.URL,
+		Events:              events,
+		AccountId:           req.AccountId,
+		LowBalanceThreshold: req.LowBalanceThreshold,
+		Secret:              req.Secret,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+
+	resp := webhookResponse(webhook)
+	resp["secret"] = webhook.Secret
+	return c.JSON(http.StatusCreated, response.Ok(resp))
+}
+
+func (w WebhookController) ListWebhooks(c echo.Context) error {
+	ctx := getContext(c)
+	webhooks, err := w.uc.ListWebhooks(ctx)
+	if err != nil {
+		return processError(c, err)
+	}
+
+	items := make([]response.M, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		items = append(items, webhookResponse(webhook))
+	}
+	return c.JSON(http.StatusOK, response.Ok(response.M{
+		"webhooks": items,
+	}))
+}
+
+func (w WebhookController) GetWebhook(c echo.Context) error {
+	var req request.WebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	ctx := getContext(c)
+	webhook, err := w.uc.GetWebhook(ctx, req.WebhookId)
+	if err != nil {
+		return processError(c, err)
+	}
+	return c.JSON(http.StatusOK, response.Ok(webhookResponse(webhook)))
+}
+
+func (w WebhookController) DeleteWebhook(c echo.Context) error {
+	var req request.WebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	ctx := getContext(c)
+	if err := w.uc.DeleteWebhook(ctx, req.WebhookId); err != nil {
+		return processError(c, err)
+	}
+	return c.JSON(http.StatusOK, response.OkStatus)
+}
+
+// ListDeliveries is delivery log of webhook, newest deliveries first.
+func (w WebhookController) ListDeliveries(c echo.Context) error {
+	var req request.ListWebhookDeliveriesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	var status application.DeliveryStatus
+	if req.Status != "" {
+		var err error
+		if status, err = application.ParseDeliveryStatus(req.Status); err != nil {
+			return c.JSON(http.StatusBadRequest, response.Error(err))
+		}
+	}
+
+	ctx := getContext(c)
+	deliveries, err := w.uc.ListWebhookDeliveries(ctx, application.ListWebhookDeliveriesCommand{
+		WebhookId: req.WebhookId,
+		Status:    status,
+		Limit:     req.Limit,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+
+	items := make([]response.M, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		items = append(items, deliveryResponse(delivery))
+	}
+	return c.JSON(http.StatusOK, response.Ok(response.M{
+		"deliveries": items,
+	}))
+}
+
+func (w WebhookController) RetryDelivery(c echo.Context) error {
+	var req request.RetryWebhookDeliveryRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			response.Error(fmt.Errorf("invalid request format: %w", err)),
+		)
+	}
+
+	ctx := getContext(c)
+	delivery, err := w.uc.RetryWebhookDelivery(ctx, application.RetryWebhookDeliveryCommand{
+		WebhookId:  req.WebhookId,
+		DeliveryId: req.DeliveryId,
+	})
+	if err != nil {
+		return processError(c, err)
+	}
+	return c.JSON(http.StatusOK, response.Ok(deliveryResponse(delivery)))
+}
+
+func webhookResponse(webhook application.Webhook) response.M {
+	resp := response.M{
+		"id":         webhook.Id,
+		"url":        webhook.URL,
+		"events":     webhook.Events,
+		"created_at": webhook.CreatedAt,
+	}
+	if webhook.AccountId != 0 {
+		resp["account_id"] = webhook.AccountId
+	}
+	if webhook.LowBalanceThreshold != nil {
+		resp["low_balance_threshold"] = webhook.LowBalanceThreshold
+	}
+	return resp
+}
+
+func deliveryResponse(delivery application.WebhookDelivery) response.M {
+	resp := response.M{
+		"id":         delivery.Id,
+		"message_id": delivery.MessageId,
+		"event":      delivery.Event,
+		"status":     delivery.Status,
+		"attempts":   delivery.Attempts,
+		"created_at": delivery.CreatedAt,
+	}
+	if delivery.Status == application.DeliveryPending {
+		resp["next_attempt_at"] = delivery.NextAttemptAt
+	}
+	if !delivery.LastAttemptAt.IsZero() {
+		resp["last_attempt_at"] = delivery.LastAttemptAt
+	}
+	if delivery.LastStatusCode != 0 {
+		resp["last_status_code"] = delivery.LastStatusCode
+	}
+	if delivery.LastError != "" {
+		resp["last_error"] = delivery.LastError
+	}
+	return resp
+}
@@ -0,0 +1,190 @@
+package webapi_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/config"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/acquire"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/exchange"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/controllers"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/middlewares"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+// tokenAuthenticator treats bearer token as subject of user operating on accounts of tokens.
+type tokenAuthenticator map[string][]int64
+
+func (a tokenAuthenticator) Authenticate(_ context.Context, creds application.Credentials) (application.Principal, error) {
+	ids, ok := a[creds.BearerToken]
+	if !ok {
+		return application.Principal{}, application.ErrUnauthenticated
+	}
+	return application.Principal{
+		Name:          creds.BearerToken,
+		Scopes:        []application.Scope{application.ScopeRead, application.ScopeWithdraw},
+		Subject:       creds.BearerToken,
+		AccountScoped: true,
+		AccountIds:    ids,
+	}, nil
+}
+
+const (
+	streamPollInterval = 10 * time.Millisecond
+	streamStallTimeout = time.Minute
+	allowedOrigin      = "https://app.example.com"
+)
+
+type testServer struct {
+	srv     *httptest.Server
+	service *application.AccountService
+}
+
+// newTestServer serves API with in-memory storage, auth is nil to disable authentication.
+func newTestServer(t *testing.T, auth middlewares.Authenticator) testServer {
+	t.Helper()
+	repo := accounts.NewInMemory()
+	rates, err := exchange.NewStaticProvider(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := application.NewAccountService(
+		acquire.NewInMemoryAcquirer(repo),
+		repo,
+		rates,
+		account.ConversionPolicy{Rounding: account.RoundHalfEven},
+		time.Hour,
+		account.StatusPolicy{},
+		time.Hour,
+	)
+	apiKeys, err := application.NewAPIKeyService(repo, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := logging.Discard()
+	stream := application.NewBalanceStream(repo, streamPollInterval, streamStallTimeout)
+	ctx, stop := context.WithCancel(logging.Context(context.Background(), log))
+	t.Cleanup(stop)
+	go stream.Run(ctx)
+
+	router := webapi.New(
+		config.Config{},
+		controllers.NewAccountController(service),
+		controllers.NewWebhookController(application.NewWebhookService(repo)),
+		controllers.NewStreamController(stream, []string{allowedOrigin}),
+		controllers.NewAPIKeyController(apiKeys),
+		auth,
+		log,
+	)
+	srv := httptest.NewServer(router.Handler())
+	t.Cleanup(srv.Close)
+	return testServer{srv: srv, service: service}
+}
+
+// newAccount opens account with credit limit, so it can be withdrawn without deposit.
+func (s testServer) newAccount(t *testing.T) int64 {
+	t.Helper()
+	ctx := context.Background()
+	id, err := s.service.CreateAccount(ctx, application.CreateAccountCommand{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.service.SetCreditLimit(ctx, application.SetCreditLimitCommand{
+		AccountId:   id,
+		CreditLimit: account.MustParseMoney("100"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func (s testServer) balance(t *testing.T, id int64) string {
+	t.Helper()
+	balance, err := s.service.GetBalance(context.Background(), application.GetBalanceCommand{AccountId: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return balance.Balance.String()
+}
+
+func (s testServer) withdraw(t *testing.T, token string, id int64, key string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(
+		http.MethodPost,
+		s.srv.URL+"/accounts/"+strconv.FormatInt(id, 10)+"/withdraw",
+		strings.NewReader(`{"amount":"10"}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(controllers.HeaderIdempotencyKey, key)
+	resp, err := s.srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestIdempotencyKeyIsScopedByPrincipal(t *testing.T) {
+	auth := tokenAuthenticator{}
+	s := newTestServer(t, auth)
+	aliceAccount, bobAccount := s.newAccount(t), s.newAccount(t)
+	auth["alice"] = []int64{aliceAccount}
+	auth["bob"] = []int64{bobAccount}
+
+	if resp := s.withdraw(t, "alice", aliceAccount, "key"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("withdraw of alice: status %d", resp.StatusCode)
+	}
+
+	// the same key of other user is independent
+	resp := s.withdraw(t, "bob", bobAccount, "key")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(controllers.HeaderIdempotentReplayed) != "" {
+		t.Fatalf("withdraw of bob with key of alice: status %d, replayed %q",
+			resp.StatusCode, resp.Header.Get(controllers.HeaderIdempotentReplayed))
+	}
+	if got := s.balance(t, bobAccount); got != "-10.00" {
+		t.Fatalf("withdraw of bob isn't applied, balance %s", got)
+	}
+
+	// repeated request of alice is replayed
+	resp = s.withdraw(t, "alice", aliceAccount, "key")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(controllers.HeaderIdempotentReplayed) != "true" {
+		t.Fatalf("repeated withdraw of alice: status %d, replayed %q",
+			resp.StatusCode, resp.Header.Get(controllers.HeaderIdempotentReplayed))
+	}
+	if got := s.balance(t, aliceAccount); got != "-10.00" {
+		t.Fatalf("repeated withdraw is applied twice, balance %s", got)
+	}
+}
+
+func TestIdempotencyReplayRequiresAuthorization(t *testing.T) {
+	auth := tokenAuthenticator{}
+	s := newTestServer(t, auth)
+	aliceAccount := s.newAccount(t)
+	auth["alice"] = []int64{aliceAccount}
+	auth["mallory"] = nil
+
+	if resp := s.withdraw(t, "alice", aliceAccount, "key"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("withdraw of alice: status %d", resp.StatusCode)
+	}
+
+	resp := s.withdraw(t, "mallory", aliceAccount, "key")
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("request of other user to account of alice: want 403, got %d (replayed %q)",
+			resp.StatusCode, resp.Header.Get(controllers.HeaderIdempotentReplayed))
+	}
+}
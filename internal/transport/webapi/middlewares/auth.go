@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/response"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+const HeaderAPIKey = "X-API-Key"
+
+// Authenticator resolves API key or bearer token into principal,
+// it returns application.ErrUnauthenticated for invalid credentials.
+type Authenticator interface {
+	Authenticate(ctx context.Context, creds application.Credentials) (application.Principal, error)
+}
+
+// RouteScopes maps "METHOD path" of route (e.g. "GET /accounts/:id/balance") to scope required for it.
+// Empty scope makes route public, routes missing in map require application.ScopeAdmin.
+type RouteScopes map[string]application.Scope
+
+// Authenticate must be used after WrapRequestContextWithLogger, it puts principal
+// on request context and adds its id to request logger.
+func Authenticate(auth Authenticator, routes RouteScopes) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scope, ok := routes[c.Request().Method+" "+c.Path()]
+			if !ok {
+				scope = application.ScopeAdmin
+			}
+			if ok && scope == "" {
+				return next(c)
+			}
+
+			request := c.Request()
+			ctx := request.Context()
+			principal, err := auth.Authenticate(ctx, application.Credentials{
+				APIKey:      request.Header.Get(HeaderAPIKey),
+				BearerToken: bearerToken(request.Header.Get(echo.HeaderAuthorization)),
+			})
+			if errors.Is(err, application.ErrUnauthenticated) {
+				return c.JSON(http.StatusUnauthorized, response.Error(application.ErrUnauthenticated))
+			}
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, response.Fail("unknown error occurred"))
+			}
+
+			log := logging.FromContext(ctx).With(principal.LogAttr())
+			ctx = logging.Context(application.ContextWithPrincipal(ctx, principal), log)
+			c.SetRequest(request.WithContext(ctx))
+
+			if !principal.Allows(scope) {
+				return c.JSON(
+					http.StatusForbidden,
+					response.Error(fmt.Errorf("%w: %s scope is required", application.ErrPermissionDenied, scope)),
+				)
+			}
+			return next(c)
+		}
+	}
+}
+
+// bearerToken returns token of "Bearer <token>" authorization, empty for other schemes.
+func bearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
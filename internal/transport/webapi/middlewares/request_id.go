@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
+)
+
+func WrapRequestContextWithRequestId() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			reqId := c.Response().Header().Get(echo.HeaderXRequestID)
+			if reqId == "" {
+				return next(c)
+			}
+			request := c.Request()
+			ctx := requestid.Context(request.Context(), reqId)
+
+			c.SetRequest(request.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
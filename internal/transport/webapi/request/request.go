@@ -1,15 +1,125 @@
 package request
 
+import (
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+)
+
+type CreateAccountRequest struct {
+	Currency string `json:"currency"`
+}
+
 type DepositRequest struct {
-	AccountId int64   `param:"id"`
-	Amount    float64 `json:"amount"`
+	AccountId int64         `param:"id"`
+	Amount    account.Money `json:"amount"`
+	Currency  string        `json:"currency"`
 }
 
 type WithdrawRequest struct {
-	AccountId int64   `param:"id"`
-	Amount    float64 `json:"amount"`
+	AccountId int64         `param:"id"`
+	Amount    account.Money `json:"amount"`
+	Currency  string        `json:"currency"`
 }
 
 type GetBalanceRequest struct {
 	AccountId int64 `param:"id"`
 }
+
+type TransferRequest struct {
+	FromAccountId int64         `json:"from_account_id"`
+	ToAccountId   int64         `json:"to_account_id"`
+	Amount        account.Money `json:"amount"`
+	Currency      string        `json:"currency"`
+}
+
+type ListTransactionsRequest struct {
+	AccountId int64     `param:"id"`
+	Cursor    string    `query:"cursor"`
+	Limit     int       `query:"limit"`
+	From      time.Time `query:"from"`
+	To        time.Time `query:"to"`
+	Types     []string  `query:"type"`
+}
+
+type ListAccountsRequest struct {
+	Cursor string `query:"cursor"`
+	Limit  int    `query:"limit"`
+	Status string `query:"status"`
+}
+
+type PlaceHoldRequest struct {
+	AccountId int64         `param:"id"`
+	Amount    account.Money `json:"amount"`
+	Currency  string        `json:"currency"`
+}
+
+type CaptureHoldRequest struct {
+	AccountId int64  `param:"id"`
+	HoldId    string `param:"hold_id"`
+	// Amount is optional, full hold captured by default.
+	Amount *account.Money `json:"amount"`
+}
+
+type ReleaseHoldRequest struct {
+	AccountId int64  `param:"id"`
+	HoldId    string `param:"hold_id"`
+}
+
+type ChangeStatusRequest struct {
+	AccountId int64  `param:"id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+}
+
+type SetCreditLimitRequest struct {
+	AccountId   int64         `param:"id"`
+	CreditLimit account.Money `json:"credit_limit"`
+	Currency    string        `json:"currency"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// AccountId is optional, webhook receives events of all accounts by default.
+	AccountId           int64          `json:"account_id"`
+	LowBalanceThreshold *account.Money `json:"low_balance_threshold"`
+	// Secret is optional, it is generated by default.
+	Secret string `json:"secret"`
+}
+
+type WebhookRequest struct {
+	WebhookId string `param:"id"`
+}
+
+type ListWebhookDeliveriesRequest struct {
+	WebhookId string `param:"id"`
+	Status    string `query:"status"`
+	Limit     int    `query:"limit"`
+}
+
+type RetryWebhookDeliveryRequest struct {
+	WebhookId  string `param:"id"`
+	DeliveryId string `param:"delivery_id"`
+}
+
+type StreamBalanceRequest struct {
+	AccountId int64 `param:"id"`
+	// LastEventId resumes stream, Last-Event-ID header of SSE reconnect takes precedence.
+	LastEventId int64 `query:"last_event_id"`
+}
+
+type IssueAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type APIKeyRequest struct {
+	KeyId string `param:"id"`
+}
+
+type RotateAPIKeyRequest struct {
+	KeyId string `param:"id"`
+	// GracePeriod is optional duration (e.g. "10m") when previous secret is accepted.
+	GracePeriod string `json:"grace_period"`
+}
@@ -25,8 +25,17 @@ type Response struct {
 type ErrorResponse struct {
 	Ok    bool   `json:"ok"`
 	Error string `json:"error"`
+	// Code is stable id of error handled by clients, messages may change.
+	Code string `json:"code,omitempty"`
 }
 
+// Codes of ErrorResponse.
+const (
+	CodeAccountBusy            = "account_busy"
+	CodeInsufficientFunds      = "insufficient_funds"
+	CodeIdempotencyKeyConflict = "idempotency_key_conflict"
+)
+
 func Ok(result any) Response {
 	return Response{
 		Ok:     true,
@@ -4,6 +4,7 @@ import (
 	"context"
 	_ "embed"
 	"errors"
+	"expvar"
 	"fmt"
 	"log/slog"
 	"net"
@@ -16,6 +17,7 @@ import (
 	"github.com/swaggest/swgui"
 	"github.com/swaggest/swgui/v5emb"
 	"github.com/vitaliy-ukiru/bank-service/api"
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
 	"github.com/vitaliy-ukiru/bank-service/internal/config"
 	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/controllers"
 	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/middlewares"
@@ -27,11 +29,32 @@ type ApiRouter struct {
 	e                 *echo.Echo
 	cfg               config.Config
 	accountController *controllers.AccountController
+	webhookController *controllers.WebhookController
+	streamController  *controllers.StreamController
+	apiKeyController  *controllers.APIKeyController
+}
+
+// routeScopes are required when authentication is enabled, other routes require admin scope.
+var routeScopes = middlewares.RouteScopes{
+	"POST /accounts":                            application.ScopeDeposit,
+	"POST /accounts/:id/deposit":                application.ScopeDeposit,
+	"POST /accounts/:id/withdraw":               application.ScopeWithdraw,
+	"GET /accounts/:id/balance":                 application.ScopeRead,
+	"GET /accounts/:id/transactions":            application.ScopeRead,
+	"GET /accounts/:id/stream":                  application.ScopeRead,
+	"GET /accounts/:id/stream/ws":               application.ScopeRead,
+	"POST /accounts/:id/holds":                  application.ScopeWithdraw,
+	"POST /accounts/:id/holds/:hold_id/capture": application.ScopeWithdraw,
+	"POST /accounts/:id/holds/:hold_id/release": application.ScopeWithdraw,
+	"POST /transfers":                           application.ScopeWithdraw,
+
+	"GET /docs*":        "",
+	"GET /openapi.json": "",
 }
 
 func configureEcho(e *echo.Echo, logger logging.Logger) {
 	e.HideBanner = true
-	stdLog := logging.ConfigureLogLogger(logger, slog.LevelInfo)
+	stdLog := logging.NewLogLogger(logger, slog.LevelInfo)
 	e.Logger.SetLevel(99)
 	e.Logger.SetOutput(stdLog.Writer())
 	e.Server.ErrorLog = stdLog
@@ -47,6 +70,7 @@ func configureEcho(e *echo.Echo, logger logging.Logger) {
 	e.Use(middleware.RemoveTrailingSlash())
 	e.Use(middleware.RequestID())
 	e.Use(middlewares.WrapRequestContextWithLogger(logger))
+	e.Use(middlewares.WrapRequestContextWithRequestId())
 	e.HTTPErrorHandler = func(err error, c echo.Context) {
 		var httpErr *echo.HTTPError
 		if errors.As(err, &httpErr) {
@@ -69,12 +93,23 @@ func plainErrorHandler(code int) func(c echo.Context) error {
 func New(
 	cfg config.Config,
 	controller *controllers.AccountController,
+	webhookController *controllers.WebhookController,
+	streamController *controllers.StreamController,
+	apiKeyController *controllers.APIKeyController,
+	// auth is nil when authentication is disabled
+	auth middlewares.Authenticator,
 	logger logging.Logger,
 ) *ApiRouter {
 	e := echo.New()
 	configureEcho(e, logger)
+	if auth != nil {
+		e.Use(middlewares.Authenticate(auth, routeScopes))
+	}
 
 	controller.Bind(e)
+	webhookController.Bind(e)
+	streamController.Bind(e)
+	apiKeyController.Bind(e)
 
 	e.Any("/docs*", echo.WrapHandler(
 		v5emb.NewHandlerWithConfig(swgui.Config{
@@ -89,13 +124,26 @@ func New(
 		return c.JSONBlob(200, api.OpenAPISpec)
 	})
 
+	// metrics aren't in routeScopes, they require admin scope and aren't served without authentication
+	if auth != nil {
+		e.GET("/debug/vars", echo.WrapHandler(expvar.Handler()))
+	}
+
 	return &ApiRouter{
 		e:                 e,
 		cfg:               cfg,
 		accountController: controller,
+		webhookController: webhookController,
+		streamController:  streamController,
+		apiKeyController:  apiKeyController,
 	}
 }
 
+// Handler serves API without starting server.
+func (a *ApiRouter) Handler() http.Handler {
+	return a.e
+}
+
 func (a *ApiRouter) Start() error {
 	return a.e.Start(net.JoinHostPort(a.cfg.Server.Host, strconv.Itoa(a.cfg.Server.Port)))
 }
@@ -0,0 +1,55 @@
+package webapi_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/middlewares"
+)
+
+// adminAuthenticator accepts any bearer token as admin.
+type adminAuthenticator struct{}
+
+func (adminAuthenticator) Authenticate(_ context.Context, creds application.Credentials) (application.Principal, error) {
+	if creds.BearerToken == "" {
+		return application.Principal{}, application.ErrUnauthenticated
+	}
+	return application.Principal{Name: "admin", Scopes: []application.Scope{application.ScopeAdmin}}, nil
+}
+
+func TestDebugVars(t *testing.T) {
+	tests := []struct {
+		name string
+		auth middlewares.Authenticator
+		// token is empty for request without credentials
+		token string
+		want  int
+	}{
+		{name: "without authentication", want: http.StatusNotFound},
+		{name: "without credentials", auth: tokenAuthenticator{"alice": nil}, want: http.StatusUnauthorized},
+		{name: "without admin scope", auth: tokenAuthenticator{"alice": nil}, token: "alice", want: http.StatusForbidden},
+		{name: "admin", auth: adminAuthenticator{}, token: "admin", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, tt.auth)
+			req, err := http.NewRequest(http.MethodGet, s.srv.URL+"/debug/vars", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := s.srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package webapi_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"golang.org/x/net/websocket"
+)
+
+// withdrawals withdraws from account n times and returns ids of journal entries oldest first.
+func (s testServer) withdrawals(t *testing.T, id int64, n int) []int64 {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		err := s.service.WithdrawBalance(ctx, application.WithdrawBalanceCommand{
+			AccountId: id,
+			Amount:    account.MustParseMoney("1"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	page, err := s.service.ListTransactions(ctx, application.ListTransactionsCommand{AccountId: id, Limit: n})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int64, 0, len(page.Items))
+	for _, entry := range page.Items {
+		ids = append(ids, entry.Id)
+	}
+	slices.Reverse(ids)
+	return ids
+}
+
+func (s testServer) streamURL(id int64) string {
+	return s.srv.URL + "/accounts/" + strconv.FormatInt(id, 10) + "/stream"
+}
+
+// readEvents returns ids of n events of SSE stream.
+func readEvents(t *testing.T, r *bufio.Reader, n int) []int64 {
+	t.Helper()
+	var ids []int64
+	for len(ids) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream after events %v: %v", ids, err)
+		}
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "id: "); ok {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestStreamEventsResume(t *testing.T) {
+	auth := tokenAuthenticator{}
+	s := newTestServer(t, auth)
+	id := s.newAccount(t)
+	auth["alice"] = []int64{id}
+	ids := s.withdrawals(t, id, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.streamURL(id), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer alice")
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(ids[0], 10))
+	resp, err := s.srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	if got := readEvents(t, body, 2); !slices.Equal(got, ids[1:]) {
+		t.Fatalf("resumed with events %v, want %v", got, ids[1:])
+	}
+	// later changes are found by polling of journal
+	next := s.withdrawals(t, id, 1)
+	if got := readEvents(t, body, 1); !slices.Equal(got, next) {
+		t.Fatalf("got event %v, want %v", got, next)
+	}
+}
+
+func (s testServer) dialStream(t *testing.T, id int64, query, origin string) (*websocket.Conn, error) {
+	t.Helper()
+	wsURL := "ws" + strings.TrimPrefix(s.streamURL(id), "http") + "/ws" + query
+	config, err := websocket.NewConfig(wsURL, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Header.Set("Authorization", "Bearer alice")
+	return websocket.DialConfig(config)
+}
+
+func TestStreamWebSocketResume(t *testing.T) {
+	auth := tokenAuthenticator{}
+	s := newTestServer(t, auth)
+	id := s.newAccount(t)
+	auth["alice"] = []int64{id}
+	ids := s.withdrawals(t, id, 3)
+
+	ws, err := s.dialStream(t, id, "?last_event_id="+strconv.FormatInt(ids[0], 10), s.srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	if err := ws.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int64
+	for len(got) < 2 {
+		var message struct {
+			Id int64 `json:"id"`
+		}
+		if err := websocket.JSON.Receive(ws, &message); err != nil {
+			t.Fatalf("receive after %v: %v", got, err)
+		}
+		got = append(got, message.Id)
+	}
+	if !slices.Equal(got, ids[1:]) {
+		t.Fatalf("resumed with messages %v, want %v", got, ids[1:])
+	}
+}
+
+func TestStreamWebSocketOrigin(t *testing.T) {
+	auth := tokenAuthenticator{}
+	s := newTestServer(t, auth)
+	id := s.newAccount(t)
+	auth["alice"] = []int64{id}
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "origin of api", origin: s.srv.URL, allowed: true},
+		{name: "allowed origin", origin: allowedOrigin, allowed: true},
+		{name: "client without origin", origin: "", allowed: true},
+		{name: "other origin", origin: "https://evil.example.com"},
+		{name: "other port", origin: "http://127.0.0.1:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// handshake is made by hand, websocket client always sends origin
+			req, err := http.NewRequest(http.MethodGet, s.streamURL(id)+"/ws", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer alice")
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Sec-WebSocket-Version", "13")
+			req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			resp, err := s.srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			want := http.StatusForbidden
+			if tt.allowed {
+				want = http.StatusSwitchingProtocols
+			}
+			if resp.StatusCode != want {
+				t.Fatalf("status %d, want %d", resp.StatusCode, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+BEGIN;
+alter table accounts
+    drop column version;
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,4 @@
+BEGIN;
+alter table accounts
+    add column version bigint not null default 0;
+COMMIT;
\ No newline at end of file
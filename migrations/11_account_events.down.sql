@@ -0,0 +1,5 @@
+BEGIN;
+drop table account_snapshots;
+drop table account_events;
+drop function forbid_account_events_change();
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,34 @@
+BEGIN;
+create table account_events
+(
+    account_id integer     not null references accounts (id),
+    version    bigint      not null check ( version > 0 ),
+    type       text        not null,
+    data       jsonb       not null,
+    request_id text,
+    created_at timestamptz not null default now(),
+    primary key (account_id, version)
+);
+
+create table account_snapshots
+(
+    account_id integer primary key references accounts (id),
+    version    bigint      not null,
+    state      jsonb       not null,
+    created_at timestamptz not null default now()
+);
+
+-- event stream is append-only
+create function forbid_account_events_change() returns trigger as
+$$
+begin
+    raise exception 'account events are immutable';
+end;
+$$ language plpgsql;
+
+create trigger account_events_immutable
+    before update or delete
+    on account_events
+    for each row
+execute function forbid_account_events_change();
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,3 @@
+BEGIN;
+drop table outbox;
+COMMIT;
\ No newline at end of file
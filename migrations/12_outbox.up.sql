@@ -0,0 +1,12 @@
+BEGIN;
+-- events are kept until relay publishes them
+create table outbox
+(
+    id         bigint generated always as identity primary key,
+    account_id integer     not null references accounts (id),
+    type       text        not null,
+    payload    jsonb       not null,
+    request_id text,
+    created_at timestamptz not null default now()
+);
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,4 @@
+BEGIN;
+drop table webhook_deliveries;
+drop table webhooks;
+COMMIT;
\ No newline at end of file
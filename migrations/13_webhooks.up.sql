@@ -0,0 +1,34 @@
+BEGIN;
+create table webhooks
+(
+    id                    text primary key,
+    url                   text        not null,
+    events                text[]      not null,
+    -- null for webhook of all accounts
+    account_id            integer,
+    low_balance_threshold numeric,
+    secret                text        not null,
+    created_at            timestamptz not null default now()
+);
+
+create table webhook_deliveries
+(
+    id               text primary key,
+    webhook_id       text        not null references webhooks (id) on delete cascade,
+    message_id       bigint      not null,
+    event            text        not null,
+    body             bytea       not null,
+    status           text        not null
+        check ( status in ('pending', 'delivered', 'dead') ),
+    attempts         integer     not null default 0,
+    next_attempt_at  timestamptz not null,
+    last_attempt_at  timestamptz,
+    last_status_code integer,
+    last_error       text,
+    created_at       timestamptz not null default now(),
+    unique (webhook_id, message_id, event)
+);
+
+create index webhook_deliveries_due_idx on webhook_deliveries (next_attempt_at) where status = 'pending';
+create index webhook_deliveries_webhook_idx on webhook_deliveries (webhook_id, created_at);
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,3 @@
+BEGIN;
+drop table api_keys;
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,16 @@
+BEGIN;
+create table api_keys
+(
+    id                   text primary key,
+    name                 text        not null,
+    scopes               text[]      not null,
+    -- hex encoded sha-256 of secret, secret itself isn't stored
+    secret_hash          text        not null,
+    -- secret replaced by rotation is accepted until previous_expires_at
+    previous_secret_hash text,
+    previous_expires_at  timestamptz,
+    created_at           timestamptz not null default now(),
+    rotated_at           timestamptz,
+    revoked_at           timestamptz
+);
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,8 @@
+BEGIN;
+drop index outbox_account_id_idx;
+alter table outbox
+    drop column attempts,
+    drop column available_at,
+    drop column last_error,
+    drop column dead_at;
+COMMIT;
\ No newline at end of file
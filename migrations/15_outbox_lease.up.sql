@@ -0,0 +1,12 @@
+BEGIN;
+-- relay leases messages by available_at and retries failed ones after backoff,
+-- dead messages are kept for inspection and never published again
+alter table outbox
+    add column attempts     integer     not null default 0,
+    add column available_at timestamptz not null default now(),
+    add column last_error   text,
+    add column dead_at      timestamptz;
+
+-- earlier messages of account are looked up on every claim
+create index outbox_account_id_idx on outbox (account_id, id) where dead_at is null;
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,6 @@
+BEGIN;
+alter table accounts
+    alter column balance drop not null,
+    alter column balance drop default,
+    alter column balance type double precision using balance::double precision;
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,10 @@
+BEGIN;
+-- Column is numeric without scale, so it keeps any precision accepted by domain (up to MaxMoneyScale = 4)
+-- and exact count of minor units of account currency. Only existing double precision balances are
+-- rounded to 2 places: before this migration every account was in cents, float noise
+-- (e.g. 10.299999999) is removed rather than kept as excess precision.
+alter table accounts
+    alter column balance type numeric using round(balance::numeric, 2),
+    alter column balance set default 0,
+    alter column balance set not null;
+COMMIT;
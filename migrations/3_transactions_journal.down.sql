@@ -0,0 +1,6 @@
+BEGIN;
+drop table transactions;
+drop function forbid_transactions_change();
+alter table accounts
+    drop constraint accounts_pkey;
+COMMIT;
\ No newline at end of file
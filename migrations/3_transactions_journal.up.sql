@@ -0,0 +1,31 @@
+BEGIN;
+alter table accounts
+    add primary key (id);
+
+create table transactions
+(
+    id            bigint generated always as identity primary key,
+    account_id    integer     not null references accounts (id),
+    type          varchar(32) not null,
+    amount        numeric     not null,
+    balance_after numeric     not null,
+    request_id    text,
+    created_at    timestamptz not null default now()
+);
+
+create index transactions_account_id_idx on transactions (account_id, id desc);
+
+-- journal is append-only
+create function forbid_transactions_change() returns trigger as
+$$
+begin
+    raise exception 'transactions journal is immutable';
+end;
+$$ language plpgsql;
+
+create trigger transactions_immutable
+    before update or delete
+    on transactions
+    for each row
+execute function forbid_transactions_change();
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,3 @@
+BEGIN;
+drop table idempotency_keys;
+COMMIT;
\ No newline at end of file
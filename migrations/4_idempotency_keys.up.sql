@@ -0,0 +1,12 @@
+BEGIN;
+create table idempotency_keys
+(
+    key             text primary key,
+    fingerprint     text        not null,
+    response_status integer     not null,
+    response_body   bytea       not null,
+    created_at      timestamptz not null default now()
+);
+
+create index idempotency_keys_created_at_idx on idempotency_keys (created_at);
+COMMIT;
\ No newline at end of file
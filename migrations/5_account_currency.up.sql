@@ -0,0 +1,7 @@
+BEGIN;
+alter table accounts
+    add column currency char(3) not null default 'USD';
+
+alter table accounts
+    alter column currency drop default;
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,11 @@
+BEGIN;
+drop table exchange_rates;
+
+alter table transactions
+    drop column counterparty_account_id,
+    drop column counter_amount,
+    drop column counter_currency,
+    drop column exchange_rate,
+    drop column market_rate,
+    drop column spread;
+COMMIT;
\ No newline at end of file
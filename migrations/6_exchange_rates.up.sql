@@ -0,0 +1,18 @@
+BEGIN;
+alter table transactions
+    add column counterparty_account_id integer references accounts (id),
+    add column counter_amount          numeric,
+    add column counter_currency        char(3),
+    add column exchange_rate           numeric,
+    add column market_rate             numeric,
+    add column spread                  numeric;
+
+create table exchange_rates
+(
+    base_currency  char(3)     not null,
+    quote_currency char(3)     not null,
+    rate           numeric     not null check ( rate > 0 ),
+    updated_at     timestamptz not null default now(),
+    primary key (base_currency, quote_currency)
+);
+COMMIT;
\ No newline at end of file
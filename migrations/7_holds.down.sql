@@ -0,0 +1,6 @@
+BEGIN;
+alter table transactions
+    drop column hold_id;
+
+drop table holds;
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,19 @@
+BEGIN;
+create table holds
+(
+    id              text primary key,
+    account_id      integer     not null references accounts (id),
+    amount          numeric     not null check ( amount > 0 ),
+    captured_amount numeric     not null default 0,
+    status          text        not null,
+    expires_at      timestamptz not null,
+    created_at      timestamptz not null default now(),
+    updated_at      timestamptz not null default now()
+);
+
+create index holds_active_idx on holds (account_id) where status = 'active';
+create index holds_active_expires_at_idx on holds (expires_at) where status = 'active';
+
+alter table transactions
+    add column hold_id text references holds (id);
+COMMIT;
\ No newline at end of file
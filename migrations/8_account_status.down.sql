@@ -0,0 +1,6 @@
+BEGIN;
+drop table account_status_changes;
+
+alter table accounts
+    drop column status;
+COMMIT;
\ No newline at end of file
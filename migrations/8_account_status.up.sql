@@ -0,0 +1,18 @@
+BEGIN;
+alter table accounts
+    add column status text not null default 'active'
+        check ( status in ('active', 'frozen', 'closed') );
+
+create table account_status_changes
+(
+    id          bigserial primary key,
+    account_id  integer     not null references accounts (id),
+    from_status text        not null,
+    to_status   text        not null,
+    reason      text        not null,
+    request_id  text,
+    created_at  timestamptz not null default now()
+);
+
+create index account_status_changes_account_id_idx on account_status_changes (account_id, id);
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+alter table accounts
+    drop constraint accounts_balance_credit_limit_check,
+    drop column credit_limit;
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+alter table accounts
+    add column credit_limit numeric not null default 0 check ( credit_limit >= 0 ),
+    add constraint accounts_balance_credit_limit_check check ( balance >= -credit_limit );
+COMMIT;
\ No newline at end of file
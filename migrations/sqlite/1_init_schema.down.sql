@@ -0,0 +1,5 @@
+drop table account_status_changes;
+drop table idempotency_keys;
+drop table transactions;
+drop table holds;
+drop table accounts;
\ No newline at end of file
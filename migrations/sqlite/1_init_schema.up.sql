@@ -0,0 +1,85 @@
+-- money is stored as text to keep exact decimal, time as fixed width UTC text
+create table accounts
+(
+    id           integer primary key autoincrement,
+    currency     text    not null,
+    balance      text    not null default '0',
+    credit_limit text    not null default '0',
+    status       text    not null default 'active'
+        check ( status in ('active', 'frozen', 'closed') ),
+    version      integer not null default 0
+);
+
+create table holds
+(
+    id              text primary key,
+    account_id      integer not null references accounts (id),
+    amount          text    not null,
+    captured_amount text    not null default '0',
+    status          text    not null,
+    expires_at      text    not null,
+    created_at      text    not null,
+    updated_at      text    not null
+);
+
+create index holds_active_idx on holds (account_id) where status = 'active';
+create index holds_active_expires_at_idx on holds (expires_at) where status = 'active';
+
+create table transactions
+(
+    id                      integer primary key autoincrement,
+    account_id              integer not null references accounts (id),
+    type                    text    not null,
+    amount                  text    not null,
+    balance_after           text    not null,
+    request_id              text,
+    created_at              text    not null,
+    counterparty_account_id integer references accounts (id),
+    counter_amount          text,
+    counter_currency        text,
+    exchange_rate           text,
+    market_rate             text,
+    spread                  text,
+    hold_id                 text references holds (id)
+);
+
+create index transactions_account_id_idx on transactions (account_id, id desc);
+
+-- journal is append-only
+create trigger transactions_no_update
+    before update
+    on transactions
+begin
+    select raise(abort, 'transactions journal is immutable');
+end;
+
+create trigger transactions_no_delete
+    before delete
+    on transactions
+begin
+    select raise(abort, 'transactions journal is immutable');
+end;
+
+create table idempotency_keys
+(
+    key             text primary key,
+    fingerprint     text    not null,
+    response_status integer not null,
+    response_body   blob    not null,
+    created_at      text    not null
+);
+
+create index idempotency_keys_created_at_idx on idempotency_keys (created_at);
+
+create table account_status_changes
+(
+    id          integer primary key autoincrement,
+    account_id  integer not null references accounts (id),
+    from_status text    not null,
+    to_status   text    not null,
+    reason      text    not null,
+    request_id  text,
+    created_at  text    not null
+);
+
+create index account_status_changes_account_id_idx on account_status_changes (account_id, id);
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- events are kept until relay publishes them
+create table outbox
+(
+    id         integer primary key autoincrement,
+    account_id integer not null references accounts (id),
+    type       text    not null,
+    payload    text    not null,
+    request_id text,
+    created_at text    not null
+);
\ No newline at end of file
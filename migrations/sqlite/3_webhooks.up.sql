@@ -0,0 +1,33 @@
+create table webhooks
+(
+    id                    text primary key,
+    url                   text not null,
+    -- json array of event types
+    events                text not null,
+    -- null for webhook of all accounts
+    account_id            integer,
+    low_balance_threshold text,
+    secret                text not null,
+    created_at            text not null
+);
+
+create table webhook_deliveries
+(
+    id               text primary key,
+    webhook_id       text    not null references webhooks (id) on delete cascade,
+    message_id       integer not null,
+    event            text    not null,
+    body             blob    not null,
+    status           text    not null
+        check ( status in ('pending', 'delivered', 'dead') ),
+    attempts         integer not null default 0,
+    next_attempt_at  text    not null,
+    last_attempt_at  text,
+    last_status_code integer,
+    last_error       text,
+    created_at       text    not null,
+    unique (webhook_id, message_id, event)
+);
+
+create index webhook_deliveries_due_idx on webhook_deliveries (next_attempt_at) where status = 'pending';
+create index webhook_deliveries_webhook_idx on webhook_deliveries (webhook_id, created_at);
\ No newline at end of file
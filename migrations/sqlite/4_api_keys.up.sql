@@ -0,0 +1,15 @@
+create table api_keys
+(
+    id                   text primary key,
+    name                 text not null,
+    -- json array of scopes
+    scopes               text not null,
+    -- hex encoded sha-256 of secret, secret itself isn't stored
+    secret_hash          text not null,
+    -- secret replaced by rotation is accepted until previous_expires_at
+    previous_secret_hash text,
+    previous_expires_at  text,
+    created_at           text not null,
+    rotated_at           text,
+    revoked_at           text
+);
\ No newline at end of file
@@ -0,0 +1,5 @@
+drop index outbox_account_id_idx;
+alter table outbox drop column dead_at;
+alter table outbox drop column last_error;
+alter table outbox drop column available_at;
+alter table outbox drop column attempts;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- relay leases messages by available_at and retries failed ones after backoff,
+-- dead messages are kept for inspection and never published again
+alter table outbox add column attempts integer not null default 0;
+alter table outbox add column available_at text not null default '';
+alter table outbox add column last_error text;
+alter table outbox add column dead_at text;
+update outbox set available_at = created_at;
+
+-- earlier messages of account are looked up on every claim
+create index outbox_account_id_idx on outbox (account_id, id) where dead_at is null;
\ No newline at end of file
@@ -0,0 +1,200 @@
+package bank
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Operations changing accounts are sent with idempotency key, so they are retried safely.
+// Empty currency of operation means currency of account.
+
+func accountPath(accountId int64, suffix string) string {
+	return "/accounts/" + strconv.FormatInt(accountId, 10) + suffix
+}
+
+type amountBody struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency,omitempty"`
+}
+
+// CreateAccount opens account in currency, default currency of service if empty.
+func (c *Client) CreateAccount(ctx context.Context, currency string) (int64, error) {
+	var result struct {
+		Id int64 `json:"id"`
+	}
+	err := c.do(ctx, call{
+		method: http.MethodPost,
+		path:   "/accounts",
+		body: struct {
+			Currency string `json:"currency,omitempty"`
+		}{currency},
+		idempotent: true,
+	}, &result)
+	return result.Id, err
+}
+
+func (c *Client) Deposit(ctx context.Context, accountId int64, amount, currency string) error {
+	return c.do(ctx, call{
+		method:     http.MethodPost,
+		path:       accountPath(accountId, "/deposit"),
+		body:       amountBody{Amount: amount, Currency: currency},
+		idempotent: true,
+	}, nil)
+}
+
+func (c *Client) Withdraw(ctx context.Context, accountId int64, amount, currency string) error {
+	return c.do(ctx, call{
+		method:     http.MethodPost,
+		path:       accountPath(accountId, "/withdraw"),
+		body:       amountBody{Amount: amount, Currency: currency},
+		idempotent: true,
+	}, nil)
+}
+
+func (c *Client) GetBalance(ctx context.Context, accountId int64) (Balance, error) {
+	var balance Balance
+	err := c.do(ctx, call{
+		method: http.MethodGet,
+		path:   accountPath(accountId, "/balance"),
+		safe:   true,
+	}, &balance)
+	return balance, err
+}
+
+func (c *Client) Transfer(ctx context.Context, req TransferRequest) (Transfer, error) {
+	var transfer Transfer
+	err := c.do(ctx, call{
+		method:     http.MethodPost,
+		path:       "/transfers",
+		body:       req,
+		idempotent: true,
+	}, &transfer)
+	return transfer, err
+}
+
+// ListTransactions returns journal of account newest first,
+// the next page is requested with NextCursor of previous one.
+func (c *Client) ListTransactions(ctx context.Context, accountId int64, filter TransactionsFilter) (TransactionsPage, error) {
+	query := url.Values{}
+	if filter.Cursor != "" {
+		query.Set("cursor", filter.Cursor)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if !filter.From.IsZero() {
+		query.Set("from", filter.From.Format(time.RFC3339Nano))
+	}
+	if !filter.To.IsZero() {
+		query.Set("to", filter.To.Format(time.RFC3339Nano))
+	}
+	for _, t := range filter.Types {
+		query.Add("type", t)
+	}
+
+	var page TransactionsPage
+	err := c.do(ctx, call{
+		method: http.MethodGet,
+		path:   accountPath(accountId, "/transactions"),
+		query:  query,
+		safe:   true,
+	}, &page)
+	return page, err
+}
+
+func (c *Client) PlaceHold(ctx context.Context, accountId int64, amount, currency string) (Hold, error) {
+	var hold Hold
+	err := c.do(ctx, call{
+		method:     http.MethodPost,
+		path:       accountPath(accountId, "/holds"),
+		body:       amountBody{Amount: amount, Currency: currency},
+		idempotent: true,
+	}, &hold)
+	return hold, err
+}
+
+// CaptureHold withdraws amount of hold, empty amount captures the whole hold.
+func (c *Client) CaptureHold(ctx context.Context, accountId int64, holdId string, amount string) (Hold, error) {
+	var body struct {
+		Amount *string `json:"amount,omitempty"`
+	}
+	if amount != "" {
+		body.Amount = &amount
+	}
+
+	var hold Hold
+	err := c.do(ctx, call{
+		method:     http.MethodPost,
+		path:       accountPath(accountId, "/holds/"+url.PathEscape(holdId)+"/capture"),
+		body:       body,
+		idempotent: true,
+	}, &hold)
+	return hold, err
+}
+
+func (c *Client) ReleaseHold(ctx context.Context, accountId int64, holdId string) (Hold, error) {
+	var hold Hold
+	err := c.do(ctx, call{
+		method:     http.MethodPost,
+		path:       accountPath(accountId, "/holds/"+url.PathEscape(holdId)+"/release"),
+		idempotent: true,
+	}, &hold)
+	return hold, err
+}
+
+// ListAccounts is admin operation, it returns accounts oldest first,
+// the next page is requested with NextCursor of previous one.
+func (c *Client) ListAccounts(ctx context.Context, filter AccountsFilter) (AccountsPage, error) {
+	query := url.Values{}
+	if filter.Cursor != "" {
+		query.Set("cursor", filter.Cursor)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+
+	var page AccountsPage
+	err := c.do(ctx, call{
+		method: http.MethodGet,
+		path:   "/admin/accounts",
+		query:  query,
+		safe:   true,
+	}, &page)
+	return page, err
+}
+
+// ChangeStatus is admin operation, status is active, frozen or closed.
+func (c *Client) ChangeStatus(ctx context.Context, accountId int64, status, reason string) (StatusChange, error) {
+	var change StatusChange
+	err := c.do(ctx, call{
+		method: http.MethodPost,
+		path:   "/admin" + accountPath(accountId, "/status"),
+		body: struct {
+			Status string `json:"status"`
+			Reason string `json:"reason,omitempty"`
+		}{status, reason},
+		idempotent: true,
+	}, &change)
+	return change, err
+}
+
+// SetCreditLimit is admin operation, it returns balance with new limit.
+func (c *Client) SetCreditLimit(ctx context.Context, accountId int64, limit, currency string) (Balance, error) {
+	var balance Balance
+	err := c.do(ctx, call{
+		method: http.MethodPut,
+		path:   "/admin" + accountPath(accountId, "/credit-limit"),
+		body: struct {
+			CreditLimit string `json:"credit_limit"`
+			Currency    string `json:"currency,omitempty"`
+		}{limit, currency},
+		idempotent: true,
+	}, &balance)
+	return balance, err
+}
@@ -0,0 +1,74 @@
+package bank
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+func apiKeyPath(keyId string, suffix string) string {
+	return "/admin/api-keys/" + url.PathEscape(keyId) + suffix
+}
+
+// IssueAPIKey returns the only copy of key, service stores only its hash.
+func (c *Client) IssueAPIKey(ctx context.Context, req IssueAPIKeyRequest) (APIKey, error) {
+	var key APIKey
+	err := c.do(ctx, call{
+		method: http.MethodPost,
+		path:   "/admin/api-keys",
+		body:   req,
+	}, &key)
+	return key, err
+}
+
+func (c *Client) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var result struct {
+		APIKeys []APIKey `json:"api_keys"`
+	}
+	err := c.do(ctx, call{
+		method: http.MethodGet,
+		path:   "/admin/api-keys",
+		safe:   true,
+	}, &result)
+	return result.APIKeys, err
+}
+
+func (c *Client) GetAPIKey(ctx context.Context, keyId string) (APIKey, error) {
+	var key APIKey
+	err := c.do(ctx, call{
+		method: http.MethodGet,
+		path:   apiKeyPath(keyId, ""),
+		safe:   true,
+	}, &key)
+	return key, err
+}
+
+// RotateAPIKey returns new key, the previous one is accepted during gracePeriod.
+func (c *Client) RotateAPIKey(ctx context.Context, keyId string, gracePeriod time.Duration) (APIKey, error) {
+	var body struct {
+		GracePeriod string `json:"grace_period,omitempty"`
+	}
+	if gracePeriod > 0 {
+		body.GracePeriod = gracePeriod.String()
+	}
+
+	var key APIKey
+	err := c.do(ctx, call{
+		method: http.MethodPost,
+		path:   apiKeyPath(keyId, "/rotate"),
+		body:   body,
+	}, &key)
+	return key, err
+}
+
+// RevokeAPIKey disables key at once, revoking of revoked key succeeds.
+func (c *Client) RevokeAPIKey(ctx context.Context, keyId string) (APIKey, error) {
+	var key APIKey
+	err := c.do(ctx, call{
+		method: http.MethodPost,
+		path:   apiKeyPath(keyId, "/revoke"),
+		safe:   true,
+	}, &key)
+	return key, err
+}
@@ -0,0 +1,178 @@
+// Package banktest runs bank service in process for tests of its clients.
+package banktest
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/internal/application"
+	"github.com/vitaliy-ukiru/bank-service/internal/config"
+	"github.com/vitaliy-ukiru/bank-service/internal/domain/account"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/acquire"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/exchange"
+	"github.com/vitaliy-ukiru/bank-service/internal/infrastructure/repository/accounts"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi"
+	"github.com/vitaliy-ukiru/bank-service/internal/transport/webapi/controllers"
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/bank"
+	"github.com/vitaliy-ukiru/bank-service/pkg/logging"
+)
+
+const (
+	holdTTL            = 7 * 24 * time.Hour
+	streamPollInterval = 10 * time.Millisecond
+	streamStallTimeout = 30 * time.Second
+	// idempotencyRetention outlives tests, records aren't cleaned
+	idempotencyRetention = 24 * time.Hour
+)
+
+// Server serves HTTP API with in-memory storage, every server has its own accounts.
+// Exchange rates aren't configured, so only transfers within one currency succeed.
+type Server struct {
+	// URL is base url of API, e.g. http://127.0.0.1:1234
+	URL string
+
+	srv    *httptest.Server
+	stop   context.CancelFunc
+	locker application.Acquirer
+}
+
+type options struct {
+	lockWait time.Duration
+}
+
+type Option func(*options)
+
+// WithLockWait limits waiting for account held by other operation,
+// request waiting longer fails with account busy error (409 and code "account_busy").
+// By default, requests wait until account is released.
+func WithLockWait(d time.Duration) Option {
+	return func(o *options) {
+		o.lockWait = d
+	}
+}
+
+// NewServer starts server, it must be closed by Close.
+func NewServer(opts ...Option) *Server {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	repo := accounts.NewInMemory()
+	rates, err := exchange.NewStaticProvider(nil)
+	if err != nil {
+		// provider without rates can't be invalid
+		panic(err)
+	}
+
+	var locker application.Acquirer = acquire.NewInMemoryAcquirer(repo)
+	if o.lockWait > 0 {
+		locker = lockWaitAcquirer{Acquirer: locker, wait: o.lockWait}
+	}
+
+	accountService := application.NewAccountService(
+		locker,
+		repo,
+		rates,
+		account.ConversionPolicy{Rounding: account.RoundHalfEven},
+		holdTTL,
+		account.StatusPolicy{FrozenAcceptsDeposits: true},
+		idempotencyRetention,
+	)
+	balanceStream := application.NewBalanceStream(repo, streamPollInterval, streamStallTimeout)
+	apiKeyService, err := application.NewAPIKeyService(repo, "")
+	if err != nil {
+		// service without bootstrap key can't be invalid
+		panic(err)
+	}
+
+	log := logging.Discard()
+	ctx, stop := context.WithCancel(logging.Context(context.Background(), log))
+	go balanceStream.Run(ctx)
+
+	router := webapi.New(
+		config.Config{},
+		controllers.NewAccountController(accountService),
+		controllers.NewWebhookController(application.NewWebhookService(repo)),
+		controllers.NewStreamController(balanceStream, nil),
+		controllers.NewAPIKeyController(apiKeyService),
+		// authentication is disabled, so tests don't need keys
+		nil,
+		log,
+	)
+	srv := httptest.NewServer(router.Handler())
+	return &Server{
+		URL:    srv.URL,
+		srv:    srv,
+		stop:   stop,
+		locker: locker,
+	}
+}
+
+// errReleased discards changes of account held by LockAccount.
+var errReleased = errors.New("banktest: account is released")
+
+// LockAccount holds account as operation in progress, so concurrent requests to it
+// wait or fail with account busy error, see WithLockWait. Returned func releases account.
+func (s *Server) LockAccount(id int64) (release func(), err error) {
+	locked := make(chan struct{})
+	unlock := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- s.locker.Acquire(context.Background(), id, func(application.BankAccount) error {
+			close(locked)
+			<-unlock
+			return errReleased
+		})
+	}()
+
+	select {
+	case <-locked:
+	case err := <-done:
+		return nil, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(unlock)
+			<-done
+		})
+	}, nil
+}
+
+// Client returns client of server, retries are disabled unless policy is passed in opts.
+func (s *Server) Client(opts ...bank.Option) *bank.Client {
+	opts = append([]bank.Option{
+		bank.WithHTTPClient(s.srv.Client()),
+		bank.WithRetryPolicy(bank.RetryPolicy{MaxAttempts: 1}),
+	}, opts...)
+	return bank.New(s.URL, opts...)
+}
+
+// Close closes open balance streams and shuts down server.
+func (s *Server) Close() {
+	s.stop()
+	s.srv.Close()
+}
+
+// lockWaitAcquirer limits operations by wait. In-memory storage doesn't check context,
+// so in effect only waiting for account lock is limited.
+type lockWaitAcquirer struct {
+	application.Acquirer
+	wait time.Duration
+}
+
+func (a lockWaitAcquirer) Acquire(ctx context.Context, id int64, fn application.AccountProcessFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, a.wait)
+	defer cancel()
+	return a.Acquirer.Acquire(ctx, id, fn)
+}
+
+func (a lockWaitAcquirer) AcquirePair(ctx context.Context, firstId, secondId int64, fn application.PairProcessFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, a.wait)
+	defer cancel()
+	return a.Acquirer.AcquirePair(ctx, firstId, secondId, fn)
+}
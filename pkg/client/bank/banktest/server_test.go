@@ -0,0 +1,95 @@
+package banktest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/bank"
+	"github.com/vitaliy-ukiru/bank-service/pkg/client/bank/banktest"
+)
+
+func newAccount(t *testing.T, client *bank.Client, amount string) int64 {
+	t.Helper()
+	ctx := context.Background()
+
+	id, err := client.CreateAccount(ctx, "")
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	if err := client.Deposit(ctx, id, amount, ""); err != nil {
+		t.Fatalf("deposit: %v", err)
+	}
+	return id
+}
+
+func TestAccountBusy(t *testing.T) {
+	srv := banktest.NewServer(banktest.WithLockWait(50 * time.Millisecond))
+	defer srv.Close()
+	client := srv.Client()
+	id := newAccount(t, client, "100")
+
+	release, err := srv.LockAccount(id)
+	if err != nil {
+		t.Fatalf("lock account: %v", err)
+	}
+	defer release()
+
+	err = client.Withdraw(context.Background(), id, "10", "")
+	var apiErr *bank.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("withdraw of locked account: want APIError, got %v", err)
+	}
+	if apiErr.StatusCode != 409 || apiErr.Code != bank.CodeAccountBusy {
+		t.Fatalf("withdraw of locked account: want 409 %q, got %d %q (%s)",
+			bank.CodeAccountBusy, apiErr.StatusCode, apiErr.Code, apiErr.Message)
+	}
+	if !errors.Is(err, bank.ErrConflict) {
+		t.Errorf("busy account error %v isn't ErrConflict", err)
+	}
+}
+
+func TestAccountBusyIsRetried(t *testing.T) {
+	srv := banktest.NewServer(banktest.WithLockWait(20 * time.Millisecond))
+	defer srv.Close()
+	client := srv.Client(bank.WithRetryPolicy(bank.RetryPolicy{
+		MaxAttempts: 10,
+		Backoff:     20 * time.Millisecond,
+		MaxBackoff:  100 * time.Millisecond,
+	}))
+	id := newAccount(t, client, "100")
+
+	release, err := srv.LockAccount(id)
+	if err != nil {
+		t.Fatalf("lock account: %v", err)
+	}
+	time.AfterFunc(100*time.Millisecond, release)
+
+	if err := client.Withdraw(context.Background(), id, "10", ""); err != nil {
+		t.Fatalf("withdraw isn't retried until account is released: %v", err)
+	}
+	balance, err := client.GetBalance(context.Background(), id)
+	if err != nil {
+		t.Fatalf("get balance: %v", err)
+	}
+	if balance.Balance != "90.00" {
+		t.Errorf("want balance 90.00 after single withdraw, got %s", balance.Balance)
+	}
+}
+
+func TestInsufficientFunds(t *testing.T) {
+	srv := banktest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	id := newAccount(t, client, "100")
+
+	err := client.Withdraw(context.Background(), id, "1000", "")
+	if !errors.Is(err, bank.ErrInsufficientFunds) {
+		t.Fatalf("want ErrInsufficientFunds, got %v", err)
+	}
+	var apiErr *bank.APIError
+	if errors.As(err, &apiErr) && apiErr.Code != bank.CodeInsufficientFunds {
+		t.Errorf("want code %q, got %q", bank.CodeInsufficientFunds, apiErr.Code)
+	}
+}
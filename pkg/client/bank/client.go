@@ -0,0 +1,261 @@
+// Package bank is client of HTTP API of bank service.
+package bank
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vitaliy-ukiru/bank-service/pkg/requestid"
+)
+
+const (
+	headerIdempotencyKey = "Idempotency-Key"
+	headerRequestId      = "X-Request-Id"
+	headerAPIKey         = "X-API-Key"
+)
+
+// RetryPolicy limits retries of calls which are safe to repeat: reads and operations
+// sent with idempotency key. Delay before n-th retry is Backoff * 2^(n-1) with jitter, up to MaxBackoff.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt, 1 disables retries.
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	Backoff:     100 * time.Millisecond,
+	MaxBackoff:  2 * time.Second,
+}
+
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := float64(p.Backoff) * math.Pow(2, float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	// full delay is waited at most, so concurrent clients don't retry together
+	return time.Duration(d/2 + mathrand.Float64()*d/2)
+}
+
+type Option func(c *Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithAPIKey sets key sent in X-API-Key header, it is required when authentication of service is enabled.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithBearerToken sets JWT of end user sent in Authorization header, it is preferred over API key by service.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.bearerToken = token
+	}
+}
+
+// Client is safe for concurrent use.
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	retry       RetryPolicy
+	apiKey      string
+	bearerToken string
+}
+
+// New returns client of service at baseURL, e.g. http://localhost:8080.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+	return c
+}
+
+type idempotencyKeyKey struct{}
+
+// WithIdempotencyKey sets key of operation called with ctx instead of generated one.
+// Operation repeated by caller with the same key after failure is applied once.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// WithRequestId sets request id sent in X-Request-Id header, so call can be found in logs of service.
+// Request id set by requestid.Context is sent as well.
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return requestid.Context(ctx, requestId)
+}
+
+func newIdempotencyKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// call is request to API.
+type call struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	// safe call is retried as is
+	safe bool
+	// idempotent call is sent with idempotency key, so it can be retried
+	idempotent bool
+}
+
+// do calls API and decodes result of response into result if it isn't nil.
+func (c *Client) do(ctx context.Context, cl call, result any) error {
+	var body []byte
+	if cl.body != nil {
+		var err error
+		body, err = json.Marshal(cl.body)
+		if err != nil {
+			return fmt.Errorf("bank: encode request: %w", err)
+		}
+	}
+
+	var key string
+	if cl.idempotent {
+		key, _ = ctx.Value(idempotencyKeyKey{}).(string)
+		if key == "" {
+			var err error
+			if key, err = newIdempotencyKey(); err != nil {
+				return fmt.Errorf("bank: generate idempotency key: %w", err)
+			}
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := c.send(ctx, cl, body, key, result)
+		if err == nil ||
+			!(cl.safe || cl.idempotent) ||
+			attempt >= c.retry.MaxAttempts ||
+			!retryable(ctx, err) {
+			return err
+		}
+
+		timer := time.NewTimer(c.retry.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, cl call, body []byte, key string, result any) error {
+	req, err := c.newRequest(ctx, cl, body)
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		req.Header.Set(headerIdempotencyKey, key)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("bank: %s %s: %w", cl.method, cl.path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("bank: read response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newAPIError(resp, data)
+	}
+	if result == nil {
+		return nil
+	}
+
+	var envelope struct {
+		Ok     bool            `json:"ok"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("bank: decode response: %w", err)
+	}
+	if err := json.Unmarshal(envelope.Result, result); err != nil {
+		return fmt.Errorf("bank: decode result: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, cl call, body []byte) (*http.Request, error) {
+	u := c.baseURL + cl.path
+	if len(cl.query) != 0 {
+		u += "?" + cl.query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, cl.method, u, reader)
+	if err != nil {
+		return nil, fmt.Errorf("bank: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set(headerAPIKey, c.apiKey)
+	}
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+	if reqId := requestid.FromContext(ctx); reqId != "" {
+		req.Header.Set(headerRequestId, reqId)
+	}
+	return req, nil
+}
+
+// retryable reports whether call may succeed if it is repeated.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.temporary()
+	}
+	// request isn't sent or response isn't received
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
@@ -0,0 +1,104 @@
+package bank
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kinds of APIError, they are checked with errors.Is.
+var (
+	ErrNotFound          = errors.New("bank: not found")
+	ErrInsufficientFunds = errors.New("bank: insufficient funds")
+	ErrValidation        = errors.New("bank: invalid request")
+	// ErrUnauthorized is returned when API key is missing or invalid.
+	ErrUnauthorized = errors.New("bank: unauthorized")
+	// ErrForbidden is returned for operation not allowed on frozen or closed account
+	// or not allowed by scopes of API key.
+	ErrForbidden = errors.New("bank: operation is forbidden")
+	// ErrConflict is returned when state of account doesn't allow operation.
+	ErrConflict    = errors.New("bank: conflict")
+	ErrUnavailable = errors.New("bank: service unavailable")
+)
+
+// Codes of service errors distinguished by client.
+const (
+	CodeAccountBusy            = "account_busy"
+	CodeInsufficientFunds      = "insufficient_funds"
+	CodeIdempotencyKeyConflict = "idempotency_key_conflict"
+)
+
+// APIError is error response of service.
+type APIError struct {
+	StatusCode int
+	// Message is error of service, e.g. "account not found".
+	Message string
+	// Code is stable id of error, e.g. CodeAccountBusy, empty for most errors.
+	Code      string
+	RequestId string
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestId:  resp.Header.Get(headerRequestId),
+	}
+	var errResp struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error != "" {
+		apiErr.Message = errResp.Error
+		apiErr.Code = errResp.Code
+	} else {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("bank: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// Is matches kind of error, e.g. errors.Is(err, bank.ErrNotFound).
+func (e *APIError) Is(target error) bool {
+	kind := e.kind()
+	return kind != nil && kind == target
+}
+
+func (e *APIError) kind() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrValidation
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusConflict:
+		if e.Code == CodeInsufficientFunds {
+			return ErrInsufficientFunds
+		}
+		return ErrConflict
+	case http.StatusServiceUnavailable:
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// temporary reports whether the same request may succeed later.
+func (e *APIError) temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		return e.Code == CodeAccountBusy || e.Code == CodeIdempotencyKeyConflict
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package bank
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BalanceStream reads balance changes of account sent as Server-Sent Events.
+type BalanceStream struct {
+	body        io.ReadCloser
+	reader      *bufio.Reader
+	lastEventId int64
+}
+
+// StreamBalance follows journal of account until ctx is done. New stream (zero lastEventId)
+// receives the latest entry first, stream resumed after lastEventId receives all later entries.
+// Stream isn't limited by timeout of http client, so client without timeout should be used.
+func (c *Client) StreamBalance(ctx context.Context, accountId int64, lastEventId int64) (*BalanceStream, error) {
+	req, err := c.newRequest(ctx, call{method: http.MethodGet, path: accountPath(accountId, "/stream")}, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventId > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(lastEventId, 10))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("bank: %s %s: %w", req.Method, req.URL.Path, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp, data)
+	}
+	return &BalanceStream{
+		body:        resp.Body,
+		reader:      bufio.NewReader(resp.Body),
+		lastEventId: lastEventId,
+	}, nil
+}
+
+// Recv waits for the next entry. It returns io.EOF when service closes stream,
+// e.g. on shutdown or when client doesn't keep up, stream is resumed with LastEventId.
+func (s *BalanceStream) Recv() (Transaction, error) {
+	var (
+		id   int64
+		data strings.Builder
+	)
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return Transaction{}, io.EOF
+			}
+			return Transaction{}, fmt.Errorf("bank: read stream: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			// end of event, comments (pings) have no data
+			if data.Len() == 0 {
+				continue
+			}
+			var entry Transaction
+			if err := json.Unmarshal([]byte(data.String()), &entry); err != nil {
+				return Transaction{}, fmt.Errorf("bank: decode event: %w", err)
+			}
+			if id != 0 {
+				s.lastEventId = id
+			}
+			return entry, nil
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "id:"):
+			id, _ = strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "id:")), 10, 64)
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() != 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
+
+// LastEventId is id of the last received entry.
+func (s *BalanceStream) LastEventId() int64 {
+	return s.lastEventId
+}
+
+func (s *BalanceStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,168 @@
+package bank
+
+import "time"
+
+// Amounts and rates are exact decimal strings, e.g. "10.50",
+// precision of amount is limited by minor units of currency.
+
+type Balance struct {
+	// Balance is ledger balance, it is negative when credit is used.
+	Balance string `json:"balance"`
+	// AvailableBalance includes unused credit and excludes active holds.
+	AvailableBalance string `json:"available_balance"`
+	CreditLimit      string `json:"credit_limit"`
+	CreditHeadroom   string `json:"credit_headroom"`
+	Currency         string `json:"currency"`
+	Status           string `json:"status"`
+}
+
+// AccountSummary is account with its balance, listed by admin.
+type AccountSummary struct {
+	Id int64 `json:"id"`
+	Balance
+}
+
+type AccountsPage struct {
+	Items []AccountSummary `json:"items"`
+	// NextCursor is empty when there are no more accounts.
+	NextCursor string `json:"next_cursor"`
+}
+
+// AccountsFilter is optional, zero fields don't filter.
+type AccountsFilter struct {
+	Cursor string
+	Limit  int
+	Status string
+}
+
+// Transfer is result of transfer, Credit = round(Debit * Rate).
+type Transfer struct {
+	FromAccountId  int64  `json:"from_account_id"`
+	ToAccountId    int64  `json:"to_account_id"`
+	Debit          string `json:"debit"`
+	DebitCurrency  string `json:"debit_currency"`
+	Credit         string `json:"credit"`
+	CreditCurrency string `json:"credit_currency"`
+	Rate           string `json:"rate"`
+	MarketRate     string `json:"market_rate"`
+	Spread         string `json:"spread"`
+}
+
+// Transaction is entry of account journal.
+type Transaction struct {
+	Id           int64     `json:"id"`
+	AccountId    int64     `json:"account_id"`
+	Type         string    `json:"type"`
+	Currency     string    `json:"currency"`
+	Amount       string    `json:"amount"`
+	BalanceAfter string    `json:"balance_after"`
+	RequestId    string    `json:"request_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	// Transfer is set for transfer operations.
+	Transfer *Transfer `json:"transfer"`
+	// HoldId is set for capture of hold.
+	HoldId string `json:"hold_id"`
+}
+
+type TransactionsPage struct {
+	Items []Transaction `json:"items"`
+	// NextCursor is empty when there are no more entries.
+	NextCursor string `json:"next_cursor"`
+}
+
+// TransactionsFilter is optional, zero fields don't filter.
+type TransactionsFilter struct {
+	Cursor string
+	Limit  int
+	From   time.Time
+	To     time.Time
+	Types  []string
+}
+
+type Hold struct {
+	Id             string    `json:"id"`
+	AccountId      int64     `json:"account_id"`
+	Amount         string    `json:"amount"`
+	CapturedAmount string    `json:"captured_amount"`
+	Status         string    `json:"status"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type StatusChange struct {
+	AccountId      int64  `json:"account_id"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status"`
+	Reason         string `json:"reason"`
+}
+
+type TransferRequest struct {
+	FromAccountId int64  `json:"from_account_id"`
+	ToAccountId   int64  `json:"to_account_id"`
+	Amount        string `json:"amount"`
+	// Currency is optional, it must match currency of source account.
+	Currency string `json:"currency,omitempty"`
+}
+
+type Webhook struct {
+	Id     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// AccountId is zero for webhook of all accounts.
+	AccountId           int64  `json:"account_id"`
+	LowBalanceThreshold string `json:"low_balance_threshold"`
+	// Secret is returned only by CreateWebhook.
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// AccountId is optional, webhook receives events of all accounts by default.
+	AccountId           int64  `json:"account_id,omitempty"`
+	LowBalanceThreshold string `json:"low_balance_threshold,omitempty"`
+	// Secret is optional, it is generated by default.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDelivery struct {
+	Id        string `json:"id"`
+	MessageId int64  `json:"message_id"`
+	Event     string `json:"event"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	// NextAttemptAt is set for pending delivery.
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastAttemptAt  time.Time `json:"last_attempt_at"`
+	LastStatusCode int       `json:"last_status_code"`
+	LastError      string    `json:"last_error"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Scopes of API keys, ScopeAdmin includes all others.
+const (
+	ScopeRead     = "read"
+	ScopeDeposit  = "deposit"
+	ScopeWithdraw = "withdraw"
+	ScopeAdmin    = "admin"
+)
+
+type APIKey struct {
+	Id     string   `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Key is returned only by IssueAPIKey and RotateAPIKey.
+	Key       string    `json:"key"`
+	CreatedAt time.Time `json:"created_at"`
+	// RotatedAt and RevokedAt are zero until key is rotated or revoked.
+	RotatedAt time.Time `json:"rotated_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+	// PreviousExpiresAt is end of grace period of key replaced by the last rotation.
+	PreviousExpiresAt time.Time `json:"previous_expires_at"`
+}
+
+type IssueAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
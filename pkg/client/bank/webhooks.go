@@ -0,0 +1,84 @@
+package bank
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func webhookPath(webhookId string, suffix string) string {
+	return "/webhooks/" + url.PathEscape(webhookId) + suffix
+}
+
+// CreateWebhook returns the only copy of webhook secret.
+func (c *Client) CreateWebhook(ctx context.Context, req CreateWebhookRequest) (Webhook, error) {
+	var webhook Webhook
+	err := c.do(ctx, call{
+		method: http.MethodPost,
+		path:   "/webhooks",
+		body:   req,
+	}, &webhook)
+	return webhook, err
+}
+
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var result struct {
+		Webhooks []Webhook `json:"webhooks"`
+	}
+	err := c.do(ctx, call{
+		method: http.MethodGet,
+		path:   "/webhooks",
+		safe:   true,
+	}, &result)
+	return result.Webhooks, err
+}
+
+func (c *Client) GetWebhook(ctx context.Context, webhookId string) (Webhook, error) {
+	var webhook Webhook
+	err := c.do(ctx, call{
+		method: http.MethodGet,
+		path:   webhookPath(webhookId, ""),
+		safe:   true,
+	}, &webhook)
+	return webhook, err
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, webhookId string) error {
+	return c.do(ctx, call{
+		method: http.MethodDelete,
+		path:   webhookPath(webhookId, ""),
+	}, nil)
+}
+
+// ListWebhookDeliveries returns deliveries newest first, empty status and zero limit don't filter.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, webhookId string, status string, limit int) ([]WebhookDelivery, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var result struct {
+		Deliveries []WebhookDelivery `json:"deliveries"`
+	}
+	err := c.do(ctx, call{
+		method: http.MethodGet,
+		path:   webhookPath(webhookId, "/deliveries"),
+		query:  query,
+		safe:   true,
+	}, &result)
+	return result.Deliveries, err
+}
+
+// RetryWebhookDelivery sends dead delivery again.
+func (c *Client) RetryWebhookDelivery(ctx context.Context, webhookId, deliveryId string) (WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	err := c.do(ctx, call{
+		method: http.MethodPost,
+		path:   webhookPath(webhookId, "/deliveries/"+url.PathEscape(deliveryId)+"/retry"),
+	}, &delivery)
+	return delivery, err
+}
@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const DefaultBusyTimeout = 5 * time.Second
+
+// ConnString enables WAL journal and foreign keys, locked database
+// is waited up to busyTimeout before SQLITE_BUSY is returned.
+func ConnString(path string, busyTimeout time.Duration) string {
+	if busyTimeout <= 0 {
+		busyTimeout = DefaultBusyTimeout
+	}
+	return fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)",
+		path,
+		busyTimeout.Milliseconds(),
+	)
+}
+
+// MigrateConnString is database url for golang-migrate sqlite driver.
+func MigrateConnString(path string) string {
+	return fmt.Sprintf("sqlite://%s?_pragma=busy_timeout(%d)&_pragma=foreign_keys(1)",
+		path,
+		DefaultBusyTimeout.Milliseconds(),
+	)
+}
+
+func New(ctx context.Context, connString string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", connString)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -59,6 +59,11 @@ func New(w io.Writer, isDebug bool) Logger {
 	return Logger{log: log}
 }
 
+// Discard returns logger dropping all records, it doesn't replace default logger.
+func Discard() Logger {
+	return Logger{log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
 func (l Logger) Info(op string, msg string, args ...Attr) {
 	l.log.LogAttrs(
 		context.Background(),
@@ -105,8 +110,13 @@ func prepareAttrs(attrs []Attr, preparedAttrs ...slog.Attr) []slog.Attr {
 	return res
 }
 
+// NewLogLogger writes to l, unlike ConfigureLogLogger it doesn't change output of log package.
+func NewLogLogger(l Logger, level slog.Level) *stdLog.Logger {
+	return slog.NewLogLogger(l.log.Handler(), level)
+}
+
 func ConfigureLogLogger(l Logger, level slog.Level) *stdLog.Logger {
-	stdLogWrapper := slog.NewLogLogger(l.log.Handler(), level)
+	stdLogWrapper := NewLogLogger(l, level)
 	stdLog.SetOutput(stdLogWrapper.Writer())
 	return stdLogWrapper
 }
@@ -0,0 +1,15 @@
+package requestid
+
+import "context"
+
+type requestIdKey struct{}
+
+// FromContext returns request id stored in context or empty string.
+func FromContext(ctx context.Context) string {
+	v, _ := ctx.Value(requestIdKey{}).(string)
+	return v
+}
+
+func Context(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
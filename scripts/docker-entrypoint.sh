@@ -11,5 +11,14 @@ function migrate () {
   fi
 }
 
+function check_auth () {
+  local enabled="${AUTH_ENABLED:-true}"
+  if [[ "${enabled,,}" != "false" && "${enabled}" != "0" && -z "${AUTH_BOOTSTRAP_KEY}" ]]; then
+    echo "authentication is enabled without AUTH_BOOTSTRAP_KEY, issue the first admin key with" \
+      "./bin/bankctl -admin keys issue -name admin -scopes admin" >&2
+  fi
+}
+
 migrate
+check_auth
 ./bin/web-server $*